@@ -10,12 +10,16 @@ import (
 	"iot-backend-room-monitoring/internal/config"
 	"iot-backend-room-monitoring/internal/database"
 	"iot-backend-room-monitoring/internal/handler"
+	"iot-backend-room-monitoring/internal/metrics"
 	"iot-backend-room-monitoring/internal/middleware"
+	"iot-backend-room-monitoring/internal/models"
 	"iot-backend-room-monitoring/internal/repository"
 	"iot-backend-room-monitoring/internal/service"
 	"iot-backend-room-monitoring/pkg/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
@@ -31,8 +35,14 @@ func main() {
 		cfg.JWT.RefreshTokenExpiry,
 	)
 
+	// Initialize device API key hashing
+	utils.InitAPIKeyHasher(cfg.APIKey.HMACSecret)
+
 	// 3. Initialize database connection
 	db := database.Connect(cfg)
+	if err := metrics.InstrumentDB(db); err != nil {
+		log.Fatalf("Failed to instrument database: %v", err)
+	}
 
 	// 4. Initialize repositories
 	userRepo := repository.NewUserRepo(db)
@@ -42,6 +52,17 @@ func main() {
 	roomRepo := repository.NewRoomRepo(db)
 	userHospitalRepo := repository.NewUserHospitalRepo(db)
 	apiKeyRepo := repository.NewDeviceAPIKeyRepo(db)
+	historyRepo := repository.NewTelemetryHistoryRepo(db)
+	rollupRepo := repository.NewTelemetryRollupRepo(db)
+	alarmRepo := repository.NewAlarmRepo(db)
+	deviceRepo := repository.NewDeviceRepo(db)
+	deviceConfigRepo := repository.NewDeviceConfigRepo(db)
+	calibrationRepo := repository.NewCalibrationRepo(db)
+	sensorSchemaRepo := repository.NewSensorSchemaRepo(db)
+	sessionRepo := repository.NewOperationSessionRepo(db)
+	countdownRepo := repository.NewCountdownRepo(db)
+	notificationRepo := repository.NewNotificationRepo(db)
+	webhookRepo := repository.NewWebhookRepo(db)
 
 	// Ensure live state exists for OT-01
 	if err := theaterRepo.CreateLiveStateIfNotExists("OT-01"); err != nil {
@@ -50,16 +71,44 @@ func main() {
 
 	// 5. Initialize services
 	authService := service.NewAuthService(userRepo, auditRepo)
-	theaterService := service.NewTheaterService(theaterRepo, auditRepo)
-	workerService := service.NewWorkerService(theaterRepo)
-	hospitalService := service.NewHospitalService(hospitalRepo, userHospitalRepo, auditRepo)
-	roomService := service.NewRoomService(roomRepo, hospitalRepo, userHospitalRepo, auditRepo, theaterRepo, apiKeyRepo)
-	esp32Service := service.NewESP32Service(theaterRepo, roomRepo)
+	stalenessService := service.NewStalenessService(roomRepo, cfg.Telemetry)
+	sensorSchemaService := service.NewSensorSchemaService(sensorSchemaRepo, roomRepo, auditRepo)
+	sessionService := service.NewOperationSessionService(sessionRepo, roomRepo, userHospitalRepo, auditRepo)
+	liveStateAnnotator := service.NewLiveStateAnnotator(stalenessService, sensorSchemaService, sessionService)
+	streamService := service.NewStreamService(theaterRepo, roomRepo, userRepo, userHospitalRepo, liveStateAnnotator)
+	webhookService := service.NewWebhookService(webhookRepo, roomRepo, auditRepo, cfg.Webhook)
+	notificationService := service.NewNotificationService(notificationRepo, userHospitalRepo, auditRepo, service.NewMailer(cfg.Notify), cfg.Notify)
+	countdownService := service.NewCountdownService(countdownRepo, theaterRepo, roomRepo, userHospitalRepo, auditRepo, streamService, notificationService, webhookService)
+	theaterService := service.NewTheaterService(theaterRepo, auditRepo, streamService, liveStateAnnotator, sessionService, countdownService, webhookService)
+	rollupService := service.NewRollupService(historyRepo, rollupRepo)
+	alarmService := service.NewAlarmService(alarmRepo, roomRepo, userHospitalRepo, auditRepo, webhookService, sensorSchemaService)
+	deviceService := service.NewDeviceService(deviceRepo, apiKeyRepo, roomRepo, userHospitalRepo, cfg.Device)
+	workerService := service.NewWorkerService(theaterRepo, rollupService, alarmService, streamService, deviceService, stalenessService, countdownService, webhookService, cfg.Worker)
+	hospitalService := service.NewHospitalService(hospitalRepo, userHospitalRepo, auditRepo, userRepo)
+	roomService := service.NewRoomService(roomRepo, hospitalRepo, userHospitalRepo, auditRepo, theaterRepo, apiKeyRepo, streamService)
+	clockSkewService := service.NewClockSkewService(cfg.Telemetry)
+	calibrationService := service.NewCalibrationService(calibrationRepo, roomRepo, deviceRepo, deviceConfigRepo, userHospitalRepo, auditRepo, deviceService)
+	esp32Service := service.NewESP32Service(theaterRepo, roomRepo, historyRepo, workerService, rollupService, clockSkewService, deviceService, calibrationService, sensorSchemaService)
+	telemetryHistoryService := service.NewTelemetryHistoryService(historyRepo, rollupRepo, roomRepo, userHospitalRepo, sensorSchemaService)
+	complianceReportService := service.NewComplianceReportService(historyRepo, sessionRepo, roomRepo, userHospitalRepo, alarmService, stalenessService)
+	fhirService := service.NewFHIRService(theaterRepo, historyRepo, roomRepo, hospitalRepo, userHospitalRepo, sensorSchemaService)
+	apiKeyService := service.NewDeviceAPIKeyService(apiKeyRepo, roomRepo, auditRepo)
+	deviceConfigService := service.NewDeviceConfigService(deviceConfigRepo, calibrationRepo, roomRepo, userHospitalRepo, auditRepo, sensorSchemaService)
+	mqttService := service.NewMQTTService(cfg.MQTT, esp32Service, apiKeyService, hospitalRepo, roomRepo)
 
 	// 6. Start background worker in goroutine
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go workerService.Start(ctx)
+	go apiKeyService.StartExpiryJob(ctx)
+	go webhookService.StartPublisher(ctx)
+	go webhookService.StartDeliveryJob(ctx)
+	go notificationService.StartDeliveryJob(ctx)
+
+	// Start MQTT telemetry ingestion only when a broker is configured
+	if cfg.MQTT.BrokerURL != "" {
+		go mqttService.Start(ctx)
+	}
 
 	// 7. Setup Gin mode
 	gin.SetMode(cfg.Server.GinMode)
@@ -76,6 +125,22 @@ func main() {
 	hospitalHandler := handler.NewHospitalHandler(hospitalService)
 	roomHandler := handler.NewRoomHandler(roomService)
 	esp32Handler := handler.NewESP32Handler(esp32Service)
+	telemetryHandler := handler.NewTelemetryHandler(telemetryHistoryService)
+	alarmHandler := handler.NewAlarmHandler(alarmService)
+	streamHandler := handler.NewStreamHandler(streamService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	deviceHandler := handler.NewDeviceHandler(deviceService)
+	deviceConfigHandler := handler.NewDeviceConfigHandler(deviceConfigService)
+	calibrationHandler := handler.NewCalibrationHandler(calibrationService)
+	sensorSchemaHandler := handler.NewSensorSchemaHandler(sensorSchemaService)
+	sessionHandler := handler.NewOperationSessionHandler(sessionService, complianceReportService)
+	countdownHandler := handler.NewCountdownHandler(countdownService)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	fhirHandler := handler.NewFHIRHandler(fhirService)
+
+	// Role permissions: super admins hold all of them, other users get them from their hospital roles
+	perm := middleware.NewPermissionMiddleware(userHospitalRepo, roomRepo)
 
 	// 10. Define routes
 	// Health check endpoint
@@ -86,6 +151,19 @@ func main() {
 		})
 	})
 
+	// Prometheus metrics: room live state gauges plus worker, ingestion and database internals
+	prometheus.MustRegister(metrics.NewRoomCollector(theaterRepo))
+	// Scraping needs the metrics token; serving them openly must be asked for explicitly
+	switch {
+	case cfg.Metrics.Token != "":
+		r.GET("/metrics", middleware.MetricsAuth(cfg.Metrics.Token), gin.WrapH(promhttp.Handler()))
+	case cfg.Metrics.Public:
+		log.Println("Warning: /metrics is served without authentication (METRICS_PUBLIC=true)")
+		r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	default:
+		log.Println("METRICS_TOKEN is not set, /metrics is disabled")
+	}
+
 	// Auth routes (public)
 	auth := r.Group("/auth")
 	{
@@ -97,33 +175,51 @@ func main() {
 
 	// Theater routes (authenticated)
 	theater := r.Group("/theater")
-	theater.Use(middleware.AuthMiddleware())
+	theater.Use(middleware.AuthMiddleware(userRepo))
 	{
 		theater.GET("/state", theaterHandler.GetState)       // Get single room state
 		theater.GET("/states", theaterHandler.GetAllStates)  // Get all room states
 		theater.GET("/rooms", theaterHandler.GetRooms)       // Get list of room names
 
-		// Admin-only routes
-		theater.POST("/timer/op", middleware.RequireAdmin(), theaterHandler.UpdateTimer)
-		theater.POST("/timer/cd", middleware.RequireAdmin(), theaterHandler.UpdateCountdownTimer)
-		theater.PATCH("/timer/cd/adjust", middleware.RequireAdmin(), theaterHandler.AdjustCountdownTimer)
+		// Legacy timer routes address rooms by name, outside any hospital, so only super admins may use them;
+		// hospital operators use the /api/v1/dashboard/rooms/:room_id timer routes
+		theater.POST("/timer/op", perm.Require(models.PermissionManageSystem), theaterHandler.UpdateTimer)
+		theater.POST("/timer/cd", perm.Require(models.PermissionManageSystem), theaterHandler.UpdateCountdownTimer)
+		theater.PATCH("/timer/cd/adjust", perm.Require(models.PermissionManageSystem), theaterHandler.AdjustCountdownTimer)
 	}
 
 	// API v1 routes
 	api := r.Group("/api/v1")
-	api.Use(middleware.AuthMiddleware())
+	api.Use(middleware.AuthMiddleware(userRepo))
 	{
+		// User accounts; users set their own notification email, super admins anyone's and grant the super admin role
+		users := api.Group("/users")
+		{
+			users.GET("/me", authHandler.GetMe)
+			users.PUT("/me/email", authHandler.UpdateMyEmail)
+			users.PUT("/:id/email", perm.Require(models.PermissionManageSystem), authHandler.UpdateUserEmail)
+			users.PUT("/:id/role", perm.Require(models.PermissionManageSystem), authHandler.UpdateUserRole)
+		}
+
 		// Hospital Management
 		hospitals := api.Group("/hospitals")
 		{
 			hospitals.GET("", hospitalHandler.GetAllHospitals)     // List hospitals (filtered by user access)
 			hospitals.GET("/:id", hospitalHandler.GetHospital)     // Get hospital details
 			hospitals.GET("/:id/rooms", roomHandler.GetRoomsByHospital) // Get rooms in hospital
+			hospitals.GET("/:id/devices", deviceHandler.GetHospitalDevices) // Device health in hospital
+			hospitals.GET("/:id/utilization", sessionHandler.GetHospitalUtilization) // Operation stopwatch use per room
+			hospitals.GET("/:id/fhir/live", fhirHandler.GetHospitalLive)             // FHIR R4 bundle of every room's latest readings
+
+			// Creating and deleting hospitals is super admin only; hospital admins manage their own hospital
+			hospitals.POST("", perm.Require(models.PermissionManageSystem), hospitalHandler.CreateHospital)
+			hospitals.PUT("/:id", perm.RequireForHospital(models.PermissionManageHospital, "id"), hospitalHandler.UpdateHospital)
+			hospitals.DELETE("/:id", perm.Require(models.PermissionManageSystem), hospitalHandler.DeleteHospital)
 
-			// Admin-only operations
-			hospitals.POST("", middleware.RequireAdmin(), hospitalHandler.CreateHospital)
-			hospitals.PUT("/:id", middleware.RequireAdmin(), hospitalHandler.UpdateHospital)
-			hospitals.DELETE("/:id", middleware.RequireAdmin(), hospitalHandler.DeleteHospital)
+			// Users of the hospital and their hospital roles
+			hospitals.GET("/:id/users", perm.RequireForHospital(models.PermissionManageHospital, "id"), hospitalHandler.GetHospitalUsers)
+			hospitals.PUT("/:id/users/:user_id", perm.RequireForHospital(models.PermissionManageHospital, "id"), hospitalHandler.SetHospitalUserRole)
+			hospitals.DELETE("/:id/users/:user_id", perm.RequireForHospital(models.PermissionManageHospital, "id"), hospitalHandler.RemoveHospitalUser)
 		}
 
 		// Room Management
@@ -131,22 +227,131 @@ func main() {
 		{
 			rooms.GET("", roomHandler.GetAllRooms)             // List all rooms (filtered by user access)
 			rooms.GET("/:id", roomHandler.GetRoom)             // Get room details
+			rooms.GET("/:id/telemetry/history", telemetryHandler.GetTelemetryHistory) // Historical readings
+			rooms.GET("/:id/devices", deviceHandler.GetRoomDevices)                    // Device health in room
+			rooms.GET("/:id/device-config", deviceConfigHandler.GetRoomDeviceConfig)  // Config pushed to the room's device
+			rooms.GET("/:id/commands", deviceConfigHandler.GetRoomCommands)           // Recent device commands
+			rooms.GET("/:id/calibration", calibrationHandler.GetRoomCalibration)      // Calibration profiles
+			rooms.GET("/:id/utilization", sessionHandler.GetRoomUtilization)          // Operation stopwatch use
+			rooms.GET("/:id/fhir/live", fhirHandler.GetRoomLive)                      // FHIR R4 bundle of the latest readings
+			rooms.GET("/:id/fhir/history", fhirHandler.GetRoomHistory)                // FHIR R4 bundle of historical readings
+
+			// Room management (hospital admins of the room's hospital; the service checks the hospital of a new room)
+			rooms.POST("", perm.RequireInAnyHospital(models.PermissionManageRooms), roomHandler.CreateRoom)
+			rooms.PUT("/:id", perm.RequireForRoom(models.PermissionManageRooms, "id"), roomHandler.UpdateRoom)
+			rooms.DELETE("/:id", perm.RequireForRoom(models.PermissionManageRooms, "id"), roomHandler.DeleteRoom)
+			rooms.PUT("/:id/device-config", perm.RequireForRoom(models.PermissionManageRooms, "id"), deviceConfigHandler.UpdateRoomDeviceConfig)
+			rooms.POST("/:id/commands", perm.RequireForRoom(models.PermissionManageRooms, "id"), deviceConfigHandler.CreateRoomCommand)
+
+			// Calibration applied at ingestion
+			rooms.PUT("/:id/calibration", perm.RequireForRoom(models.PermissionManageRooms, "id"), calibrationHandler.SaveRoomCalibration)
+			rooms.DELETE("/:id/calibration", perm.RequireForRoom(models.PermissionManageRooms, "id"), calibrationHandler.DeleteRoomCalibration)
+			rooms.PUT("/:id/devices/:device_id/calibration", perm.RequireForRoom(models.PermissionManageRooms, "id"), calibrationHandler.SaveDeviceCalibration)
+			rooms.DELETE("/:id/devices/:device_id/calibration", perm.RequireForRoom(models.PermissionManageRooms, "id"), calibrationHandler.DeleteDeviceCalibration)
+
+			// Device API key management
+			rooms.GET("/:id/api-keys", perm.RequireForRoom(models.PermissionManageRooms, "id"), apiKeyHandler.GetAPIKeys)
+			rooms.POST("/:id/api-keys", perm.RequireForRoom(models.PermissionManageRooms, "id"), apiKeyHandler.CreateAPIKey)
+			rooms.POST("/:id/api-keys/:key_id/rotate", perm.RequireForRoom(models.PermissionManageRooms, "id"), apiKeyHandler.RotateAPIKey)
+			rooms.POST("/:id/api-keys/:key_id/revoke", perm.RequireForRoom(models.PermissionManageRooms, "id"), apiKeyHandler.RevokeAPIKey)
+			rooms.DELETE("/:id/api-keys/:key_id", perm.RequireForRoom(models.PermissionManageRooms, "id"), apiKeyHandler.DeleteAPIKey)
+		}
+
+		// Alarms
+		alarms := api.Group("/alarms")
+		{
+			alarms.GET("", alarmHandler.GetAlarms) // List alarms (filtered by user access)
+			// Acknowledge a raised alarm (operators; the service checks the alarm's room)
+			alarms.POST("/:id/acknowledge", perm.RequireInAnyHospital(models.PermissionAckAlarms), alarmHandler.AcknowledgeAlarm)
+		}
+
+		// Alarm rule management (super admin only)
+		alarmRules := api.Group("/alarm-rules")
+		alarmRules.Use(perm.Require(models.PermissionManageSystem))
+		{
+			alarmRules.GET("", alarmHandler.GetAlarmRules)
+			alarmRules.POST("", alarmHandler.CreateAlarmRule)
+			alarmRules.PUT("/:id", alarmHandler.UpdateAlarmRule)
+			alarmRules.DELETE("/:id", alarmHandler.DeleteAlarmRule)
+		}
 
-			// Admin-only operations
-			rooms.POST("", middleware.RequireAdmin(), roomHandler.CreateRoom)
-			rooms.PUT("/:id", middleware.RequireAdmin(), roomHandler.UpdateRoom)
-			rooms.DELETE("/:id", middleware.RequireAdmin(), roomHandler.DeleteRoom)
+		// Operation sessions recorded from the operation stopwatch
+		sessions := api.Group("/operation-sessions")
+		{
+			sessions.GET("", sessionHandler.GetSessions) // List sessions (filtered by user access)
+			sessions.GET("/:id", sessionHandler.GetSession)
+			sessions.GET("/:id/compliance-report", sessionHandler.GetComplianceReport) // ?format=json|csv|pdf
+			sessions.PATCH("/:id", perm.RequireInAnyHospital(models.PermissionOperateTimers), sessionHandler.UpdateSession) // Service checks the session's room
+		}
+
+		// Countdown presets per room type (changes are super admin only)
+		countdownPresets := api.Group("/countdown-presets")
+		{
+			countdownPresets.GET("", countdownHandler.GetPresets) // ?room_type=
+			countdownPresets.POST("", perm.Require(models.PermissionManageSystem), countdownHandler.CreatePreset)
+			countdownPresets.PUT("/:id", perm.Require(models.PermissionManageSystem), countdownHandler.UpdatePreset)
+			countdownPresets.DELETE("/:id", perm.Require(models.PermissionManageSystem), countdownHandler.DeletePreset)
 		}
 
+		// Countdowns scheduled in advance, started by the background worker
+		countdownSchedules := api.Group("/countdown-schedules")
+		{
+			countdownSchedules.GET("", countdownHandler.GetSchedules) // List schedules (filtered by user access)
+			countdownSchedules.POST("", perm.RequireInAnyHospital(models.PermissionOperateTimers), countdownHandler.CreateSchedule)             // Service checks the room
+			countdownSchedules.POST("/:id/cancel", perm.RequireInAnyHospital(models.PermissionOperateTimers), countdownHandler.CancelSchedule) // Service checks the room
+		}
+		api.GET("/countdown-events", countdownHandler.GetEvents) // Countdown start/completion/expiry log
+
+		// Notification channels for room events (super admin only)
+		notificationChannels := api.Group("/notification-channels")
+		notificationChannels.Use(perm.Require(models.PermissionManageSystem))
+		{
+			notificationChannels.GET("", notificationHandler.GetChannels)
+			notificationChannels.POST("", notificationHandler.CreateChannel)
+			notificationChannels.PUT("/:id", notificationHandler.UpdateChannel)
+			notificationChannels.DELETE("/:id", notificationHandler.DeleteChannel)
+			notificationChannels.POST("/:id/test", notificationHandler.TestChannel)
+		}
+
+		// Outbound webhooks for the hospital integration engine (super admin only)
+		webhooks := api.Group("/webhooks")
+		webhooks.Use(perm.Require(models.PermissionManageSystem))
+		{
+			webhooks.GET("", webhookHandler.GetSubscriptions)
+			webhooks.POST("", webhookHandler.CreateSubscription)
+			webhooks.PUT("/:id", webhookHandler.UpdateSubscription)
+			webhooks.DELETE("/:id", webhookHandler.DeleteSubscription)
+			webhooks.POST("/:id/rotate-secret", webhookHandler.RotateSecret)
+		}
+
+		webhookDeliveries := api.Group("/webhook-deliveries")
+		webhookDeliveries.Use(perm.Require(models.PermissionManageSystem))
+		{
+			webhookDeliveries.GET("", webhookHandler.GetDeliveries)
+			webhookDeliveries.POST("/:id/retry", webhookHandler.RetryDelivery)
+		}
+
+		// Sensor schemas per room type (changes are super admin only)
+		sensorSchemas := api.Group("/sensor-schemas")
+		{
+			sensorSchemas.GET("", sensorSchemaHandler.GetSensorSchemas)
+			sensorSchemas.GET("/:room_type", sensorSchemaHandler.GetSensorSchema)
+			sensorSchemas.PUT("/:room_type", perm.Require(models.PermissionManageSystem), sensorSchemaHandler.SaveSensorSchema)
+			sensorSchemas.DELETE("/:room_type", perm.Require(models.PermissionManageSystem), sensorSchemaHandler.DeleteSensorSchema)
+		}
+
+		// Live state streaming (Server-Sent Events, filtered by user access)
+		api.GET("/stream/live-states", streamHandler.StreamLiveStates)
+
 		// Dashboard endpoints
 		dashboard := api.Group("/dashboard")
 		{
 			dashboard.GET("/rooms/:room_id", theaterHandler.GetRoomDashboard)
 			
-			// Admin-only timer operations by room_id
-			dashboard.POST("/rooms/:room_id/timer/op", middleware.RequireAdmin(), theaterHandler.UpdateTimerByRoomID)
-			dashboard.POST("/rooms/:room_id/timer/cd", middleware.RequireAdmin(), theaterHandler.UpdateCountdownTimerByRoomID)
-			dashboard.PATCH("/rooms/:room_id/timer/cd/adjust", middleware.RequireAdmin(), theaterHandler.AdjustCountdownTimerByRoomID)
+			// Timer operations by room_id (operators of the room's hospital)
+			dashboard.POST("/rooms/:room_id/timer/op", perm.RequireForRoom(models.PermissionOperateTimers, "room_id"), theaterHandler.UpdateTimerByRoomID)
+			dashboard.POST("/rooms/:room_id/timer/cd", perm.RequireForRoom(models.PermissionOperateTimers, "room_id"), theaterHandler.UpdateCountdownTimerByRoomID)
+			dashboard.PATCH("/rooms/:room_id/timer/cd/adjust", perm.RequireForRoom(models.PermissionOperateTimers, "room_id"), theaterHandler.AdjustCountdownTimerByRoomID)
 		}
 	}
 
@@ -154,8 +359,14 @@ func main() {
 	esp32 := r.Group("/api/v1/esp32")
 	{
 		// Telemetry endpoint - requires API key in X-API-Key header
-		esp32.POST("/telemetry/:room_id", middleware.APIKeyAuthMiddleware(apiKeyRepo), esp32Handler.UpdateTelemetry)
-		esp32.GET("/telemetry/:room_id", middleware.APIKeyAuthMiddleware(apiKeyRepo), esp32Handler.GetTelemetry)
+		esp32.POST("/telemetry/:room_id", middleware.APIKeyAuthMiddleware(apiKeyService), esp32Handler.UpdateTelemetry)
+		esp32.POST("/telemetry/:room_id/batch", middleware.APIKeyAuthMiddleware(apiKeyService), esp32Handler.UpdateTelemetryBatch)
+		esp32.GET("/telemetry/:room_id", middleware.APIKeyAuthMiddleware(apiKeyService), esp32Handler.GetTelemetry)
+
+		// Remote configuration and commands
+		esp32.GET("/config/:room_id", middleware.APIKeyAuthMiddleware(apiKeyService), deviceConfigHandler.GetDeviceConfig)
+		esp32.GET("/commands/:room_id", middleware.APIKeyAuthMiddleware(apiKeyService), deviceConfigHandler.GetDeviceCommands)
+		esp32.POST("/commands/:room_id/:command_id/ack", middleware.APIKeyAuthMiddleware(apiKeyService), deviceConfigHandler.AcknowledgeDeviceCommand)
 	}
 
 	// 11. Setup graceful shutdown
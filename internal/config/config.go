@@ -3,16 +3,25 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Server   ServerConfig
-	CORS     CORSConfig
+	Database  DatabaseConfig
+	JWT       JWTConfig
+	APIKey    APIKeyConfig
+	Server    ServerConfig
+	CORS      CORSConfig
+	Worker    WorkerConfig
+	MQTT      MQTTConfig
+	Telemetry TelemetryConfig
+	Device    DeviceConfig
+	Notify    NotificationConfig
+	Webhook   WebhookConfig
+	Metrics   MetricsConfig
 }
 
 type DatabaseConfig struct {
@@ -30,6 +39,10 @@ type JWTConfig struct {
 	RefreshTokenExpiry time.Duration
 }
 
+type APIKeyConfig struct {
+	HMACSecret string // Server-side secret for hashing device API keys; changing it invalidates all non-legacy keys
+}
+
 type ServerConfig struct {
 	Port    string
 	GinMode string
@@ -39,6 +52,57 @@ type CORSConfig struct {
 	AllowedOrigins []string
 }
 
+type WorkerConfig struct {
+	Shards            int           // Number of goroutines processing telemetry
+	QueueSize         int           // Buffered readings per shard before falling back to reconciliation
+	ReconcileInterval time.Duration // How often the fallback poll scans all rooms
+}
+
+type MQTTConfig struct {
+	BrokerURL    string // e.g. tcp://localhost:1883; empty disables MQTT ingestion
+	ClientID     string
+	Username     string
+	Password     string
+	TopicPattern string // {code} and {room_code} are matched against the hospital and room codes
+	QoS          byte
+	Workers      int // Goroutines processing messages; each topic always goes to the same one
+	QueueSize    int // Buffered messages per worker before the client stops reading from the broker
+}
+
+type TelemetryConfig struct {
+	ClockSkewTolerance time.Duration // Device timestamps further than this from receive time are flagged and not used for timing
+	StaleAfter         time.Duration // Default age after which a room's live state is stale; rooms may override it
+}
+
+type DeviceConfig struct {
+	OfflineAfter time.Duration // Devices silent for longer than this are marked offline
+}
+
+type NotificationConfig struct {
+	SMTPHost       string // Empty logs emails instead of sending them
+	SMTPPort       string
+	SMTPUsername   string
+	SMTPPassword   string
+	SMTPFrom       string
+	MaxAttempts    int           // A delivery is marked failed after this many attempts
+	RetryBaseDelay time.Duration // Delay after the first failed attempt, doubled after each further failure
+	RetryMaxDelay  time.Duration // Upper bound of the retry delay
+	Retention      time.Duration // Delivered and failed deliveries are deleted after this long; 0 keeps them
+}
+
+type WebhookConfig struct {
+	Timeout        time.Duration // Per delivery attempt
+	MaxAttempts    int           // A delivery is marked failed after this many attempts
+	RetryBaseDelay time.Duration // Delay after the first failed attempt, doubled after each further failure
+	RetryMaxDelay  time.Duration // Upper bound of the retry delay
+	Retention      time.Duration // Delivered and failed deliveries are deleted after this long; 0 keeps them
+}
+
+type MetricsConfig struct {
+	Token  string // Bearer token required to scrape /metrics
+	Public bool   // Serve /metrics without a token, for scrapers on a private network; ignored when a token is set
+}
+
 func LoadConfig() *Config {
 	// Load .env file if it exists
 	_ = godotenv.Load()
@@ -57,6 +121,9 @@ func LoadConfig() *Config {
 			AccessTokenExpiry:  parseDuration(getEnv("ACCESS_TOKEN_EXPIRY", "15m")),
 			RefreshTokenExpiry: parseDuration(getEnv("REFRESH_TOKEN_EXPIRY", "168h")),
 		},
+		APIKey: APIKeyConfig{
+			HMACSecret: getEnv("API_KEY_HMAC_SECRET", "your-api-key-hmac-secret"),
+		},
 		Server: ServerConfig{
 			Port:    getEnv("PORT", "8080"),
 			GinMode: getEnv("GIN_MODE", "debug"),
@@ -64,6 +131,50 @@ func LoadConfig() *Config {
 		CORS: CORSConfig{
 			AllowedOrigins: parseOrigins(getEnv("ALLOWED_ORIGINS", "http://localhost:3000,http://localhost:5173")),
 		},
+		Worker: WorkerConfig{
+			Shards:            parseInt(getEnv("WORKER_SHARDS", "4"), 4),
+			QueueSize:         parseInt(getEnv("WORKER_QUEUE_SIZE", "256"), 256),
+			ReconcileInterval: parsePositiveDuration(getEnv("WORKER_RECONCILE_INTERVAL", "30s"), 30*time.Second),
+		},
+		MQTT: MQTTConfig{
+			BrokerURL:    getEnv("MQTT_BROKER_URL", ""),
+			ClientID:     getEnv("MQTT_CLIENT_ID", "iot-backend-room-monitoring"),
+			Username:     getEnv("MQTT_USERNAME", ""),
+			Password:     getEnv("MQTT_PASSWORD", ""),
+			TopicPattern: getEnv("MQTT_TOPIC_PATTERN", "hospitals/{code}/rooms/{room_code}/telemetry"),
+			QoS:          parseQoS(getEnv("MQTT_QOS", "1")),
+			Workers:      parseInt(getEnv("MQTT_WORKERS", "4"), 4),
+			QueueSize:    parseInt(getEnv("MQTT_QUEUE_SIZE", "256"), 256),
+		},
+		Telemetry: TelemetryConfig{
+			ClockSkewTolerance: parseDuration(getEnv("TELEMETRY_CLOCK_SKEW_TOLERANCE", "2s")),
+			StaleAfter:         parseDuration(getEnv("TELEMETRY_STALE_AFTER", "60s")),
+		},
+		Device: DeviceConfig{
+			OfflineAfter: parseDuration(getEnv("DEVICE_OFFLINE_AFTER", "2m")),
+		},
+		Notify: NotificationConfig{
+			SMTPHost:       getEnv("SMTP_HOST", ""),
+			SMTPPort:       getEnv("SMTP_PORT", "587"),
+			SMTPUsername:   getEnv("SMTP_USERNAME", ""),
+			SMTPPassword:   getEnv("SMTP_PASSWORD", ""),
+			SMTPFrom:       getEnv("SMTP_FROM", "noreply@room-monitoring.local"),
+			MaxAttempts:    parseInt(getEnv("NOTIFY_MAX_ATTEMPTS", "6"), 6),
+			RetryBaseDelay: parseDuration(getEnv("NOTIFY_RETRY_BASE_DELAY", "30s")),
+			RetryMaxDelay:  parseDuration(getEnv("NOTIFY_RETRY_MAX_DELAY", "15m")),
+			Retention:      parseDuration(getEnv("NOTIFY_DELIVERY_RETENTION", "720h")),
+		},
+		Webhook: WebhookConfig{
+			Timeout:        parseDuration(getEnv("WEBHOOK_TIMEOUT", "10s")),
+			MaxAttempts:    parseInt(getEnv("WEBHOOK_MAX_ATTEMPTS", "8"), 8),
+			RetryBaseDelay: parseDuration(getEnv("WEBHOOK_RETRY_BASE_DELAY", "30s")),
+			RetryMaxDelay:  parseDuration(getEnv("WEBHOOK_RETRY_MAX_DELAY", "1h")),
+			Retention:      parseDuration(getEnv("WEBHOOK_DELIVERY_RETENTION", "720h")),
+		},
+		Metrics: MetricsConfig{
+			Token:  getEnv("METRICS_TOKEN", ""),
+			Public: getEnv("METRICS_PUBLIC", "false") == "true",
+		},
 	}
 
 	return config
@@ -85,6 +196,38 @@ func parseDuration(s string) time.Duration {
 	return duration
 }
 
+// parsePositiveDuration parses a duration that must be above zero, such as a ticker interval
+func parsePositiveDuration(s string, defaultValue time.Duration) time.Duration {
+	duration, err := time.ParseDuration(s)
+	if err != nil || duration <= 0 {
+		fmt.Printf("Warning: Invalid duration '%s', using default %s\n", s, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+func parseInt(s string, defaultValue int) int {
+	value, err := strconv.Atoi(s)
+	if err != nil || value <= 0 {
+		fmt.Printf("Warning: Invalid integer '%s', using default %d\n", s, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+func parseQoS(s string) byte {
+	switch s {
+	case "0":
+		return 0
+	case "1":
+		return 1
+	case "2":
+		return 2
+	}
+	fmt.Printf("Warning: Invalid MQTT QoS '%s', using default 1\n", s)
+	return 1
+}
+
 func parseOrigins(s string) []string {
 	if s == "" {
 		return []string{}
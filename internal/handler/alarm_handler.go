@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+	"iot-backend-room-monitoring/internal/service"
+	"iot-backend-room-monitoring/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AlarmHandler struct {
+	alarmService *service.AlarmService
+}
+
+func NewAlarmHandler(alarmService *service.AlarmService) *AlarmHandler {
+	return &AlarmHandler{
+		alarmService: alarmService,
+	}
+}
+
+// AcknowledgeAlarmRequest represents the request body for acknowledging an alarm
+type AcknowledgeAlarmRequest struct {
+	Note string `json:"note" binding:"max=255"`
+}
+
+// GetAlarms lists alarms visible to the user
+// GET /api/v1/alarms?room_id=&state=&active=&page=&limit=
+func (h *AlarmHandler) GetAlarms(c *gin.Context) {
+	filter := repository.AlarmFilter{}
+
+	if roomIDStr := c.Query("room_id"); roomIDStr != "" {
+		roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+			return
+		}
+		id := uint(roomID)
+		filter.RoomID = &id
+	}
+
+	filter.State = c.Query("state")
+	if filter.State != "" && filter.State != models.AlarmStateRaised &&
+		filter.State != models.AlarmStateAcknowledged && filter.State != models.AlarmStateCleared {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid state. Must be 'raised', 'acknowledged', or 'cleared'")
+		return
+	}
+	filter.ActiveOnly = c.Query("active") == "true"
+
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
+
+	// Get user info from context
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	alarms, total, err := h.alarmService.GetAlarms(filter, userID.(uint), role.(string))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch alarms")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"alarms": alarms,
+		"count":  len(alarms),
+		"total":  total,
+		"page":   page,
+		"limit":  limit,
+	})
+}
+
+// AcknowledgeAlarm acknowledges a raised alarm
+// POST /api/v1/alarms/:id/acknowledge
+func (h *AlarmHandler) AcknowledgeAlarm(c *gin.Context) {
+	alarmID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid alarm ID")
+		return
+	}
+
+	// Note is optional, so an empty body is allowed
+	var req AcknowledgeAlarmRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+			return
+		}
+	}
+
+	// Get user info from context
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	alarm, err := h.alarmService.AcknowledgeAlarm(uint(alarmID), req.Note, userID.(uint), role.(string))
+	if err != nil {
+		if err.Error() == "alarm not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		} else if strings.HasPrefix(err.Error(), "access denied") {
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+		} else if strings.HasPrefix(err.Error(), "failed to") {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to acknowledge alarm")
+		} else {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "Alarm acknowledged successfully",
+		"alarm":   alarm,
+	})
+}
+
+// GetAlarmRules lists alarm rules (admin only)
+// GET /api/v1/alarm-rules?room_id=&room_type=
+func (h *AlarmHandler) GetAlarmRules(c *gin.Context) {
+	var roomID *uint
+	if roomIDStr := c.Query("room_id"); roomIDStr != "" {
+		id, err := strconv.ParseUint(roomIDStr, 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+			return
+		}
+		value := uint(id)
+		roomID = &value
+	}
+
+	rules, err := h.alarmService.GetAlarmRules(roomID, c.Query("room_type"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch alarm rules")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"rules": rules,
+		"count": len(rules),
+	})
+}
+
+// CreateAlarmRule creates a new alarm rule (admin only)
+// POST /api/v1/alarm-rules
+func (h *AlarmHandler) CreateAlarmRule(c *gin.Context) {
+	rule := models.AlarmRule{IsActive: true}
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	rule.ID = 0
+
+	// Get user ID from context
+	userID, _ := c.Get("userID")
+
+	if err := h.alarmService.CreateAlarmRule(&rule, userID.(uint)); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "Alarm rule created successfully",
+		"rule":    rule,
+	})
+}
+
+// UpdateAlarmRule updates an existing alarm rule (admin only)
+// PUT /api/v1/alarm-rules/:id
+func (h *AlarmHandler) UpdateAlarmRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid alarm rule ID")
+		return
+	}
+
+	var rule models.AlarmRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	// Set the ID from path parameter
+	rule.ID = uint(id)
+
+	// Get user ID from context
+	userID, _ := c.Get("userID")
+
+	if err := h.alarmService.UpdateAlarmRule(&rule, userID.(uint)); err != nil {
+		if err.Error() == "alarm rule not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		} else {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "Alarm rule updated successfully",
+		"rule":    rule,
+	})
+}
+
+// DeleteAlarmRule deletes an alarm rule (admin only)
+// DELETE /api/v1/alarm-rules/:id
+func (h *AlarmHandler) DeleteAlarmRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid alarm rule ID")
+		return
+	}
+
+	// Get user ID from context
+	userID, _ := c.Get("userID")
+
+	if err := h.alarmService.DeleteAlarmRule(uint(id), userID.(uint)); err != nil {
+		if err.Error() == "alarm rule not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete alarm rule")
+		}
+		return
+	}
+
+	utils.MessageResponse(c, "Alarm rule deleted successfully")
+}
+
+// parsePagination reads page and limit query parameters, writing an error response when invalid
+// Defaults to page 1 with 50 items, capped at 500 items per page
+func parsePagination(c *gin.Context) (page int, limit int, ok bool) {
+	page, limit = 1, 50
+	var err error
+
+	if pageStr := c.Query("page"); pageStr != "" {
+		page, err = strconv.Atoi(pageStr)
+		if err != nil || page < 1 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid page")
+			return 0, 0, false
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid limit")
+			return 0, 0, false
+		}
+	}
+	if limit > 500 {
+		limit = 500
+	}
+	return page, limit, true
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"iot-backend-room-monitoring/internal/service"
+	"iot-backend-room-monitoring/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *service.DeviceAPIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService *service.DeviceAPIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateAPIKeyRequest is the request body for generating a device API key
+type CreateAPIKeyRequest struct {
+	Description string     `json:"description"`
+	ExpiresAt   *time.Time `json:"expires_at"` // Optional, RFC3339; omit for a key that never expires
+}
+
+// RotateAPIKeyRequest is the request body for rotating a device API key
+type RotateAPIKeyRequest struct {
+	OverlapMinutes *int   `json:"overlap_minutes"` // How long the old key keeps working, default 24 hours
+	Description    string `json:"description"`     // Defaults to the old key's description
+}
+
+// defaultRotationOverlapMinutes is used when a rotation request doesn't specify an overlap
+const defaultRotationOverlapMinutes = 24 * 60
+
+// GetAPIKeys lists the API keys of a room (hashes are never returned)
+// GET /api/v1/rooms/:id/api-keys
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	keys, err := h.apiKeyService.GetAPIKeysByRoomID(uint(roomID), userID.(uint))
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "room not found") {
+			utils.ErrorResponse(c, http.StatusNotFound, "room not found")
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch API keys")
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"api_keys": keys,
+		"count":    len(keys),
+	})
+}
+
+// CreateAPIKey generates a new API key for a room
+// POST /api/v1/rooms/:id/api-keys
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	uid := userID.(uint)
+
+	key, err := h.apiKeyService.GenerateAPIKey(uint(roomID), strings.TrimSpace(req.Description), req.ExpiresAt, &uid)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "room not found") {
+			utils.ErrorResponse(c, http.StatusNotFound, "room not found")
+		} else if strings.HasPrefix(err.Error(), "failed to") {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		} else {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "API key created. It is shown only once - please save it securely.",
+		"api_key": key,
+	})
+}
+
+// RotateAPIKey issues a successor key; the old key keeps working until the overlap window ends
+// POST /api/v1/rooms/:id/api-keys/:key_id/rotate
+func (h *APIKeyHandler) RotateAPIKey(c *gin.Context) {
+	roomID, keyID, ok := parseAPIKeyParams(c)
+	if !ok {
+		return
+	}
+
+	var req RotateAPIKeyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+			return
+		}
+	}
+	overlapMinutes := defaultRotationOverlapMinutes
+	if req.OverlapMinutes != nil {
+		overlapMinutes = *req.OverlapMinutes
+	}
+
+	userID, _ := c.Get("userID")
+
+	key, err := h.apiKeyService.RotateAPIKey(roomID, keyID, time.Duration(overlapMinutes)*time.Minute,
+		strings.TrimSpace(req.Description), userID.(uint))
+	if err != nil {
+		if err.Error() == "API key not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		} else if strings.HasPrefix(err.Error(), "failed to") {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		} else {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "API key rotated. The new key is shown only once - please save it securely.",
+		"api_key": key,
+	})
+}
+
+// RevokeAPIKey deactivates an API key so devices using it are rejected
+// POST /api/v1/rooms/:id/api-keys/:key_id/revoke
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	roomID, keyID, ok := parseAPIKeyParams(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	if err := h.apiKeyService.RevokeAPIKey(roomID, keyID, userID.(uint)); err != nil {
+		if err.Error() == "API key not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke API key")
+		}
+		return
+	}
+
+	utils.MessageResponse(c, "API key revoked successfully")
+}
+
+// DeleteAPIKey permanently deletes an API key
+// DELETE /api/v1/rooms/:id/api-keys/:key_id
+func (h *APIKeyHandler) DeleteAPIKey(c *gin.Context) {
+	roomID, keyID, ok := parseAPIKeyParams(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	if err := h.apiKeyService.DeleteAPIKey(roomID, keyID, userID.(uint)); err != nil {
+		if err.Error() == "API key not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete API key")
+		}
+		return
+	}
+
+	utils.MessageResponse(c, "API key deleted successfully")
+}
+
+// parseAPIKeyParams reads the room and key IDs from the URL, writing an error response if invalid
+func parseAPIKeyParams(c *gin.Context) (uint, uint, bool) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+		return 0, 0, false
+	}
+	keyID, err := strconv.ParseUint(c.Param("key_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid API key ID")
+		return 0, 0, false
+	}
+	return uint(roomID), uint(keyID), true
+}
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"iot-backend-room-monitoring/internal/service"
@@ -28,7 +30,15 @@ type LoginRequest struct {
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
 	Password string `json:"password" binding:"required,min=6"`
-	Role     string `json:"role" binding:"omitempty,oneof=admin user"`
+	Email    string `json:"email" binding:"omitempty,email,max=255"` // Optional, receives hospital notifications
+}
+
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin user"`
+}
+
+type UpdateEmailRequest struct {
+	Email string `json:"email" binding:"omitempty,email,max=255"` // Empty stops email notifications
 }
 
 // Login handles user authentication
@@ -116,13 +126,8 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Set default role if not specified
-	if req.Role == "" {
-		req.Role = "user"
-	}
-
-	// Register user
-	response, err := h.authService.Register(req.Username, req.Password, req.Role)
+	// Register user; new accounts are always regular users
+	response, err := h.authService.Register(req.Username, req.Password, req.Email)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
@@ -145,3 +150,85 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		"user":         response.User,
 	})
 }
+
+// GetMe returns the authenticated user's account details
+func (h *AuthHandler) GetMe(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	user, err := h.authService.GetUser(userID.(uint))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, user)
+}
+
+// UpdateMyEmail sets the authenticated user's notification email
+func (h *AuthHandler) UpdateMyEmail(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	h.updateEmail(c, userID.(uint))
+}
+
+// UpdateUserEmail sets any user's notification email (super admin only)
+func (h *AuthHandler) UpdateUserEmail(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	h.updateEmail(c, uint(id))
+}
+
+func (h *AuthHandler) updateEmail(c *gin.Context, targetUserID uint) {
+	var req UpdateEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	user, err := h.authService.UpdateEmail(targetUserID, req.Email, userID.(uint))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "user not found" {
+			status = http.StatusNotFound
+		}
+		utils.ErrorResponse(c, status, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, user)
+}
+
+// UpdateUserRole promotes a user to super admin or demotes one (super admin only)
+func (h *AuthHandler) UpdateUserRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	user, err := h.authService.SetRole(uint(id), req.Role, userID.(uint))
+	if err != nil {
+		status := http.StatusBadRequest
+		if err.Error() == "user not found" {
+			status = http.StatusNotFound
+		} else if strings.HasPrefix(err.Error(), "failed to") {
+			status = http.StatusInternalServerError
+		}
+		utils.ErrorResponse(c, status, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, user)
+}
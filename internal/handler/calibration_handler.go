@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"iot-backend-room-monitoring/internal/service"
+	"iot-backend-room-monitoring/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CalibrationHandler struct {
+	calibrationService *service.CalibrationService
+}
+
+func NewCalibrationHandler(calibrationService *service.CalibrationService) *CalibrationHandler {
+	return &CalibrationHandler{
+		calibrationService: calibrationService,
+	}
+}
+
+// GetRoomCalibration lists the calibration profiles of a room and the storage unit of each field
+// GET /api/v1/rooms/:id/calibration
+func (h *CalibrationHandler) GetRoomCalibration(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	calibration, err := h.calibrationService.GetRoomCalibration(uint(roomID), userID.(uint), role.(string))
+	if err != nil {
+		respondCalibrationError(c, err, "Failed to fetch calibration profiles")
+		return
+	}
+
+	utils.SuccessResponse(c, calibration)
+}
+
+// SaveRoomCalibration creates or replaces the calibration profile of a room (hospital admins of the room's hospital)
+// PUT /api/v1/rooms/:id/calibration
+func (h *CalibrationHandler) SaveRoomCalibration(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+	h.saveProfile(c, uint(roomID), nil)
+}
+
+// DeleteRoomCalibration removes the calibration profile of a room (hospital admins of the room's hospital)
+// DELETE /api/v1/rooms/:id/calibration
+func (h *CalibrationHandler) DeleteRoomCalibration(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+	h.deleteProfile(c, uint(roomID), nil)
+}
+
+// SaveDeviceCalibration creates or replaces the calibration profile of one device in a room (hospital admins of the room's hospital)
+// PUT /api/v1/rooms/:id/devices/:device_id/calibration
+func (h *CalibrationHandler) SaveDeviceCalibration(c *gin.Context) {
+	roomID, deviceID, ok := parseDeviceParams(c)
+	if !ok {
+		return
+	}
+	h.saveProfile(c, roomID, &deviceID)
+}
+
+// DeleteDeviceCalibration removes the calibration profile of one device in a room (hospital admins of the room's hospital)
+// DELETE /api/v1/rooms/:id/devices/:device_id/calibration
+func (h *CalibrationHandler) DeleteDeviceCalibration(c *gin.Context) {
+	roomID, deviceID, ok := parseDeviceParams(c)
+	if !ok {
+		return
+	}
+	h.deleteProfile(c, roomID, &deviceID)
+}
+
+// saveProfile binds the request body and saves a room or device profile
+func (h *CalibrationHandler) saveProfile(c *gin.Context, roomID uint, deviceID *uint) {
+	var req service.SaveCalibrationProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	profile, err := h.calibrationService.SaveProfile(roomID, deviceID, &req, userID.(uint))
+	if err != nil {
+		respondCalibrationError(c, err, "Failed to save calibration profile")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "Calibration profile saved successfully",
+		"profile": profile,
+	})
+}
+
+// deleteProfile deletes a room or device profile
+func (h *CalibrationHandler) deleteProfile(c *gin.Context, roomID uint, deviceID *uint) {
+	userID, _ := c.Get("userID")
+
+	if err := h.calibrationService.DeleteProfile(roomID, deviceID, userID.(uint)); err != nil {
+		respondCalibrationError(c, err, "Failed to delete calibration profile")
+		return
+	}
+
+	utils.MessageResponse(c, "Calibration profile deleted successfully")
+}
+
+// parseDeviceParams reads the room and device IDs from the URL, writing an error response if invalid
+func parseDeviceParams(c *gin.Context) (uint, uint, bool) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+		return 0, 0, false
+	}
+	deviceID, err := strconv.ParseUint(c.Param("device_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid device ID")
+		return 0, 0, false
+	}
+	return uint(roomID), uint(deviceID), true
+}
+
+// respondCalibrationError maps calibration service errors to HTTP responses
+func respondCalibrationError(c *gin.Context, err error, internalMessage string) {
+	switch {
+	case err.Error() == "room not found" || err.Error() == "device not found" ||
+		err.Error() == "calibration profile not found":
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case strings.HasPrefix(err.Error(), "access denied"):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	case strings.HasPrefix(err.Error(), "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, internalMessage)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	}
+}
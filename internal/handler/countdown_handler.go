@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+	"iot-backend-room-monitoring/internal/service"
+	"iot-backend-room-monitoring/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CountdownHandler struct {
+	countdownService *service.CountdownService
+}
+
+func NewCountdownHandler(countdownService *service.CountdownService) *CountdownHandler {
+	return &CountdownHandler{
+		countdownService: countdownService,
+	}
+}
+
+// GetPresets lists countdown presets
+// GET /api/v1/countdown-presets?room_type=
+func (h *CountdownHandler) GetPresets(c *gin.Context) {
+	presets, err := h.countdownService.GetPresets(c.Query("room_type"))
+	if err != nil {
+		respondCountdownError(c, err, "Failed to fetch countdown presets")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"presets": presets,
+		"count":   len(presets),
+	})
+}
+
+// CreatePreset creates a countdown preset for a room type (admin only)
+// POST /api/v1/countdown-presets
+func (h *CountdownHandler) CreatePreset(c *gin.Context) {
+	var req service.CountdownPresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	preset, err := h.countdownService.CreatePreset(&req, userID.(uint))
+	if err != nil {
+		respondCountdownError(c, err, "Failed to create countdown preset")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "Countdown preset created successfully",
+		"preset":  preset,
+	})
+}
+
+// UpdatePreset updates a countdown preset (admin only)
+// PUT /api/v1/countdown-presets/:id
+func (h *CountdownHandler) UpdatePreset(c *gin.Context) {
+	presetID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid countdown preset ID")
+		return
+	}
+
+	var req service.CountdownPresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	preset, err := h.countdownService.UpdatePreset(uint(presetID), &req, userID.(uint))
+	if err != nil {
+		respondCountdownError(c, err, "Failed to update countdown preset")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "Countdown preset updated successfully",
+		"preset":  preset,
+	})
+}
+
+// DeletePreset deletes a countdown preset (admin only)
+// DELETE /api/v1/countdown-presets/:id
+func (h *CountdownHandler) DeletePreset(c *gin.Context) {
+	presetID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid countdown preset ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	if err := h.countdownService.DeletePreset(uint(presetID), userID.(uint)); err != nil {
+		respondCountdownError(c, err, "Failed to delete countdown preset")
+		return
+	}
+
+	utils.MessageResponse(c, "Countdown preset deleted successfully")
+}
+
+// GetSchedules lists countdown schedules visible to the user
+// GET /api/v1/countdown-schedules?room_id=&status=&from=&to=&page=&limit=
+func (h *CountdownHandler) GetSchedules(c *gin.Context) {
+	filter := repository.CountdownScheduleFilter{}
+
+	if roomIDStr := c.Query("room_id"); roomIDStr != "" {
+		roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+			return
+		}
+		id := uint(roomID)
+		filter.RoomID = &id
+	}
+
+	filter.Status = c.Query("status")
+	if filter.Status != "" && filter.Status != models.CountdownSchedulePending && filter.Status != models.CountdownScheduleStarted &&
+		filter.Status != models.CountdownScheduleMissed && filter.Status != models.CountdownScheduleCancelled {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid status. Must be 'pending', 'started', 'missed', or 'cancelled'")
+		return
+	}
+
+	var ok bool
+	if filter.From, ok = parseTimeQuery(c, "from"); !ok {
+		return
+	}
+	if filter.To, ok = parseTimeQuery(c, "to"); !ok {
+		return
+	}
+
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	schedules, total, err := h.countdownService.GetSchedules(filter, userID.(uint), role.(string))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch countdown schedules")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"schedules": schedules,
+		"count":     len(schedules),
+		"total":     total,
+		"page":      page,
+		"limit":     limit,
+	})
+}
+
+// CreateSchedule schedules a countdown for a room (operators of the room's hospital)
+// POST /api/v1/countdown-schedules
+func (h *CountdownHandler) CreateSchedule(c *gin.Context) {
+	var req service.CreateCountdownScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	schedule, err := h.countdownService.CreateSchedule(&req, userID.(uint), role.(string))
+	if err != nil {
+		respondCountdownError(c, err, "Failed to create countdown schedule")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message":  "Countdown scheduled successfully",
+		"schedule": schedule,
+	})
+}
+
+// CancelSchedule cancels a pending countdown schedule (operators of the room's hospital)
+// POST /api/v1/countdown-schedules/:id/cancel
+func (h *CountdownHandler) CancelSchedule(c *gin.Context) {
+	scheduleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid countdown schedule ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	schedule, err := h.countdownService.CancelSchedule(uint(scheduleID), userID.(uint), role.(string))
+	if err != nil {
+		respondCountdownError(c, err, "Failed to cancel countdown schedule")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message":  "Countdown schedule cancelled successfully",
+		"schedule": schedule,
+	})
+}
+
+// GetEvents lists countdown start, completion, expiry and missed events visible to the user
+// GET /api/v1/countdown-events?room_id=&schedule_id=&event=&from=&to=&page=&limit=
+func (h *CountdownHandler) GetEvents(c *gin.Context) {
+	filter := repository.CountdownEventFilter{}
+
+	if roomIDStr := c.Query("room_id"); roomIDStr != "" {
+		roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+			return
+		}
+		id := uint(roomID)
+		filter.RoomID = &id
+	}
+	if scheduleIDStr := c.Query("schedule_id"); scheduleIDStr != "" {
+		scheduleID, err := strconv.ParseUint(scheduleIDStr, 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid countdown schedule ID")
+			return
+		}
+		id := uint(scheduleID)
+		filter.ScheduleID = &id
+	}
+
+	filter.Event = c.Query("event")
+	if filter.Event != "" && filter.Event != models.CountdownEventStarted && filter.Event != models.CountdownEventCompleted &&
+		filter.Event != models.CountdownEventExpired && filter.Event != models.CountdownEventMissed {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid event. Must be 'started', 'completed', 'expired', or 'missed'")
+		return
+	}
+
+	var ok bool
+	if filter.From, ok = parseTimeQuery(c, "from"); !ok {
+		return
+	}
+	if filter.To, ok = parseTimeQuery(c, "to"); !ok {
+		return
+	}
+
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	events, total, err := h.countdownService.GetEvents(filter, userID.(uint), role.(string))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch countdown events")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"events": events,
+		"count":  len(events),
+		"total":  total,
+		"page":   page,
+		"limit":  limit,
+	})
+}
+
+// respondCountdownError maps countdown service errors to HTTP responses
+func respondCountdownError(c *gin.Context, err error, internalMessage string) {
+	switch {
+	case strings.HasSuffix(err.Error(), "not found"):
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case strings.HasPrefix(err.Error(), "access denied"):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	case strings.HasPrefix(err.Error(), "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, internalMessage)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	}
+}
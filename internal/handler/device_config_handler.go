@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"iot-backend-room-monitoring/internal/service"
+	"iot-backend-room-monitoring/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeviceConfigHandler struct {
+	deviceConfigService *service.DeviceConfigService
+}
+
+func NewDeviceConfigHandler(deviceConfigService *service.DeviceConfigService) *DeviceConfigHandler {
+	return &DeviceConfigHandler{
+		deviceConfigService: deviceConfigService,
+	}
+}
+
+// GetRoomDeviceConfig returns the device config of a room
+// GET /api/v1/rooms/:id/device-config
+func (h *DeviceConfigHandler) GetRoomDeviceConfig(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	config, err := h.deviceConfigService.GetDeviceConfigForUser(uint(roomID), userID.(uint), role.(string))
+	if err != nil {
+		respondDeviceConfigError(c, err, "Failed to fetch device config")
+		return
+	}
+
+	utils.SuccessResponse(c, config)
+}
+
+// UpdateRoomDeviceConfig changes the device config of a room (hospital admins of the room's hospital)
+// PUT /api/v1/rooms/:id/device-config
+func (h *DeviceConfigHandler) UpdateRoomDeviceConfig(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	var req service.UpdateDeviceConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	config, err := h.deviceConfigService.UpdateDeviceConfig(uint(roomID), &req, userID.(uint))
+	if err != nil {
+		respondDeviceConfigError(c, err, "Failed to update device config")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "Device config updated successfully",
+		"config":  config,
+	})
+}
+
+// GetRoomCommands lists the recent device commands of a room
+// GET /api/v1/rooms/:id/commands
+func (h *DeviceConfigHandler) GetRoomCommands(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	commands, err := h.deviceConfigService.GetCommands(uint(roomID), userID.(uint), role.(string))
+	if err != nil {
+		respondDeviceConfigError(c, err, "Failed to fetch commands")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"commands": commands,
+		"count":    len(commands),
+	})
+}
+
+// CreateRoomCommand queues a command for the device of a room (hospital admins of the room's hospital)
+// POST /api/v1/rooms/:id/commands
+func (h *DeviceConfigHandler) CreateRoomCommand(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	var req service.CreateDeviceCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	command, err := h.deviceConfigService.CreateCommand(uint(roomID), &req, userID.(uint))
+	if err != nil {
+		respondDeviceConfigError(c, err, "Failed to queue command")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "Command queued successfully",
+		"command": command,
+	})
+}
+
+// GetDeviceConfig returns the config for the calling device
+// Devices pass the version they already have and get 304 Not Modified if it is current
+// GET /api/v1/esp32/config/:room_id?version=
+func (h *DeviceConfigHandler) GetDeviceConfig(c *gin.Context) {
+	// Get room_id from context (set by API key middleware)
+	roomID, exists := c.Get("room_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Room ID not found in context")
+		return
+	}
+
+	config, err := h.deviceConfigService.GetDeviceConfig(roomID.(uint))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch device config")
+		return
+	}
+
+	if versionStr := c.Query("version"); versionStr != "" {
+		version, err := strconv.ParseUint(versionStr, 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid version")
+			return
+		}
+		if uint(version) == config.Version {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	utils.SuccessResponse(c, config)
+}
+
+// GetDeviceCommands returns the commands the calling device has not acknowledged
+// GET /api/v1/esp32/commands/:room_id
+func (h *DeviceConfigHandler) GetDeviceCommands(c *gin.Context) {
+	// Get room_id from context (set by API key middleware)
+	roomID, exists := c.Get("room_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Room ID not found in context")
+		return
+	}
+
+	commands, err := h.deviceConfigService.FetchCommands(roomID.(uint))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch commands")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"commands": commands,
+		"count":    len(commands),
+	})
+}
+
+// AcknowledgeDeviceCommand records the calling device's result for a command
+// POST /api/v1/esp32/commands/:room_id/:command_id/ack
+func (h *DeviceConfigHandler) AcknowledgeDeviceCommand(c *gin.Context) {
+	// Get room_id from context (set by API key middleware)
+	roomID, exists := c.Get("room_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Room ID not found in context")
+		return
+	}
+
+	commandID, err := strconv.ParseUint(c.Param("command_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid command ID")
+		return
+	}
+
+	var req service.AcknowledgeDeviceCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	command, err := h.deviceConfigService.AcknowledgeCommand(roomID.(uint), uint(commandID), &req)
+	if err != nil {
+		respondDeviceConfigError(c, err, "Failed to acknowledge command")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "Command acknowledged",
+		"command": command,
+	})
+}
+
+// respondDeviceConfigError maps device config service errors to HTTP responses
+func respondDeviceConfigError(c *gin.Context, err error, internalMessage string) {
+	switch {
+	case err.Error() == "room not found" || err.Error() == "command not found":
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case strings.HasPrefix(err.Error(), "access denied"):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	case err.Error() == "device config was changed by another update":
+		utils.ErrorResponse(c, http.StatusConflict, "Device config was changed by another update, please retry")
+	case strings.HasPrefix(err.Error(), "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, internalMessage)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	}
+}
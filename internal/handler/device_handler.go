@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"iot-backend-room-monitoring/internal/service"
+	"iot-backend-room-monitoring/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeviceHandler struct {
+	deviceService *service.DeviceService
+}
+
+func NewDeviceHandler(deviceService *service.DeviceService) *DeviceHandler {
+	return &DeviceHandler{
+		deviceService: deviceService,
+	}
+}
+
+// GetHospitalDevices lists the devices of a hospital with online/offline counts
+// GET /api/v1/hospitals/:id/devices
+func (h *DeviceHandler) GetHospitalDevices(c *gin.Context) {
+	hospitalID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid hospital ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	summary, err := h.deviceService.GetDevicesByHospital(uint(hospitalID), userID.(uint), role.(string))
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "access denied") {
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch devices")
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, summary)
+}
+
+// GetRoomDevices lists the devices of a room with online/offline counts
+// GET /api/v1/rooms/:id/devices
+func (h *DeviceHandler) GetRoomDevices(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	summary, err := h.deviceService.GetDevicesByRoom(uint(roomID), userID.(uint), role.(string))
+	if err != nil {
+		if err.Error() == "room not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		} else if strings.HasPrefix(err.Error(), "access denied") {
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch devices")
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, summary)
+}
@@ -2,8 +2,10 @@ package handler
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
+	"iot-backend-room-monitoring/internal/metrics"
 	"iot-backend-room-monitoring/internal/service"
 	"iot-backend-room-monitoring/pkg/utils"
 
@@ -33,12 +35,13 @@ func (h *ESP32Handler) UpdateTelemetry(c *gin.Context) {
 	// Parse request body
 	var telemetryData service.TelemetryUpdateRequest
 	if err := c.ShouldBindJSON(&telemetryData); err != nil {
+		metrics.TelemetryRejected.WithLabelValues(metrics.RoomLabel(roomID.(uint)), metrics.RejectMalformed).Inc()
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
 		return
 	}
 
 	// Update telemetry
-	if err := h.esp32Service.UpdateTelemetry(roomID.(uint), &telemetryData); err != nil {
+	if err := h.esp32Service.UpdateTelemetry(roomID.(uint), telemetrySource(c), &telemetryData); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -53,6 +56,46 @@ func (h *ESP32Handler) UpdateTelemetry(c *gin.Context) {
 	})
 }
 
+// UpdateTelemetryBatch handles readings an ESP32 buffered while offline
+// POST /api/v1/esp32/telemetry/:room_id/batch
+func (h *ESP32Handler) UpdateTelemetryBatch(c *gin.Context) {
+	// Get room_id from context (set by API key middleware)
+	roomID, exists := c.Get("room_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Room ID not found in context")
+		return
+	}
+
+	// Parse request body
+	var batch service.TelemetryBatchRequest
+	if err := c.ShouldBindJSON(&batch); err != nil {
+		metrics.TelemetryRejected.WithLabelValues(metrics.RoomLabel(roomID.(uint)), metrics.RejectMalformed).Inc()
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	result, err := h.esp32Service.UpdateTelemetryBatch(roomID.(uint), telemetrySource(c), &batch)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "failed to") {
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "Telemetry batch stored successfully",
+		"data": gin.H{
+			"room_id":      roomID,
+			"stored":       result.Stored,
+			"oldest_at":    result.OldestAt.Format(time.RFC3339),
+			"newest_at":    result.NewestAt.Format(time.RFC3339),
+			"live_updated": result.LiveUpdated,
+		},
+	})
+}
+
 // GetTelemetry retrieves current telemetry data for a room (optional endpoint)
 // GET /api/v1/esp32/telemetry/:room_id
 func (h *ESP32Handler) GetTelemetry(c *gin.Context) {
@@ -72,3 +115,11 @@ func (h *ESP32Handler) GetTelemetry(c *gin.Context) {
 
 	utils.SuccessResponse(c, telemetry)
 }
+
+// telemetrySource identifies the calling device (API key set by the API key middleware)
+func telemetrySource(c *gin.Context) service.TelemetrySource {
+	return service.TelemetrySource{
+		APIKeyID: c.GetUint("api_key_id"),
+		IP:       c.ClientIP(),
+	}
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"iot-backend-room-monitoring/internal/service"
+	"iot-backend-room-monitoring/pkg/fhir"
+	"iot-backend-room-monitoring/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FHIRHandler struct {
+	fhirService *service.FHIRService
+}
+
+func NewFHIRHandler(fhirService *service.FHIRService) *FHIRHandler {
+	return &FHIRHandler{
+		fhirService: fhirService,
+	}
+}
+
+// GetRoomLive exports a room's latest readings as a FHIR bundle
+// GET /api/v1/rooms/:id/fhir/live?bundle_type=collection|transaction
+func (h *FHIRHandler) GetRoomLive(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	bundle, err := h.fhirService.GetRoomLiveBundle(uint(roomID), bundleTypeQuery(c), userID.(uint), role.(string))
+	if err != nil {
+		respondFHIRError(c, err, "Failed to export live readings")
+		return
+	}
+
+	respondFHIR(c, bundle)
+}
+
+// GetHospitalLive exports the latest readings of every room of a hospital as a FHIR bundle
+// GET /api/v1/hospitals/:id/fhir/live?bundle_type=collection|transaction
+func (h *FHIRHandler) GetHospitalLive(c *gin.Context) {
+	hospitalID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid hospital ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	bundle, err := h.fhirService.GetHospitalLiveBundle(uint(hospitalID), bundleTypeQuery(c), userID.(uint), role.(string))
+	if err != nil {
+		respondFHIRError(c, err, "Failed to export live readings")
+		return
+	}
+
+	respondFHIR(c, bundle)
+}
+
+// GetRoomHistory exports a page of a room's historical readings as a FHIR bundle
+// GET /api/v1/rooms/:id/fhir/history?from=&to=&page=&limit=&bundle_type=collection|transaction
+func (h *FHIRHandler) GetRoomHistory(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	query := service.FHIRHistoryQuery{BundleType: bundleTypeQuery(c)}
+
+	var ok bool
+	if query.From, ok = parseTimeQuery(c, "from"); !ok {
+		return
+	}
+	if query.To, ok = parseTimeQuery(c, "to"); !ok {
+		return
+	}
+	if page := c.Query("page"); page != "" {
+		query.Page, err = strconv.Atoi(page)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid page")
+			return
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	result, err := h.fhirService.GetRoomHistoryBundle(uint(roomID), query, userID.(uint), role.(string))
+	if err != nil {
+		respondFHIRError(c, err, "Failed to export telemetry history")
+		return
+	}
+
+	// Link the next page so a FHIR client can page through the period
+	bundle := result.Bundle
+	total := int(result.Total)
+	bundle.Total = &total
+	bundle.Link = append(bundle.Link, fhir.BundleLink{Relation: "self", URL: pageURL(c, result.Page, result.Limit)})
+	if result.HasMore {
+		bundle.Link = append(bundle.Link, fhir.BundleLink{Relation: "next", URL: pageURL(c, result.Page+1, result.Limit)})
+	}
+
+	respondFHIR(c, bundle)
+}
+
+// bundleTypeQuery reads the requested bundle type, defaulting to a collection
+func bundleTypeQuery(c *gin.Context) string {
+	return c.DefaultQuery("bundle_type", fhir.BundleTypeCollection)
+}
+
+// pageURL returns the request's URL for another page
+func pageURL(c *gin.Context, page, limit int) string {
+	values := c.Request.URL.Query()
+	values.Set("page", strconv.Itoa(page))
+	values.Set("limit", strconv.Itoa(limit))
+	u := url.URL{Path: c.Request.URL.Path, RawQuery: values.Encode()}
+	return u.String()
+}
+
+// respondFHIR writes a FHIR resource as the raw response body, without the API's response envelope
+func respondFHIR(c *gin.Context, resource interface{}) {
+	c.Header("Content-Type", fhir.ContentType)
+	c.JSON(http.StatusOK, resource)
+}
+
+// respondFHIRError maps FHIR export service errors to HTTP responses
+func respondFHIRError(c *gin.Context, err error, internalMessage string) {
+	switch {
+	case err.Error() == "room not found" || err.Error() == "hospital not found":
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case strings.HasPrefix(err.Error(), "access denied"):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	case strings.HasPrefix(err.Error(), "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, internalMessage)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	}
+}
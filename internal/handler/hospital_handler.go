@@ -3,6 +3,7 @@ package handler
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"iot-backend-room-monitoring/internal/models"
 	"iot-backend-room-monitoring/internal/service"
@@ -96,7 +97,7 @@ func (h *HospitalHandler) CreateHospital(c *gin.Context) {
 	})
 }
 
-// UpdateHospital updates an existing hospital (admin only)
+// UpdateHospital updates an existing hospital (hospital admins of the hospital)
 func (h *HospitalHandler) UpdateHospital(c *gin.Context) {
 	// Parse hospital ID
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -155,3 +156,104 @@ func (h *HospitalHandler) DeleteHospital(c *gin.Context) {
 
 	utils.MessageResponse(c, "Hospital deleted successfully")
 }
+
+// HospitalUserRoleRequest assigns a hospital role to a user
+type HospitalUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=viewer operator hospital_admin"`
+}
+
+// GetHospitalUsers lists the users assigned to a hospital with their roles (hospital admins of the hospital)
+// GET /api/v1/hospitals/:id/users
+func (h *HospitalHandler) GetHospitalUsers(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid hospital ID")
+		return
+	}
+
+	members, err := h.hospitalService.GetHospitalMembers(uint(id))
+	if err != nil {
+		respondHospitalUserError(c, err, "Failed to fetch hospital users")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"users": members,
+		"count": len(members),
+	})
+}
+
+// SetHospitalUserRole assigns a user to a hospital with a role, replacing any previous role
+// (hospital admins of the hospital)
+// PUT /api/v1/hospitals/:id/users/:user_id
+func (h *HospitalHandler) SetHospitalUserRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid hospital ID")
+		return
+	}
+	targetUserID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req HospitalUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	member, err := h.hospitalService.AssignUserToHospital(uint(targetUserID), uint(id), req.Role, userID.(uint), role.(string))
+	if err != nil {
+		respondHospitalUserError(c, err, "Failed to assign user to hospital")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "Hospital role assigned successfully",
+		"user":    member,
+	})
+}
+
+// RemoveHospitalUser removes a user's access to a hospital (hospital admins of the hospital)
+// DELETE /api/v1/hospitals/:id/users/:user_id
+func (h *HospitalHandler) RemoveHospitalUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid hospital ID")
+		return
+	}
+	targetUserID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	if err := h.hospitalService.RemoveUserFromHospital(uint(targetUserID), uint(id), userID.(uint), role.(string)); err != nil {
+		respondHospitalUserError(c, err, "Failed to remove user from hospital")
+		return
+	}
+
+	utils.MessageResponse(c, "User removed from hospital successfully")
+}
+
+// respondHospitalUserError maps hospital user service errors to HTTP responses
+func respondHospitalUserError(c *gin.Context, err error, internalMessage string) {
+	switch {
+	case err.Error() == "hospital not found" || err.Error() == "user not found":
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case strings.HasPrefix(err.Error(), "access denied"):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	case strings.HasPrefix(err.Error(), "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, internalMessage)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	}
+}
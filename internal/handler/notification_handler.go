@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"iot-backend-room-monitoring/internal/service"
+	"iot-backend-room-monitoring/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+}
+
+func NewNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+	}
+}
+
+// GetChannels lists notification channels (admin only)
+// GET /api/v1/notification-channels
+func (h *NotificationHandler) GetChannels(c *gin.Context) {
+	channels, err := h.notificationService.GetChannels()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch notification channels")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"channels": channels,
+		"count":    len(channels),
+	})
+}
+
+// CreateChannel creates a notification channel (admin only)
+// POST /api/v1/notification-channels
+func (h *NotificationHandler) CreateChannel(c *gin.Context) {
+	var req service.NotificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	channel, err := h.notificationService.CreateChannel(&req, userID.(uint))
+	if err != nil {
+		respondNotificationError(c, err, "Failed to create notification channel")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "Notification channel created successfully",
+		"channel": channel,
+	})
+}
+
+// UpdateChannel updates a notification channel (admin only)
+// PUT /api/v1/notification-channels/:id
+func (h *NotificationHandler) UpdateChannel(c *gin.Context) {
+	channelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid notification channel ID")
+		return
+	}
+
+	var req service.NotificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	channel, err := h.notificationService.UpdateChannel(uint(channelID), &req, userID.(uint))
+	if err != nil {
+		respondNotificationError(c, err, "Failed to update notification channel")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "Notification channel updated successfully",
+		"channel": channel,
+	})
+}
+
+// DeleteChannel deletes a notification channel (admin only)
+// DELETE /api/v1/notification-channels/:id
+func (h *NotificationHandler) DeleteChannel(c *gin.Context) {
+	channelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid notification channel ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	if err := h.notificationService.DeleteChannel(uint(channelID), userID.(uint)); err != nil {
+		respondNotificationError(c, err, "Failed to delete notification channel")
+		return
+	}
+
+	utils.MessageResponse(c, "Notification channel deleted successfully")
+}
+
+// TestChannel sends a test notification through a channel (admin only)
+// POST /api/v1/notification-channels/:id/test
+func (h *NotificationHandler) TestChannel(c *gin.Context) {
+	channelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid notification channel ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	if err := h.notificationService.TestChannel(uint(channelID), userID.(uint)); err != nil {
+		if strings.HasPrefix(err.Error(), "test notification failed") {
+			utils.ErrorResponse(c, http.StatusBadGateway, err.Error())
+			return
+		}
+		respondNotificationError(c, err, "Failed to send test notification")
+		return
+	}
+
+	utils.MessageResponse(c, "Test notification sent successfully")
+}
+
+// respondNotificationError maps notification service errors to HTTP responses
+func respondNotificationError(c *gin.Context, err error, internalMessage string) {
+	switch {
+	case err.Error() == "notification channel not found":
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case strings.HasPrefix(err.Error(), "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, internalMessage)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	}
+}
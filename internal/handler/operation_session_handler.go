@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+	"iot-backend-room-monitoring/internal/service"
+	"iot-backend-room-monitoring/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OperationSessionHandler struct {
+	sessionService *service.OperationSessionService
+	reportService  *service.ComplianceReportService
+}
+
+func NewOperationSessionHandler(sessionService *service.OperationSessionService, reportService *service.ComplianceReportService) *OperationSessionHandler {
+	return &OperationSessionHandler{
+		sessionService: sessionService,
+		reportService:  reportService,
+	}
+}
+
+// GetSessions lists operation sessions visible to the user
+// GET /api/v1/operation-sessions?room_id=&hospital_id=&status=&procedure=&from=&to=&page=&limit=
+func (h *OperationSessionHandler) GetSessions(c *gin.Context) {
+	filter := repository.OperationSessionFilter{}
+
+	if roomIDStr := c.Query("room_id"); roomIDStr != "" {
+		roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+			return
+		}
+		id := uint(roomID)
+		filter.RoomID = &id
+	}
+	if hospitalIDStr := c.Query("hospital_id"); hospitalIDStr != "" {
+		hospitalID, err := strconv.ParseUint(hospitalIDStr, 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid hospital ID")
+			return
+		}
+		id := uint(hospitalID)
+		filter.HospitalID = &id
+	}
+
+	filter.Status = c.Query("status")
+	if filter.Status != "" && filter.Status != models.OperationSessionRunning &&
+		filter.Status != models.OperationSessionPaused && filter.Status != models.OperationSessionCompleted {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid status. Must be 'running', 'paused', or 'completed'")
+		return
+	}
+	filter.ProcedureLabel = c.Query("procedure")
+
+	var ok bool
+	if filter.From, ok = parseTimeQuery(c, "from"); !ok {
+		return
+	}
+	if filter.To, ok = parseTimeQuery(c, "to"); !ok {
+		return
+	}
+
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	sessions, total, err := h.sessionService.GetSessions(filter, userID.(uint), role.(string))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch operation sessions")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"sessions": sessions,
+		"count":    len(sessions),
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+	})
+}
+
+// GetSession returns one operation session with its intervals
+// GET /api/v1/operation-sessions/:id
+func (h *OperationSessionHandler) GetSession(c *gin.Context) {
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid operation session ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	session, err := h.sessionService.GetSession(uint(sessionID), userID.(uint), role.(string))
+	if err != nil {
+		respondOperationSessionError(c, err, "Failed to fetch operation session")
+		return
+	}
+
+	utils.SuccessResponse(c, session)
+}
+
+// UpdateSession changes the procedure label of an operation session (operators of the room's hospital)
+// PATCH /api/v1/operation-sessions/:id
+func (h *OperationSessionHandler) UpdateSession(c *gin.Context) {
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid operation session ID")
+		return
+	}
+
+	var req service.UpdateOperationSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	session, err := h.sessionService.UpdateSession(uint(sessionID), &req, userID.(uint), role.(string))
+	if err != nil {
+		respondOperationSessionError(c, err, "Failed to update operation session")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "Operation session updated successfully",
+		"session": session,
+	})
+}
+
+// GetComplianceReport returns the environmental compliance report of an operation session
+// GET /api/v1/operation-sessions/:id/compliance-report?format=json|csv|pdf
+func (h *OperationSessionHandler) GetComplianceReport(c *gin.Context) {
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid operation session ID")
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" && format != "pdf" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid format. Must be 'json', 'csv', or 'pdf'")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	report, err := h.reportService.GetSessionReport(uint(sessionID), userID.(uint), role.(string))
+	if err != nil {
+		respondOperationSessionError(c, err, "Failed to build compliance report")
+		return
+	}
+
+	filename := fmt.Sprintf("compliance-report-session-%d.%s", report.SessionID, format)
+	switch format {
+	case "csv":
+		data, err := service.ComplianceReportCSV(report)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to build compliance report")
+			return
+		}
+		c.Header("Content-Disposition", "attachment; filename="+filename)
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+	case "pdf":
+		c.Header("Content-Disposition", "attachment; filename="+filename)
+		c.Data(http.StatusOK, "application/pdf", service.ComplianceReportPDF(report))
+	default:
+		utils.SuccessResponse(c, report)
+	}
+}
+
+// GetRoomUtilization summarizes how long a room's operation stopwatch ran
+// GET /api/v1/rooms/:id/utilization?from=&to=
+func (h *OperationSessionHandler) GetRoomUtilization(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+	from, ok := parseTimeQuery(c, "from")
+	if !ok {
+		return
+	}
+	to, ok := parseTimeQuery(c, "to")
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	utilization, err := h.sessionService.GetRoomUtilization(uint(roomID), from, to, userID.(uint), role.(string))
+	if err != nil {
+		respondOperationSessionError(c, err, "Failed to fetch room utilization")
+		return
+	}
+
+	utils.SuccessResponse(c, utilization)
+}
+
+// GetHospitalUtilization summarizes operation stopwatch use of every room of a hospital
+// GET /api/v1/hospitals/:id/utilization?from=&to=
+func (h *OperationSessionHandler) GetHospitalUtilization(c *gin.Context) {
+	hospitalID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid hospital ID")
+		return
+	}
+	from, ok := parseTimeQuery(c, "from")
+	if !ok {
+		return
+	}
+	to, ok := parseTimeQuery(c, "to")
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	utilization, err := h.sessionService.GetHospitalUtilization(uint(hospitalID), from, to, userID.(uint), role.(string))
+	if err != nil {
+		respondOperationSessionError(c, err, "Failed to fetch hospital utilization")
+		return
+	}
+
+	utils.SuccessResponse(c, utilization)
+}
+
+// parseTimeQuery reads an optional RFC3339 query parameter, writing an error response when invalid
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid '"+name+"' time, expected RFC3339")
+		return nil, false
+	}
+	return &t, true
+}
+
+// respondOperationSessionError maps operation session service errors to HTTP responses
+func respondOperationSessionError(c *gin.Context, err error, internalMessage string) {
+	switch {
+	case err.Error() == "operation session not found" || err.Error() == "room not found":
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case strings.HasPrefix(err.Error(), "access denied"):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	case strings.HasPrefix(err.Error(), "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, internalMessage)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	}
+}
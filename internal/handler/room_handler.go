@@ -3,6 +3,7 @@ package handler
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"iot-backend-room-monitoring/internal/models"
 	"iot-backend-room-monitoring/internal/service"
@@ -96,7 +97,7 @@ func (h *RoomHandler) GetAllRooms(c *gin.Context) {
 	})
 }
 
-// CreateRoom creates a new room (admin only)
+// CreateRoom creates a new room (hospital admins of the room's hospital)
 func (h *RoomHandler) CreateRoom(c *gin.Context) {
 	var room models.Room
 	if err := c.ShouldBindJSON(&room); err != nil {
@@ -110,12 +111,17 @@ func (h *RoomHandler) CreateRoom(c *gin.Context) {
 		return
 	}
 
-	// Get user ID from context
+	// Get user info from context
 	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
 
-	response, err := h.roomService.CreateRoom(&room, userID.(uint))
+	response, err := h.roomService.CreateRoom(&room, userID.(uint), role.(string))
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		if strings.HasPrefix(err.Error(), "access denied") {
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+		} else {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		}
 		return
 	}
 
@@ -151,7 +157,7 @@ func (h *RoomHandler) CreateRoom(c *gin.Context) {
 	utils.SuccessResponse(c, responseData)
 }
 
-// UpdateRoom updates an existing room (admin only)
+// UpdateRoom updates an existing room (hospital admins of the room's hospital)
 func (h *RoomHandler) UpdateRoom(c *gin.Context) {
 	// Parse room ID
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -169,12 +175,15 @@ func (h *RoomHandler) UpdateRoom(c *gin.Context) {
 	// Set the ID from path parameter
 	room.ID = uint(id)
 
-	// Get user ID from context
+	// Get user info from context
 	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
 
-	if err := h.roomService.UpdateRoom(&room, userID.(uint)); err != nil {
+	if err := h.roomService.UpdateRoom(&room, userID.(uint), role.(string)); err != nil {
 		if err.Error() == "room not found" {
 			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		} else if strings.HasPrefix(err.Error(), "access denied") {
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
 		} else {
 			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		}
@@ -187,7 +196,7 @@ func (h *RoomHandler) UpdateRoom(c *gin.Context) {
 	})
 }
 
-// DeleteRoom soft deletes a room (admin only)
+// DeleteRoom soft deletes a room (hospital admins of the room's hospital)
 func (h *RoomHandler) DeleteRoom(c *gin.Context) {
 	// Parse room ID
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
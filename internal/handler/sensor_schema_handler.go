@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"iot-backend-room-monitoring/internal/service"
+	"iot-backend-room-monitoring/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SensorSchemaHandler struct {
+	sensorSchemaService *service.SensorSchemaService
+}
+
+func NewSensorSchemaHandler(sensorSchemaService *service.SensorSchemaService) *SensorSchemaHandler {
+	return &SensorSchemaHandler{
+		sensorSchemaService: sensorSchemaService,
+	}
+}
+
+// GetSensorSchemas lists the effective sensor schema of every room type
+// GET /api/v1/sensor-schemas
+func (h *SensorSchemaHandler) GetSensorSchemas(c *gin.Context) {
+	schemas := h.sensorSchemaService.GetSchemas()
+	utils.SuccessResponse(c, gin.H{
+		"schemas": schemas,
+		"count":   len(schemas),
+	})
+}
+
+// GetSensorSchema returns the effective sensor schema of one room type
+// GET /api/v1/sensor-schemas/:room_type
+func (h *SensorSchemaHandler) GetSensorSchema(c *gin.Context) {
+	schema, err := h.sensorSchemaService.GetSchema(c.Param("room_type"))
+	if err != nil {
+		respondSensorSchemaError(c, err, "Failed to fetch sensor schema")
+		return
+	}
+
+	utils.SuccessResponse(c, schema)
+}
+
+// SaveSensorSchema replaces the sensor schema of a room type (admin only)
+// PUT /api/v1/sensor-schemas/:room_type
+func (h *SensorSchemaHandler) SaveSensorSchema(c *gin.Context) {
+	var req service.SaveSensorSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	schema, err := h.sensorSchemaService.SaveSchema(c.Param("room_type"), &req, userID.(uint))
+	if err != nil {
+		respondSensorSchemaError(c, err, "Failed to save sensor schema")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message": "Sensor schema saved successfully",
+		"schema":  schema,
+	})
+}
+
+// DeleteSensorSchema removes the sensor schema of a room type, restoring the built-in schema (admin only)
+// DELETE /api/v1/sensor-schemas/:room_type
+func (h *SensorSchemaHandler) DeleteSensorSchema(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	if err := h.sensorSchemaService.DeleteSchema(c.Param("room_type"), userID.(uint)); err != nil {
+		respondSensorSchemaError(c, err, "Failed to delete sensor schema")
+		return
+	}
+
+	utils.MessageResponse(c, "Sensor schema deleted successfully")
+}
+
+// respondSensorSchemaError maps sensor schema service errors to HTTP responses
+func respondSensorSchemaError(c *gin.Context, err error, internalMessage string) {
+	switch {
+	case err.Error() == "room type not found":
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case strings.HasPrefix(err.Error(), "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, internalMessage)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	}
+}
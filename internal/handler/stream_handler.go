@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"iot-backend-room-monitoring/internal/service"
+	"iot-backend-room-monitoring/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamHeartbeatInterval keeps idle connections open through proxies
+const streamHeartbeatInterval = 15 * time.Second
+
+// streamAccessRefreshInterval is how often an open stream re-reads the user's role and hospitals
+const streamAccessRefreshInterval = 30 * time.Second
+
+type StreamHandler struct {
+	streamService *service.StreamService
+}
+
+func NewStreamHandler(streamService *service.StreamService) *StreamHandler {
+	return &StreamHandler{
+		streamService: streamService,
+	}
+}
+
+// StreamLiveStates pushes live state changes to the client as Server-Sent Events
+// GET /api/v1/stream/live-states
+// Sends a "snapshot" event with every accessible room, then "state" events carrying only changed fields
+func (h *StreamHandler) StreamLiveStates(c *gin.Context) {
+	// Get user info from context
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	sub, err := h.streamService.Subscribe(userID.(uint), role.(string))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to open live state stream")
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable nginx response buffering
+
+	c.SSEvent("snapshot", sub.Snapshot)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+	accessRefresh := time.NewTicker(streamAccessRefreshInterval)
+	defer accessRefresh.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-sub.Events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case now := <-heartbeat.C:
+			c.SSEvent("heartbeat", gin.H{"at": now})
+			return true
+		case <-accessRefresh.C:
+			if err := h.streamService.RefreshAccess(sub); err != nil {
+				if err.Error() == "user not found" {
+					return false
+				}
+				log.Printf("Error refreshing live state stream access for user %d: %v", userID, err)
+			}
+			return true
+		}
+	})
+}
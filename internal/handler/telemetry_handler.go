@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"iot-backend-room-monitoring/internal/service"
+	"iot-backend-room-monitoring/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TelemetryHandler struct {
+	historyService *service.TelemetryHistoryService
+}
+
+func NewTelemetryHandler(historyService *service.TelemetryHistoryService) *TelemetryHandler {
+	return &TelemetryHandler{
+		historyService: historyService,
+	}
+}
+
+// GetTelemetryHistory returns historical telemetry readings for a room
+// GET /api/v1/rooms/:id/telemetry/history?from=&to=&fields=&resolution=&page=&limit=
+func (h *TelemetryHandler) GetTelemetryHistory(c *gin.Context) {
+	// Parse room ID
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+		return
+	}
+
+	query := service.TelemetryHistoryQuery{}
+
+	// Parse optional time range (RFC3339)
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid 'from' time, expected RFC3339")
+			return
+		}
+		query.From = &t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid 'to' time, expected RFC3339")
+			return
+		}
+		query.To = &t
+	}
+
+	// Parse optional field filter (comma separated)
+	if fields := c.Query("fields"); fields != "" {
+		query.Fields = strings.Split(fields, ",")
+	}
+
+	// raw (default), 1m, 15m, 1h or auto
+	query.Resolution = c.Query("resolution")
+
+	// Parse pagination
+	if page := c.Query("page"); page != "" {
+		query.Page, err = strconv.Atoi(page)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid page")
+			return
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+	}
+
+	// Get user info from context
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	result, err := h.historyService.GetHistory(uint(roomID), query, userID.(uint), role.(string))
+	if err != nil {
+		if err.Error() == "room not found" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		} else if err.Error() == "access denied: you don't have permission to access this room" {
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+		} else if strings.HasPrefix(err.Error(), "failed to") {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch telemetry history")
+		} else {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, result)
+}
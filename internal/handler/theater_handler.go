@@ -60,13 +60,18 @@ func (h *TheaterHandler) GetRooms(c *gin.Context) {
 }
 
 type TimerOperationRequest struct {
-	Action string `json:"action" binding:"required,oneof=start stop reset"`
+	Action         string `json:"action" binding:"required,oneof=start pause resume stop reset lap"`
+	ProcedureLabel string `json:"procedure_label" binding:"max=255"` // Optional, for start action
+	Reason         string `json:"reason" binding:"max=255"`          // Required for pause, optional for resume/stop/reset
+	Name           string `json:"name" binding:"max=100"`            // Required for lap action, e.g. "incision"
 }
 
 // CountdownTimerRequest represents the request body for countdown timer operations
 type CountdownTimerRequest struct {
 	Action          string `json:"action" binding:"required,oneof=start stop reset"`
-	DurationMinutes *int   `json:"duration_minutes"` // Optional, for start action
+	DurationMinutes *int   `json:"duration_minutes"`        // Optional, for start action
+	PresetID        *uint  `json:"preset_id"`               // Optional, for start action by room_id
+	Label           string `json:"label" binding:"max=100"` // Optional, for start action by room_id
 }
 
 // AdjustTimerRequest represents the request body for adjusting countdown timer
@@ -78,7 +83,7 @@ type AdjustTimerRequest struct {
 func (h *TheaterHandler) UpdateTimer(c *gin.Context) {
 	var req TimerOperationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request. Action must be 'start', 'stop', or 'reset'")
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request. Action must be 'start', 'pause', 'resume', 'stop', 'reset' or 'lap'")
 		return
 	}
 
@@ -93,7 +98,7 @@ func (h *TheaterHandler) UpdateTimer(c *gin.Context) {
 	roomName := c.DefaultQuery("room", "OT-01")
 
 	// Update the timer
-	if err := h.theaterService.UpdateOperationTimer(roomName, req.Action, userID.(uint)); err != nil {
+	if err := h.theaterService.UpdateOperationTimer(roomName, req.Action, req.ProcedureLabel, req.Reason, req.Name, userID.(uint)); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -185,11 +190,11 @@ func (h *TheaterHandler) GetRoomDashboard(c *gin.Context) {
 	utils.SuccessResponse(c, state)
 }
 
-// UpdateTimerByRoomID handles operation timer control by room_id (admin only)
+// UpdateTimerByRoomID handles operation timer control by room_id (operators of the room's hospital)
 func (h *TheaterHandler) UpdateTimerByRoomID(c *gin.Context) {
 	var req TimerOperationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request. Action must be 'start', 'stop', or 'reset'")
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request. Action must be 'start', 'pause', 'resume', 'stop', 'reset' or 'lap'")
 		return
 	}
 
@@ -205,7 +210,7 @@ func (h *TheaterHandler) UpdateTimerByRoomID(c *gin.Context) {
 	role, _ := c.Get("role")
 
 	// Update the timer
-	if err := h.theaterService.UpdateOperationTimerByRoomID(roomID, req.Action, userID.(uint), role.(string)); err != nil {
+	if err := h.theaterService.UpdateOperationTimerByRoomID(roomID, req.Action, req.ProcedureLabel, req.Reason, req.Name, userID.(uint), role.(string)); err != nil {
 		if err.Error() == "access denied: you don't have permission to access this room" {
 			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
 		} else {
@@ -217,7 +222,7 @@ func (h *TheaterHandler) UpdateTimerByRoomID(c *gin.Context) {
 	utils.MessageResponse(c, "Timer operation completed successfully")
 }
 
-// UpdateCountdownTimerByRoomID handles countdown timer control by room_id (admin only)
+// UpdateCountdownTimerByRoomID handles countdown timer control by room_id (operators of the room's hospital)
 func (h *TheaterHandler) UpdateCountdownTimerByRoomID(c *gin.Context) {
 	var req CountdownTimerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -237,7 +242,7 @@ func (h *TheaterHandler) UpdateCountdownTimerByRoomID(c *gin.Context) {
 	role, _ := c.Get("role")
 
 	// Update the countdown timer
-	if err := h.theaterService.UpdateCountdownTimerByRoomID(roomID, req.Action, req.DurationMinutes, userID.(uint), role.(string)); err != nil {
+	if err := h.theaterService.UpdateCountdownTimerByRoomID(roomID, req.Action, req.DurationMinutes, req.PresetID, req.Label, userID.(uint), role.(string)); err != nil {
 		if err.Error() == "access denied: you don't have permission to access this room" {
 			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
 		} else {
@@ -249,7 +254,7 @@ func (h *TheaterHandler) UpdateCountdownTimerByRoomID(c *gin.Context) {
 	utils.MessageResponse(c, "Countdown timer operation completed successfully")
 }
 
-// AdjustCountdownTimerByRoomID handles adjusting countdown timer by +/- 1 minute by room_id (admin only)
+// AdjustCountdownTimerByRoomID handles adjusting countdown timer by +/- 1 minute by room_id (operators of the room's hospital)
 func (h *TheaterHandler) AdjustCountdownTimerByRoomID(c *gin.Context) {
 	var req AdjustTimerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+	"iot-backend-room-monitoring/internal/service"
+	"iot-backend-room-monitoring/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+}
+
+func NewWebhookHandler(webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// GetSubscriptions lists webhook subscriptions (admin only)
+// GET /api/v1/webhooks
+func (h *WebhookHandler) GetSubscriptions(c *gin.Context) {
+	subscriptions, err := h.webhookService.GetSubscriptions()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch webhook subscriptions")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"subscriptions": subscriptions,
+		"count":         len(subscriptions),
+	})
+}
+
+// CreateSubscription creates a webhook subscription (admin only)
+// The signing secret is only included in this response
+// POST /api/v1/webhooks
+func (h *WebhookHandler) CreateSubscription(c *gin.Context) {
+	var req service.WebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	subscription, secret, err := h.webhookService.CreateSubscription(&req, userID.(uint))
+	if err != nil {
+		respondWebhookError(c, err, "Failed to create webhook subscription")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message":      "Webhook subscription created successfully",
+		"subscription": subscription,
+		"secret":       secret,
+	})
+}
+
+// UpdateSubscription updates a webhook subscription (admin only)
+// PUT /api/v1/webhooks/:id
+func (h *WebhookHandler) UpdateSubscription(c *gin.Context) {
+	subscriptionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid webhook subscription ID")
+		return
+	}
+
+	var req service.WebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	subscription, err := h.webhookService.UpdateSubscription(uint(subscriptionID), &req, userID.(uint))
+	if err != nil {
+		respondWebhookError(c, err, "Failed to update webhook subscription")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message":      "Webhook subscription updated successfully",
+		"subscription": subscription,
+	})
+}
+
+// RotateSecret replaces the signing secret of a webhook subscription (admin only)
+// POST /api/v1/webhooks/:id/rotate-secret
+func (h *WebhookHandler) RotateSecret(c *gin.Context) {
+	subscriptionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid webhook subscription ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	subscription, secret, err := h.webhookService.RotateSecret(uint(subscriptionID), userID.(uint))
+	if err != nil {
+		respondWebhookError(c, err, "Failed to rotate webhook secret")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message":      "Webhook secret rotated successfully",
+		"subscription": subscription,
+		"secret":       secret,
+	})
+}
+
+// DeleteSubscription deletes a webhook subscription and its delivery log (admin only)
+// DELETE /api/v1/webhooks/:id
+func (h *WebhookHandler) DeleteSubscription(c *gin.Context) {
+	subscriptionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid webhook subscription ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	if err := h.webhookService.DeleteSubscription(uint(subscriptionID), userID.(uint)); err != nil {
+		respondWebhookError(c, err, "Failed to delete webhook subscription")
+		return
+	}
+
+	utils.MessageResponse(c, "Webhook subscription deleted successfully")
+}
+
+// GetDeliveries lists the webhook delivery log, newest first (admin only)
+// GET /api/v1/webhook-deliveries?subscription_id=&room_id=&status=&event_type=&page=&limit=
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	filter := repository.WebhookDeliveryFilter{}
+
+	if subscriptionIDStr := c.Query("subscription_id"); subscriptionIDStr != "" {
+		subscriptionID, err := strconv.ParseUint(subscriptionIDStr, 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid webhook subscription ID")
+			return
+		}
+		id := uint(subscriptionID)
+		filter.SubscriptionID = &id
+	}
+	if roomIDStr := c.Query("room_id"); roomIDStr != "" {
+		roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+			return
+		}
+		id := uint(roomID)
+		filter.RoomID = &id
+	}
+
+	filter.Status = c.Query("status")
+	if filter.Status != "" && filter.Status != models.WebhookDeliveryPending &&
+		filter.Status != models.WebhookDeliveryDelivered && filter.Status != models.WebhookDeliveryFailed {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid status. Must be 'pending', 'delivered', or 'failed'")
+		return
+	}
+	filter.EventType = c.Query("event_type")
+
+	page, limit, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
+
+	deliveries, total, err := h.webhookService.GetDeliveries(filter)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch webhook deliveries")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+	})
+}
+
+// RetryDelivery queues a failed webhook delivery for another attempt (admin only)
+// POST /api/v1/webhook-deliveries/:id/retry
+func (h *WebhookHandler) RetryDelivery(c *gin.Context) {
+	deliveryID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid webhook delivery ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	delivery, err := h.webhookService.RetryDelivery(uint(deliveryID), userID.(uint))
+	if err != nil {
+		respondWebhookError(c, err, "Failed to retry webhook delivery")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"message":  "Webhook delivery queued for retry",
+		"delivery": delivery,
+	})
+}
+
+// respondWebhookError maps webhook service errors to HTTP responses
+func respondWebhookError(c *gin.Context, err error, internalMessage string) {
+	switch {
+	case err.Error() == "webhook subscription not found" || err.Error() == "webhook delivery not found":
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case strings.HasPrefix(err.Error(), "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, internalMessage)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	}
+}
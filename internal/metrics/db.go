@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// InstrumentDB registers GORM callbacks counting failed database operations in DBErrors
+func InstrumentDB(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().After("gorm:create").Register("metrics:create", countDBError("create")),
+		callbacks.Query().After("gorm:query").Register("metrics:query", countDBError("query")),
+		callbacks.Update().After("gorm:update").Register("metrics:update", countDBError("update")),
+		callbacks.Delete().After("gorm:delete").Register("metrics:delete", countDBError("delete")),
+		callbacks.Row().After("gorm:row").Register("metrics:row", countDBError("row")),
+		callbacks.Raw().After("gorm:raw").Register("metrics:raw", countDBError("raw")),
+	)
+}
+
+// countDBError returns a callback counting the statement's error, if any
+// Record not found is an expected outcome of lookups and is not counted
+func countDBError(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			DBErrors.WithLabelValues(operation).Inc()
+		}
+	}
+}
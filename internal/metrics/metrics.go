@@ -0,0 +1,146 @@
+// Package metrics holds the Prometheus metrics exported on /metrics
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "room_monitoring"
+
+// Telemetry rejection reasons
+const (
+	RejectMalformed   = "malformed"    // Body could not be decoded
+	RejectInvalid     = "invalid"      // Failed calibration or sensor schema validation
+	RejectUnknownRoom = "unknown_room" // Room does not exist
+	RejectStorage     = "storage"      // Database write failed
+)
+
+// Reasons a reading's device timestamp is not trusted for timing
+const (
+	ClockSkewExceeded     = "skew_exceeded"
+	ClockWentBackwards    = "clock_backwards"
+	ClockDuplicateReading = "duplicate_sequence"
+)
+
+// Device sequence number anomalies
+const (
+	SequenceGap        = "gap"          // Sequence skipped ahead; readings were lost
+	SequenceOutOfOrder = "out_of_order" // Sequence went back while the device clock did too
+	SequenceDuplicate  = "duplicate"    // Same sequence as the previous reading
+	SequenceReset      = "reset"        // Sequence went back while the device clock moved on, e.g. a reboot
+)
+
+// API key authentication failure reasons
+const (
+	AuthMissingKey  = "missing"
+	AuthInvalidKey  = "invalid"
+	AuthInvalidRoom = "invalid_room" // The room_id in the path is missing or not a number
+	AuthError       = "error"        // Keys could not be looked up
+)
+
+var (
+	// WorkerJobDuration times each job of the background worker loop
+	WorkerJobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "job_duration_seconds",
+		Help:      "Duration of background worker jobs.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"job"})
+
+	// WorkerRoomsProcessed counts live state updates made by the worker
+	WorkerRoomsProcessed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "rooms_processed_total",
+		Help:      "Room live state updates applied by the background worker.",
+	})
+
+	// WorkerReadingsApplied counts readings applied to live states
+	WorkerReadingsApplied = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "readings_applied_total",
+		Help:      "Telemetry readings applied to room live states.",
+	})
+
+	// WorkerQueueFull counts readings left to reconciliation because a shard's queue was full
+	WorkerQueueFull = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "queue_full_total",
+		Help:      "Readings not queued because the worker shard was full.",
+	})
+
+	// TelemetryIngested counts accepted readings per room
+	TelemetryIngested = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "telemetry",
+		Name:      "readings_ingested_total",
+		Help:      "Telemetry readings accepted, per room.",
+	}, []string{"room_id"})
+
+	// TelemetryRejected counts rejected uploads per room and reason
+	TelemetryRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "telemetry",
+		Name:      "uploads_rejected_total",
+		Help:      "Telemetry uploads rejected, per room and reason.",
+	}, []string{"room_id", "reason"})
+
+	// DeviceClockSkew is the running average clock skew of each device, identified by its API key
+	DeviceClockSkew = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "device",
+		Name:      "clock_skew_milliseconds",
+		Help:      "Running average of server receive time minus device time, per room and API key.",
+	}, []string{"room_id", "api_key_id"})
+
+	// TelemetryClockFlagged counts readings whose device timestamp was not used for timing
+	TelemetryClockFlagged = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "telemetry",
+		Name:      "clock_flagged_total",
+		Help:      "Readings whose device timestamp was not trusted, per room and reason.",
+	}, []string{"room_id", "reason"})
+
+	// TelemetrySequenceAnomalies counts device sequence number anomalies
+	TelemetrySequenceAnomalies = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "telemetry",
+		Name:      "sequence_anomalies_total",
+		Help:      "Device sequence number anomalies, per room and kind.",
+	}, []string{"room_id", "kind"})
+
+	// TelemetrySequenceMissed counts readings missing from gaps in device sequence numbers
+	TelemetrySequenceMissed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "telemetry",
+		Name:      "sequence_missed_readings_total",
+		Help:      "Readings missing from gaps in device sequence numbers, per room.",
+	}, []string{"room_id"})
+
+	// APIKeyAuthFailures counts device API key authentication failures
+	APIKeyAuthFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "api_key",
+		Name:      "auth_failures_total",
+		Help:      "Device API key authentication failures, per reason.",
+	}, []string{"reason"})
+
+	// DBErrors counts failed database operations, excluding record not found
+	DBErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "errors_total",
+		Help:      "Failed database operations, per operation.",
+	}, []string{"operation"})
+)
+
+// RoomLabel formats a room ID as a label value
+func RoomLabel(roomID uint) string {
+	return strconv.FormatUint(uint64(roomID), 10)
+}
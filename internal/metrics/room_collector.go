@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"log"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var roomLabels = []string{"room_id", "room", "hospital"}
+
+// medicalGases are the live state gas columns exported as pressures
+var medicalGases = []string{"oxygen", "nitrous", "air", "vacuum", "instrument", "carbon"}
+
+// RoomCollector exports the live state of every room as gauges, read from the database at scrape time
+// Sensor gauges are only exported for rooms that reported at least once
+type RoomCollector struct {
+	theaterRepo *repository.TheaterRepository
+
+	temperature      *prometheus.Desc
+	humidity         *prometheus.Desc
+	pressure         *prometheus.Desc
+	ach              *prometheus.Desc
+	gasPressure      *prometheus.Desc
+	ahuOn            *prometheus.Desc
+	telemetryAge     *prometheus.Desc
+	opRunning        *prometheus.Desc
+	opPaused         *prometheus.Desc
+	opElapsed        *prometheus.Desc
+	countdownRunning *prometheus.Desc
+	countdownLeft    *prometheus.Desc
+}
+
+func NewRoomCollector(theaterRepo *repository.TheaterRepository) *RoomCollector {
+	desc := func(name, help string, extra ...string) *prometheus.Desc {
+		labels := append(append([]string{}, roomLabels...), extra...)
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "room", name), help, labels, nil)
+	}
+
+	return &RoomCollector{
+		theaterRepo:      theaterRepo,
+		temperature:      desc("temperature_celsius", "Latest room temperature."),
+		humidity:         desc("humidity_percent", "Latest room relative humidity."),
+		pressure:         desc("pressure_pascals", "Latest room differential pressure."),
+		ach:              desc("air_changes_per_hour", "Air changes per hour, from AHU flow (theoretical) or AHU cycle timing (empirical).", "method"),
+		gasPressure:      desc("medical_gas_pressure_kpa", "Latest medical gas supply pressure.", "gas"),
+		ahuOn:            desc("ahu_on", "Whether the AHU was running at the latest reading."),
+		telemetryAge:     desc("telemetry_age_seconds", "Seconds since the room's latest processed reading."),
+		opRunning:        desc("operation_timer_running", "Whether the operation stopwatch is running."),
+		opPaused:         desc("operation_timer_paused", "Whether the operation stopwatch is paused."),
+		opElapsed:        desc("operation_timer_elapsed_seconds", "Elapsed time on the operation stopwatch."),
+		countdownRunning: desc("countdown_running", "Whether the countdown timer is running."),
+		countdownLeft:    desc("countdown_remaining_seconds", "Seconds left on the running countdown."),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *RoomCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.temperature, c.humidity, c.pressure, c.ach, c.gasPressure, c.ahuOn, c.telemetryAge,
+		c.opRunning, c.opPaused, c.opElapsed, c.countdownRunning, c.countdownLeft,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *RoomCollector) Collect(ch chan<- prometheus.Metric) {
+	states, err := c.theaterRepo.GetAllLiveStatesWithRooms()
+	if err != nil {
+		log.Printf("Error fetching live states for metrics: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range states {
+		state := &states[i]
+		labels := stateLabels(state)
+		gauge := func(desc *prometheus.Desc, value float64, extra ...string) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, append(labels, extra...)...)
+		}
+
+		// Timers are set by staff and exist whether or not the room reports
+		gauge(c.opRunning, boolValue(state.OpIsRunning))
+		gauge(c.opPaused, boolValue(state.OpIsPaused))
+		elapsed := float64(state.OpAccumulatedSeconds)
+		if state.OpIsRunning && state.OpStartTime != nil {
+			elapsed += now.Sub(*state.OpStartTime).Seconds()
+		}
+		gauge(c.opElapsed, elapsed)
+		gauge(c.countdownRunning, boolValue(state.CdIsRunning))
+		if state.CdIsRunning && state.CdTargetTime != nil {
+			gauge(c.countdownLeft, max(state.CdTargetTime.Sub(now).Seconds(), 0))
+		}
+
+		if state.LastProcessedAt == nil {
+			continue
+		}
+		gauge(c.telemetryAge, now.Sub(*state.LastProcessedAt).Seconds())
+		gauge(c.ahuOn, float64(state.CurrentLogicAhu))
+		gauge(c.ach, state.AchTheoretical, "theoretical")
+		gauge(c.ach, state.AchEmpirical, "empirical")
+		if value, ok := state.SensorValues["temp"]; ok {
+			gauge(c.temperature, value)
+		}
+		if value, ok := state.SensorValues["humidity"]; ok {
+			gauge(c.humidity, value)
+		}
+		if value, ok := state.SensorValues["room_pressure"]; ok {
+			gauge(c.pressure, value)
+		}
+		for _, gas := range medicalGases {
+			if value, ok := state.SensorValues[gas]; ok {
+				gauge(c.gasPressure, value, gas)
+			}
+		}
+	}
+}
+
+// stateLabels returns the room labels of a live state; legacy rows without a room are labelled by name only
+func stateLabels(state *models.TheaterLiveState) []string {
+	if state.RoomID == nil {
+		return []string{"", state.RoomName, ""}
+	}
+	return []string{RoomLabel(*state.RoomID), state.RoomName, state.Room.Hospital.Code}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"iot-backend-room-monitoring/internal/models"
 	"iot-backend-room-monitoring/internal/repository"
 	"iot-backend-room-monitoring/pkg/utils"
 
@@ -47,7 +48,7 @@ func (m *AccessControlMiddleware) CheckHospitalAccess() gin.HandlerFunc {
 		}
 
 		// Admin users have access to all hospitals
-		if role.(string) == "admin" {
+		if role.(string) == models.UserRoleSuperAdmin {
 			c.Next()
 			return
 		}
@@ -103,7 +104,7 @@ func (m *AccessControlMiddleware) CheckRoomAccess() gin.HandlerFunc {
 		}
 
 		// Admin users have access to all rooms
-		if role.(string) == "admin" {
+		if role.(string) == models.UserRoleSuperAdmin {
 			c.Next()
 			return
 		}
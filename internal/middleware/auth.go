@@ -4,13 +4,16 @@ import (
 	"net/http"
 	"strings"
 
+	"iot-backend-room-monitoring/internal/repository"
 	"iot-backend-room-monitoring/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 // AuthMiddleware validates JWT access token from Authorization header
-func AuthMiddleware() gin.HandlerFunc {
+// The user's role is read from the database rather than the token, so a role change applies
+// to the next request instead of when the token expires
+func AuthMiddleware(userRepo *repository.UserRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Extract token from Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -38,29 +41,20 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Inject claims into context
-		c.Set("userID", claims.UserID)
-		c.Set("role", claims.Role)
-
-		c.Next()
-	}
-}
-
-// RequireAdmin checks if the authenticated user has admin role
-func RequireAdmin() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists {
-			utils.ErrorResponse(c, http.StatusUnauthorized, "Authentication required")
+		user, err := userRepo.FindUserByID(claims.UserID)
+		if err != nil {
+			if err.Error() == "user not found" {
+				utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired token")
+			} else {
+				utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify token")
+			}
 			c.Abort()
 			return
 		}
 
-		if role != "admin" {
-			utils.ErrorResponse(c, http.StatusForbidden, "Admin access required")
-			c.Abort()
-			return
-		}
+		// Inject the user into context
+		c.Set("userID", user.ID)
+		c.Set("role", user.Role)
 
 		c.Next()
 	}
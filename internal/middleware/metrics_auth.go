@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"iot-backend-room-monitoring/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsAuth requires the configured bearer token to scrape metrics
+// An empty token rejects every request rather than leaving the endpoint open
+func MetricsAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		expected := "Bearer " + token
+		if token == "" || subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte(expected)) != 1 {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or missing metrics token")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+	"iot-backend-room-monitoring/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionMiddleware enforces role permissions on routes
+// Super admins pass every check; other users need a hospital role granting the permission
+// in the hospital the route acts on
+type PermissionMiddleware struct {
+	userHospitalRepo *repository.UserHospitalRepository
+	roomRepo         *repository.RoomRepository
+}
+
+// NewPermissionMiddleware creates a new permission middleware
+func NewPermissionMiddleware(
+	userHospitalRepo *repository.UserHospitalRepository,
+	roomRepo *repository.RoomRepository,
+) *PermissionMiddleware {
+	return &PermissionMiddleware{
+		userHospitalRepo: userHospitalRepo,
+		roomRepo:         roomRepo,
+	}
+}
+
+// Require checks a permission that is not scoped to a hospital
+// Hospital roles never grant these, so only super admins pass; it panics at route setup when
+// given a hospital permission, which must be checked with RequireForHospital or RequireForRoom
+func (m *PermissionMiddleware) Require(permission models.Permission) gin.HandlerFunc {
+	if models.IsHospitalScoped(permission) {
+		panic("permission " + string(permission) + " is granted by hospital roles and must be checked per hospital")
+	}
+
+	return func(c *gin.Context) {
+		userID, ok := requireSuperAdminOr(c)
+		if ok {
+			c.Next()
+			return
+		}
+		if userID == nil {
+			return
+		}
+
+		utils.ErrorResponse(c, http.StatusForbidden, "Access denied: missing permission "+string(permission))
+		c.Abort()
+	}
+}
+
+// RequireForHospital checks a permission in the hospital given by a path parameter
+func (m *PermissionMiddleware) RequireForHospital(permission models.Permission, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := requireSuperAdminOr(c)
+		if ok {
+			c.Next()
+			return
+		}
+		if userID == nil {
+			return
+		}
+
+		hospitalID, err := strconv.ParseUint(c.Param(param), 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid hospital ID")
+			c.Abort()
+			return
+		}
+
+		m.checkHospitalPermission(c, *userID, uint(hospitalID), permission)
+	}
+}
+
+// RequireForRoom checks a permission in the hospital of the room given by a path parameter
+func (m *PermissionMiddleware) RequireForRoom(permission models.Permission, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := requireSuperAdminOr(c)
+		if ok {
+			c.Next()
+			return
+		}
+		if userID == nil {
+			return
+		}
+
+		roomID, err := strconv.ParseUint(c.Param(param), 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid room ID")
+			c.Abort()
+			return
+		}
+
+		room, err := m.roomRepo.GetRoomByID(uint(roomID))
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusNotFound, "Room not found")
+			c.Abort()
+			return
+		}
+
+		m.checkHospitalPermission(c, *userID, room.HospitalID, permission)
+	}
+}
+
+// RequireInAnyHospital checks a permission in at least one of the user's hospitals
+// For routes whose hospital is only known from the body or a stored record; the service
+// must check the permission in that hospital
+func (m *PermissionMiddleware) RequireInAnyHospital(permission models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := requireSuperAdminOr(c)
+		if ok {
+			c.Next()
+			return
+		}
+		if userID == nil {
+			return
+		}
+
+		allowed, err := m.userHospitalRepo.UserHasPermissionInAnyHospital(*userID, permission)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify access")
+			c.Abort()
+			return
+		}
+		if !allowed {
+			utils.ErrorResponse(c, http.StatusForbidden, "Access denied: missing permission "+string(permission))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// checkHospitalPermission lets the request through if the user's role in the hospital grants the permission
+func (m *PermissionMiddleware) checkHospitalPermission(c *gin.Context, userID, hospitalID uint, permission models.Permission) {
+	allowed, err := m.userHospitalRepo.UserHasHospitalPermission(userID, hospitalID, permission)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify access")
+		c.Abort()
+		return
+	}
+	if !allowed {
+		utils.ErrorResponse(c, http.StatusForbidden, "Access denied: missing permission "+string(permission)+" in this hospital")
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// requireSuperAdminOr reports whether the authenticated user is a super admin
+// Otherwise it returns the user's ID for a hospital check, or nil after aborting an unauthenticated request
+func requireSuperAdminOr(c *gin.Context) (*uint, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Authentication required")
+		c.Abort()
+		return nil, false
+	}
+	role, _ := c.Get("role")
+	if role == models.UserRoleSuperAdmin {
+		return nil, true
+	}
+
+	id := userID.(uint)
+	return &id, false
+}
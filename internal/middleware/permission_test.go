@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"iot-backend-room-monitoring/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireAllowsOnlySuperAdmins(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Require never looks up hospital roles, so no repositories are needed
+	perm := NewPermissionMiddleware(nil, nil)
+
+	tests := []struct {
+		name   string
+		role   string // Empty for an unauthenticated request
+		status int
+	}{
+		{name: "super admin", role: models.UserRoleSuperAdmin, status: http.StatusOK},
+		{name: "user", role: models.UserRoleUser, status: http.StatusForbidden},
+		{name: "unauthenticated", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		r := gin.New()
+		r.GET("/", func(c *gin.Context) {
+			if tt.role != "" {
+				c.Set("userID", uint(7))
+				c.Set("role", tt.role)
+			}
+			c.Next()
+		}, perm.Require(models.PermissionManageSystem), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.status)
+		}
+	}
+}
+
+func TestRequireRejectsHospitalPermissions(t *testing.T) {
+	perm := NewPermissionMiddleware(nil, nil)
+
+	for _, permission := range []models.Permission{
+		models.PermissionViewRooms,
+		models.PermissionOperateTimers,
+		models.PermissionAckAlarms,
+		models.PermissionManageRooms,
+		models.PermissionManageHospital,
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Require(%s) did not panic, want hospital permissions refused", permission)
+				}
+			}()
+			perm.Require(permission)
+		}()
+	}
+}
@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// AlarmRule represents the alarm_rules table
+// A rule applies either to a single room (RoomID) or to every room of a type (RoomType)
+// Room-specific rules override room-type rules for the same sensor
+type AlarmRule struct {
+	ID                 uint      `gorm:"primaryKey" json:"id"`
+	RoomID             *uint     `gorm:"index" json:"room_id"`
+	RoomType           *string   `gorm:"type:enum('operating_theater','icu','isolation','general')" json:"room_type"`
+	Sensor             string    `gorm:"size:50;not null" json:"sensor"`
+	LowLimit           *float64  `gorm:"column:low_limit" json:"low_limit"`
+	HighLimit          *float64  `gorm:"column:high_limit" json:"high_limit"`
+	Hysteresis         float64   `gorm:"default:0" json:"hysteresis"`                                       // Margin the value must recover by before clearing
+	MinDurationSeconds int       `gorm:"column:min_duration_seconds;default:0" json:"min_duration_seconds"` // Violation must persist this long before raising
+	Severity           string    `gorm:"type:enum('info','warning','critical');default:'warning'" json:"severity"`
+	Description        string    `gorm:"size:255" json:"description,omitempty"`
+	IsActive           bool      `gorm:"default:true" json:"is_active"`
+	CreatedAt          time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt          time.Time `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName specifies the table name for AlarmRule model
+func (AlarmRule) TableName() string {
+	return "alarm_rules"
+}
+
+// Alarm lifecycle states
+const (
+	AlarmStateRaised       = "raised"
+	AlarmStateAcknowledged = "acknowledged"
+	AlarmStateCleared      = "cleared"
+)
+
+// Alarm types
+const (
+	AlarmTypeThreshold     = "threshold"      // Raised by an alarm rule
+	AlarmTypeDeviceOffline = "device_offline" // Raised when a room's telemetry goes stale
+)
+
+// Alarm represents the alarms table
+// An alarm is raised by the background worker and stays open until its condition clears
+type Alarm struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	RuleID          *uint      `gorm:"index" json:"rule_id"`
+	RoomID          uint       `gorm:"not null;index" json:"room_id"`
+	AlarmType       string     `gorm:"size:50;not null;default:'threshold'" json:"alarm_type"`
+	Sensor          string     `gorm:"size:50" json:"sensor,omitempty"`
+	Severity        string     `gorm:"type:enum('info','warning','critical');default:'warning'" json:"severity"`
+	State           string     `gorm:"type:enum('raised','acknowledged','cleared');default:'raised';index" json:"state"`
+	LimitType       string     `gorm:"size:20" json:"limit_type,omitempty"` // "low" or "high"
+	TriggerValue    *float64   `json:"trigger_value"`
+	LimitValue      *float64   `json:"limit_value"`
+	Message         string     `gorm:"size:255" json:"message"`
+	RaisedAt        time.Time  `gorm:"not null" json:"raised_at"`
+	AcknowledgedAt  *time.Time `json:"acknowledged_at"`
+	AcknowledgedBy  *uint      `json:"acknowledged_by"`
+	AcknowledgeNote string     `gorm:"size:255" json:"acknowledge_note,omitempty"`
+	ClearedAt       *time.Time `json:"cleared_at"`
+	UpdatedAt       time.Time  `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Relationships
+	Room *Room `gorm:"foreignKey:RoomID" json:"room,omitempty"`
+}
+
+// TableName specifies the table name for Alarm model
+func (Alarm) TableName() string {
+	return "alarms"
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// CalibrationProfile represents the calibration_profiles table
+// A profile belongs to a room, or to one device of the room when DeviceID is set;
+// a device profile replaces the room profile for readings from that device
+type CalibrationProfile struct {
+	ID          uint              `gorm:"primaryKey" json:"id"`
+	RoomID      uint              `gorm:"not null;index" json:"room_id"`
+	DeviceID    *uint             `gorm:"index" json:"device_id"`
+	Fields      CalibrationFields `gorm:"type:json;not null" json:"fields"`
+	Description string            `gorm:"size:255" json:"description,omitempty"`
+	UpdatedBy   *uint             `json:"updated_by,omitempty"`
+	CreatedAt   time.Time         `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   time.Time         `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName specifies the table name for CalibrationProfile model
+func (CalibrationProfile) TableName() string {
+	return "calibration_profiles"
+}
+
+// FieldCalibration corrects one telemetry field
+// The reading is first corrected as gain * value + offset in the device's unit, then converted to the storage unit
+type FieldCalibration struct {
+	Gain   *float64 `json:"gain,omitempty"` // Defaults to 1
+	Offset float64  `json:"offset"`         // In the device's unit
+	Unit   string   `json:"unit,omitempty"` // Unit the device reports in; empty means the storage unit
+}
+
+// CalibrationFields maps telemetry field names to their calibration, stored as a JSON object
+type CalibrationFields map[string]FieldCalibration
+
+// Value implements driver.Valuer
+func (f CalibrationFields) Value() (driver.Value, error) {
+	if f == nil {
+		return "{}", nil
+	}
+	encoded, err := json.Marshal(f)
+	return string(encoded), err
+}
+
+// Scan implements sql.Scanner
+func (f *CalibrationFields) Scan(value interface{}) error {
+	*f = CalibrationFields{}
+	return scanJSON(value, f)
+}
@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+// Countdown schedule states
+const (
+	CountdownSchedulePending   = "pending"
+	CountdownScheduleStarted   = "started"
+	CountdownScheduleMissed    = "missed" // Room's countdown was busy for the whole scheduled duration
+	CountdownScheduleCancelled = "cancelled"
+)
+
+// Countdown schedule recurrences
+const (
+	CountdownRecurrenceNone   = "none"
+	CountdownRecurrenceDaily  = "daily"
+	CountdownRecurrenceWeekly = "weekly"
+)
+
+// Countdown events
+const (
+	CountdownEventStarted   = "started"
+	CountdownEventCompleted = "completed" // Stopped or reset by staff before reaching zero
+	CountdownEventExpired   = "expired"   // Reached zero while running
+	CountdownEventMissed    = "missed"
+)
+
+// CountdownPreset represents the countdown_presets table
+// A named countdown duration offered for every room of a room type, e.g. "Turnaround cleaning"
+type CountdownPreset struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	RoomType        string    `gorm:"type:enum('operating_theater','icu','isolation','general');not null;uniqueIndex:idx_countdown_presets_type_name,priority:1" json:"room_type"`
+	Name            string    `gorm:"size:100;not null;uniqueIndex:idx_countdown_presets_type_name,priority:2" json:"name"`
+	DurationMinutes int       `gorm:"column:duration_minutes;not null" json:"duration_minutes"`
+	Description     string    `gorm:"size:255" json:"description,omitempty"`
+	CreatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName specifies the table name for CountdownPreset model
+func (CountdownPreset) TableName() string {
+	return "countdown_presets"
+}
+
+// CountdownSchedule represents the countdown_schedules table
+// The worker starts a room's countdown at ScheduledAt; a recurring schedule queues its next occurrence when it fires
+type CountdownSchedule struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	RoomID          uint       `gorm:"not null;index" json:"room_id"`
+	PresetID        *uint      `gorm:"index" json:"preset_id"`
+	Label           string     `gorm:"size:100" json:"label"`
+	DurationMinutes int        `gorm:"column:duration_minutes;not null" json:"duration_minutes"`
+	ScheduledAt     time.Time  `gorm:"not null;index:idx_countdown_schedules_status_scheduled,priority:2" json:"scheduled_at"`
+	Recurrence      string     `gorm:"type:enum('none','daily','weekly');default:'none'" json:"recurrence"`
+	Status          string     `gorm:"type:enum('pending','started','missed','cancelled');default:'pending';index:idx_countdown_schedules_status_scheduled,priority:1" json:"status"`
+	StartedAt       *time.Time `json:"started_at"`
+	CreatedBy       *uint      `json:"created_by"`
+	CreatedAt       time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Relationships
+	Room *Room `gorm:"foreignKey:RoomID" json:"room,omitempty"`
+}
+
+// TableName specifies the table name for CountdownSchedule model
+func (CountdownSchedule) TableName() string {
+	return "countdown_schedules"
+}
+
+// CountdownEvent represents the countdown_events table
+// Lifecycle log of room countdowns, whether started by staff or by a schedule
+type CountdownEvent struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	RoomID          uint      `gorm:"not null;index:idx_countdown_events_room_occurred,priority:1" json:"room_id"`
+	ScheduleID      *uint     `gorm:"index" json:"schedule_id"`
+	Event           string    `gorm:"type:enum('started','completed','expired','missed');not null" json:"event"`
+	Label           string    `gorm:"size:100" json:"label"`
+	DurationSeconds int       `gorm:"column:duration_seconds;default:0" json:"duration_seconds"` // Countdown length
+	OccurredAt      time.Time `gorm:"not null;index:idx_countdown_events_room_occurred,priority:2" json:"occurred_at"`
+	UserID          *uint     `json:"user_id"` // Nil when triggered by the worker
+	CreatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName specifies the table name for CountdownEvent model
+func (CountdownEvent) TableName() string {
+	return "countdown_events"
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Device status values
+const (
+	DeviceStatusOnline  = "online"
+	DeviceStatusOffline = "offline"
+)
+
+// Device represents the devices table
+// The physical ESP32 behind a room, registered automatically from its telemetry calls
+type Device struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	RoomID          uint       `gorm:"not null;index" json:"room_id"`
+	APIKeyID        *uint      `gorm:"column:api_key_id;uniqueIndex" json:"api_key_id"` // Key the device last authenticated with
+	MacAddress      *string    `gorm:"size:17;uniqueIndex" json:"mac_address"`
+	FirmwareVersion string     `gorm:"size:50" json:"firmware_version,omitempty"`
+	IPAddress       string     `gorm:"column:ip_address;size:45" json:"ip_address,omitempty"`
+	UptimeSeconds   *int64     `json:"uptime_seconds"`
+	Status          string     `gorm:"type:enum('online','offline');default:'online'" json:"status"`
+	LastSeenAt      *time.Time `json:"last_seen_at"`
+	CreatedAt       time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Relationships
+	Room *Room `gorm:"foreignKey:RoomID" json:"room,omitempty"`
+}
+
+// TableName specifies the table name for Device model
+func (Device) TableName() string {
+	return "devices"
+}
@@ -7,12 +7,19 @@ import "time"
 type DeviceAPIKey struct {
 	ID          uint       `gorm:"primaryKey" json:"id"`
 	RoomID      uint       `gorm:"not null;index" json:"room_id"`
-	APIKeyHash  string     `gorm:"size:255;not null;uniqueIndex" json:"-"` // Hidden from JSON for security
+	KeyID       *string    `gorm:"size:16;uniqueIndex" json:"key_id,omitempty"` // Public prefix of the key, nil for legacy keys
+	APIKeyHash  string     `gorm:"size:255;not null;uniqueIndex" json:"-"`      // Hidden from JSON for security
+	HashScheme  string     `gorm:"size:20;not null;default:'bcrypt'" json:"hash_scheme"`
 	CreatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	ExpiresAt   *time.Time `json:"expires_at"`
 	IsActive    bool       `gorm:"default:true" json:"is_active"`
 	Description string     `gorm:"size:255" json:"description,omitempty"`
 
+	// Rotation and usage tracking
+	ReplacesKeyID *uint      `json:"replaces_key_id,omitempty"` // Key this one was issued to succeed
+	LastUsedAt    *time.Time `json:"last_used_at"`
+	LastUsedIP    string     `gorm:"column:last_used_ip;size:45" json:"last_used_ip,omitempty"`
+
 	// Relationships
 	Room Room `gorm:"foreignKey:RoomID" json:"room,omitempty"`
 }
@@ -27,9 +34,15 @@ func (DeviceAPIKey) TableName() string {
 type DeviceAPIKeyResponse struct {
 	ID          uint       `json:"id"`
 	RoomID      uint       `json:"room_id"`
+	KeyID       *string    `json:"key_id,omitempty"`
+	HashScheme  string     `json:"hash_scheme"`       // "bcrypt" keys are legacy and should be rotated
 	APIKey      string     `json:"api_key,omitempty"` // Plain-text key, only populated during generation
 	CreatedAt   time.Time  `json:"created_at"`
 	ExpiresAt   *time.Time `json:"expires_at"`
 	IsActive    bool       `json:"is_active"`
 	Description string     `json:"description,omitempty"`
+
+	ReplacesKeyID *uint      `json:"replaces_key_id,omitempty"`
+	LastUsedAt    *time.Time `json:"last_used_at"`
+	LastUsedIP    string     `json:"last_used_ip,omitempty"`
 }
@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// DeviceConfig represents the device_configs table
+// One row per room; Version is bumped on every change so devices only download a config they don't have
+type DeviceConfig struct {
+	ID                       uint           `gorm:"primaryKey" json:"-"`
+	RoomID                   uint           `gorm:"not null;uniqueIndex" json:"room_id"`
+	Version                  uint           `gorm:"not null;default:1" json:"version"`
+	ReportingIntervalSeconds int            `gorm:"column:reporting_interval_seconds;not null;default:1" json:"reporting_interval_seconds"`
+	CalibrationOffsets       SensorValues   `gorm:"column:calibration_offsets;type:json" json:"calibration_offsets"` // Added to the raw reading on the device
+	EnabledSensors           SensorNameList `gorm:"column:enabled_sensors;type:json" json:"enabled_sensors"`
+	UpdatedBy                *uint          `json:"updated_by,omitempty"`
+	CreatedAt                time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"-"`
+	UpdatedAt                time.Time      `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName specifies the table name for DeviceConfig model
+func (DeviceConfig) TableName() string {
+	return "device_configs"
+}
+
+// Device command types
+const (
+	DeviceCommandReboot      = "reboot"
+	DeviceCommandRecalibrate = "recalibrate"
+	DeviceCommandIdentify    = "identify" // Blink the status LED so staff can find the device
+)
+
+// Device command lifecycle states
+const (
+	DeviceCommandPending   = "pending"   // Queued, not yet fetched by the device
+	DeviceCommandDelivered = "delivered" // Fetched, waiting for the device to acknowledge
+	DeviceCommandCompleted = "completed"
+	DeviceCommandFailed    = "failed"  // Reported failed, or never acknowledged within its deliveries
+	DeviceCommandExpired   = "expired" // Not acknowledged before ExpiresAt
+)
+
+// DeviceCommandMaxDeliveries is how many times a command is handed to the device before it is
+// failed unacknowledged. A reboot is delivered once: a device restarting before its acknowledgement
+// would otherwise fetch it again and reboot in a loop
+func DeviceCommandMaxDeliveries(command string) uint {
+	if command == DeviceCommandReboot {
+		return 1
+	}
+	return 3
+}
+
+// DeviceCommand represents the device_commands table
+// Commands are queued by admins and picked up by the room's device on its next poll
+type DeviceCommand struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	RoomID         uint       `gorm:"not null;index" json:"room_id"`
+	Command        string     `gorm:"type:enum('reboot','recalibrate','identify');not null" json:"command"`
+	State          string     `gorm:"type:enum('pending','delivered','completed','failed','expired');default:'pending';index" json:"state"`
+	Result         string     `gorm:"size:255" json:"result,omitempty"` // Message reported by the device on acknowledgement
+	CreatedBy      *uint      `json:"created_by,omitempty"`
+	ExpiresAt      time.Time  `gorm:"not null" json:"expires_at"`
+	DeliveryCount  uint       `gorm:"not null;default:0" json:"delivery_count"` // Times the device fetched it unacknowledged
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+	CreatedAt      time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName specifies the table name for DeviceCommand model
+func (DeviceCommand) TableName() string {
+	return "device_commands"
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Notification channel types
+// Webhooks are webhook subscriptions, which receive countdown events as countdown_timer
+const (
+	NotificationChannelEmail = "email"
+)
+
+// Notification event types
+const (
+	NotificationCountdownExpired = "countdown_expired"
+	NotificationCountdownMissed  = "countdown_missed"
+)
+
+// Notification delivery states
+const (
+	NotificationDeliveryPending   = "pending"   // Waiting for its first or next attempt
+	NotificationDeliveryDelivered = "delivered" // Sent through the channel
+	NotificationDeliveryFailed    = "failed"    // Gave up after the last retry
+)
+
+// NotificationChannel represents the notification_channels table
+// An outbound destination for room notifications: email to extra recipients and the hospital's staff
+type NotificationChannel struct {
+	ID                  uint      `gorm:"primaryKey" json:"id"`
+	HospitalID          *uint     `gorm:"index" json:"hospital_id"` // Nil = rooms of every hospital
+	Name                string    `gorm:"size:100;not null" json:"name"`
+	Type                string    `gorm:"type:enum('email');not null" json:"type"`
+	Recipients          string    `gorm:"size:1000" json:"recipients,omitempty"`                                  // Extra email addresses, comma separated
+	NotifyHospitalStaff bool      `gorm:"column:notify_hospital_staff;default:true" json:"notify_hospital_staff"` // Email users assigned to the room's hospital
+	EventTypes          string    `gorm:"column:event_types;size:255" json:"event_types"`                         // Comma separated; empty = every event
+	IsActive            bool      `gorm:"default:true" json:"is_active"`
+	CreatedAt           time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt           time.Time `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName specifies the table name for NotificationChannel model
+func (NotificationChannel) TableName() string {
+	return "notification_channels"
+}
+
+// NotificationDelivery represents the notification_deliveries table
+// One notification queued for one channel; the delivery job retries it with exponential backoff
+type NotificationDelivery struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	ChannelID     uint       `gorm:"not null;index" json:"channel_id"`
+	EventType     string     `gorm:"column:event_type;size:50;not null" json:"event_type"`
+	RoomID        *uint      `gorm:"index" json:"room_id"`
+	Payload       string     `gorm:"type:text;not null" json:"payload"` // The notification as JSON
+	Status        string     `gorm:"type:enum('pending','delivered','failed');default:'pending';index:idx_notification_deliveries_status_next,priority:1" json:"status"`
+	Attempts      int        `gorm:"default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"column:next_attempt_at;not null;index:idx_notification_deliveries_status_next,priority:2" json:"next_attempt_at"`
+	LastAttemptAt *time.Time `gorm:"column:last_attempt_at" json:"last_attempt_at"`
+	LastError     string     `gorm:"column:last_error;size:500" json:"last_error,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	CreatedAt     time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Relationships
+	Channel *NotificationChannel `gorm:"foreignKey:ChannelID" json:"channel,omitempty"`
+}
+
+// TableName specifies the table name for NotificationDelivery model
+func (NotificationDelivery) TableName() string {
+	return "notification_deliveries"
+}
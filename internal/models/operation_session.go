@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+// Operation session states
+const (
+	OperationSessionRunning   = "running"
+	OperationSessionPaused    = "paused"    // Stopwatch paused, can be resumed
+	OperationSessionCompleted = "completed" // Stopwatch stopped or reset, duration final
+)
+
+// Operation marker types
+const (
+	OperationMarkerStart  = "start"
+	OperationMarkerPause  = "pause"
+	OperationMarkerResume = "resume"
+	OperationMarkerStop   = "stop"
+	OperationMarkerLap    = "lap" // Named milestone such as induction, incision or closure
+)
+
+// OperationSession represents the operation_sessions table
+// A session is opened when a room's operation stopwatch starts and completed when it is stopped
+// or reset; every period the stopwatch ran in between is one interval
+type OperationSession struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	RoomID         uint       `gorm:"not null;index:idx_operation_sessions_room_started,priority:1" json:"room_id"`
+	ProcedureLabel string     `gorm:"size:255" json:"procedure_label,omitempty"`
+	Status         string     `gorm:"type:enum('running','paused','completed');default:'running';index" json:"status"`
+	StartedAt      time.Time  `gorm:"not null;index:idx_operation_sessions_room_started,priority:2" json:"started_at"`
+	EndedAt        *time.Time `json:"ended_at"`
+	TotalSeconds   int        `gorm:"column:total_seconds;default:0" json:"total_seconds"` // Sum of closed intervals
+	StartedBy      *uint      `json:"started_by"`
+	StoppedBy      *uint      `json:"stopped_by"` // User who stopped or reset the stopwatch
+	CreatedAt      time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Computed at read time, including the open interval of a running session
+	ElapsedSeconds int `gorm:"-" json:"elapsed_seconds"`
+
+	// Relationships
+	Intervals []OperationInterval `gorm:"foreignKey:SessionID" json:"intervals"`
+	Markers   []OperationMarker   `gorm:"foreignKey:SessionID" json:"markers"`
+	Room      *Room               `gorm:"foreignKey:RoomID" json:"room,omitempty"`
+}
+
+// TableName specifies the table name for OperationSession model
+func (OperationSession) TableName() string {
+	return "operation_sessions"
+}
+
+// OperationInterval represents the operation_intervals table
+// One period during which the stopwatch was running; EndedAt is nil while it still runs
+type OperationInterval struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	SessionID       uint       `gorm:"not null;index" json:"session_id"`
+	StartedAt       time.Time  `gorm:"not null" json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at"`
+	DurationSeconds int        `gorm:"column:duration_seconds;default:0" json:"duration_seconds"`
+	StartedBy       *uint      `json:"started_by"`
+	StoppedBy       *uint      `json:"stopped_by"`
+}
+
+// TableName specifies the table name for OperationInterval model
+func (OperationInterval) TableName() string {
+	return "operation_intervals"
+}
+
+// OperationMarker represents the operation_markers table
+// One entry of a session's timeline: a stopwatch transition or a named lap
+type OperationMarker struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	SessionID      uint      `gorm:"not null;index:idx_operation_markers_session_occurred,priority:1" json:"session_id"`
+	RoomID         uint      `gorm:"not null" json:"room_id"`
+	Type           string    `gorm:"type:enum('start','pause','resume','stop','lap');not null" json:"type"`
+	Name           string    `gorm:"size:100" json:"name,omitempty"`                          // Lap name
+	Reason         string    `gorm:"size:255" json:"reason,omitempty"`                        // Why the stopwatch was paused, resumed or stopped
+	ElapsedSeconds int       `gorm:"column:elapsed_seconds;default:0" json:"elapsed_seconds"` // Stopwatch reading at the marker
+	OccurredAt     time.Time `gorm:"not null;index:idx_operation_markers_session_occurred,priority:2" json:"occurred_at"`
+	UserID         *uint     `json:"user_id"`
+	CreatedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName specifies the table name for OperationMarker model
+func (OperationMarker) TableName() string {
+	return "operation_markers"
+}
@@ -0,0 +1,62 @@
+package models
+
+// User roles (users.role)
+// A super admin holds every permission in every hospital; other users get permissions from their hospital roles
+const (
+	UserRoleSuperAdmin = "admin"
+	UserRoleUser       = "user"
+)
+
+// Hospital roles, assigned per hospital through UserHospital
+const (
+	HospitalRoleViewer        = "viewer"         // Sees the hospital's rooms, readings and reports
+	HospitalRoleOperator      = "operator"       // Viewer who also runs the operation stopwatch and countdowns and acknowledges alarms
+	HospitalRoleHospitalAdmin = "hospital_admin" // Operator who also manages the hospital, its rooms, devices and users
+)
+
+// Permission is an action a route or service requires
+type Permission string
+
+const (
+	PermissionViewRooms      Permission = "rooms:view"         // Any assignment to a hospital grants it
+	PermissionOperateTimers  Permission = "timers:operate"     // Stopwatch, countdowns, countdown schedules and session labels
+	PermissionAckAlarms      Permission = "alarms:acknowledge" // Acknowledging the hospital's raised alarms
+	PermissionManageRooms    Permission = "rooms:manage"       // Rooms, device config and commands, calibration, device API keys
+	PermissionManageHospital Permission = "hospital:manage"    // Hospital details and its users' roles
+	PermissionManageSystem   Permission = "system:manage"      // Settings shared by all hospitals; super admin only
+)
+
+// hospitalRolePermissions lists the permissions each hospital role grants within its hospital
+var hospitalRolePermissions = map[string][]Permission{
+	HospitalRoleViewer:   {PermissionViewRooms},
+	HospitalRoleOperator: {PermissionViewRooms, PermissionOperateTimers, PermissionAckAlarms},
+	HospitalRoleHospitalAdmin: {
+		PermissionViewRooms, PermissionOperateTimers, PermissionAckAlarms, PermissionManageRooms, PermissionManageHospital,
+	},
+}
+
+// IsHospitalRole reports whether a role can be assigned per hospital
+func IsHospitalRole(role string) bool {
+	_, ok := hospitalRolePermissions[role]
+	return ok
+}
+
+// HospitalRoleHasPermission reports whether a hospital role grants a permission
+func HospitalRoleHasPermission(role string, permission Permission) bool {
+	for _, p := range hospitalRolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsHospitalScoped reports whether any hospital role grants a permission, so it must be checked per hospital
+func IsHospitalScoped(permission Permission) bool {
+	for role := range hospitalRolePermissions {
+		if HospitalRoleHasPermission(role, permission) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// SensorDefinition represents the sensor_definitions table
+// The definitions of a room type make up its sensor schema, which drives telemetry validation,
+// storage and dashboard output; room types without definitions use the built-in schema
+type SensorDefinition struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	RoomType  string    `gorm:"type:enum('operating_theater','icu','isolation','general');not null;uniqueIndex:idx_sensor_definitions_type_name,priority:1" json:"room_type"`
+	Name      string    `gorm:"size:50;not null;uniqueIndex:idx_sensor_definitions_type_name,priority:2" json:"name"` // Telemetry field name
+	Label     string    `gorm:"size:100" json:"label"`
+	Unit      string    `gorm:"size:20" json:"unit"`
+	Required  bool      `gorm:"default:false" json:"required"` // Readings without this sensor are rejected
+	MinValue  *float64  `gorm:"column:min_value" json:"min_value"`
+	MaxValue  *float64  `gorm:"column:max_value" json:"max_value"`
+	SortOrder int       `gorm:"column:sort_order;default:0" json:"sort_order"` // Dashboard display order
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"-"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName specifies the table name for SensorDefinition model
+func (SensorDefinition) TableName() string {
+	return "sensor_definitions"
+}
+
+// SensorReading is the latest value of one schema sensor, as shown on the dashboard
+type SensorReading struct {
+	Name     string   `json:"name"`
+	Label    string   `json:"label"`
+	Unit     string   `json:"unit"`
+	Required bool     `json:"required"`
+	Value    *float64 `json:"value"` // Nil when the sensor has not reported
+}
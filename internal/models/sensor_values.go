@@ -0,0 +1,56 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// SensorValues maps sensor names to a number, stored as a JSON object (NULL when nil)
+type SensorValues map[string]float64
+
+// Value implements driver.Valuer
+func (o SensorValues) Value() (driver.Value, error) {
+	if o == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(o)
+	return string(encoded), err
+}
+
+// Scan implements sql.Scanner
+func (o *SensorValues) Scan(value interface{}) error {
+	*o = SensorValues{}
+	return scanJSON(value, o)
+}
+
+// SensorNameList is a list of sensor names, stored as a JSON array (NULL when nil)
+type SensorNameList []string
+
+// Value implements driver.Valuer
+func (l SensorNameList) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(l)
+	return string(encoded), err
+}
+
+// Scan implements sql.Scanner
+func (l *SensorNameList) Scan(value interface{}) error {
+	*l = SensorNameList{}
+	return scanJSON(value, l)
+}
+
+// scanJSON decodes a JSON column into dest, leaving it untouched for NULL
+func scanJSON(value interface{}, dest interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	}
+	return errors.New("unsupported JSON column type")
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// TheaterTelemetryHistory represents the theater_telemetry_history table
+// Append-only log of every reading received from a room's ESP32 device
+type TheaterTelemetryHistory struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	RoomID     uint      `gorm:"not null;index:idx_history_room_recorded,priority:1" json:"room_id"`
+	RecordedAt time.Time `gorm:"not null;index:idx_history_room_recorded,priority:2" json:"recorded_at"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// Sensor inputs from hardware
+	Temp         *float64 `json:"temp"`
+	Humidity     *int     `json:"humidity"`
+	RoomPressure *float64 `gorm:"column:room_pressure" json:"room_pressure"`
+	RoomStatus   int      `gorm:"default:0" json:"room_status"`
+
+	// ACH Calculation inputs
+	LajuAliranAhu int `gorm:"column:laju_aliran_ahu;default:0" json:"laju_aliran_ahu"`
+	VolumeRuangan int `gorm:"column:volume_ruangan;default:0" json:"volume_ruangan"`
+	LogicAhu      int `gorm:"column:logic_ahu;default:0" json:"logic_ahu"`
+
+	// Medical gases
+	Oxygen     *float64 `json:"oxygen"`
+	Nitrous    *float64 `json:"nitrous"`
+	Air        *float64 `json:"air"`
+	Vacuum     *int     `json:"vacuum"`
+	Instrument *float64 `json:"instrument"`
+	Carbon     *float64 `json:"carbon"`
+
+	// Sensors of the room's schema without a dedicated column
+	ExtraSensors SensorValues `gorm:"column:extra_sensors;type:json" json:"extra_sensors,omitempty"`
+
+	// Device clock (optional, reported by newer firmware)
+	DeviceTimestamp  *time.Time `gorm:"column:device_timestamp" json:"device_timestamp"`
+	Sequence         *uint32    `json:"sequence"`
+	ClockSkewMs      *int64     `gorm:"column:clock_skew_ms" json:"clock_skew_ms"`
+	ClockSkewFlagged bool       `gorm:"column:clock_skew_flagged;default:false" json:"clock_skew_flagged"`
+
+	// Calibration (values above are calibrated; the device's original values are kept for audit)
+	CalibrationProfileID *uint        `gorm:"column:calibration_profile_id" json:"calibration_profile_id"`
+	UncalibratedValues   SensorValues `gorm:"column:uncalibrated_values;type:json" json:"uncalibrated_values,omitempty"`
+}
+
+// TableName specifies the table name for TheaterTelemetryHistory model
+func (TheaterTelemetryHistory) TableName() string {
+	return "theater_telemetry_history"
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// TelemetryRollup represents the telemetry_rollups table
+// Each row aggregates one sensor of one room over a fixed time bucket
+type TelemetryRollup struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	RoomID      uint      `gorm:"not null;uniqueIndex:uq_rollup_bucket,priority:1" json:"room_id"`
+	Resolution  string    `gorm:"type:enum('1m','15m','1h');not null;uniqueIndex:uq_rollup_bucket,priority:2" json:"resolution"`
+	BucketStart time.Time `gorm:"not null;uniqueIndex:uq_rollup_bucket,priority:3" json:"bucket_start"`
+	Sensor      string    `gorm:"size:50;not null;uniqueIndex:uq_rollup_bucket,priority:4" json:"sensor"`
+	MinValue    float64   `gorm:"column:min_value" json:"min"`
+	MaxValue    float64   `gorm:"column:max_value" json:"max"`
+	AvgValue    float64   `gorm:"column:avg_value" json:"avg"`
+	SampleCount int       `gorm:"column:sample_count;default:0" json:"sample_count"`
+	UpdatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName specifies the table name for TelemetryRollup model
+func (TelemetryRollup) TableName() string {
+	return "telemetry_rollups"
+}
@@ -30,6 +30,15 @@ type TheaterRawTelemetry struct {
 	Instrument *float64 `json:"instrument"`
 	Carbon     *float64 `json:"carbon"`
 
+	// Sensors of the room's schema without a dedicated column
+	ExtraSensors SensorValues `gorm:"column:extra_sensors;type:json" json:"extra_sensors,omitempty"`
+
+	// Device clock (optional, reported by newer firmware)
+	DeviceTimestamp  *time.Time `gorm:"column:device_timestamp" json:"device_timestamp"`                   // When the device took the reading
+	Sequence         *uint32    `json:"sequence"`                                                          // Per-device reading counter
+	ClockSkewMs      *int64     `gorm:"column:clock_skew_ms" json:"clock_skew_ms"`                         // Server receive time minus device time
+	ClockSkewFlagged bool       `gorm:"column:clock_skew_flagged;default:false" json:"clock_skew_flagged"` // Device time not trusted for timing
+
 	// Relationships
 	Room Room `gorm:"foreignKey:RoomID" json:"room,omitempty"`
 }
@@ -59,11 +68,15 @@ type TheaterLiveState struct {
 	OpStartTime          *time.Time `gorm:"column:op_start_time" json:"op_start_time"`
 	OpAccumulatedSeconds int        `gorm:"column:op_accumulated_seconds;default:0" json:"op_accumulated_seconds"`
 	OpIsRunning          bool       `gorm:"column:op_is_running;default:false" json:"op_is_running"`
+	OpIsPaused           bool       `gorm:"column:op_is_paused;default:false" json:"op_is_paused"`
+	OpPauseReason        string     `gorm:"column:op_pause_reason;size:255" json:"op_pause_reason"`
 
 	// D. Countdown logic (admin controlled)
 	CdTargetTime      *time.Time `gorm:"column:cd_target_time" json:"cd_target_time"`
 	CdDurationSeconds int        `gorm:"column:cd_duration_seconds;default:3600" json:"cd_duration_seconds"`
 	CdIsRunning       bool       `gorm:"column:cd_is_running;default:false" json:"cd_is_running"`
+	CdLabel           string     `gorm:"column:cd_label;size:100" json:"cd_label"`    // Preset or schedule name, if any
+	CdScheduleID      *uint      `gorm:"column:cd_schedule_id" json:"cd_schedule_id"` // Schedule that started the countdown
 
 	// E. Internal worker state (for Method 2 timing)
 	AhuCycleStartTime  *time.Time `gorm:"column:ahu_cycle_start_time" json:"ahu_cycle_start_time"`
@@ -78,8 +91,23 @@ type TheaterLiveState struct {
 	Instrument *float64 `json:"instrument"`
 	Carbon     *float64 `json:"carbon"`
 
+	// Latest value of every sensor reported under the room's schema
+	SensorValues SensorValues `gorm:"column:sensor_values;type:json" json:"sensor_values"`
+
 	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
 
+	// G. Data freshness (computed from LastProcessedAt, not stored)
+	DataAgeSeconds    *int64 `gorm:"-" json:"data_age_seconds"` // Nil if the room never reported
+	StaleAfterSeconds int64  `gorm:"-" json:"stale_after_seconds"`
+	IsStale           bool   `gorm:"-" json:"is_stale"`
+
+	// H. Dashboard sensors (from the room type's sensor schema, not stored)
+	SensorReadings []SensorReading `gorm:"-" json:"sensor_readings"`
+
+	// I. Stopwatch timeline (markers of the room's latest operation session, not stored)
+	OpSessionID *uint             `gorm:"-" json:"op_session_id"`
+	OpMarkers   []OperationMarker `gorm:"-" json:"op_markers"`
+
 	// Relationships
 	Room Room `gorm:"foreignKey:RoomID" json:"room,omitempty"`
 }
@@ -8,6 +8,7 @@ type User struct {
 	Username     string    `gorm:"uniqueIndex;not null;size:50" json:"username"`
 	PasswordHash string    `gorm:"not null;size:255" json:"-"`
 	Role         string    `gorm:"type:enum('admin','user');default:'user'" json:"role"`
+	Email        string    `gorm:"size:255" json:"email,omitempty"` // Notifications for the user's hospitals go here
 	CreatedAt    time.Time `json:"created_at"`
 }
 
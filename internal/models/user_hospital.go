@@ -3,11 +3,12 @@ package models
 import "time"
 
 // UserHospital represents the many-to-many relationship between users and hospitals
-// This table controls which hospitals a user has access to
+// This table controls which hospitals a user has access to, and with which hospital role
 type UserHospital struct {
 	ID         uint      `gorm:"primaryKey" json:"id"`
 	UserID     uint      `gorm:"not null;index" json:"user_id"`
 	HospitalID uint      `gorm:"not null;index" json:"hospital_id"`
+	Role       string    `gorm:"type:enum('viewer','operator','hospital_admin');default:'viewer'" json:"role"` // Permissions within this hospital
 	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 
 	// Relationships
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Webhook event types
+const (
+	WebhookEventOperationTimer    = "operation_timer" // Stopwatch started, paused, resumed, stopped, reset or lapped
+	WebhookEventCountdownTimer    = "countdown_timer" // Countdown started, completed, expired or missed
+	WebhookEventAlarmRaised       = "alarm_raised"    // Includes device offline alarms for stale rooms
+	WebhookEventAlarmAcknowledged = "alarm_acknowledged"
+	WebhookEventAlarmCleared      = "alarm_cleared"
+	WebhookEventDeviceOffline     = "device_offline" // A device stopped reporting
+)
+
+// Webhook delivery states
+const (
+	WebhookDeliveryPending   = "pending"   // Waiting for its first or next attempt
+	WebhookDeliveryDelivered = "delivered" // Receiver answered with a 2xx status
+	WebhookDeliveryFailed    = "failed"    // Gave up after the last retry
+)
+
+// WebhookSubscription represents the webhook_subscriptions table
+// An external endpoint that receives signed room events
+type WebhookSubscription struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Name       string    `gorm:"size:100;not null" json:"name"`
+	URL        string    `gorm:"column:url;size:500;not null" json:"url"`
+	Secret     string    `gorm:"size:100;not null" json:"-"`                     // HMAC-SHA256 signing secret
+	EventTypes string    `gorm:"column:event_types;size:255" json:"event_types"` // Comma separated; empty = every event
+	HospitalID *uint     `gorm:"index" json:"hospital_id"`                       // Nil = rooms of every hospital
+	IsActive   bool      `gorm:"default:true" json:"is_active"`
+	CreatedBy  *uint     `json:"created_by"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName specifies the table name for WebhookSubscription model
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// WebhookDelivery represents the webhook_deliveries table
+// One event queued for one subscription; the delivery job retries it with exponential backoff
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	SubscriptionID uint       `gorm:"not null;index" json:"subscription_id"`
+	EventID        string     `gorm:"column:event_id;size:36;not null" json:"event_id"` // Same for every subscription receiving the event
+	EventType      string     `gorm:"column:event_type;size:50;not null" json:"event_type"`
+	RoomID         *uint      `gorm:"index" json:"room_id"`
+	Payload        string     `gorm:"type:text;not null" json:"payload"`
+	Status         string     `gorm:"type:enum('pending','delivered','failed');default:'pending';index:idx_webhook_deliveries_status_next,priority:1" json:"status"`
+	Attempts       int        `gorm:"default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"column:next_attempt_at;not null;index:idx_webhook_deliveries_status_next,priority:2" json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `gorm:"column:last_attempt_at" json:"last_attempt_at"`
+	LastStatusCode *int       `gorm:"column:last_status_code" json:"last_status_code"`
+	LastError      string     `gorm:"column:last_error;size:500" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Relationships
+	Subscription *WebhookSubscription `gorm:"foreignKey:SubscriptionID" json:"subscription,omitempty"`
+}
+
+// TableName specifies the table name for WebhookDelivery model
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
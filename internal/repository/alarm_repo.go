@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type AlarmRepository struct {
+	db *gorm.DB
+}
+
+func NewAlarmRepo(db *gorm.DB) *AlarmRepository {
+	return &AlarmRepository{db: db}
+}
+
+// AlarmFilter holds the optional filters for listing alarms
+type AlarmFilter struct {
+	RoomID      *uint
+	State       string
+	ActiveOnly  bool   // Only raised or acknowledged alarms
+	HospitalIDs []uint // Restrict to rooms in these hospitals (nil = no restriction)
+	Limit       int
+	Offset      int
+}
+
+// GetActiveAlarmRules retrieves all active alarm rules
+func (r *AlarmRepository) GetActiveAlarmRules() ([]models.AlarmRule, error) {
+	var rules []models.AlarmRule
+	err := r.db.Where("is_active = ?", true).Find(&rules).Error
+	return rules, err
+}
+
+// GetAlarmRules retrieves alarm rules, optionally filtered by room or room type
+func (r *AlarmRepository) GetAlarmRules(roomID *uint, roomType string) ([]models.AlarmRule, error) {
+	query := r.db.Model(&models.AlarmRule{})
+	if roomID != nil {
+		query = query.Where("room_id = ?", *roomID)
+	}
+	if roomType != "" {
+		query = query.Where("room_type = ?", roomType)
+	}
+
+	var rules []models.AlarmRule
+	err := query.Order("id ASC").Find(&rules).Error
+	return rules, err
+}
+
+// GetAlarmRuleByID retrieves an alarm rule by ID
+func (r *AlarmRepository) GetAlarmRuleByID(id uint) (*models.AlarmRule, error) {
+	var rule models.AlarmRule
+	err := r.db.Where("id = ?", id).First(&rule).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("alarm rule not found")
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// CreateAlarmRule creates a new alarm rule
+func (r *AlarmRepository) CreateAlarmRule(rule *models.AlarmRule) error {
+	return r.db.Create(rule).Error
+}
+
+// UpdateAlarmRule updates an existing alarm rule
+// If the rule is deactivated its open alarms are cleared at clearedAt in the same transaction
+// and returned
+func (r *AlarmRepository) UpdateAlarmRule(rule *models.AlarmRule, clearedAt time.Time) ([]models.Alarm, error) {
+	var cleared []models.Alarm
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(rule).Error; err != nil {
+			return err
+		}
+		if rule.IsActive {
+			return nil
+		}
+		var err error
+		cleared, err = clearOpenAlarmsByRuleID(tx, rule.ID, clearedAt)
+		return err
+	})
+	return cleared, err
+}
+
+// DeleteAlarmRule permanently deletes an alarm rule
+// Its open alarms are cleared at clearedAt in the same transaction and returned
+func (r *AlarmRepository) DeleteAlarmRule(id uint, clearedAt time.Time) ([]models.Alarm, error) {
+	var cleared []models.Alarm
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		cleared, err = clearOpenAlarmsByRuleID(tx, id, clearedAt)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Delete(&models.AlarmRule{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("alarm rule not found")
+		}
+		return nil
+	})
+	return cleared, err
+}
+
+// clearOpenAlarmsByRuleID clears a rule's raised or acknowledged alarms and returns them as cleared
+func clearOpenAlarmsByRuleID(tx *gorm.DB, ruleID uint, clearedAt time.Time) ([]models.Alarm, error) {
+	var alarms []models.Alarm
+	err := tx.Where("rule_id = ? AND state IN ?", ruleID,
+		[]string{models.AlarmStateRaised, models.AlarmStateAcknowledged}).
+		Find(&alarms).Error
+	if err != nil || len(alarms) == 0 {
+		return nil, err
+	}
+
+	ids := make([]uint, len(alarms))
+	for i := range alarms {
+		ids[i] = alarms[i].ID
+		alarms[i].State = models.AlarmStateCleared
+		alarms[i].ClearedAt = &clearedAt
+	}
+	err = tx.Model(&models.Alarm{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{"state": models.AlarmStateCleared, "cleared_at": clearedAt}).Error
+	return alarms, err
+}
+
+// CreateAlarm records a newly raised alarm
+func (r *AlarmRepository) CreateAlarm(alarm *models.Alarm) error {
+	return r.db.Create(alarm).Error
+}
+
+// UpdateAlarm updates an existing alarm
+func (r *AlarmRepository) UpdateAlarm(alarm *models.Alarm) error {
+	return r.db.Save(alarm).Error
+}
+
+// ClearAlarm marks an alarm as cleared, writing only its state and cleared_at
+// The rest of the row is left alone, so a clear never rewrites a rule_id nulled by a rule deletion
+func (r *AlarmRepository) ClearAlarm(id uint, clearedAt time.Time) error {
+	return r.db.Model(&models.Alarm{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"state": models.AlarmStateCleared, "cleared_at": clearedAt}).Error
+}
+
+// GetAlarmByID retrieves an alarm by ID
+func (r *AlarmRepository) GetAlarmByID(id uint) (*models.Alarm, error) {
+	var alarm models.Alarm
+	err := r.db.Where("id = ?", id).First(&alarm).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("alarm not found")
+		}
+		return nil, err
+	}
+	return &alarm, nil
+}
+
+// GetOpenAlarmsByRoomID retrieves raised or acknowledged alarms for a room
+func (r *AlarmRepository) GetOpenAlarmsByRoomID(roomID uint) ([]models.Alarm, error) {
+	var alarms []models.Alarm
+	err := r.db.Where("room_id = ? AND state IN ?", roomID,
+		[]string{models.AlarmStateRaised, models.AlarmStateAcknowledged}).
+		Find(&alarms).Error
+	return alarms, err
+}
+
+// GetAlarms retrieves alarms matching the filter, newest first, with the total count
+func (r *AlarmRepository) GetAlarms(filter AlarmFilter) ([]models.Alarm, int64, error) {
+	query := r.db.Model(&models.Alarm{})
+	if filter.RoomID != nil {
+		query = query.Where("alarms.room_id = ?", *filter.RoomID)
+	}
+	if filter.State != "" {
+		query = query.Where("alarms.state = ?", filter.State)
+	}
+	if filter.ActiveOnly {
+		query = query.Where("alarms.state IN ?", []string{models.AlarmStateRaised, models.AlarmStateAcknowledged})
+	}
+	if filter.HospitalIDs != nil {
+		query = query.Joins("INNER JOIN rooms ON rooms.id = alarms.room_id").
+			Where("rooms.hospital_id IN ?", filter.HospitalIDs)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var alarms []models.Alarm
+	err := query.Preload("Room").
+		Order("alarms.raised_at DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&alarms).Error
+	return alarms, total, err
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"errors"
+
+	"iot-backend-room-monitoring/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type CalibrationRepository struct {
+	db *gorm.DB
+}
+
+func NewCalibrationRepo(db *gorm.DB) *CalibrationRepository {
+	return &CalibrationRepository{db: db}
+}
+
+// GetAllProfiles retrieves every calibration profile
+func (r *CalibrationRepository) GetAllProfiles() ([]models.CalibrationProfile, error) {
+	var profiles []models.CalibrationProfile
+	err := r.db.Find(&profiles).Error
+	return profiles, err
+}
+
+// GetProfilesByRoomID retrieves the room profile and device profiles of a room
+func (r *CalibrationRepository) GetProfilesByRoomID(roomID uint) ([]models.CalibrationProfile, error) {
+	var profiles []models.CalibrationProfile
+	err := r.db.Where("room_id = ?", roomID).Order("device_id ASC").Find(&profiles).Error
+	return profiles, err
+}
+
+// GetProfile retrieves the room profile (deviceID nil) or a device profile of a room
+func (r *CalibrationRepository) GetProfile(roomID uint, deviceID *uint) (*models.CalibrationProfile, error) {
+	query := r.db.Where("room_id = ?", roomID)
+	if deviceID == nil {
+		query = query.Where("device_id IS NULL")
+	} else {
+		query = query.Where("device_id = ?", *deviceID)
+	}
+
+	var profile models.CalibrationProfile
+	err := query.First(&profile).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("calibration profile not found")
+		}
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// SaveProfile creates or updates a calibration profile
+func (r *CalibrationRepository) SaveProfile(profile *models.CalibrationProfile) error {
+	return r.db.Save(profile).Error
+}
+
+// DeleteProfile permanently deletes a calibration profile
+func (r *CalibrationRepository) DeleteProfile(id uint) error {
+	return r.db.Delete(&models.CalibrationProfile{}, id).Error
+}
@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type CountdownRepository struct {
+	db *gorm.DB
+}
+
+func NewCountdownRepo(db *gorm.DB) *CountdownRepository {
+	return &CountdownRepository{db: db}
+}
+
+// CountdownScheduleFilter holds the optional filters for listing countdown schedules
+type CountdownScheduleFilter struct {
+	RoomID      *uint
+	Status      string
+	From        *time.Time // Scheduled at or after
+	To          *time.Time // Scheduled before
+	HospitalIDs []uint     // Restrict to rooms in these hospitals (nil = no restriction)
+	Limit       int
+	Offset      int
+}
+
+// CountdownEventFilter holds the optional filters for listing countdown events
+type CountdownEventFilter struct {
+	RoomID      *uint
+	ScheduleID  *uint
+	Event       string
+	From        *time.Time // Occurred at or after
+	To          *time.Time // Occurred before
+	HospitalIDs []uint     // Restrict to rooms in these hospitals (nil = no restriction)
+	Limit       int
+	Offset      int
+}
+
+// GetPresets retrieves countdown presets, optionally of one room type, ordered by name
+func (r *CountdownRepository) GetPresets(roomType string) ([]models.CountdownPreset, error) {
+	var presets []models.CountdownPreset
+	query := r.db.Order("room_type ASC, name ASC")
+	if roomType != "" {
+		query = query.Where("room_type = ?", roomType)
+	}
+	err := query.Find(&presets).Error
+	return presets, err
+}
+
+// GetPresetByID retrieves a countdown preset by ID
+func (r *CountdownRepository) GetPresetByID(id uint) (*models.CountdownPreset, error) {
+	var preset models.CountdownPreset
+	err := r.db.Where("id = ?", id).First(&preset).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("countdown preset not found")
+		}
+		return nil, err
+	}
+	return &preset, nil
+}
+
+// CreatePreset creates a new countdown preset
+func (r *CountdownRepository) CreatePreset(preset *models.CountdownPreset) error {
+	return r.db.Create(preset).Error
+}
+
+// UpdatePreset updates an existing countdown preset
+func (r *CountdownRepository) UpdatePreset(preset *models.CountdownPreset) error {
+	return r.db.Save(preset).Error
+}
+
+// DeletePreset deletes a countdown preset
+// Schedules created from it keep their own label and duration
+func (r *CountdownRepository) DeletePreset(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.CountdownSchedule{}).Where("preset_id = ?", id).Update("preset_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.CountdownPreset{}, id).Error
+	})
+}
+
+// GetScheduleByID retrieves a countdown schedule with its room
+func (r *CountdownRepository) GetScheduleByID(id uint) (*models.CountdownSchedule, error) {
+	var schedule models.CountdownSchedule
+	err := r.db.Where("id = ?", id).Preload("Room").First(&schedule).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("countdown schedule not found")
+		}
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// GetSchedules retrieves schedules matching the filter, soonest first, with the total count
+func (r *CountdownRepository) GetSchedules(filter CountdownScheduleFilter) ([]models.CountdownSchedule, int64, error) {
+	query := r.db.Model(&models.CountdownSchedule{})
+	if filter.RoomID != nil {
+		query = query.Where("countdown_schedules.room_id = ?", *filter.RoomID)
+	}
+	if filter.Status != "" {
+		query = query.Where("countdown_schedules.status = ?", filter.Status)
+	}
+	if filter.From != nil {
+		query = query.Where("countdown_schedules.scheduled_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("countdown_schedules.scheduled_at < ?", *filter.To)
+	}
+	if filter.HospitalIDs != nil {
+		query = query.Joins("INNER JOIN rooms ON rooms.id = countdown_schedules.room_id").
+			Where("rooms.hospital_id IN ?", filter.HospitalIDs)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var schedules []models.CountdownSchedule
+	err := query.Preload("Room").
+		Order("countdown_schedules.scheduled_at ASC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&schedules).Error
+	return schedules, total, err
+}
+
+// GetDueSchedules retrieves pending schedules whose time has come, oldest first
+func (r *CountdownRepository) GetDueSchedules(now time.Time) ([]models.CountdownSchedule, error) {
+	var schedules []models.CountdownSchedule
+	err := r.db.Where("status = ? AND scheduled_at <= ?", models.CountdownSchedulePending, now).
+		Order("scheduled_at ASC, id ASC").
+		Find(&schedules).Error
+	return schedules, err
+}
+
+// CreateSchedule creates a new countdown schedule
+func (r *CountdownRepository) CreateSchedule(schedule *models.CountdownSchedule) error {
+	return r.db.Omit("Room").Create(schedule).Error
+}
+
+// UpdatePendingScheduleStatus moves a schedule out of pending
+// Returns false when the schedule is no longer pending, e.g. it was cancelled or already started
+func (r *CountdownRepository) UpdatePendingScheduleStatus(id uint, status string) (bool, error) {
+	result := r.db.Model(&models.CountdownSchedule{}).
+		Where("id = ? AND status = ?", id, models.CountdownSchedulePending).
+		Update("status", status)
+	return result.RowsAffected > 0, result.Error
+}
+
+// errScheduleNotStarted rolls back a schedule start that was cancelled or found the countdown running
+var errScheduleNotStarted = errors.New("schedule not started")
+
+// StartPendingSchedule marks a pending schedule started and starts its room's countdown in one transaction
+// countdown holds the live state countdown fields to set
+// Returns false, changing nothing, when the schedule is no longer pending or the room's countdown is running
+func (r *CountdownRepository) StartPendingSchedule(schedule *models.CountdownSchedule, startedAt time.Time, countdown map[string]interface{}) (bool, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.CountdownSchedule{}).
+			Where("id = ? AND status = ?", schedule.ID, models.CountdownSchedulePending).
+			Updates(map[string]interface{}{"status": models.CountdownScheduleStarted, "started_at": startedAt})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errScheduleNotStarted
+		}
+
+		result = tx.Model(&models.TheaterLiveState{}).
+			Where("room_id = ? AND cd_is_running = ?", schedule.RoomID, false).
+			Updates(countdown)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errScheduleNotStarted
+		}
+		return nil
+	})
+	if errors.Is(err, errScheduleNotStarted) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CreateEvent records a countdown event
+func (r *CountdownRepository) CreateEvent(event *models.CountdownEvent) error {
+	return r.db.Create(event).Error
+}
+
+// GetEvents retrieves events matching the filter, newest first, with the total count
+func (r *CountdownRepository) GetEvents(filter CountdownEventFilter) ([]models.CountdownEvent, int64, error) {
+	query := r.db.Model(&models.CountdownEvent{})
+	if filter.RoomID != nil {
+		query = query.Where("countdown_events.room_id = ?", *filter.RoomID)
+	}
+	if filter.ScheduleID != nil {
+		query = query.Where("countdown_events.schedule_id = ?", *filter.ScheduleID)
+	}
+	if filter.Event != "" {
+		query = query.Where("countdown_events.event = ?", filter.Event)
+	}
+	if filter.From != nil {
+		query = query.Where("countdown_events.occurred_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("countdown_events.occurred_at < ?", *filter.To)
+	}
+	if filter.HospitalIDs != nil {
+		query = query.Joins("INNER JOIN rooms ON rooms.id = countdown_events.room_id").
+			Where("rooms.hospital_id IN ?", filter.HospitalIDs)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []models.CountdownEvent
+	err := query.Order("countdown_events.occurred_at DESC, countdown_events.id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&events).Error
+	return events, total, err
+}
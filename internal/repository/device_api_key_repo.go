@@ -56,6 +56,28 @@ func (r *DeviceAPIKeyRepository) ValidateAPIKey(keyHash string, roomID uint) (bo
 	return true, nil
 }
 
+// GetAPIKeyByKeyID retrieves an API key by its public key ID
+func (r *DeviceAPIKeyRepository) GetAPIKeyByKeyID(keyID string) (*models.DeviceAPIKey, error) {
+	var key models.DeviceAPIKey
+	err := r.db.Where("key_id = ?", keyID).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("API key not found")
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetLegacyAPIKeysByRoomID retrieves the active bcrypt-hashed API keys of a room
+func (r *DeviceAPIKeyRepository) GetLegacyAPIKeysByRoomID(roomID uint) ([]models.DeviceAPIKey, error) {
+	var keys []models.DeviceAPIKey
+	err := r.db.Where("room_id = ? AND hash_scheme = ? AND is_active = ?", roomID, "bcrypt", true).
+		Order("created_at DESC").
+		Find(&keys).Error
+	return keys, err
+}
+
 // GetAPIKeysByRoomID retrieves all API keys for a specific room
 func (r *DeviceAPIKeyRepository) GetAPIKeysByRoomID(roomID uint) ([]models.DeviceAPIKey, error) {
 	var keys []models.DeviceAPIKey
@@ -118,3 +140,30 @@ func (r *DeviceAPIKeyRepository) GetActiveAPIKeysCount(roomID uint) (int64, erro
 		Count(&count).Error
 	return count, err
 }
+
+// UpdateAPIKeyUsage records when and from where a key was last used
+// An empty ip leaves the stored address unchanged
+func (r *DeviceAPIKeyRepository) UpdateAPIKeyUsage(keyID uint, usedAt time.Time, ip string) error {
+	updates := map[string]interface{}{"last_used_at": usedAt}
+	if ip != "" {
+		updates["last_used_ip"] = ip
+	}
+	return r.db.Model(&models.DeviceAPIKey{}).
+		Where("id = ?", keyID).
+		Updates(updates).Error
+}
+
+// UpdateAPIKeyExpiry sets the expiry time of a key
+func (r *DeviceAPIKeyRepository) UpdateAPIKeyExpiry(keyID uint, expiresAt time.Time) error {
+	return r.db.Model(&models.DeviceAPIKey{}).
+		Where("id = ?", keyID).
+		Update("expires_at", expiresAt).Error
+}
+
+// GetExpiredActiveAPIKeys retrieves active keys whose expiry time has passed
+func (r *DeviceAPIKeyRepository) GetExpiredActiveAPIKeys(now time.Time) ([]models.DeviceAPIKey, error) {
+	var keys []models.DeviceAPIKey
+	err := r.db.Where("is_active = ? AND expires_at IS NOT NULL AND expires_at <= ?", true, now).
+		Find(&keys).Error
+	return keys, err
+}
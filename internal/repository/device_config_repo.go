@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DeviceConfigRepository struct {
+	db *gorm.DB
+}
+
+func NewDeviceConfigRepo(db *gorm.DB) *DeviceConfigRepository {
+	return &DeviceConfigRepository{db: db}
+}
+
+// GetConfigByRoomID retrieves the device config of a room
+func (r *DeviceConfigRepository) GetConfigByRoomID(roomID uint) (*models.DeviceConfig, error) {
+	var config models.DeviceConfig
+	err := r.db.Where("room_id = ?", roomID).First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("device config not found")
+		}
+		return nil, err
+	}
+	return &config, nil
+}
+
+// CreateConfig stores the first config of a room
+func (r *DeviceConfigRepository) CreateConfig(config *models.DeviceConfig) error {
+	return r.db.Create(config).Error
+}
+
+// UpdateConfig saves a config whose version was bumped from previousVersion
+// Fails if another update changed the version in the meantime
+func (r *DeviceConfigRepository) UpdateConfig(config *models.DeviceConfig, previousVersion uint) error {
+	result := r.db.Model(&models.DeviceConfig{}).
+		Where("id = ? AND version = ?", config.ID, previousVersion).
+		Updates(map[string]interface{}{
+			"version":                    config.Version,
+			"reporting_interval_seconds": config.ReportingIntervalSeconds,
+			"calibration_offsets":        config.CalibrationOffsets,
+			"enabled_sensors":            config.EnabledSensors,
+			"updated_by":                 config.UpdatedBy,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("device config was changed by another update")
+	}
+	return nil
+}
+
+// CreateCommand queues a command for a room's device
+func (r *DeviceConfigRepository) CreateCommand(command *models.DeviceCommand) error {
+	return r.db.Create(command).Error
+}
+
+// GetCommandByID retrieves a command of a room
+func (r *DeviceConfigRepository) GetCommandByID(roomID, commandID uint) (*models.DeviceCommand, error) {
+	var command models.DeviceCommand
+	err := r.db.Where("id = ? AND room_id = ?", commandID, roomID).First(&command).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("command not found")
+		}
+		return nil, err
+	}
+	return &command, nil
+}
+
+// GetCommandsByRoomID lists the most recent commands of a room, newest first
+func (r *DeviceConfigRepository) GetCommandsByRoomID(roomID uint, limit int) ([]models.DeviceCommand, error) {
+	var commands []models.DeviceCommand
+	err := r.db.Where("room_id = ?", roomID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&commands).Error
+	return commands, err
+}
+
+// DeliverCommands returns a room's unacknowledged commands, oldest first, marking them delivered
+// Commands past their expiry are expired, and commands already handed out as often as
+// models.DeviceCommandMaxDeliveries allows are failed, instead of being delivered again
+func (r *DeviceConfigRepository) DeliverCommands(roomID uint, now time.Time) ([]models.DeviceCommand, error) {
+	var commands []models.DeviceCommand
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.DeviceCommand{}).
+			Where("room_id = ? AND state IN ? AND expires_at <= ?", roomID,
+				[]string{models.DeviceCommandPending, models.DeviceCommandDelivered}, now).
+			Update("state", models.DeviceCommandExpired).Error; err != nil {
+			return err
+		}
+
+		var open []models.DeviceCommand
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("room_id = ? AND state IN ?", roomID,
+				[]string{models.DeviceCommandPending, models.DeviceCommandDelivered}).
+			Order("id ASC").
+			Find(&open).Error; err != nil {
+			return err
+		}
+
+		var deliverIDs, exhaustedIDs []uint
+		for i := range open {
+			command := open[i]
+			if command.DeliveryCount >= models.DeviceCommandMaxDeliveries(command.Command) {
+				exhaustedIDs = append(exhaustedIDs, command.ID)
+				continue
+			}
+			deliverIDs = append(deliverIDs, command.ID)
+			command.State = models.DeviceCommandDelivered
+			command.DeliveryCount++
+			if command.DeliveredAt == nil {
+				command.DeliveredAt = &now
+			}
+			commands = append(commands, command)
+		}
+
+		if len(exhaustedIDs) > 0 {
+			if err := tx.Model(&models.DeviceCommand{}).
+				Where("id IN ?", exhaustedIDs).
+				Updates(map[string]interface{}{
+					"state":  models.DeviceCommandFailed,
+					"result": "Not acknowledged by the device",
+				}).Error; err != nil {
+				return err
+			}
+		}
+		if len(deliverIDs) == 0 {
+			return nil
+		}
+		return tx.Model(&models.DeviceCommand{}).
+			Where("id IN ?", deliverIDs).
+			Updates(map[string]interface{}{
+				"state":          models.DeviceCommandDelivered,
+				"delivery_count": gorm.Expr("delivery_count + 1"),
+				"delivered_at":   gorm.Expr("COALESCE(delivered_at, ?)", now),
+			}).Error
+	})
+	return commands, err
+}
+
+// UpdateCommand saves a command
+func (r *DeviceConfigRepository) UpdateCommand(command *models.DeviceCommand) error {
+	return r.db.Save(command).Error
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type DeviceRepository struct {
+	db *gorm.DB
+}
+
+func NewDeviceRepo(db *gorm.DB) *DeviceRepository {
+	return &DeviceRepository{db: db}
+}
+
+// CreateDevice registers a new device
+// Fails with "device already registered" when another device holds its MAC address or API key
+func (r *DeviceRepository) CreateDevice(device *models.Device) error {
+	return r.translateDuplicate(r.db.Create(device).Error)
+}
+
+// UpdateDevice updates an existing device
+// Fails with "device already registered" when another device holds its MAC address or API key
+func (r *DeviceRepository) UpdateDevice(device *models.Device) error {
+	return r.translateDuplicate(r.db.Omit("Room").Save(device).Error)
+}
+
+// ReleaseAPIKey clears an API key from the devices that used it, so the keeper can take it over
+// Devices with the keeper's ID or MAC address are the keeper itself and are left alone
+func (r *DeviceRepository) ReleaseAPIKey(apiKeyID uint, keeper *models.Device) error {
+	query := r.db.Model(&models.Device{}).Where("api_key_id = ?", apiKeyID)
+	if keeper.ID != 0 {
+		query = query.Where("id <> ?", keeper.ID)
+	}
+	if keeper.MacAddress != nil {
+		query = query.Where("(mac_address IS NULL OR mac_address <> ?)", *keeper.MacAddress)
+	}
+	return query.Update("api_key_id", nil).Error
+}
+
+// translateDuplicate reports a unique index violation on devices as an existing registration
+func (r *DeviceRepository) translateDuplicate(err error) error {
+	if translator, ok := r.db.Dialector.(gorm.ErrorTranslator); ok && err != nil {
+		if errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey) {
+			return errors.New("device already registered")
+		}
+	}
+	return err
+}
+
+// GetDeviceByID retrieves a device by ID
+func (r *DeviceRepository) GetDeviceByID(id uint) (*models.Device, error) {
+	var device models.Device
+	err := r.db.Where("id = ?", id).First(&device).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("device not found")
+		}
+		return nil, err
+	}
+	return &device, nil
+}
+
+// GetDeviceByMAC retrieves a device by its MAC address
+func (r *DeviceRepository) GetDeviceByMAC(mac string) (*models.Device, error) {
+	var device models.Device
+	err := r.db.Where("mac_address = ?", mac).First(&device).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("device not found")
+		}
+		return nil, err
+	}
+	return &device, nil
+}
+
+// GetDeviceByAPIKeyID retrieves the device that last authenticated with a key
+func (r *DeviceRepository) GetDeviceByAPIKeyID(apiKeyID uint) (*models.Device, error) {
+	var device models.Device
+	err := r.db.Where("api_key_id = ?", apiKeyID).First(&device).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("device not found")
+		}
+		return nil, err
+	}
+	return &device, nil
+}
+
+// GetDevicesByRoomID retrieves the devices of a room
+func (r *DeviceRepository) GetDevicesByRoomID(roomID uint) ([]models.Device, error) {
+	var devices []models.Device
+	err := r.db.Where("room_id = ?", roomID).
+		Order("last_seen_at DESC").
+		Find(&devices).Error
+	return devices, err
+}
+
+// GetDevicesByHospitalID retrieves the devices of every active room in a hospital
+func (r *DeviceRepository) GetDevicesByHospitalID(hospitalID uint) ([]models.Device, error) {
+	var devices []models.Device
+	err := r.db.Joins("INNER JOIN rooms ON rooms.id = devices.room_id").
+		Where("rooms.hospital_id = ? AND rooms.is_active = ?", hospitalID, true).
+		Preload("Room").
+		Order("rooms.room_code ASC, devices.id ASC").
+		Find(&devices).Error
+	return devices, err
+}
+
+// GetOnlineDevicesSeenBefore retrieves online devices that have been silent since the cutoff
+func (r *DeviceRepository) GetOnlineDevicesSeenBefore(cutoff time.Time) ([]models.Device, error) {
+	var devices []models.Device
+	err := r.db.Where("status = ? AND last_seen_at < ?", models.DeviceStatusOnline, cutoff).
+		Find(&devices).Error
+	return devices, err
+}
+
+// MarkDevicesOffline sets the given devices offline unless they were seen after the cutoff
+func (r *DeviceRepository) MarkDevicesOffline(ids []uint, cutoff time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&models.Device{}).
+		Where("id IN ? AND last_seen_at < ?", ids, cutoff).
+		Update("status", models.DeviceStatusOffline).Error
+}
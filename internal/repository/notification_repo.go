@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepo(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// GetChannels retrieves every notification channel
+func (r *NotificationRepository) GetChannels() ([]models.NotificationChannel, error) {
+	var channels []models.NotificationChannel
+	err := r.db.Order("id ASC").Find(&channels).Error
+	return channels, err
+}
+
+// GetActiveChannelsForHospital retrieves the active channels covering a hospital's rooms
+func (r *NotificationRepository) GetActiveChannelsForHospital(hospitalID uint) ([]models.NotificationChannel, error) {
+	var channels []models.NotificationChannel
+	err := r.db.Where("is_active = ? AND (hospital_id IS NULL OR hospital_id = ?)", true, hospitalID).
+		Order("id ASC").
+		Find(&channels).Error
+	return channels, err
+}
+
+// GetChannelByID retrieves a notification channel by ID
+func (r *NotificationRepository) GetChannelByID(id uint) (*models.NotificationChannel, error) {
+	var channel models.NotificationChannel
+	err := r.db.Where("id = ?", id).First(&channel).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("notification channel not found")
+		}
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// CreateChannel creates a new notification channel
+func (r *NotificationRepository) CreateChannel(channel *models.NotificationChannel) error {
+	return r.db.Create(channel).Error
+}
+
+// UpdateChannel updates an existing notification channel
+func (r *NotificationRepository) UpdateChannel(channel *models.NotificationChannel) error {
+	return r.db.Save(channel).Error
+}
+
+// DeleteChannel deletes a notification channel together with its delivery log
+func (r *NotificationRepository) DeleteChannel(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("channel_id = ?", id).Delete(&models.NotificationDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.NotificationChannel{}, id).Error
+	})
+}
+
+// CreateDeliveries queues deliveries of one notification
+func (r *NotificationRepository) CreateDeliveries(deliveries []models.NotificationDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.Create(&deliveries).Error
+}
+
+// GetDueDeliveries retrieves pending deliveries whose next attempt is due, oldest first
+func (r *NotificationRepository) GetDueDeliveries(now time.Time, limit int) ([]models.NotificationDelivery, error) {
+	var deliveries []models.NotificationDelivery
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", models.NotificationDeliveryPending, now).
+		Preload("Channel").
+		Order("next_attempt_at ASC, id ASC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimDelivery pushes a due delivery's next attempt to leaseUntil so no other worker sends it meanwhile
+// Returns false if the delivery is no longer due; an attempt that never reports back is retried after the lease
+func (r *NotificationRepository) ClaimDelivery(id uint, now, leaseUntil time.Time) (bool, error) {
+	result := r.db.Model(&models.NotificationDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, models.NotificationDeliveryPending, now).
+		Update("next_attempt_at", leaseUntil)
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteFinishedDeliveriesBefore deletes up to limit delivered or failed deliveries last updated before the cutoff
+// Returns the number of deliveries deleted; pending deliveries are never deleted
+func (r *NotificationRepository) DeleteFinishedDeliveriesBefore(cutoff time.Time, limit int) (int64, error) {
+	var ids []uint
+	err := r.db.Model(&models.NotificationDelivery{}).
+		Where("status IN ? AND updated_at < ?", []string{models.NotificationDeliveryDelivered, models.NotificationDeliveryFailed}, cutoff).
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	result := r.db.Where("id IN ?", ids).Delete(&models.NotificationDelivery{})
+	return result.RowsAffected, result.Error
+}
+
+// UpdateDelivery saves the outcome of a delivery attempt
+func (r *NotificationRepository) UpdateDelivery(delivery *models.NotificationDelivery) error {
+	return r.db.Omit("Channel").Save(delivery).Error
+}
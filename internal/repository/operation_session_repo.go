@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type OperationSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewOperationSessionRepo(db *gorm.DB) *OperationSessionRepository {
+	return &OperationSessionRepository{db: db}
+}
+
+// OperationSessionFilter holds the optional filters for listing operation sessions
+type OperationSessionFilter struct {
+	RoomID         *uint
+	HospitalID     *uint
+	Status         string
+	ProcedureLabel string     // Substring match
+	From           *time.Time // Sessions started at or after
+	To             *time.Time // Sessions started before
+	HospitalIDs    []uint     // Restrict to rooms in these hospitals (nil = no restriction)
+	Limit          int
+	Offset         int
+}
+
+// GetOpenSessionByRoomID retrieves the running or paused session of a room with its intervals
+func (r *OperationSessionRepository) GetOpenSessionByRoomID(roomID uint) (*models.OperationSession, error) {
+	var session models.OperationSession
+	err := r.db.Where("room_id = ? AND status IN ?", roomID,
+		[]string{models.OperationSessionRunning, models.OperationSessionPaused}).
+		Preload("Intervals", func(db *gorm.DB) *gorm.DB { return db.Order("started_at ASC") }).
+		Order("started_at DESC").
+		First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("operation session not found")
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetLatestSessionsByRoomIDs retrieves the most recently started session of each of the given rooms with its markers
+func (r *OperationSessionRepository) GetLatestSessionsByRoomIDs(roomIDs []uint) ([]models.OperationSession, error) {
+	var sessions []models.OperationSession
+	if len(roomIDs) == 0 {
+		return sessions, nil
+	}
+	latest := r.db.Model(&models.OperationSession{}).
+		Select("room_id, MAX(started_at)").
+		Where("room_id IN ?", roomIDs).
+		Group("room_id")
+	err := r.db.Where("(room_id, started_at) IN (?)", latest).
+		Preload("Markers", orderMarkers).
+		Order("id ASC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// GetSessionByID retrieves a session with its room, intervals and markers
+func (r *OperationSessionRepository) GetSessionByID(id uint) (*models.OperationSession, error) {
+	var session models.OperationSession
+	err := r.db.Where("id = ?", id).
+		Preload("Intervals", func(db *gorm.DB) *gorm.DB { return db.Order("started_at ASC") }).
+		Preload("Markers", orderMarkers).
+		Preload("Room").
+		First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("operation session not found")
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetSessions retrieves sessions matching the filter, newest first, with the total count
+func (r *OperationSessionRepository) GetSessions(filter OperationSessionFilter) ([]models.OperationSession, int64, error) {
+	query := r.db.Model(&models.OperationSession{})
+	if filter.RoomID != nil {
+		query = query.Where("operation_sessions.room_id = ?", *filter.RoomID)
+	}
+	if filter.Status != "" {
+		query = query.Where("operation_sessions.status = ?", filter.Status)
+	}
+	if filter.ProcedureLabel != "" {
+		query = query.Where("operation_sessions.procedure_label LIKE ?", "%"+filter.ProcedureLabel+"%")
+	}
+	if filter.From != nil {
+		query = query.Where("operation_sessions.started_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("operation_sessions.started_at < ?", *filter.To)
+	}
+	if filter.HospitalID != nil || filter.HospitalIDs != nil {
+		query = query.Joins("INNER JOIN rooms ON rooms.id = operation_sessions.room_id")
+		if filter.HospitalID != nil {
+			query = query.Where("rooms.hospital_id = ?", *filter.HospitalID)
+		}
+		if filter.HospitalIDs != nil {
+			query = query.Where("rooms.hospital_id IN ?", filter.HospitalIDs)
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var sessions []models.OperationSession
+	err := query.Preload("Intervals", func(db *gorm.DB) *gorm.DB { return db.Order("started_at ASC") }).
+		Preload("Markers", orderMarkers).
+		Preload("Room").
+		Order("operation_sessions.started_at DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&sessions).Error
+	return sessions, total, err
+}
+
+// GetSessionsOverlapping retrieves the sessions of the given rooms that ran at any time in [from, to)
+func (r *OperationSessionRepository) GetSessionsOverlapping(roomIDs []uint, from, to time.Time) ([]models.OperationSession, error) {
+	var sessions []models.OperationSession
+	if len(roomIDs) == 0 {
+		return sessions, nil
+	}
+	err := r.db.Where("room_id IN ? AND started_at < ? AND (ended_at IS NULL OR ended_at > ?)", roomIDs, to, from).
+		Preload("Intervals").
+		Order("started_at ASC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// CreateSession stores a new session together with its first interval and start marker
+func (r *OperationSessionRepository) CreateSession(session *models.OperationSession) error {
+	return r.db.Create(session).Error
+}
+
+// SaveSession updates a session and creates or updates the given interval and marker in one transaction
+func (r *OperationSessionRepository) SaveSession(session *models.OperationSession, interval *models.OperationInterval, marker *models.OperationMarker) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Intervals", "Markers", "Room").Save(session).Error; err != nil {
+			return err
+		}
+		if interval != nil {
+			interval.SessionID = session.ID
+			if err := tx.Save(interval).Error; err != nil {
+				return err
+			}
+		}
+		if marker != nil {
+			marker.SessionID = session.ID
+			if err := tx.Create(marker).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateProcedureLabel sets the procedure label of a session
+func (r *OperationSessionRepository) UpdateProcedureLabel(id uint, label string) error {
+	return r.db.Model(&models.OperationSession{}).
+		Where("id = ?", id).
+		Update("procedure_label", label).Error
+}
+
+// orderMarkers sorts preloaded markers into timeline order
+func orderMarkers(db *gorm.DB) *gorm.DB {
+	return db.Order("occurred_at ASC, id ASC")
+}
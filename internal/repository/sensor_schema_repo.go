@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"iot-backend-room-monitoring/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type SensorSchemaRepository struct {
+	db *gorm.DB
+}
+
+func NewSensorSchemaRepo(db *gorm.DB) *SensorSchemaRepository {
+	return &SensorSchemaRepository{db: db}
+}
+
+// GetAllDefinitions retrieves the sensor definitions of every room type in display order
+func (r *SensorSchemaRepository) GetAllDefinitions() ([]models.SensorDefinition, error) {
+	var definitions []models.SensorDefinition
+	err := r.db.Order("room_type ASC, sort_order ASC, id ASC").Find(&definitions).Error
+	return definitions, err
+}
+
+// ReplaceDefinitions replaces the sensor definitions of a room type in one transaction
+// An empty list removes the room type's schema
+func (r *SensorSchemaRepository) ReplaceDefinitions(roomType string, definitions []models.SensorDefinition) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("room_type = ?", roomType).Delete(&models.SensorDefinition{}).Error; err != nil {
+			return err
+		}
+		if len(definitions) == 0 {
+			return nil
+		}
+		return tx.Create(&definitions).Error
+	})
+}
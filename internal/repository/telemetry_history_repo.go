@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type TelemetryHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewTelemetryHistoryRepo(db *gorm.DB) *TelemetryHistoryRepository {
+	return &TelemetryHistoryRepository{db: db}
+}
+
+// CreateHistory appends a reading to the telemetry history
+func (r *TelemetryHistoryRepository) CreateHistory(entry *models.TheaterTelemetryHistory) error {
+	return r.db.Create(entry).Error
+}
+
+// CreateHistoryBatch appends several readings to the telemetry history in one statement
+func (r *TelemetryHistoryRepository) CreateHistoryBatch(entries []models.TheaterTelemetryHistory) error {
+	return r.db.CreateInBatches(entries, 200).Error
+}
+
+// GetHistoryByRoomID retrieves a page of readings for a room within [from, to]
+// Returns the readings ordered by recorded_at and the total number of matching rows
+func (r *TelemetryHistoryRepository) GetHistoryByRoomID(roomID uint, from, to time.Time, limit, offset int) ([]models.TheaterTelemetryHistory, int64, error) {
+	query := r.db.Model(&models.TheaterTelemetryHistory{}).
+		Where("room_id = ? AND recorded_at >= ? AND recorded_at <= ?", roomID, from, to)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var history []models.TheaterTelemetryHistory
+	err := query.Order("recorded_at ASC, id ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&history).Error
+	return history, total, err
+}
+
+// GetAHUStateBefore reports whether a room's AHU was on in the reading before the given one, and when that cycle started
+// The cycle start is the first on reading after the last off reading; it is nil when the AHU was never seen turning on
+func (r *TelemetryHistoryRepository) GetAHUStateBefore(roomID uint, reading *models.TheaterTelemetryHistory) (bool, *time.Time, error) {
+	earlier := r.db.Model(&models.TheaterTelemetryHistory{}).
+		Select("id, recorded_at, logic_ahu").
+		Where("room_id = ? AND (recorded_at < ? OR (recorded_at = ? AND id < ?))", roomID, reading.RecordedAt, reading.RecordedAt, reading.ID).
+		Session(&gorm.Session{})
+
+	var previous models.TheaterTelemetryHistory
+	if err := earlier.Order("recorded_at DESC, id DESC").First(&previous).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil, nil
+		}
+		return false, nil, err
+	}
+	if previous.LogicAhu != 1 {
+		return false, nil, nil
+	}
+
+	var off models.TheaterTelemetryHistory
+	if err := earlier.Where("logic_ahu = ?", 0).Order("recorded_at DESC, id DESC").First(&off).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true, nil, nil
+		}
+		return false, nil, err
+	}
+
+	var start models.TheaterTelemetryHistory
+	err := earlier.Where("logic_ahu = ? AND (recorded_at > ? OR (recorded_at = ? AND id > ?))", 1, off.RecordedAt, off.RecordedAt, off.ID).
+		Order("recorded_at ASC, id ASC").
+		First(&start).Error
+	if err != nil {
+		return false, nil, err
+	}
+	return true, &start.RecordedAt, nil
+}
+
+// GetRoomHistoryInRange retrieves every reading of a room recorded within [from, to), oldest first
+func (r *TelemetryHistoryRepository) GetRoomHistoryInRange(roomID uint, from, to time.Time) ([]models.TheaterTelemetryHistory, error) {
+	var history []models.TheaterTelemetryHistory
+	err := r.db.Where("room_id = ? AND recorded_at >= ? AND recorded_at < ?", roomID, from, to).
+		Order("recorded_at ASC, id ASC").
+		Find(&history).Error
+	return history, err
+}
+
+// GetLatestBefore retrieves the last reading of a room recorded before t, or nil if there is none
+func (r *TelemetryHistoryRepository) GetLatestBefore(roomID uint, t time.Time) (*models.TheaterTelemetryHistory, error) {
+	var history models.TheaterTelemetryHistory
+	err := r.db.Where("room_id = ? AND recorded_at < ?", roomID, t).
+		Order("recorded_at DESC, id DESC").
+		Limit(1).
+		Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	if history.ID == 0 {
+		return nil, nil
+	}
+	return &history, nil
+}
+
+// GetHistoryPageInRange retrieves up to limit readings for all rooms recorded within [from, to) with an ID after afterID
+// Used by the rollup worker to page through closed time buckets without loading them all at once
+func (r *TelemetryHistoryRepository) GetHistoryPageInRange(from, to time.Time, afterID uint, limit int) ([]models.TheaterTelemetryHistory, error) {
+	var history []models.TheaterTelemetryHistory
+	err := r.db.Where("recorded_at >= ? AND recorded_at < ? AND id > ?", from, to, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&history).Error
+	return history, err
+}
+
+// GetEarliestRecordedAt returns the timestamp of the oldest stored reading, or nil if there is none
+func (r *TelemetryHistoryRepository) GetEarliestRecordedAt() (*time.Time, error) {
+	var history models.TheaterTelemetryHistory
+	err := r.db.Order("recorded_at ASC").Limit(1).Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	if history.ID == 0 {
+		return nil, nil
+	}
+	return &history.RecordedAt, nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TelemetryRollupRepository struct {
+	db *gorm.DB
+}
+
+func NewTelemetryRollupRepo(db *gorm.DB) *TelemetryRollupRepository {
+	return &TelemetryRollupRepository{db: db}
+}
+
+// UpsertRollups inserts rollup rows, replacing the aggregates of buckets that already exist
+func (r *TelemetryRollupRepository) UpsertRollups(rollups []models.TelemetryRollup) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"min_value", "max_value", "avg_value", "sample_count"}),
+	}).CreateInBatches(rollups, 500).Error
+}
+
+// GetRollupsInRange retrieves rollups of a resolution for all rooms with bucket_start within [from, to)
+func (r *TelemetryRollupRepository) GetRollupsInRange(resolution string, from, to time.Time) ([]models.TelemetryRollup, error) {
+	var rollups []models.TelemetryRollup
+	err := r.db.Where("resolution = ? AND bucket_start >= ? AND bucket_start < ?", resolution, from, to).
+		Order("room_id ASC, bucket_start ASC").
+		Find(&rollups).Error
+	return rollups, err
+}
+
+// GetLatestBucketStart returns the most recent bucket_start stored for a resolution, or nil if there is none
+func (r *TelemetryRollupRepository) GetLatestBucketStart(resolution string) (*time.Time, error) {
+	var rollup models.TelemetryRollup
+	err := r.db.Where("resolution = ?", resolution).
+		Order("bucket_start DESC").
+		Limit(1).
+		Find(&rollup).Error
+	if err != nil {
+		return nil, err
+	}
+	if rollup.ID == 0 {
+		return nil, nil
+	}
+	return &rollup.BucketStart, nil
+}
+
+// GetRollupBuckets retrieves a page of distinct bucket starts for a room within [from, to]
+// Returns the bucket starts in ascending order and the total number of buckets
+func (r *TelemetryRollupRepository) GetRollupBuckets(roomID uint, resolution string, from, to time.Time, limit, offset int) ([]time.Time, int64, error) {
+	query := r.db.Model(&models.TelemetryRollup{}).
+		Where("room_id = ? AND resolution = ? AND bucket_start >= ? AND bucket_start <= ?", roomID, resolution, from, to)
+
+	var total int64
+	if err := query.Distinct("bucket_start").Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var buckets []time.Time
+	err := r.db.Model(&models.TelemetryRollup{}).
+		Where("room_id = ? AND resolution = ? AND bucket_start >= ? AND bucket_start <= ?", roomID, resolution, from, to).
+		Distinct("bucket_start").
+		Order("bucket_start ASC").
+		Limit(limit).
+		Offset(offset).
+		Pluck("bucket_start", &buckets).Error
+	return buckets, total, err
+}
+
+// GetRollupsByRoomID retrieves rollups of a room for the given sensors with bucket_start within [from, to]
+func (r *TelemetryRollupRepository) GetRollupsByRoomID(roomID uint, resolution string, from, to time.Time, sensors []string) ([]models.TelemetryRollup, error) {
+	var rollups []models.TelemetryRollup
+	err := r.db.Where("room_id = ? AND resolution = ? AND bucket_start >= ? AND bucket_start <= ? AND sensor IN ?",
+		roomID, resolution, from, to, sensors).
+		Order("bucket_start ASC").
+		Find(&rollups).Error
+	return rollups, err
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"errors"
+	"time"
 
 	"iot-backend-room-monitoring/internal/models"
 
@@ -41,6 +42,13 @@ func (r *TheaterRepository) GetAllLiveStates() ([]models.TheaterLiveState, error
 	return states, err
 }
 
+// GetAllLiveStatesWithRooms retrieves live states for all rooms with their room and hospital
+func (r *TheaterRepository) GetAllLiveStatesWithRooms() ([]models.TheaterLiveState, error) {
+	var states []models.TheaterLiveState
+	err := r.db.Preload("Room.Hospital").Order("room_name ASC").Find(&states).Error
+	return states, err
+}
+
 // GetLiveState retrieves the live state for a specific room (legacy method using room_name)
 func (r *TheaterRepository) GetLiveState(roomName string) (*models.TheaterLiveState, error) {
 	var state models.TheaterLiveState
@@ -67,9 +75,30 @@ func (r *TheaterRepository) GetLiveStateByRoomID(roomID uint) (*models.TheaterLi
 	return &state, nil
 }
 
+// FindLiveStateByRoomID retrieves the live state for a room by room_id without preloading relationships
+// Used by the background worker, which saves the whole row back
+func (r *TheaterRepository) FindLiveStateByRoomID(roomID uint) (*models.TheaterLiveState, error) {
+	var state models.TheaterLiveState
+	err := r.db.Where("room_id = ?", roomID).First(&state).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("live state not found for room")
+		}
+		return nil, err
+	}
+	return &state, nil
+}
+
+// timerColumns are the live state columns owned by staff timer actions and the countdown jobs
+var timerColumns = []string{
+	"op_start_time", "op_accumulated_seconds", "op_is_running", "op_is_paused", "op_pause_reason",
+	"cd_target_time", "cd_duration_seconds", "cd_is_running", "cd_label", "cd_schedule_id",
+}
+
 // UpdateLiveState updates the theater live state
+// Timer columns are left alone so a telemetry update never undoes a concurrent timer change
 func (r *TheaterRepository) UpdateLiveState(state *models.TheaterLiveState) error {
-	return r.db.Save(state).Error
+	return r.db.Omit(timerColumns...).Save(state).Error
 }
 
 // CreateLiveStateIfNotExists creates a live state entry if it doesn't exist
@@ -114,6 +143,23 @@ func (r *TheaterRepository) UpdateCountdownTimerByRoomID(roomID uint, updates ma
 		Updates(updates).Error
 }
 
+// GetExpiredCountdowns retrieves live states whose countdown is running past its target time
+func (r *TheaterRepository) GetExpiredCountdowns(now time.Time) ([]models.TheaterLiveState, error) {
+	var states []models.TheaterLiveState
+	err := r.db.Where("cd_is_running = ? AND cd_target_time IS NOT NULL AND cd_target_time <= ?", true, now).
+		Find(&states).Error
+	return states, err
+}
+
+// ExpireCountdown stops a countdown that reached its target time
+// Returns false when staff already stopped, reset or adjusted the countdown
+func (r *TheaterRepository) ExpireCountdown(id uint, targetTime time.Time) (bool, error) {
+	result := r.db.Model(&models.TheaterLiveState{}).
+		Where("id = ? AND cd_is_running = ? AND cd_target_time = ?", id, true, targetTime).
+		Update("cd_is_running", false)
+	return result.RowsAffected > 0, result.Error
+}
+
 // GetRawTelemetryByRoomID retrieves raw telemetry for a specific room
 func (r *TheaterRepository) GetRawTelemetryByRoomID(roomID uint) (*models.TheaterRawTelemetry, error) {
 	var telemetry models.TheaterRawTelemetry
@@ -183,7 +229,9 @@ func (r *TheaterRepository) CreateLiveStateForRoom(roomID uint, roomName string)
 
 // UpdateRawTelemetryByRoomID updates raw telemetry data for a specific room
 // Used by ESP32 devices to update sensor readings
-func (r *TheaterRepository) UpdateRawTelemetryByRoomID(roomID uint, data *models.TheaterRawTelemetry) error {
+// If data.UpdatedAt is set it is stored as the reading time; data.ID and data.RoomName are filled from the row
+// sensors lists the sensors of the room's schema: unreported ones keep their last value, any other sensor is cleared
+func (r *TheaterRepository) UpdateRawTelemetryByRoomID(roomID uint, data *models.TheaterRawTelemetry, sensors []string) error {
 	// First check if the telemetry record exists
 	var existing models.TheaterRawTelemetry
 	err := r.db.Where("room_id = ?", roomID).First(&existing).Error
@@ -194,16 +242,20 @@ func (r *TheaterRepository) UpdateRawTelemetryByRoomID(roomID uint, data *models
 		return err
 	}
 
-	// Update only the fields provided in data
+	inSchema := make(map[string]bool, len(sensors))
+	for _, name := range sensors {
+		inSchema[name] = true
+	}
+
+	// Update only the fields provided in data, and clear sensors the schema no longer defines
 	updates := make(map[string]interface{})
-	
-	if data.Temp != nil {
+	if data.Temp != nil || !inSchema["temp"] {
 		updates["temp"] = data.Temp
 	}
-	if data.Humidity != nil {
+	if data.Humidity != nil || !inSchema["humidity"] {
 		updates["humidity"] = data.Humidity
 	}
-	if data.RoomPressure != nil {
+	if data.RoomPressure != nil || !inSchema["room_pressure"] {
 		updates["room_pressure"] = data.RoomPressure
 	}
 	updates["room_status"] = data.RoomStatus
@@ -211,25 +263,52 @@ func (r *TheaterRepository) UpdateRawTelemetryByRoomID(roomID uint, data *models
 	updates["volume_ruangan"] = data.VolumeRuangan
 	updates["logic_ahu"] = data.LogicAhu
 	
-	if data.Oxygen != nil {
+	if data.Oxygen != nil || !inSchema["oxygen"] {
 		updates["oxygen"] = data.Oxygen
 	}
-	if data.Nitrous != nil {
+	if data.Nitrous != nil || !inSchema["nitrous"] {
 		updates["nitrous"] = data.Nitrous
 	}
-	if data.Air != nil {
+	if data.Air != nil || !inSchema["air"] {
 		updates["air"] = data.Air
 	}
-	if data.Vacuum != nil {
+	if data.Vacuum != nil || !inSchema["vacuum"] {
 		updates["vacuum"] = data.Vacuum
 	}
-	if data.Instrument != nil {
+	if data.Instrument != nil || !inSchema["instrument"] {
 		updates["instrument"] = data.Instrument
 	}
-	if data.Carbon != nil {
+	if data.Carbon != nil || !inSchema["carbon"] {
 		updates["carbon"] = data.Carbon
 	}
 
+	// Extra sensors are merged the same way: reported values win, unreported schema sensors are kept
+	extra := models.SensorValues{}
+	for name, value := range existing.ExtraSensors {
+		if inSchema[name] {
+			extra[name] = value
+		}
+	}
+	for name, value := range data.ExtraSensors {
+		extra[name] = value
+	}
+	if len(extra) == 0 {
+		extra = nil
+	}
+	updates["extra_sensors"] = extra
+
+	// Device clock fields describe this reading only, so they are always overwritten
+	updates["device_timestamp"] = data.DeviceTimestamp
+	updates["sequence"] = data.Sequence
+	updates["clock_skew_ms"] = data.ClockSkewMs
+	updates["clock_skew_flagged"] = data.ClockSkewFlagged
+
+	if !data.UpdatedAt.IsZero() {
+		updates["updated_at"] = data.UpdatedAt
+	}
+	data.ID = existing.ID
+	data.RoomName = existing.RoomName
+
 	// Perform the update
 	return r.db.Model(&models.TheaterRawTelemetry{}).
 		Where("room_id = ?", roomID).
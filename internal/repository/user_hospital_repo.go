@@ -25,6 +25,15 @@ func (r *UserHospitalRepository) AssignUserToHospital(userID, hospitalID uint) e
 		FirstOrCreate(userHospital).Error
 }
 
+// SetUserHospitalRole assigns a user to a hospital with a hospital role, replacing any previous role
+func (r *UserHospitalRepository) SetUserHospitalRole(userID, hospitalID uint, role string) (*models.UserHospital, error) {
+	userHospital := &models.UserHospital{}
+	err := r.db.Where(models.UserHospital{UserID: userID, HospitalID: hospitalID}).
+		Assign(models.UserHospital{Role: role}).
+		FirstOrCreate(userHospital).Error
+	return userHospital, err
+}
+
 // RemoveUserFromHospital removes a user's access to a hospital
 func (r *UserHospitalRepository) RemoveUserFromHospital(userID, hospitalID uint) error {
 	return r.db.Where("user_id = ? AND hospital_id = ?", userID, hospitalID).
@@ -49,6 +58,77 @@ func (r *UserHospitalRepository) GetHospitalUsers(hospitalID uint) ([]uint, erro
 	return userIDs, err
 }
 
+// GetHospitalUserEmails retrieves the email addresses of users assigned to a hospital
+// Users without an email address are skipped
+func (r *UserHospitalRepository) GetHospitalUserEmails(hospitalID uint) ([]string, error) {
+	var emails []string
+	err := r.db.Model(&models.UserHospital{}).
+		Joins("INNER JOIN users ON users.id = user_hospitals.user_id").
+		Where("user_hospitals.hospital_id = ? AND users.email IS NOT NULL AND users.email <> ''", hospitalID).
+		Distinct().
+		Pluck("users.email", &emails).Error
+	return emails, err
+}
+
+// GetHospitalMembers retrieves the users assigned to a hospital with their hospital roles
+func (r *UserHospitalRepository) GetHospitalMembers(hospitalID uint) ([]models.UserHospital, error) {
+	var members []models.UserHospital
+	err := r.db.Preload("User").
+		Where("hospital_id = ?", hospitalID).
+		Order("user_id ASC").
+		Find(&members).Error
+	return members, err
+}
+
+// GetUserHospitalRole retrieves a user's role in a hospital, empty if the user isn't assigned to it
+func (r *UserHospitalRepository) GetUserHospitalRole(userID, hospitalID uint) (string, error) {
+	var roles []string
+	err := r.db.Model(&models.UserHospital{}).
+		Where("user_id = ? AND hospital_id = ?", userID, hospitalID).
+		Limit(1).
+		Pluck("role", &roles).Error
+	if err != nil || len(roles) == 0 {
+		return "", err
+	}
+	return roles[0], nil
+}
+
+// CountHospitalRole counts the users holding a role in a hospital
+func (r *UserHospitalRepository) CountHospitalRole(hospitalID uint, role string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.UserHospital{}).
+		Where("hospital_id = ? AND role = ?", hospitalID, role).
+		Count(&count).Error
+	return count, err
+}
+
+// UserHasHospitalPermission checks if a user's role in a hospital grants a permission
+func (r *UserHospitalRepository) UserHasHospitalPermission(userID, hospitalID uint, permission models.Permission) (bool, error) {
+	role, err := r.GetUserHospitalRole(userID, hospitalID)
+	if err != nil {
+		return false, err
+	}
+	return models.HospitalRoleHasPermission(role, permission), nil
+}
+
+// UserHasPermissionInAnyHospital checks if a user's role in at least one hospital grants a permission
+func (r *UserHospitalRepository) UserHasPermissionInAnyHospital(userID uint, permission models.Permission) (bool, error) {
+	var roles []string
+	err := r.db.Model(&models.UserHospital{}).
+		Where("user_id = ?", userID).
+		Distinct().
+		Pluck("role", &roles).Error
+	if err != nil {
+		return false, err
+	}
+	for _, role := range roles {
+		if models.HospitalRoleHasPermission(role, permission) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // UserHasAccessToHospital checks if a user has access to a specific hospital
 func (r *UserHospitalRepository) UserHasAccessToHospital(userID, hospitalID uint) (bool, error) {
 	var count int64
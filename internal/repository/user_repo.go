@@ -29,11 +29,60 @@ func (r *UserRepository) FindUserByUsername(username string) (*models.User, erro
 	return &user, nil
 }
 
+// FindUserByID finds a user by ID
+func (r *UserRepository) FindUserByID(id uint) (*models.User, error) {
+	var user models.User
+	err := r.db.First(&user, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
 // CreateUser creates a new user
 func (r *UserRepository) CreateUser(user *models.User) error {
 	return r.db.Create(user).Error
 }
 
+// UpdateUserEmail sets a user's notification email address
+func (r *UserRepository) UpdateUserEmail(id uint, email string) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Update("email", email)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// MySQL reports no affected rows for an unchanged value, so check the user exists
+		if _, err := r.FindUserByID(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateUserRole sets a user's global role
+func (r *UserRepository) UpdateUserRole(id uint, role string) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := r.FindUserByID(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountUsersByRole counts the users holding a global role
+func (r *UserRepository) CountUsersByRole(role string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where("role = ?", role).Count(&count).Error
+	return count, err
+}
+
 // CreateRefreshToken creates a new refresh token
 func (r *UserRepository) CreateRefreshToken(token *models.RefreshToken) error {
 	return r.db.Create(token).Error
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepo(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// WebhookDeliveryFilter holds the optional filters for listing webhook deliveries
+type WebhookDeliveryFilter struct {
+	SubscriptionID *uint
+	RoomID         *uint
+	Status         string
+	EventType      string
+	Limit          int
+	Offset         int
+}
+
+// GetSubscriptions retrieves every webhook subscription
+func (r *WebhookRepository) GetSubscriptions() ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := r.db.Order("id ASC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// GetActiveSubscriptionsForHospital retrieves the active subscriptions covering a hospital's rooms
+// A nil hospital (legacy room_name rooms) only matches subscriptions without a hospital filter
+func (r *WebhookRepository) GetActiveSubscriptionsForHospital(hospitalID *uint) ([]models.WebhookSubscription, error) {
+	query := r.db.Where("is_active = ?", true)
+	if hospitalID != nil {
+		query = query.Where("hospital_id IS NULL OR hospital_id = ?", *hospitalID)
+	} else {
+		query = query.Where("hospital_id IS NULL")
+	}
+
+	var subscriptions []models.WebhookSubscription
+	err := query.Order("id ASC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// GetSubscriptionByID retrieves a webhook subscription by ID
+func (r *WebhookRepository) GetSubscriptionByID(id uint) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	err := r.db.Where("id = ?", id).First(&subscription).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("webhook subscription not found")
+		}
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// CreateSubscription creates a new webhook subscription
+func (r *WebhookRepository) CreateSubscription(subscription *models.WebhookSubscription) error {
+	return r.db.Create(subscription).Error
+}
+
+// UpdateSubscription updates an existing webhook subscription
+func (r *WebhookRepository) UpdateSubscription(subscription *models.WebhookSubscription) error {
+	return r.db.Save(subscription).Error
+}
+
+// DeleteSubscription deletes a webhook subscription together with its delivery log
+func (r *WebhookRepository) DeleteSubscription(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.WebhookSubscription{}, id).Error
+	})
+}
+
+// CreateDeliveries queues deliveries of one event
+func (r *WebhookRepository) CreateDeliveries(deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.Create(&deliveries).Error
+}
+
+// GetDueDeliveries retrieves pending deliveries whose next attempt is due, oldest first
+func (r *WebhookRepository) GetDueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+		Preload("Subscription").
+		Order("next_attempt_at ASC, id ASC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimDelivery pushes a due delivery's next attempt to leaseUntil so no other worker sends it meanwhile
+// Returns false if the delivery is no longer due; an attempt that never reports back is retried after the lease
+func (r *WebhookRepository) ClaimDelivery(id uint, now, leaseUntil time.Time) (bool, error) {
+	result := r.db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, models.WebhookDeliveryPending, now).
+		Update("next_attempt_at", leaseUntil)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateDelivery saves the outcome of a delivery attempt
+func (r *WebhookRepository) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	return r.db.Omit("Subscription").Save(delivery).Error
+}
+
+// DeleteFinishedDeliveriesBefore deletes up to limit delivered or failed deliveries last updated before the cutoff
+// Returns the number of deliveries deleted; pending deliveries are never deleted
+func (r *WebhookRepository) DeleteFinishedDeliveriesBefore(cutoff time.Time, limit int) (int64, error) {
+	var ids []uint
+	err := r.db.Model(&models.WebhookDelivery{}).
+		Where("status IN ? AND updated_at < ?", []string{models.WebhookDeliveryDelivered, models.WebhookDeliveryFailed}, cutoff).
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	result := r.db.Where("id IN ?", ids).Delete(&models.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
+
+// GetDeliveryByID retrieves a webhook delivery by ID
+func (r *WebhookRepository) GetDeliveryByID(id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.db.Where("id = ?", id).First(&delivery).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("webhook delivery not found")
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// GetDeliveries retrieves deliveries matching the filter, newest first, with the total count
+func (r *WebhookRepository) GetDeliveries(filter WebhookDeliveryFilter) ([]models.WebhookDelivery, int64, error) {
+	query := r.db.Model(&models.WebhookDelivery{})
+	if filter.SubscriptionID != nil {
+		query = query.Where("subscription_id = ?", *filter.SubscriptionID)
+	}
+	if filter.RoomID != nil {
+		query = query.Where("room_id = ?", *filter.RoomID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.EventType != "" {
+		query = query.Where("event_type = ?", filter.EventType)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	err := query.Order("created_at DESC, id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&deliveries).Error
+	return deliveries, total, err
+}
@@ -0,0 +1,603 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+)
+
+// alarmRuleCacheTTL controls how often the worker reloads rules and room types
+const alarmRuleCacheTTL = 30 * time.Second
+
+type AlarmService struct {
+	alarmRepo           *repository.AlarmRepository
+	roomRepo            *repository.RoomRepository
+	userHospitalRepo    *repository.UserHospitalRepository
+	auditRepo           *repository.AuditRepository
+	webhookService      *WebhookService
+	sensorSchemaService *SensorSchemaService
+
+	mu            sync.Mutex
+	rules         []models.AlarmRule
+	roomTypes     map[uint]string
+	rulesLoadedAt time.Time
+	pending       map[alarmKey]time.Time     // First time a not-yet-raised violation was seen
+	active        map[alarmKey]*models.Alarm // Open alarms by rule and room
+	offline       map[uint]*models.Alarm     // Open device offline alarms by room
+	loadedRooms   map[uint]bool              // Rooms whose open alarms have been loaded into active
+}
+
+// alarmKey identifies the alarm state of one rule in one room
+type alarmKey struct {
+	ruleID uint
+	roomID uint
+}
+
+func NewAlarmService(
+	alarmRepo *repository.AlarmRepository,
+	roomRepo *repository.RoomRepository,
+	userHospitalRepo *repository.UserHospitalRepository,
+	auditRepo *repository.AuditRepository,
+	webhookService *WebhookService,
+	sensorSchemaService *SensorSchemaService,
+) *AlarmService {
+	return &AlarmService{
+		alarmRepo:           alarmRepo,
+		roomRepo:            roomRepo,
+		userHospitalRepo:    userHospitalRepo,
+		auditRepo:           auditRepo,
+		webhookService:      webhookService,
+		sensorSchemaService: sensorSchemaService,
+		roomTypes:           make(map[uint]string),
+		pending:             make(map[alarmKey]time.Time),
+		active:              make(map[alarmKey]*models.Alarm),
+		offline:             make(map[uint]*models.Alarm),
+		loadedRooms:         make(map[uint]bool),
+	}
+}
+
+// EvaluateTelemetry checks a room's latest sensor values against its alarm rules
+// Called by the background worker for every processed reading
+func (s *AlarmService) EvaluateTelemetry(roomID uint, values map[string]float64, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.refreshRules(); err != nil {
+		log.Printf("Error loading alarm rules: %v", err)
+		return
+	}
+	if err := s.loadOpenAlarms(roomID); err != nil {
+		log.Printf("Error loading open alarms for room_id=%d: %v", roomID, err)
+		return
+	}
+
+	rules := s.rulesForRoom(roomID)
+
+	// Clear open alarms whose rule was deleted, deactivated or no longer applies to the room
+	applicable := make(map[uint]bool, len(rules))
+	for _, rule := range rules {
+		applicable[rule.ID] = true
+	}
+	for key, alarm := range s.active {
+		if key.roomID == roomID && !applicable[key.ruleID] {
+			s.clearAlarm(key, alarm, at)
+		}
+	}
+
+	for _, rule := range rules {
+		value, ok := values[rule.Sensor]
+		if !ok {
+			continue
+		}
+		key := alarmKey{ruleID: rule.ID, roomID: roomID}
+
+		alarm, open := s.active[key]
+		var since *time.Time
+		if pendingSince, seen := s.pending[key]; seen {
+			since = &pendingSince
+		}
+		step := stepAlarm(&rule, value, at, open, since)
+		if step.pending != nil {
+			s.pending[key] = *step.pending
+		} else {
+			delete(s.pending, key)
+		}
+
+		switch {
+		case step.clear:
+			s.clearAlarm(key, alarm, at)
+		case step.raise:
+			s.raiseAlarm(key, &rule, value, step.limitType, step.limit, at)
+		}
+	}
+}
+
+// alarmStep is what one reading does to the alarm state of one rule in one room
+type alarmStep struct {
+	raise     bool
+	clear     bool
+	pending   *time.Time // Start of a violation not raised yet, nil when there is none
+	limitType string     // Limit crossed when raising
+	limit     float64
+}
+
+// stepAlarm moves a rule's alarm state forward for a reading taken at the given time
+// A violation is raised once it has lasted min_duration_seconds, and an open alarm only
+// clears once the value is back inside the limits by the hysteresis margin
+func stepAlarm(rule *models.AlarmRule, value float64, at time.Time, open bool, pendingSince *time.Time) alarmStep {
+	if open {
+		return alarmStep{clear: ruleCleared(rule, value)}
+	}
+
+	limitType, limit, violated := ruleViolation(rule, value)
+	if !violated {
+		return alarmStep{}
+	}
+
+	since := at
+	if pendingSince != nil {
+		since = *pendingSince
+	}
+	if at.Sub(since) < time.Duration(rule.MinDurationSeconds)*time.Second {
+		return alarmStep{pending: &since}
+	}
+	return alarmStep{raise: true, limitType: limitType, limit: limit}
+}
+
+// raiseAlarm persists a new alarm and tracks it as open
+func (s *AlarmService) raiseAlarm(key alarmKey, rule *models.AlarmRule, value float64, limitType string, limit float64, at time.Time) {
+	ruleID := rule.ID
+	direction := "above"
+	if limitType == "low" {
+		direction = "below"
+	}
+	alarm := &models.Alarm{
+		RuleID:       &ruleID,
+		RoomID:       key.roomID,
+		AlarmType:    models.AlarmTypeThreshold,
+		Sensor:       rule.Sensor,
+		Severity:     rule.Severity,
+		State:        models.AlarmStateRaised,
+		LimitType:    limitType,
+		TriggerValue: &value,
+		LimitValue:   &limit,
+		Message:      fmt.Sprintf("%s %.2f is %s limit %.2f", rule.Sensor, value, direction, limit),
+		RaisedAt:     at,
+	}
+	if rule.Description != "" {
+		alarm.Message = rule.Description + ": " + alarm.Message
+	}
+
+	if err := s.alarmRepo.CreateAlarm(alarm); err != nil {
+		log.Printf("Error raising alarm for room_id=%d rule_id=%d: %v", key.roomID, rule.ID, err)
+		return
+	}
+	s.active[key] = alarm
+	log.Printf("[room_id=%d] Alarm raised: %s", key.roomID, alarm.Message)
+	s.publishAlarm(models.WebhookEventAlarmRaised, alarm, at)
+}
+
+// clearAlarm marks an open alarm as cleared
+func (s *AlarmService) clearAlarm(key alarmKey, alarm *models.Alarm, at time.Time) {
+	if err := s.alarmRepo.ClearAlarm(alarm.ID, at); err != nil {
+		log.Printf("Error clearing alarm %d: %v", alarm.ID, err)
+		return
+	}
+	alarm.State = models.AlarmStateCleared
+	alarm.ClearedAt = &at
+	delete(s.active, key)
+	log.Printf("[room_id=%d] Alarm %d cleared", key.roomID, alarm.ID)
+	s.publishAlarm(models.WebhookEventAlarmCleared, alarm, at)
+}
+
+// RaiseDeviceOffline raises a device offline alarm for a room whose telemetry has gone stale
+// Returns true only when a new alarm was raised; a room has at most one open at a time
+func (s *AlarmService) RaiseDeviceOffline(roomID uint, lastSeen time.Time, staleAfter time.Duration, at time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadOpenAlarms(roomID); err != nil {
+		log.Printf("Error loading open alarms for room_id=%d: %v", roomID, err)
+		return false
+	}
+	if _, open := s.offline[roomID]; open {
+		return false
+	}
+
+	age := at.Sub(lastSeen)
+	ageSeconds := age.Seconds()
+	limit := staleAfter.Seconds()
+	alarm := &models.Alarm{
+		RoomID:       roomID,
+		AlarmType:    models.AlarmTypeDeviceOffline,
+		Severity:     "critical",
+		State:        models.AlarmStateRaised,
+		LimitType:    "high",
+		TriggerValue: &ageSeconds,
+		LimitValue:   &limit,
+		Message:      fmt.Sprintf("No telemetry received for %v (limit %v)", age.Round(time.Second), staleAfter),
+		RaisedAt:     at,
+	}
+
+	if err := s.alarmRepo.CreateAlarm(alarm); err != nil {
+		log.Printf("Error raising device offline alarm for room_id=%d: %v", roomID, err)
+		return false
+	}
+	s.offline[roomID] = alarm
+	log.Printf("[room_id=%d] Alarm raised: %s", roomID, alarm.Message)
+	s.publishAlarm(models.WebhookEventAlarmRaised, alarm, at)
+	return true
+}
+
+// ClearDeviceOffline clears a room's open device offline alarm once telemetry arrives again
+func (s *AlarmService) ClearDeviceOffline(roomID uint, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadOpenAlarms(roomID); err != nil {
+		log.Printf("Error loading open alarms for room_id=%d: %v", roomID, err)
+		return
+	}
+	alarm, open := s.offline[roomID]
+	if !open {
+		return
+	}
+
+	if err := s.alarmRepo.ClearAlarm(alarm.ID, at); err != nil {
+		log.Printf("Error clearing alarm %d: %v", alarm.ID, err)
+		return
+	}
+	alarm.State = models.AlarmStateCleared
+	alarm.ClearedAt = &at
+	delete(s.offline, roomID)
+	log.Printf("[room_id=%d] Alarm %d cleared - telemetry resumed", roomID, alarm.ID)
+	s.publishAlarm(models.WebhookEventAlarmCleared, alarm, at)
+}
+
+// publishAlarm queues an alarm transition for webhook subscribers
+func (s *AlarmService) publishAlarm(eventType string, alarm *models.Alarm, at time.Time) {
+	if s.webhookService == nil {
+		return
+	}
+	data := *alarm
+	data.Room = nil
+	roomID := alarm.RoomID
+	s.webhookService.Publish(eventType, &roomID, data, at)
+}
+
+// ruleViolation reports whether value is outside the rule's limits
+func ruleViolation(rule *models.AlarmRule, value float64) (limitType string, limit float64, violated bool) {
+	if rule.LowLimit != nil && value < *rule.LowLimit {
+		return "low", *rule.LowLimit, true
+	}
+	if rule.HighLimit != nil && value > *rule.HighLimit {
+		return "high", *rule.HighLimit, true
+	}
+	return "", 0, false
+}
+
+// ruleCleared reports whether value has recovered inside the limits by the hysteresis margin
+func ruleCleared(rule *models.AlarmRule, value float64) bool {
+	if rule.LowLimit != nil && value < *rule.LowLimit+rule.Hysteresis {
+		return false
+	}
+	if rule.HighLimit != nil && value > *rule.HighLimit-rule.Hysteresis {
+		return false
+	}
+	return true
+}
+
+// RulesForRoom returns the active rules that apply to a room
+func (s *AlarmService) RulesForRoom(roomID uint) ([]models.AlarmRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refreshRules(); err != nil {
+		return nil, err
+	}
+	return s.rulesForRoom(roomID), nil
+}
+
+// rulesForRoom returns the effective rules for a room
+// A room-specific rule replaces any room-type rule for the same sensor
+func (s *AlarmService) rulesForRoom(roomID uint) []models.AlarmRule {
+	roomType := s.roomTypes[roomID]
+	specific := make(map[string]bool)
+	var effective []models.AlarmRule
+
+	for _, rule := range s.rules {
+		if rule.RoomID != nil && *rule.RoomID == roomID {
+			specific[rule.Sensor] = true
+			effective = append(effective, rule)
+		}
+	}
+	for _, rule := range s.rules {
+		if rule.RoomID == nil && rule.RoomType != nil && *rule.RoomType == roomType && !specific[rule.Sensor] {
+			effective = append(effective, rule)
+		}
+	}
+	return effective
+}
+
+// refreshRules reloads rules and room types once the cache has expired
+func (s *AlarmService) refreshRules() error {
+	if time.Since(s.rulesLoadedAt) < alarmRuleCacheTTL {
+		return nil
+	}
+
+	rules, err := s.alarmRepo.GetActiveAlarmRules()
+	if err != nil {
+		return err
+	}
+	rooms, err := s.roomRepo.GetAllRooms()
+	if err != nil {
+		return err
+	}
+
+	roomTypes := make(map[uint]string, len(rooms))
+	for _, room := range rooms {
+		roomTypes[room.ID] = room.RoomType
+	}
+
+	// Drop pending violations for rules that no longer exist
+	ruleIDs := make(map[uint]bool, len(rules))
+	for _, rule := range rules {
+		ruleIDs[rule.ID] = true
+	}
+	for key := range s.pending {
+		if !ruleIDs[key.ruleID] {
+			delete(s.pending, key)
+		}
+	}
+
+	s.rules = rules
+	s.roomTypes = roomTypes
+	s.rulesLoadedAt = time.Now()
+	return nil
+}
+
+// loadOpenAlarms loads a room's open alarms from the database the first time the room is evaluated
+// This keeps the lifecycle consistent across restarts
+func (s *AlarmService) loadOpenAlarms(roomID uint) error {
+	if s.loadedRooms[roomID] {
+		return nil
+	}
+
+	alarms, err := s.alarmRepo.GetOpenAlarmsByRoomID(roomID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range alarms {
+		switch {
+		case alarms[i].RuleID != nil:
+			s.active[alarmKey{ruleID: *alarms[i].RuleID, roomID: roomID}] = &alarms[i]
+		case alarms[i].AlarmType == models.AlarmTypeDeviceOffline:
+			s.offline[roomID] = &alarms[i]
+		default:
+			// A threshold alarm whose rule was deleted before rule deletion cleared its alarms
+			// can never be evaluated again
+			if err := s.alarmRepo.ClearAlarm(alarms[i].ID, now); err != nil {
+				return err
+			}
+		}
+	}
+	s.loadedRooms[roomID] = true
+	return nil
+}
+
+// forgetRule drops the open and pending alarm state of a deleted or deactivated rule
+// Its open alarms have already been cleared in the database
+func (s *AlarmService) forgetRule(ruleID uint) {
+	for key := range s.active {
+		if key.ruleID == ruleID {
+			delete(s.active, key)
+		}
+	}
+	for key := range s.pending {
+		if key.ruleID == ruleID {
+			delete(s.pending, key)
+		}
+	}
+}
+
+// publishCleared queues the cleared events of alarms cleared by a rule change
+func (s *AlarmService) publishCleared(alarms []models.Alarm, at time.Time) {
+	for i := range alarms {
+		log.Printf("[room_id=%d] Alarm %d cleared - rule removed", alarms[i].RoomID, alarms[i].ID)
+		s.publishAlarm(models.WebhookEventAlarmCleared, &alarms[i], at)
+	}
+}
+
+// invalidateRules forces the next evaluation to reload rules
+func (s *AlarmService) invalidateRules() {
+	s.mu.Lock()
+	s.rulesLoadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+// GetAlarms lists alarms visible to the user
+func (s *AlarmService) GetAlarms(filter repository.AlarmFilter, userID uint, role string) ([]models.Alarm, int64, error) {
+	if role != models.UserRoleSuperAdmin {
+		hospitalIDs, err := s.userHospitalRepo.GetUserHospitals(userID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if hospitalIDs == nil {
+			hospitalIDs = []uint{}
+		}
+		filter.HospitalIDs = hospitalIDs
+	}
+	return s.alarmRepo.GetAlarms(filter)
+}
+
+// AcknowledgeAlarm marks an alarm as acknowledged by a user (operators of the alarm's hospital)
+func (s *AlarmService) AcknowledgeAlarm(alarmID uint, note string, userID uint, role string) (*models.Alarm, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alarm, err := s.alarmRepo.GetAlarmByID(alarmID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkRoomPermission(s.roomRepo, s.userHospitalRepo, alarm.RoomID, userID, role, models.PermissionAckAlarms); err != nil {
+		return nil, err
+	}
+
+	if alarm.State == models.AlarmStateAcknowledged {
+		return nil, errors.New("alarm is already acknowledged")
+	}
+	if alarm.State == models.AlarmStateCleared {
+		return nil, errors.New("alarm is already cleared")
+	}
+
+	now := time.Now()
+	alarm.State = models.AlarmStateAcknowledged
+	alarm.AcknowledgedAt = &now
+	alarm.AcknowledgedBy = &userID
+	alarm.AcknowledgeNote = note
+
+	// Keep the worker's copy in sync so a later clear doesn't overwrite the acknowledgement
+	var cached *models.Alarm
+	if alarm.RuleID != nil {
+		cached = s.active[alarmKey{ruleID: *alarm.RuleID, roomID: alarm.RoomID}]
+	} else if alarm.AlarmType == models.AlarmTypeDeviceOffline {
+		cached = s.offline[alarm.RoomID]
+	}
+	if cached != nil && cached.ID == alarm.ID {
+		alarm = cached
+		alarm.State = models.AlarmStateAcknowledged
+		alarm.AcknowledgedAt = &now
+		alarm.AcknowledgedBy = &userID
+		alarm.AcknowledgeNote = note
+	}
+
+	if err := s.alarmRepo.UpdateAlarm(alarm); err != nil {
+		return nil, fmt.Errorf("failed to acknowledge alarm: %w", err)
+	}
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Acknowledged alarm ID: %d for room_id: %d", alarm.ID, alarm.RoomID)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "alarm_acknowledge", details)
+	s.publishAlarm(models.WebhookEventAlarmAcknowledged, alarm, now)
+
+	return alarm, nil
+}
+
+// GetAlarmRules lists alarm rules, optionally filtered by room or room type
+func (s *AlarmService) GetAlarmRules(roomID *uint, roomType string) ([]models.AlarmRule, error) {
+	return s.alarmRepo.GetAlarmRules(roomID, roomType)
+}
+
+// CreateAlarmRule creates a new alarm rule (admin only)
+func (s *AlarmService) CreateAlarmRule(rule *models.AlarmRule, userID uint) error {
+	if err := s.validateAlarmRule(rule); err != nil {
+		return err
+	}
+	if err := s.alarmRepo.CreateAlarmRule(rule); err != nil {
+		return fmt.Errorf("failed to create alarm rule: %w", err)
+	}
+	s.invalidateRules()
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Created alarm rule ID: %d for sensor %s", rule.ID, rule.Sensor)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "alarm_rule_create", details)
+
+	return nil
+}
+
+// UpdateAlarmRule updates an existing alarm rule (admin only)
+func (s *AlarmService) UpdateAlarmRule(rule *models.AlarmRule, userID uint) error {
+	existing, err := s.alarmRepo.GetAlarmRuleByID(rule.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.validateAlarmRule(rule); err != nil {
+		return err
+	}
+	rule.CreatedAt = existing.CreatedAt
+
+	// Hold the lock so the worker cannot raise or clear the rule's alarms mid-update
+	now := time.Now()
+	s.mu.Lock()
+	cleared, err := s.alarmRepo.UpdateAlarmRule(rule, now)
+	if err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("failed to update alarm rule: %w", err)
+	}
+	if !rule.IsActive {
+		s.forgetRule(rule.ID)
+	}
+	s.rulesLoadedAt = time.Time{}
+	s.mu.Unlock()
+	s.publishCleared(cleared, now)
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Updated alarm rule ID: %d for sensor %s", rule.ID, rule.Sensor)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "alarm_rule_update", details)
+
+	return nil
+}
+
+// DeleteAlarmRule deletes an alarm rule and clears its open alarms (admin only)
+func (s *AlarmService) DeleteAlarmRule(ruleID uint, userID uint) error {
+	// Hold the lock so the worker cannot raise or clear the rule's alarms mid-delete
+	now := time.Now()
+	s.mu.Lock()
+	cleared, err := s.alarmRepo.DeleteAlarmRule(ruleID, now)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.forgetRule(ruleID)
+	s.rulesLoadedAt = time.Time{}
+	s.mu.Unlock()
+	s.publishCleared(cleared, now)
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Deleted alarm rule ID: %d", ruleID)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "alarm_rule_delete", details)
+
+	return nil
+}
+
+// validateAlarmRule checks that a rule targets exactly one scope and has at least one limit
+func (s *AlarmService) validateAlarmRule(rule *models.AlarmRule) error {
+	if (rule.RoomID == nil) == (rule.RoomType == nil) {
+		return errors.New("exactly one of room_id or room_type is required")
+	}
+	var roomType string
+	if rule.RoomID != nil {
+		room, err := s.roomRepo.GetRoomByID(*rule.RoomID)
+		if err != nil {
+			return err
+		}
+		roomType = room.RoomType
+	} else {
+		roomType = *rule.RoomType
+	}
+	if !isRoomSensor(s.sensorSchemaService.SchemaFor(roomType), rule.Sensor) {
+		return fmt.Errorf("unknown sensor: %s", rule.Sensor)
+	}
+	if rule.LowLimit == nil && rule.HighLimit == nil {
+		return errors.New("at least one of low_limit or high_limit is required")
+	}
+	if rule.LowLimit != nil && rule.HighLimit != nil && *rule.LowLimit >= *rule.HighLimit {
+		return errors.New("low_limit must be less than high_limit")
+	}
+	if rule.Hysteresis < 0 || rule.MinDurationSeconds < 0 {
+		return errors.New("hysteresis and min_duration_seconds cannot be negative")
+	}
+	switch rule.Severity {
+	case "":
+		rule.Severity = "warning"
+	case "info", "warning", "critical":
+	default:
+		return errors.New("severity must be 'info', 'warning', or 'critical'")
+	}
+	return nil
+}
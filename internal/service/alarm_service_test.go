@@ -0,0 +1,182 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+)
+
+func TestStepAlarmLifecycle(t *testing.T) {
+	base := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	type reading struct {
+		after time.Duration // Since base
+		value float64
+		want  string // "raise", "clear", "pending" or "" for no change
+	}
+
+	tests := []struct {
+		name     string
+		rule     models.AlarmRule
+		readings []reading
+	}{
+		{
+			name: "raises at once without a minimum duration",
+			rule: models.AlarmRule{HighLimit: limitOf(25)},
+			readings: []reading{
+				{after: 0, value: 24},
+				{after: time.Second, value: 26, want: "raise"},
+			},
+		},
+		{
+			name: "value on the limit is not a violation",
+			rule: models.AlarmRule{LowLimit: limitOf(18), HighLimit: limitOf(25)},
+			readings: []reading{
+				{after: 0, value: 25},
+				{after: time.Second, value: 18},
+			},
+		},
+		{
+			name: "waits for the minimum duration before raising",
+			rule: models.AlarmRule{HighLimit: limitOf(25), MinDurationSeconds: 60},
+			readings: []reading{
+				{after: 0, value: 26, want: "pending"},
+				{after: 30 * time.Second, value: 27, want: "pending"},
+				{after: 60 * time.Second, value: 26, want: "raise"},
+			},
+		},
+		{
+			name: "a recovery in between restarts the minimum duration",
+			rule: models.AlarmRule{HighLimit: limitOf(25), MinDurationSeconds: 60},
+			readings: []reading{
+				{after: 0, value: 26, want: "pending"},
+				{after: 30 * time.Second, value: 24},
+				{after: 40 * time.Second, value: 26, want: "pending"},
+				{after: 70 * time.Second, value: 26, want: "pending"},
+				{after: 100 * time.Second, value: 26, want: "raise"},
+			},
+		},
+		{
+			name: "open alarm stays raised inside the hysteresis band",
+			rule: models.AlarmRule{HighLimit: limitOf(25), Hysteresis: 1},
+			readings: []reading{
+				{after: 0, value: 26, want: "raise"},
+				{after: time.Second, value: 24.5},
+				{after: 2 * time.Second, value: 24, want: "clear"},
+			},
+		},
+		{
+			name: "low limit hysteresis clears above the band",
+			rule: models.AlarmRule{LowLimit: limitOf(18), Hysteresis: 0.5},
+			readings: []reading{
+				{after: 0, value: 17, want: "raise"},
+				{after: time.Second, value: 18.2},
+				{after: 2 * time.Second, value: 18.5, want: "clear"},
+			},
+		},
+		{
+			name: "raises again after clearing",
+			rule: models.AlarmRule{HighLimit: limitOf(25)},
+			readings: []reading{
+				{after: 0, value: 26, want: "raise"},
+				{after: time.Second, value: 20, want: "clear"},
+				{after: 2 * time.Second, value: 30, want: "raise"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		open := false
+		var pending *time.Time
+		for i, r := range tt.readings {
+			step := stepAlarm(&tt.rule, r.value, base.Add(r.after), open, pending)
+
+			got := ""
+			switch {
+			case step.raise:
+				got = "raise"
+			case step.clear:
+				got = "clear"
+			case step.pending != nil:
+				got = "pending"
+			}
+			if got != r.want {
+				t.Errorf("%s: reading %d (%g): got %q, want %q", tt.name, i, r.value, got, r.want)
+			}
+
+			pending = step.pending
+			if step.raise {
+				open = true
+			}
+			if step.clear {
+				open = false
+			}
+		}
+	}
+}
+
+func TestStepAlarmReportsCrossedLimit(t *testing.T) {
+	rule := &models.AlarmRule{LowLimit: limitOf(18), HighLimit: limitOf(25)}
+	at := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	if step := stepAlarm(rule, 17, at, false, nil); step.limitType != "low" || step.limit != 18 {
+		t.Errorf("below range: got %s limit %g, want low limit 18", step.limitType, step.limit)
+	}
+	if step := stepAlarm(rule, 26, at, false, nil); step.limitType != "high" || step.limit != 25 {
+		t.Errorf("above range: got %s limit %g, want high limit 25", step.limitType, step.limit)
+	}
+}
+
+func TestRulesForRoomPrefersRoomRules(t *testing.T) {
+	s := NewAlarmService(nil, nil, nil, nil, nil, nil)
+	roomID, otherRoomID := uint(1), uint(2)
+	roomType := "operating_theater"
+	s.roomTypes[roomID] = roomType
+	s.roomTypes[otherRoomID] = roomType
+	s.rules = []models.AlarmRule{
+		{ID: 1, RoomType: &roomType, Sensor: "temp"},
+		{ID: 2, RoomType: &roomType, Sensor: "humidity"},
+		{ID: 3, RoomID: &roomID, Sensor: "temp"},
+	}
+
+	got := map[uint]bool{}
+	for _, rule := range s.rulesForRoom(roomID) {
+		got[rule.ID] = true
+	}
+	if len(got) != 2 || !got[2] || !got[3] {
+		t.Errorf("room rules: got %v, want the room's temp rule and the type's humidity rule", got)
+	}
+
+	got = map[uint]bool{}
+	for _, rule := range s.rulesForRoom(otherRoomID) {
+		got[rule.ID] = true
+	}
+	if len(got) != 2 || !got[1] || !got[2] {
+		t.Errorf("other room rules: got %v, want both room type rules", got)
+	}
+}
+
+func TestDeviceOfflineAlarmIsRaisedOncePerRoom(t *testing.T) {
+	s := NewAlarmService(nil, nil, nil, nil, nil, nil)
+	roomID := uint(1)
+	at := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	s.loadedRooms[roomID] = true
+	s.offline[roomID] = &models.Alarm{ID: 5, RoomID: roomID, AlarmType: models.AlarmTypeDeviceOffline}
+
+	if s.RaiseDeviceOffline(roomID, at.Add(-time.Minute), 30*time.Second, at) {
+		t.Error("raised a second offline alarm while one is open")
+	}
+
+	// Nothing open for this room, so clearing must not touch the repository
+	otherRoomID := uint(2)
+	s.loadedRooms[otherRoomID] = true
+	s.ClearDeviceOffline(otherRoomID, at)
+	if _, open := s.offline[roomID]; !open {
+		t.Error("clearing another room dropped this room's offline alarm")
+	}
+}
+
+// limitOf returns a pointer to an alarm limit literal
+func limitOf(v float64) *float64 {
+	return &v
+}
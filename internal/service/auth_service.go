@@ -3,6 +3,7 @@ package service
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"iot-backend-room-monitoring/internal/models"
@@ -33,6 +34,17 @@ type UserResponse struct {
 	ID       uint   `json:"id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	Email    string `json:"email,omitempty"`
+}
+
+// newUserResponse returns the public fields of a user
+func newUserResponse(user *models.User) UserResponse {
+	return UserResponse{
+		ID:       user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		Email:    user.Email,
+	}
 }
 
 // Login authenticates a user and returns tokens
@@ -79,11 +91,7 @@ func (s *AuthService) Login(username, password string) (*LoginResponse, error) {
 	return &LoginResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-		User: UserResponse{
-			ID:       user.ID,
-			Username: user.Username,
-			Role:     user.Role,
-		},
+		User:         newUserResponse(user),
 	}, nil
 }
 
@@ -126,7 +134,8 @@ func (s *AuthService) Logout(refreshToken string) error {
 }
 
 // Register creates a new user account
-func (s *AuthService) Register(username, password, role string) (*LoginResponse, error) {
+// Register creates a regular user account; only a super admin can grant the super admin role
+func (s *AuthService) Register(username, password, email string) (*LoginResponse, error) {
 	// Check if username already exists
 	existingUser, err := s.userRepo.FindUserByUsername(username)
 	if err == nil && existingUser != nil {
@@ -143,7 +152,8 @@ func (s *AuthService) Register(username, password, role string) (*LoginResponse,
 	user := &models.User{
 		Username:     username,
 		PasswordHash: passwordHash,
-		Role:         role,
+		Role:         models.UserRoleUser,
+		Email:        email,
 	}
 
 	if err := s.userRepo.CreateUser(user); err != nil {
@@ -181,10 +191,82 @@ func (s *AuthService) Register(username, password, role string) (*LoginResponse,
 	return &LoginResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-		User: UserResponse{
-			ID:       user.ID,
-			Username: user.Username,
-			Role:     user.Role,
-		},
+		User:         newUserResponse(user),
 	}, nil
 }
+
+// GetUser returns a user's account details
+func (s *AuthService) GetUser(userID uint) (*UserResponse, error) {
+	user, err := s.userRepo.FindUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	response := newUserResponse(user)
+	return &response, nil
+}
+
+// UpdateEmail sets the address a user's hospital notifications are sent to
+// An empty address stops email notifications to the user
+func (s *AuthService) UpdateEmail(targetUserID uint, email string, actorID uint) (*UserResponse, error) {
+	email = strings.TrimSpace(email)
+	if err := s.userRepo.UpdateUserEmail(targetUserID, email); err != nil {
+		if err.Error() == "user not found" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update email: %w", err)
+	}
+
+	user, err := s.userRepo.FindUserByID(targetUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	actorIDPtr := &actorID
+	details := fmt.Sprintf("Updated email of user %s (ID: %d)", user.Username, user.ID)
+	_ = s.auditRepo.CreateAuditLog(actorIDPtr, "user_email_update", details)
+
+	response := newUserResponse(user)
+	return &response, nil
+}
+
+// SetRole changes a user's global role between super admin and regular user
+// The last super admin cannot be demoted; the new role applies from the user's next request
+func (s *AuthService) SetRole(targetUserID uint, role string, actorID uint) (*UserResponse, error) {
+	if role != models.UserRoleSuperAdmin && role != models.UserRoleUser {
+		return nil, fmt.Errorf("invalid role: %s", role)
+	}
+
+	user, err := s.userRepo.FindUserByID(targetUserID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Role == role {
+		response := newUserResponse(user)
+		return &response, nil
+	}
+
+	if user.Role == models.UserRoleSuperAdmin {
+		count, err := s.userRepo.CountUsersByRole(models.UserRoleSuperAdmin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count super admins: %w", err)
+		}
+		if count <= 1 {
+			return nil, errors.New("cannot demote the last super admin")
+		}
+	}
+
+	if err := s.userRepo.UpdateUserRole(targetUserID, role); err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+	user.Role = role
+
+	actorIDPtr := &actorID
+	details := fmt.Sprintf("Set role of user %s (ID: %d) to %s", user.Username, user.ID, role)
+	_ = s.auditRepo.CreateAuditLog(actorIDPtr, "user_role_update", details)
+
+	response := newUserResponse(user)
+	return &response, nil
+}
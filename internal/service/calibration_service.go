@@ -0,0 +1,412 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+)
+
+// calibrationCacheTTL controls how often ingestion reloads calibration profiles
+const calibrationCacheTTL = 30 * time.Second
+
+// calibrationStorageUnits lists the fields that can be calibrated and the unit they are stored in
+var calibrationStorageUnits = map[string]string{
+	"temp":          "C",
+	"humidity":      "%",
+	"room_pressure": "Pa",
+	"oxygen":        "kPa",
+	"nitrous":       "kPa",
+	"air":           "kPa",
+	"vacuum":        "kPa",
+	"instrument":    "kPa",
+	"carbon":        "kPa",
+}
+
+// pressureUnits gives the size of each supported pressure unit in pascal
+var pressureUnits = map[string]float64{
+	"Pa":    1,
+	"hPa":   100,
+	"kPa":   1000,
+	"bar":   100000,
+	"psi":   6894.757,
+	"mmHg":  133.322,
+	"inH2O": 249.089,
+	"mmH2O": 9.80665,
+}
+
+// CalibrationResult describes the calibration applied to a reading
+type CalibrationResult struct {
+	ProfileID    *uint
+	Uncalibrated models.SensorValues // Values as reported for the fields the profile changed, nil if none
+}
+
+// SaveCalibrationProfileRequest is the body for creating or replacing a calibration profile
+type SaveCalibrationProfileRequest struct {
+	Fields      models.CalibrationFields `json:"fields" binding:"required"`
+	Description string                   `json:"description" binding:"max=255"`
+}
+
+// RoomCalibration lists the calibration profiles of a room
+type RoomCalibration struct {
+	RoomProfile    *models.CalibrationProfile  `json:"room_profile"`
+	DeviceProfiles []models.CalibrationProfile `json:"device_profiles"`
+	StorageUnits   map[string]string           `json:"storage_units"`
+}
+
+type CalibrationService struct {
+	calibrationRepo  *repository.CalibrationRepository
+	roomRepo         *repository.RoomRepository
+	deviceRepo       *repository.DeviceRepository
+	deviceConfigRepo *repository.DeviceConfigRepository
+	userHospitalRepo *repository.UserHospitalRepository
+	auditRepo        *repository.AuditRepository
+	deviceService    *DeviceService
+
+	mu             sync.Mutex
+	roomProfiles   map[uint]*models.CalibrationProfile // By room ID
+	deviceProfiles map[uint]*models.CalibrationProfile // By device ID
+	deviceRooms    map[uint]bool                       // Rooms with at least one device profile
+	loadedAt       time.Time
+}
+
+func NewCalibrationService(
+	calibrationRepo *repository.CalibrationRepository,
+	roomRepo *repository.RoomRepository,
+	deviceRepo *repository.DeviceRepository,
+	deviceConfigRepo *repository.DeviceConfigRepository,
+	userHospitalRepo *repository.UserHospitalRepository,
+	auditRepo *repository.AuditRepository,
+	deviceService *DeviceService,
+) *CalibrationService {
+	return &CalibrationService{
+		calibrationRepo:  calibrationRepo,
+		roomRepo:         roomRepo,
+		deviceRepo:       deviceRepo,
+		deviceConfigRepo: deviceConfigRepo,
+		userHospitalRepo: userHospitalRepo,
+		auditRepo:        auditRepo,
+		deviceService:    deviceService,
+		roomProfiles:     make(map[uint]*models.CalibrationProfile),
+		deviceProfiles:   make(map[uint]*models.CalibrationProfile),
+		deviceRooms:      make(map[uint]bool),
+	}
+}
+
+// Calibrate returns a copy of a reading with the applicable calibration profile applied
+// The reading passed in is left untouched
+func (s *CalibrationService) Calibrate(roomID uint, source TelemetrySource, data *TelemetryUpdateRequest) (*TelemetryUpdateRequest, CalibrationResult, error) {
+	calibrated := *data
+	profile := s.profileFor(roomID, source.APIKeyID, data.MacAddress)
+	if profile == nil {
+		return &calibrated, CalibrationResult{}, nil
+	}
+
+	profileID := profile.ID
+	result := CalibrationResult{ProfileID: &profileID}
+
+	floats := map[string]**float64{
+		"temp":          &calibrated.Temp,
+		"room_pressure": &calibrated.RoomPressure,
+		"oxygen":        &calibrated.Oxygen,
+		"nitrous":       &calibrated.Nitrous,
+		"air":           &calibrated.Air,
+		"instrument":    &calibrated.Instrument,
+		"carbon":        &calibrated.Carbon,
+	}
+	ints := map[string]**int{
+		"humidity": &calibrated.Humidity,
+		"vacuum":   &calibrated.Vacuum,
+	}
+
+	// Values are replaced rather than written through, since the copy shares pointers with data
+	for field, calibration := range profile.Fields {
+		var reported float64
+		if ptr, ok := floats[field]; ok && *ptr != nil {
+			reported = **ptr
+		} else if ptr, ok := ints[field]; ok && *ptr != nil {
+			reported = float64(**ptr)
+		} else {
+			continue
+		}
+
+		value, err := applyCalibration(field, calibration, reported)
+		if err != nil {
+			return nil, CalibrationResult{}, err
+		}
+		if value == reported {
+			continue
+		}
+
+		if ptr, ok := floats[field]; ok {
+			*ptr = &value
+		} else {
+			rounded := int(math.Round(value))
+			*ints[field] = &rounded
+		}
+		if result.Uncalibrated == nil {
+			result.Uncalibrated = models.SensorValues{}
+		}
+		result.Uncalibrated[field] = reported
+	}
+
+	return &calibrated, result, nil
+}
+
+// profileFor finds the profile for a reading: the device's own profile, otherwise the room's
+func (s *CalibrationService) profileFor(roomID uint, apiKeyID uint, macAddress string) *models.CalibrationProfile {
+	s.mu.Lock()
+	s.refresh()
+	roomProfile := s.roomProfiles[roomID]
+	hasDeviceProfiles := s.deviceRooms[roomID]
+	s.mu.Unlock()
+
+	// Resolving the device costs a lookup, so only do it for rooms that need it
+	if hasDeviceProfiles {
+		if deviceID := s.deviceService.DeviceIDFor(roomID, apiKeyID, macAddress); deviceID != nil {
+			s.mu.Lock()
+			deviceProfile := s.deviceProfiles[*deviceID]
+			s.mu.Unlock()
+			if deviceProfile != nil && deviceProfile.RoomID == roomID {
+				return deviceProfile
+			}
+		}
+	}
+	return roomProfile
+}
+
+// refresh reloads profiles once the cache has expired; the caller must hold s.mu
+// On error the previous profiles are kept until the next reload
+func (s *CalibrationService) refresh() {
+	if time.Since(s.loadedAt) < calibrationCacheTTL {
+		return
+	}
+	s.loadedAt = time.Now()
+
+	profiles, err := s.calibrationRepo.GetAllProfiles()
+	if err != nil {
+		log.Printf("Error loading calibration profiles: %v", err)
+		return
+	}
+
+	roomProfiles := make(map[uint]*models.CalibrationProfile)
+	deviceProfiles := make(map[uint]*models.CalibrationProfile)
+	deviceRooms := make(map[uint]bool)
+	for i := range profiles {
+		profile := &profiles[i]
+		if profile.DeviceID != nil {
+			deviceProfiles[*profile.DeviceID] = profile
+			deviceRooms[profile.RoomID] = true
+		} else {
+			roomProfiles[profile.RoomID] = profile
+		}
+	}
+	s.roomProfiles = roomProfiles
+	s.deviceProfiles = deviceProfiles
+	s.deviceRooms = deviceRooms
+}
+
+// invalidate forces the next reading to reload profiles
+func (s *CalibrationService) invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+// GetRoomCalibration lists the calibration profiles of a room with access control
+func (s *CalibrationService) GetRoomCalibration(roomID uint, userID uint, role string) (*RoomCalibration, error) {
+	if _, err := checkRoomAccess(s.roomRepo, s.userHospitalRepo, roomID, userID, role); err != nil {
+		return nil, err
+	}
+
+	profiles, err := s.calibrationRepo.GetProfilesByRoomID(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch calibration profiles: %w", err)
+	}
+
+	calibration := &RoomCalibration{
+		DeviceProfiles: []models.CalibrationProfile{},
+		StorageUnits:   calibrationStorageUnits,
+	}
+	for i := range profiles {
+		if profiles[i].DeviceID == nil {
+			calibration.RoomProfile = &profiles[i]
+		} else {
+			calibration.DeviceProfiles = append(calibration.DeviceProfiles, profiles[i])
+		}
+	}
+	return calibration, nil
+}
+
+// SaveProfile creates or replaces the room profile, or a device profile when deviceID is set (hospital admins of the room's hospital)
+func (s *CalibrationService) SaveProfile(roomID uint, deviceID *uint, req *SaveCalibrationProfileRequest, userID uint) (*models.CalibrationProfile, error) {
+	if err := s.checkProfileScope(roomID, deviceID); err != nil {
+		return nil, err
+	}
+	if err := validateCalibrationFields(req.Fields); err != nil {
+		return nil, err
+	}
+	if err := s.checkDeviceOffsets(roomID, req.Fields); err != nil {
+		return nil, err
+	}
+
+	profile, err := s.calibrationRepo.GetProfile(roomID, deviceID)
+	if err != nil {
+		if err.Error() != "calibration profile not found" {
+			return nil, fmt.Errorf("failed to fetch calibration profile: %w", err)
+		}
+		profile = &models.CalibrationProfile{RoomID: roomID, DeviceID: deviceID}
+	}
+	profile.Fields = req.Fields
+	profile.Description = req.Description
+	profile.UpdatedBy = &userID
+
+	if err := s.calibrationRepo.SaveProfile(profile); err != nil {
+		return nil, fmt.Errorf("failed to save calibration profile: %w", err)
+	}
+	s.invalidate()
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Saved calibration profile ID: %d for %s", profile.ID, calibrationScope(roomID, deviceID))
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "calibration_profile_save", details)
+
+	return profile, nil
+}
+
+// DeleteProfile removes the room profile, or a device profile when deviceID is set (hospital admins of the room's hospital)
+func (s *CalibrationService) DeleteProfile(roomID uint, deviceID *uint, userID uint) error {
+	profile, err := s.calibrationRepo.GetProfile(roomID, deviceID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.calibrationRepo.DeleteProfile(profile.ID); err != nil {
+		return fmt.Errorf("failed to delete calibration profile: %w", err)
+	}
+	s.invalidate()
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Deleted calibration profile ID: %d for %s", profile.ID, calibrationScope(roomID, deviceID))
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "calibration_profile_delete", details)
+
+	return nil
+}
+
+// checkProfileScope verifies the room exists and the device, if any, belongs to it
+func (s *CalibrationService) checkProfileScope(roomID uint, deviceID *uint) error {
+	if _, err := s.roomRepo.GetRoomByID(roomID); err != nil {
+		return err
+	}
+	if deviceID == nil {
+		return nil
+	}
+	device, err := s.deviceRepo.GetDeviceByID(*deviceID)
+	if err != nil {
+		return err
+	}
+	if device.RoomID != roomID {
+		return errors.New("device not found")
+	}
+	return nil
+}
+
+// checkDeviceOffsets refuses to correct fields the room's device already corrects through its device config
+// A field is calibrated in one place only, otherwise the reading would be corrected twice
+func (s *CalibrationService) checkDeviceOffsets(roomID uint, fields models.CalibrationFields) error {
+	config, err := s.deviceConfigRepo.GetConfigByRoomID(roomID)
+	if err != nil {
+		if err.Error() == "device config not found" {
+			return nil
+		}
+		return fmt.Errorf("failed to fetch device config: %w", err)
+	}
+	if field, ok := doubleCorrectedField(fields, config.CalibrationOffsets); ok {
+		return fmt.Errorf("%s is already corrected by a calibration offset in the device config; remove that offset first", field)
+	}
+	return nil
+}
+
+// calibrationScope describes what a profile applies to, for audit logs
+func calibrationScope(roomID uint, deviceID *uint) string {
+	if deviceID != nil {
+		return fmt.Sprintf("room_id: %d, device_id: %d", roomID, *deviceID)
+	}
+	return fmt.Sprintf("room_id: %d", roomID)
+}
+
+// validateCalibrationFields checks that every field can be calibrated with a usable gain and unit
+func validateCalibrationFields(fields models.CalibrationFields) error {
+	if len(fields) == 0 {
+		return errors.New("fields are required")
+	}
+	for field, calibration := range fields {
+		storageUnit, ok := calibrationStorageUnits[field]
+		if !ok {
+			return fmt.Errorf("field cannot be calibrated: %s", field)
+		}
+		if calibration.Gain != nil && *calibration.Gain == 0 {
+			return fmt.Errorf("gain for %s cannot be 0", field)
+		}
+		if _, err := convertUnit(0, calibration.Unit, storageUnit); err != nil {
+			return fmt.Errorf("unsupported unit for %s: %s", field, calibration.Unit)
+		}
+	}
+	return nil
+}
+
+// correctsValue reports whether a calibration changes values beyond converting their unit
+func correctsValue(calibration models.FieldCalibration) bool {
+	return calibration.Offset != 0 || (calibration.Gain != nil && *calibration.Gain != 1)
+}
+
+// doubleCorrectedField returns the first field, by name, that both a profile and device offsets correct
+func doubleCorrectedField(fields models.CalibrationFields, offsets models.SensorValues) (string, bool) {
+	var conflicts []string
+	for field, calibration := range fields {
+		if offsets[field] != 0 && correctsValue(calibration) {
+			conflicts = append(conflicts, field)
+		}
+	}
+	if len(conflicts) == 0 {
+		return "", false
+	}
+	sort.Strings(conflicts)
+	return conflicts[0], true
+}
+
+// applyCalibration corrects a reported value and converts it to the field's storage unit
+func applyCalibration(field string, calibration models.FieldCalibration, value float64) (float64, error) {
+	gain := 1.0
+	if calibration.Gain != nil {
+		gain = *calibration.Gain
+	}
+	return convertUnit(gain*value+calibration.Offset, calibration.Unit, calibrationStorageUnits[field])
+}
+
+// convertUnit converts a value between two units of the same quantity
+// An empty from unit means the value is already in the target unit
+func convertUnit(value float64, from, to string) (float64, error) {
+	if from == "" || from == to {
+		return value, nil
+	}
+	if fromPa, ok := pressureUnits[from]; ok {
+		if toPa, ok := pressureUnits[to]; ok {
+			return value * fromPa / toPa, nil
+		}
+	}
+	if to == "C" {
+		switch from {
+		case "F":
+			return (value - 32) * 5 / 9, nil
+		case "K":
+			return value - 273.15, nil
+		}
+	}
+	return 0, fmt.Errorf("cannot convert %s to %s", from, to)
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"iot-backend-room-monitoring/internal/config"
+	"iot-backend-room-monitoring/internal/metrics"
+)
+
+// clockSkewSmoothing is the weight of the newest sample in a device's running skew average
+const clockSkewSmoothing = 0.1
+
+// ClockSkewResult is the assessment of one device-timestamped reading
+type ClockSkewResult struct {
+	SkewMs   int64  // Receive time minus device time
+	Flagged  bool   // Device time should not be used for timing
+	Reason   string // metrics.Clock* reason when flagged
+	Sequence string // metrics.Sequence* anomaly, empty when the sequence followed on or was not reported
+	Missed   uint32 // Readings skipped by a sequence gap
+}
+
+// deviceClock is the tracked clock state of one device
+type deviceClock struct {
+	lastDeviceTime time.Time
+	lastSequence   *uint32
+	avgSkewMs      float64
+	samples        int
+	flagged        bool
+}
+
+// ClockSkewService tracks per-device clock skew and decides whether device timestamps can be trusted
+// Devices are identified by the API key they authenticate with
+type ClockSkewService struct {
+	tolerance time.Duration
+
+	mu      sync.Mutex
+	devices map[uint]*deviceClock
+}
+
+func NewClockSkewService(cfg config.TelemetryConfig) *ClockSkewService {
+	return &ClockSkewService{
+		tolerance: cfg.ClockSkewTolerance,
+		devices:   make(map[uint]*deviceClock),
+	}
+}
+
+// Observe records a reading's device timestamp against the server receive time
+// A reading is flagged when its skew exceeds the tolerance or the device clock ran backwards
+// Skew, flagged readings and sequence anomalies are exported as metrics per room
+func (s *ClockSkewService) Observe(roomID, apiKeyID uint, deviceTime time.Time, sequence *uint32, receivedAt time.Time) ClockSkewResult {
+	skew := receivedAt.Sub(deviceTime)
+	result := ClockSkewResult{SkewMs: skew.Milliseconds()}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	device, ok := s.devices[apiKeyID]
+	if !ok {
+		device = &deviceClock{}
+		s.devices[apiKeyID] = device
+	}
+
+	clockForward := device.lastDeviceTime.IsZero() || deviceTime.After(device.lastDeviceTime)
+	if sequence != nil && device.lastSequence != nil {
+		result.Sequence, result.Missed = sequenceAnomaly(*device.lastSequence, *sequence, clockForward)
+	}
+
+	switch {
+	case skew > s.tolerance || skew < -s.tolerance:
+		result.Flagged = true
+		result.Reason = metrics.ClockSkewExceeded
+	case !clockForward:
+		// A restarted device resets its sequence but its clock should still move forward
+		result.Flagged = true
+		result.Reason = metrics.ClockWentBackwards
+	case result.Sequence == metrics.SequenceDuplicate:
+		result.Flagged = true
+		result.Reason = metrics.ClockDuplicateReading
+	}
+
+	if device.samples == 0 {
+		device.avgSkewMs = float64(result.SkewMs)
+	} else {
+		device.avgSkewMs += clockSkewSmoothing * (float64(result.SkewMs) - device.avgSkewMs)
+	}
+	device.samples++
+	if deviceTime.After(device.lastDeviceTime) {
+		device.lastDeviceTime = deviceTime
+	}
+	// A late reading must not rewind the sequence, or the next reading would look like a gap
+	if result.Sequence != metrics.SequenceOutOfOrder {
+		device.lastSequence = sequence
+	}
+
+	room := metrics.RoomLabel(roomID)
+	metrics.DeviceClockSkew.WithLabelValues(room, strconv.FormatUint(uint64(apiKeyID), 10)).Set(device.avgSkewMs)
+	if result.Flagged {
+		metrics.TelemetryClockFlagged.WithLabelValues(room, result.Reason).Inc()
+	}
+	if result.Sequence != "" {
+		metrics.TelemetrySequenceAnomalies.WithLabelValues(room, result.Sequence).Inc()
+		metrics.TelemetrySequenceMissed.WithLabelValues(room).Add(float64(result.Missed))
+	}
+	if result.Sequence == metrics.SequenceGap || result.Sequence == metrics.SequenceOutOfOrder {
+		log.Printf("Warning: device API key %d sequence %s at %d (%d readings missed)",
+			apiKeyID, result.Sequence, *sequence, result.Missed)
+	}
+
+	// Log transitions only, a drifting device would otherwise log on every reading
+	if result.Flagged && !device.flagged {
+		log.Printf("Warning: device API key %d timestamps untrusted (%s) - skew %dms, average %.0fms",
+			apiKeyID, result.Reason, result.SkewMs, device.avgSkewMs)
+	} else if !result.Flagged && device.flagged {
+		log.Printf("Device API key %d timestamps trusted again - skew %dms", apiKeyID, result.SkewMs)
+	}
+	device.flagged = result.Flagged
+
+	return result
+}
+
+// sequenceAnomaly classifies a reading's sequence number against the previous one
+// A sequence going back is a reboot when the device clock moved on, otherwise the reading is out of order
+func sequenceAnomaly(last, current uint32, clockForward bool) (string, uint32) {
+	switch {
+	case current == last:
+		return metrics.SequenceDuplicate, 0
+	case current == last+1:
+		return "", 0
+	case current > last:
+		return metrics.SequenceGap, current - last - 1
+	case clockForward:
+		return metrics.SequenceReset, 0
+	default:
+		return metrics.SequenceOutOfOrder, 0
+	}
+}
@@ -0,0 +1,527 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+	"iot-backend-room-monitoring/pkg/utils"
+)
+
+// complianceMetric is an environmental condition a compliance report checks
+type complianceMetric struct {
+	Name  string
+	Label string
+	Unit  string
+
+	// Limits used when no alarm rule covers the metric (ASHRAE 170 operating room guidance)
+	DefaultLow  *float64
+	DefaultHigh *float64
+
+	// value extracts the metric from a reading; ok is false when the reading lacks it
+	value func(h *models.TheaterTelemetryHistory) (float64, bool)
+}
+
+// complianceMetrics lists the metrics of a compliance report in display order
+// ACH is the theoretical rate computed from AHU flow and room volume, as on the dashboard
+var complianceMetrics = []complianceMetric{
+	{
+		Name: "temp", Label: "Temperature", Unit: "C",
+		DefaultLow: floatPtr(20), DefaultHigh: floatPtr(24),
+		value: func(h *models.TheaterTelemetryHistory) (float64, bool) {
+			if h.Temp == nil {
+				return 0, false
+			}
+			return *h.Temp, true
+		},
+	},
+	{
+		Name: "humidity", Label: "Humidity", Unit: "%",
+		DefaultLow: floatPtr(20), DefaultHigh: floatPtr(60),
+		value: func(h *models.TheaterTelemetryHistory) (float64, bool) {
+			if h.Humidity == nil {
+				return 0, false
+			}
+			return float64(*h.Humidity), true
+		},
+	},
+	{
+		Name: "room_pressure", Label: "Room pressure", Unit: "Pa",
+		DefaultLow: floatPtr(2.5),
+		value: func(h *models.TheaterTelemetryHistory) (float64, bool) {
+			if h.RoomPressure == nil {
+				return 0, false
+			}
+			return *h.RoomPressure, true
+		},
+	},
+	{
+		Name: "ach", Label: "Air changes per hour", Unit: "ACH",
+		DefaultLow: floatPtr(20),
+		value: func(h *models.TheaterTelemetryHistory) (float64, bool) {
+			if h.VolumeRuangan <= 0 {
+				return 0, false
+			}
+			return float64(h.LajuAliranAhu*3600) / float64(h.VolumeRuangan), true
+		},
+	},
+}
+
+// Sources of compliance limits
+const (
+	ComplianceLimitAlarmRule = "alarm_rule"
+	ComplianceLimitDefault   = "default"
+)
+
+// ComplianceReport summarizes the room environment during the running time of an operation session
+type ComplianceReport struct {
+	SessionID      uint                  `json:"session_id"`
+	RoomID         uint                  `json:"room_id"`
+	RoomCode       string                `json:"room_code"`
+	RoomName       string                `json:"room_name"`
+	ProcedureLabel string                `json:"procedure_label"`
+	SessionStatus  string                `json:"session_status"`
+	StartedAt      time.Time             `json:"started_at"`
+	EndedAt        *time.Time            `json:"ended_at"`
+	GeneratedAt    time.Time             `json:"generated_at"`
+	Windows        []ComplianceWindow    `json:"windows"` // Stopwatch intervals the report covers
+	WindowSeconds  int64                 `json:"window_seconds"`
+	Compliant      bool                  `json:"compliant"` // Every metric monitored and never out of range
+	Metrics        []MetricCompliance    `json:"metrics"`
+	Excursions     []ComplianceExcursion `json:"excursions"`
+}
+
+// ComplianceWindow is one running interval of the operation stopwatch
+type ComplianceWindow struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// MetricCompliance summarizes one metric over the report windows
+// Each reading holds until the next one, but no longer than the room's staleness threshold;
+// time not covered by a fresh reading counts as unmonitored
+type MetricCompliance struct {
+	Name               string   `json:"name"`
+	Label              string   `json:"label"`
+	Unit               string   `json:"unit"`
+	LowLimit           *float64 `json:"low_limit"`
+	HighLimit          *float64 `json:"high_limit"`
+	LimitSource        string   `json:"limit_source"`
+	ReadingCount       int      `json:"reading_count"`
+	MonitoredSeconds   int64    `json:"monitored_seconds"`
+	InRangeSeconds     int64    `json:"in_range_seconds"`
+	OutOfRangeSeconds  int64    `json:"out_of_range_seconds"`
+	UnmonitoredSeconds int64    `json:"unmonitored_seconds"`
+	TimeInRangePercent float64  `json:"time_in_range_percent"` // Of monitored time
+	CoveragePercent    float64  `json:"coverage_percent"`      // Monitored share of the windows
+	Min                *float64 `json:"min"`
+	Max                *float64 `json:"max"`
+	Avg                *float64 `json:"avg"` // Time-weighted
+	ExcursionCount     int      `json:"excursion_count"`
+	Compliant          bool     `json:"compliant"`
+}
+
+// ComplianceExcursion is a continuous period a metric spent outside one of its limits
+type ComplianceExcursion struct {
+	Metric          string    `json:"metric"`
+	LimitType       string    `json:"limit_type"` // low or high
+	Limit           float64   `json:"limit"`
+	StartedAt       time.Time `json:"started_at"`
+	EndedAt         time.Time `json:"ended_at"`
+	DurationSeconds int64     `json:"duration_seconds"`
+	WorstValue      float64   `json:"worst_value"` // Furthest value past the limit
+}
+
+// ComplianceReportService builds environmental compliance reports for operation sessions
+type ComplianceReportService struct {
+	historyRepo      *repository.TelemetryHistoryRepository
+	sessionRepo      *repository.OperationSessionRepository
+	roomRepo         *repository.RoomRepository
+	userHospitalRepo *repository.UserHospitalRepository
+	alarmService     *AlarmService
+	stalenessService *StalenessService
+}
+
+func NewComplianceReportService(
+	historyRepo *repository.TelemetryHistoryRepository,
+	sessionRepo *repository.OperationSessionRepository,
+	roomRepo *repository.RoomRepository,
+	userHospitalRepo *repository.UserHospitalRepository,
+	alarmService *AlarmService,
+	stalenessService *StalenessService,
+) *ComplianceReportService {
+	return &ComplianceReportService{
+		historyRepo:      historyRepo,
+		sessionRepo:      sessionRepo,
+		roomRepo:         roomRepo,
+		userHospitalRepo: userHospitalRepo,
+		alarmService:     alarmService,
+		stalenessService: stalenessService,
+	}
+}
+
+// GetSessionReport builds the compliance report of an operation session with access control
+// A session still open is reported up to now
+func (s *ComplianceReportService) GetSessionReport(sessionID uint, userID uint, role string) (*ComplianceReport, error) {
+	session, err := s.sessionRepo.GetSessionByID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	room, err := checkRoomAccess(s.roomRepo, s.userHospitalRepo, session.RoomID, userID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	report := &ComplianceReport{
+		SessionID:      session.ID,
+		RoomID:         room.ID,
+		RoomCode:       room.RoomCode,
+		RoomName:       room.RoomName,
+		ProcedureLabel: session.ProcedureLabel,
+		SessionStatus:  session.Status,
+		StartedAt:      session.StartedAt,
+		EndedAt:        session.EndedAt,
+		GeneratedAt:    now,
+		Windows:        []ComplianceWindow{},
+		Excursions:     []ComplianceExcursion{},
+	}
+	for _, interval := range session.Intervals {
+		end := now
+		if interval.EndedAt != nil {
+			end = *interval.EndedAt
+		}
+		if end.After(interval.StartedAt) {
+			report.Windows = append(report.Windows, ComplianceWindow{From: interval.StartedAt, To: end})
+			report.WindowSeconds += int64(end.Sub(interval.StartedAt) / time.Second)
+		}
+	}
+
+	rules, err := s.alarmService.RulesForRoom(room.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch alarm rules: %w", err)
+	}
+
+	// Readings covering each window, led by the last reading before it so the window start is covered
+	windowReadings := make([][]models.TheaterTelemetryHistory, len(report.Windows))
+	for i, window := range report.Windows {
+		previous, err := s.historyRepo.GetLatestBefore(room.ID, window.From)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch telemetry history: %w", err)
+		}
+		readings, err := s.historyRepo.GetRoomHistoryInRange(room.ID, window.From, window.To)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch telemetry history: %w", err)
+		}
+		if previous != nil {
+			readings = append([]models.TheaterTelemetryHistory{*previous}, readings...)
+		}
+		windowReadings[i] = readings
+	}
+
+	staleAfter := s.stalenessService.StaleAfter(&room.ID)
+	report.Compliant = true
+	for _, metric := range complianceMetrics {
+		summary, excursions := summarizeCompliance(metric, rules, report.Windows, windowReadings, staleAfter)
+		report.Metrics = append(report.Metrics, summary)
+		report.Excursions = append(report.Excursions, excursions...)
+		if !summary.Compliant {
+			report.Compliant = false
+		}
+	}
+	return report, nil
+}
+
+// complianceLimits picks the limits of a metric from the room's alarm rule, falling back to the defaults
+func complianceLimits(metric complianceMetric, rules []models.AlarmRule) (*float64, *float64, string) {
+	for _, rule := range rules {
+		if rule.Sensor == metric.Name && (rule.LowLimit != nil || rule.HighLimit != nil) {
+			return rule.LowLimit, rule.HighLimit, ComplianceLimitAlarmRule
+		}
+	}
+	return metric.DefaultLow, metric.DefaultHigh, ComplianceLimitDefault
+}
+
+// summarizeCompliance measures one metric over every window and collects its excursions
+func summarizeCompliance(
+	metric complianceMetric,
+	rules []models.AlarmRule,
+	windows []ComplianceWindow,
+	windowReadings [][]models.TheaterTelemetryHistory,
+	staleAfter time.Duration,
+) (MetricCompliance, []ComplianceExcursion) {
+	low, high, source := complianceLimits(metric, rules)
+	summary := MetricCompliance{
+		Name:        metric.Name,
+		Label:       metric.Label,
+		Unit:        metric.Unit,
+		LowLimit:    low,
+		HighLimit:   high,
+		LimitSource: source,
+	}
+	excursions := []ComplianceExcursion{}
+
+	var monitored, inRange, windowTotal time.Duration
+	var weightedSum float64
+	for i, window := range windows {
+		windowTotal += window.To.Sub(window.From)
+
+		// Only readings carrying the metric count; the others leave the previous value in place
+		var readings []models.TheaterTelemetryHistory
+		var values []float64
+		for _, reading := range windowReadings[i] {
+			if v, ok := metric.value(&reading); ok {
+				readings = append(readings, reading)
+				values = append(values, v)
+			}
+		}
+
+		var open *ComplianceExcursion
+		closeExcursion := func() {
+			if open == nil {
+				return
+			}
+			open.DurationSeconds = int64(open.EndedAt.Sub(open.StartedAt) / time.Second)
+			excursions = append(excursions, *open)
+			open = nil
+		}
+
+		for j, reading := range readings {
+			value := values[j]
+
+			// The reading holds from when it was recorded until the next one or until it goes stale
+			start := reading.RecordedAt
+			end := start.Add(staleAfter)
+			if j+1 < len(readings) && readings[j+1].RecordedAt.Before(end) {
+				end = readings[j+1].RecordedAt
+			}
+			if start.Before(window.From) {
+				start = window.From
+			}
+			if end.After(window.To) {
+				end = window.To
+			}
+			if !reading.RecordedAt.Before(window.From) {
+				summary.ReadingCount++
+				summary.Min = minFloat(summary.Min, value)
+				summary.Max = maxFloat(summary.Max, value)
+			}
+			if !end.After(start) {
+				continue
+			}
+			held := end.Sub(start)
+			if reading.RecordedAt.Before(window.From) {
+				// The reading carried into the window still describes the room at its start
+				summary.Min = minFloat(summary.Min, value)
+				summary.Max = maxFloat(summary.Max, value)
+			}
+			monitored += held
+			weightedSum += value * held.Seconds()
+
+			limitType, limit, violated := complianceViolation(low, high, value)
+			if !violated {
+				inRange += held
+				closeExcursion()
+				continue
+			}
+			if open != nil && (open.LimitType != limitType || !open.EndedAt.Equal(start)) {
+				closeExcursion()
+			}
+			if open == nil {
+				open = &ComplianceExcursion{
+					Metric:     metric.Name,
+					LimitType:  limitType,
+					Limit:      limit,
+					StartedAt:  start,
+					WorstValue: value,
+				}
+			}
+			if (limitType == "low" && value < open.WorstValue) || (limitType == "high" && value > open.WorstValue) {
+				open.WorstValue = value
+			}
+			// Track where the excursion currently ends so a coverage gap splits it
+			open.EndedAt = end
+		}
+		closeExcursion()
+	}
+
+	summary.MonitoredSeconds = int64(monitored / time.Second)
+	summary.InRangeSeconds = int64(inRange / time.Second)
+	summary.OutOfRangeSeconds = summary.MonitoredSeconds - summary.InRangeSeconds
+	summary.UnmonitoredSeconds = int64(windowTotal/time.Second) - summary.MonitoredSeconds
+	if monitored > 0 {
+		summary.TimeInRangePercent = roundPercent(inRange.Seconds() * 100 / monitored.Seconds())
+		avg := math.Round(weightedSum/monitored.Seconds()*100) / 100
+		summary.Avg = &avg
+	}
+	if windowTotal > 0 {
+		summary.CoveragePercent = roundPercent(monitored.Seconds() * 100 / windowTotal.Seconds())
+	}
+	summary.ExcursionCount = len(excursions)
+	summary.Compliant = monitored > 0 && len(excursions) == 0
+	return summary, excursions
+}
+
+// complianceViolation reports which limit a value breaks, if any
+func complianceViolation(low, high *float64, value float64) (string, float64, bool) {
+	if low != nil && value < *low {
+		return "low", *low, true
+	}
+	if high != nil && value > *high {
+		return "high", *high, true
+	}
+	return "", 0, false
+}
+
+func minFloat(current *float64, value float64) *float64 {
+	if current == nil || value < *current {
+		return &value
+	}
+	return current
+}
+
+func maxFloat(current *float64, value float64) *float64 {
+	if current == nil || value > *current {
+		return &value
+	}
+	return current
+}
+
+// ComplianceReportCSV renders a report as CSV: a summary section, then metrics, then excursions
+func ComplianceReportCSV(report *ComplianceReport) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	rows := [][]string{
+		{"session_id", strconv.FormatUint(uint64(report.SessionID), 10)},
+		{"room_code", report.RoomCode},
+		{"room_name", report.RoomName},
+		{"procedure_label", report.ProcedureLabel},
+		{"session_status", report.SessionStatus},
+		{"started_at", report.StartedAt.Format(time.RFC3339)},
+		{"ended_at", formatOptionalTime(report.EndedAt)},
+		{"generated_at", report.GeneratedAt.Format(time.RFC3339)},
+		{"window_seconds", strconv.FormatInt(report.WindowSeconds, 10)},
+		{"compliant", strconv.FormatBool(report.Compliant)},
+		{},
+		{"metric", "unit", "low_limit", "high_limit", "limit_source", "reading_count", "monitored_seconds",
+			"in_range_seconds", "out_of_range_seconds", "unmonitored_seconds", "time_in_range_percent",
+			"coverage_percent", "min", "max", "avg", "excursion_count", "compliant"},
+	}
+	for _, m := range report.Metrics {
+		rows = append(rows, []string{
+			m.Name, m.Unit, formatOptionalFloat(m.LowLimit), formatOptionalFloat(m.HighLimit), m.LimitSource,
+			strconv.Itoa(m.ReadingCount), strconv.FormatInt(m.MonitoredSeconds, 10),
+			strconv.FormatInt(m.InRangeSeconds, 10), strconv.FormatInt(m.OutOfRangeSeconds, 10),
+			strconv.FormatInt(m.UnmonitoredSeconds, 10), formatFloat(m.TimeInRangePercent),
+			formatFloat(m.CoveragePercent), formatOptionalFloat(m.Min), formatOptionalFloat(m.Max),
+			formatOptionalFloat(m.Avg), strconv.Itoa(m.ExcursionCount), strconv.FormatBool(m.Compliant),
+		})
+	}
+	rows = append(rows, []string{}, []string{"excursion_metric", "limit_type", "limit", "started_at", "ended_at", "duration_seconds", "worst_value"})
+	for _, e := range report.Excursions {
+		rows = append(rows, []string{
+			e.Metric, e.LimitType, formatFloat(e.Limit), e.StartedAt.Format(time.RFC3339),
+			e.EndedAt.Format(time.RFC3339), strconv.FormatInt(e.DurationSeconds, 10), formatFloat(e.WorstValue),
+		})
+	}
+
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ComplianceReportPDF renders a report as a printable PDF
+func ComplianceReportPDF(report *ComplianceReport) []byte {
+	pdf := utils.NewTextPDF()
+	pdf.Heading("Environmental Compliance Report")
+	pdf.Blank()
+	pdf.Line(fmt.Sprintf("Room: %s - %s", report.RoomCode, report.RoomName))
+	pdf.Line(fmt.Sprintf("Operation session: %d (%s)", report.SessionID, report.SessionStatus))
+	if report.ProcedureLabel != "" {
+		pdf.Line("Procedure: " + report.ProcedureLabel)
+	}
+	pdf.Line("Started: " + report.StartedAt.Format(time.RFC3339))
+	pdf.Line("Ended: " + formatOptionalTime(report.EndedAt))
+	pdf.Line(fmt.Sprintf("Operating time: %s in %d interval(s)", formatSeconds(report.WindowSeconds), len(report.Windows)))
+	pdf.Line("Generated: " + report.GeneratedAt.Format(time.RFC3339))
+	pdf.Blank()
+	if report.Compliant {
+		pdf.Heading("Result: COMPLIANT")
+	} else {
+		pdf.Heading("Result: NOT COMPLIANT")
+	}
+
+	pdf.Blank()
+	pdf.Heading("Metrics")
+	for _, m := range report.Metrics {
+		status := "compliant"
+		if !m.Compliant {
+			status = "not compliant"
+		}
+		pdf.Blank()
+		pdf.Heading(fmt.Sprintf("%s (%s) - %s", m.Label, m.Unit, status))
+		pdf.Line(fmt.Sprintf("  Limits: %s (%s)", describeComplianceLimits(m.LowLimit, m.HighLimit), m.LimitSource))
+		pdf.Line(fmt.Sprintf("  Time in range: %s%% of monitored time, coverage %s%%",
+			formatFloat(m.TimeInRangePercent), formatFloat(m.CoveragePercent)))
+		pdf.Line(fmt.Sprintf("  In range %s, out of range %s, unmonitored %s",
+			formatSeconds(m.InRangeSeconds), formatSeconds(m.OutOfRangeSeconds), formatSeconds(m.UnmonitoredSeconds)))
+		pdf.Line(fmt.Sprintf("  Min %s, max %s, avg %s over %d reading(s)",
+			formatOptionalFloat(m.Min), formatOptionalFloat(m.Max), formatOptionalFloat(m.Avg), m.ReadingCount))
+		pdf.Line(fmt.Sprintf("  Excursions: %d", m.ExcursionCount))
+	}
+
+	pdf.Blank()
+	pdf.Heading("Excursions")
+	if len(report.Excursions) == 0 {
+		pdf.Line("None")
+	}
+	for _, e := range report.Excursions {
+		pdf.Line(fmt.Sprintf("%s %s limit %s: %s to %s (%s), worst %s",
+			e.Metric, e.LimitType, formatFloat(e.Limit), e.StartedAt.Format(time.RFC3339),
+			e.EndedAt.Format(time.RFC3339), formatSeconds(e.DurationSeconds), formatFloat(e.WorstValue)))
+	}
+	return pdf.Bytes()
+}
+
+// describeComplianceLimits renders limits as a range, e.g. "20 to 24" or ">= 2.5"
+func describeComplianceLimits(low, high *float64) string {
+	switch {
+	case low != nil && high != nil:
+		return formatFloat(*low) + " to " + formatFloat(*high)
+	case low != nil:
+		return ">= " + formatFloat(*low)
+	case high != nil:
+		return "<= " + formatFloat(*high)
+	}
+	return "none"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatOptionalFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return formatFloat(math.Round(*v*100) / 100)
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// formatSeconds renders a duration in seconds as e.g. "1h2m5s"
+func formatSeconds(seconds int64) string {
+	return (time.Duration(seconds) * time.Second).String()
+}
@@ -0,0 +1,560 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+)
+
+// maxCountdownMinutes caps the duration of presets and scheduled countdowns (one day)
+const maxCountdownMinutes = 24 * 60
+
+// CountdownPresetRequest is the body for creating or updating a countdown preset
+type CountdownPresetRequest struct {
+	RoomType        string `json:"room_type" binding:"required,oneof=operating_theater icu isolation general"`
+	Name            string `json:"name" binding:"required,max=100"`
+	DurationMinutes int    `json:"duration_minutes" binding:"required,min=1,max=1440"`
+	Description     string `json:"description" binding:"max=255"`
+}
+
+// CreateCountdownScheduleRequest is the body for scheduling a countdown
+// The label and duration default to the preset's when a preset is given
+type CreateCountdownScheduleRequest struct {
+	RoomID          uint      `json:"room_id" binding:"required"`
+	PresetID        *uint     `json:"preset_id"`
+	Label           string    `json:"label" binding:"max=100"`
+	DurationMinutes *int      `json:"duration_minutes" binding:"omitempty,min=1,max=1440"`
+	ScheduledAt     time.Time `json:"scheduled_at" binding:"required"`
+	Recurrence      string    `json:"recurrence" binding:"omitempty,oneof=none daily weekly"`
+}
+
+// CountdownService manages countdown presets and schedules, expires countdowns and records countdown events
+type CountdownService struct {
+	countdownRepo       *repository.CountdownRepository
+	theaterRepo         *repository.TheaterRepository
+	roomRepo            *repository.RoomRepository
+	userHospitalRepo    *repository.UserHospitalRepository
+	auditRepo           *repository.AuditRepository
+	streamService       *StreamService
+	notificationService *NotificationService
+	webhookService      *WebhookService
+}
+
+func NewCountdownService(
+	countdownRepo *repository.CountdownRepository,
+	theaterRepo *repository.TheaterRepository,
+	roomRepo *repository.RoomRepository,
+	userHospitalRepo *repository.UserHospitalRepository,
+	auditRepo *repository.AuditRepository,
+	streamService *StreamService,
+	notificationService *NotificationService,
+	webhookService *WebhookService,
+) *CountdownService {
+	return &CountdownService{
+		countdownRepo:       countdownRepo,
+		theaterRepo:         theaterRepo,
+		roomRepo:            roomRepo,
+		userHospitalRepo:    userHospitalRepo,
+		auditRepo:           auditRepo,
+		streamService:       streamService,
+		notificationService: notificationService,
+		webhookService:      webhookService,
+	}
+}
+
+// GetPresets lists countdown presets, optionally of one room type
+func (s *CountdownService) GetPresets(roomType string) ([]models.CountdownPreset, error) {
+	if roomType != "" && !isRoomType(roomType) {
+		return nil, errors.New("room type not found")
+	}
+	presets, err := s.countdownRepo.GetPresets(roomType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch countdown presets: %w", err)
+	}
+	return presets, nil
+}
+
+// CreatePreset creates a countdown preset (admin only)
+func (s *CountdownService) CreatePreset(req *CountdownPresetRequest, userID uint) (*models.CountdownPreset, error) {
+	if err := s.checkPresetName(req.RoomType, req.Name, 0); err != nil {
+		return nil, err
+	}
+
+	preset := &models.CountdownPreset{
+		RoomType:        req.RoomType,
+		Name:            strings.TrimSpace(req.Name),
+		DurationMinutes: req.DurationMinutes,
+		Description:     req.Description,
+	}
+	if err := s.countdownRepo.CreatePreset(preset); err != nil {
+		return nil, fmt.Errorf("failed to create countdown preset: %w", err)
+	}
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Created countdown preset ID: %d %q (%d minutes) for room type %s", preset.ID, preset.Name, preset.DurationMinutes, preset.RoomType)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "countdown_preset_create", details)
+
+	return preset, nil
+}
+
+// UpdatePreset updates a countdown preset (admin only)
+// Schedules already created from the preset keep their label and duration
+func (s *CountdownService) UpdatePreset(presetID uint, req *CountdownPresetRequest, userID uint) (*models.CountdownPreset, error) {
+	preset, err := s.countdownRepo.GetPresetByID(presetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPresetName(req.RoomType, req.Name, preset.ID); err != nil {
+		return nil, err
+	}
+
+	preset.RoomType = req.RoomType
+	preset.Name = strings.TrimSpace(req.Name)
+	preset.DurationMinutes = req.DurationMinutes
+	preset.Description = req.Description
+	if err := s.countdownRepo.UpdatePreset(preset); err != nil {
+		return nil, fmt.Errorf("failed to update countdown preset: %w", err)
+	}
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Updated countdown preset ID: %d %q (%d minutes) for room type %s", preset.ID, preset.Name, preset.DurationMinutes, preset.RoomType)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "countdown_preset_update", details)
+
+	return preset, nil
+}
+
+// DeletePreset deletes a countdown preset (admin only)
+func (s *CountdownService) DeletePreset(presetID uint, userID uint) error {
+	preset, err := s.countdownRepo.GetPresetByID(presetID)
+	if err != nil {
+		return err
+	}
+	if err := s.countdownRepo.DeletePreset(preset.ID); err != nil {
+		return fmt.Errorf("failed to delete countdown preset: %w", err)
+	}
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Deleted countdown preset ID: %d %q for room type %s", preset.ID, preset.Name, preset.RoomType)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "countdown_preset_delete", details)
+
+	return nil
+}
+
+// checkPresetName rejects a blank name or one already used by another preset of the room type
+func (s *CountdownService) checkPresetName(roomType, name string, presetID uint) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("preset name is required")
+	}
+	presets, err := s.countdownRepo.GetPresets(roomType)
+	if err != nil {
+		return fmt.Errorf("failed to fetch countdown presets: %w", err)
+	}
+	for _, preset := range presets {
+		if preset.ID != presetID && strings.EqualFold(preset.Name, name) {
+			return errors.New("countdown preset already exists for this room type")
+		}
+	}
+	return nil
+}
+
+// ResolvePreset returns the label and duration of a preset after checking it applies to the room
+func (s *CountdownService) ResolvePreset(presetID uint, roomID uint) (string, int, error) {
+	preset, err := s.countdownRepo.GetPresetByID(presetID)
+	if err != nil {
+		return "", 0, err
+	}
+	room, err := s.roomRepo.GetRoomByID(roomID)
+	if err != nil {
+		return "", 0, err
+	}
+	if preset.RoomType != room.RoomType {
+		return "", 0, fmt.Errorf("countdown preset is for %s rooms, not %s", preset.RoomType, room.RoomType)
+	}
+	return preset.Name, preset.DurationMinutes, nil
+}
+
+// GetSchedules lists countdown schedules visible to the user
+func (s *CountdownService) GetSchedules(filter repository.CountdownScheduleFilter, userID uint, role string) ([]models.CountdownSchedule, int64, error) {
+	hospitalIDs, err := s.visibleHospitalIDs(userID, role)
+	if err != nil {
+		return nil, 0, err
+	}
+	filter.HospitalIDs = hospitalIDs
+
+	schedules, total, err := s.countdownRepo.GetSchedules(filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch countdown schedules: %w", err)
+	}
+	return schedules, total, nil
+}
+
+// CreateSchedule schedules a countdown for a room
+func (s *CountdownService) CreateSchedule(req *CreateCountdownScheduleRequest, userID uint, role string) (*models.CountdownSchedule, error) {
+	if _, err := checkRoomPermission(s.roomRepo, s.userHospitalRepo, req.RoomID, userID, role, models.PermissionOperateTimers); err != nil {
+		return nil, err
+	}
+	if !req.ScheduledAt.After(time.Now()) {
+		return nil, errors.New("scheduled_at must be in the future")
+	}
+
+	schedule := &models.CountdownSchedule{
+		RoomID:      req.RoomID,
+		PresetID:    req.PresetID,
+		Label:       strings.TrimSpace(req.Label),
+		ScheduledAt: req.ScheduledAt,
+		Recurrence:  req.Recurrence,
+		Status:      models.CountdownSchedulePending,
+		CreatedBy:   &userID,
+	}
+	if schedule.Recurrence == "" {
+		schedule.Recurrence = models.CountdownRecurrenceNone
+	}
+
+	if req.PresetID != nil {
+		label, minutes, err := s.ResolvePreset(*req.PresetID, req.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		if schedule.Label == "" {
+			schedule.Label = label
+		}
+		schedule.DurationMinutes = minutes
+	}
+	if req.DurationMinutes != nil {
+		schedule.DurationMinutes = *req.DurationMinutes
+	}
+	if schedule.DurationMinutes <= 0 {
+		return nil, errors.New("duration_minutes is required without a preset")
+	}
+	if schedule.DurationMinutes > maxCountdownMinutes {
+		return nil, fmt.Errorf("duration_minutes cannot exceed %d", maxCountdownMinutes)
+	}
+
+	if err := s.countdownRepo.CreateSchedule(schedule); err != nil {
+		return nil, fmt.Errorf("failed to create countdown schedule: %w", err)
+	}
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Scheduled countdown %q (%d minutes) for room_id %d at %s, recurrence %s",
+		schedule.Label, schedule.DurationMinutes, schedule.RoomID, schedule.ScheduledAt.Format(time.RFC3339), schedule.Recurrence)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "countdown_schedule_create", details)
+
+	return schedule, nil
+}
+
+// CancelSchedule cancels a pending schedule
+// Cancelling the pending occurrence of a recurring schedule ends the series
+func (s *CountdownService) CancelSchedule(scheduleID uint, userID uint, role string) (*models.CountdownSchedule, error) {
+	schedule, err := s.countdownRepo.GetScheduleByID(scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkRoomPermission(s.roomRepo, s.userHospitalRepo, schedule.RoomID, userID, role, models.PermissionOperateTimers); err != nil {
+		return nil, err
+	}
+	if schedule.Status != models.CountdownSchedulePending {
+		return nil, fmt.Errorf("countdown schedule is already %s", schedule.Status)
+	}
+
+	cancelled, err := s.countdownRepo.UpdatePendingScheduleStatus(schedule.ID, models.CountdownScheduleCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel countdown schedule: %w", err)
+	}
+	if !cancelled {
+		// The worker started or missed it since it was read
+		return nil, errors.New("countdown schedule is no longer pending")
+	}
+	schedule.Status = models.CountdownScheduleCancelled
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Cancelled countdown schedule ID: %d %q for room_id %d", schedule.ID, schedule.Label, schedule.RoomID)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "countdown_schedule_cancel", details)
+
+	return schedule, nil
+}
+
+// GetEvents lists countdown events visible to the user
+func (s *CountdownService) GetEvents(filter repository.CountdownEventFilter, userID uint, role string) ([]models.CountdownEvent, int64, error) {
+	hospitalIDs, err := s.visibleHospitalIDs(userID, role)
+	if err != nil {
+		return nil, 0, err
+	}
+	filter.HospitalIDs = hospitalIDs
+
+	events, total, err := s.countdownRepo.GetEvents(filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch countdown events: %w", err)
+	}
+	return events, total, nil
+}
+
+// StartDueSchedules starts the countdowns whose scheduled time has come
+// Called periodically by the background worker. A schedule waits while the room's countdown is busy
+// and is marked missed once its whole duration has passed
+func (s *CountdownService) StartDueSchedules(now time.Time) {
+	schedules, err := s.countdownRepo.GetDueSchedules(now)
+	if err != nil {
+		log.Printf("Error fetching due countdown schedules: %v", err)
+		return
+	}
+
+	for i := range schedules {
+		schedule := &schedules[i]
+		duration := time.Duration(schedule.DurationMinutes) * time.Minute
+
+		if scheduleMissed(schedule, now) {
+			missed, err := s.countdownRepo.UpdatePendingScheduleStatus(schedule.ID, models.CountdownScheduleMissed)
+			if err != nil {
+				log.Printf("Error marking countdown schedule %d missed: %v", schedule.ID, err)
+				continue
+			}
+			if !missed {
+				// Cancelled since it was fetched
+				continue
+			}
+			log.Printf("[room_id=%d] Scheduled countdown %q missed", schedule.RoomID, schedule.Label)
+			s.RecordEvent(schedule.RoomID, &schedule.ID, models.CountdownEventMissed, schedule.Label, int(duration/time.Second), nil, now)
+			s.notify(schedule.RoomID, models.NotificationCountdownMissed, schedule.Label,
+				fmt.Sprintf("could not start at %s because the room's countdown was busy", schedule.ScheduledAt.Format(time.RFC3339)),
+				map[string]interface{}{
+					"schedule_id":      schedule.ID,
+					"scheduled_at":     schedule.ScheduledAt,
+					"duration_minutes": schedule.DurationMinutes,
+				}, now)
+			s.queueNextOccurrence(schedule, now)
+			continue
+		}
+
+		// Claiming the schedule and starting the countdown is one transaction, so a schedule
+		// cancelled since it was fetched never starts
+		started, err := s.countdownRepo.StartPendingSchedule(schedule, now, map[string]interface{}{
+			"cd_target_time":      now.Add(duration),
+			"cd_duration_seconds": int(duration / time.Second),
+			"cd_is_running":       true,
+			"cd_label":            schedule.Label,
+			"cd_schedule_id":      schedule.ID,
+		})
+		if err != nil {
+			log.Printf("Error starting scheduled countdown %d: %v", schedule.ID, err)
+			continue
+		}
+		if !started {
+			// Cancelled meanwhile, or the countdown is busy and it is retried on the next run
+			continue
+		}
+		log.Printf("[room_id=%d] Scheduled countdown %q started for %d minutes", schedule.RoomID, schedule.Label, schedule.DurationMinutes)
+		s.RecordEvent(schedule.RoomID, &schedule.ID, models.CountdownEventStarted, schedule.Label, int(duration/time.Second), nil, now)
+		s.queueNextOccurrence(schedule, now)
+
+		if s.streamService != nil {
+			if state, err := s.theaterRepo.GetLiveStateByRoomID(schedule.RoomID); err == nil {
+				s.streamService.PublishLiveState(state)
+			}
+		}
+	}
+}
+
+// ExpireCountdowns stops the countdowns that reached their target time
+// Called periodically by the background worker, so expiry doesn't depend on telemetry arriving.
+// Each expiry is logged as an event, pushed to streaming clients and sent to notification channels
+func (s *CountdownService) ExpireCountdowns(now time.Time) {
+	states, err := s.theaterRepo.GetExpiredCountdowns(now)
+	if err != nil {
+		log.Printf("Error fetching expired countdowns: %v", err)
+		return
+	}
+
+	for i := range states {
+		state := &states[i]
+		targetTime := *state.CdTargetTime
+
+		expired, err := s.theaterRepo.ExpireCountdown(state.ID, targetTime)
+		if err != nil {
+			log.Printf("Error expiring countdown of live state %d: %v", state.ID, err)
+			continue
+		}
+		if !expired {
+			// Stopped or adjusted by staff in the meantime
+			continue
+		}
+
+		log.Printf("[%s] Countdown timer expired", state.RoomName)
+		s.RecordCountdownEnd(state, nil, now)
+		state.CdIsRunning = false
+		if state.RoomID == nil {
+			continue
+		}
+
+		if s.streamService != nil {
+			s.streamService.PublishLiveState(state)
+			s.streamService.Publish(countdownExpiredEvent(state, targetTime))
+		}
+		s.notify(*state.RoomID, models.NotificationCountdownExpired, state.CdLabel,
+			fmt.Sprintf("expired at %s", targetTime.Format(time.RFC3339)),
+			map[string]interface{}{
+				"schedule_id":      state.CdScheduleID,
+				"target_time":      targetTime,
+				"duration_seconds": state.CdDurationSeconds,
+			}, targetTime)
+	}
+}
+
+// countdownExpiredEvent describes an expired countdown for streaming clients
+// It is stamped with the target time, so a late worker run doesn't shift when the countdown ended
+func countdownExpiredEvent(state *models.TheaterLiveState, targetTime time.Time) StreamEvent {
+	return StreamEvent{
+		Type:   "countdown_expired",
+		RoomID: state.RoomID,
+		Data: map[string]interface{}{
+			"room_id":          *state.RoomID,
+			"room_name":        state.RoomName,
+			"label":            state.CdLabel,
+			"schedule_id":      state.CdScheduleID,
+			"target_time":      targetTime,
+			"duration_seconds": state.CdDurationSeconds,
+		},
+		At: targetTime,
+	}
+}
+
+// notify sends a countdown notification for a room to its hospital's channels
+func (s *CountdownService) notify(roomID uint, event, label, what string, data map[string]interface{}, at time.Time) {
+	if s.notificationService == nil {
+		return
+	}
+	room, err := s.roomRepo.GetRoomByID(roomID)
+	if err != nil {
+		log.Printf("Error loading room_id=%d for %s notification: %v", roomID, event, err)
+		return
+	}
+
+	name := "Countdown"
+	if label != "" {
+		name = fmt.Sprintf("Countdown %q", label)
+	}
+	data["label"] = label
+	s.notificationService.Notify(Notification{
+		Event:      event,
+		RoomID:     room.ID,
+		RoomCode:   room.RoomCode,
+		RoomName:   room.RoomName,
+		HospitalID: room.HospitalID,
+		Subject:    fmt.Sprintf("[%s] %s %s", room.RoomCode, name, strings.Replace(event, "countdown_", "", 1)),
+		Message:    fmt.Sprintf("%s in room %s (%s) %s.", name, room.RoomName, room.RoomCode, what),
+		Data:       data,
+		At:         at,
+	})
+}
+
+// scheduleMissed reports whether a due schedule can no longer start because its whole duration has passed
+func scheduleMissed(schedule *models.CountdownSchedule, now time.Time) bool {
+	duration := time.Duration(schedule.DurationMinutes) * time.Minute
+	return !now.Before(schedule.ScheduledAt.Add(duration))
+}
+
+// nextOccurrence returns when a recurring schedule next runs after now
+// Occurrences that already passed are skipped; the boolean is false for one-off schedules
+func nextOccurrence(schedule *models.CountdownSchedule, now time.Time) (time.Time, bool) {
+	days := 0
+	switch schedule.Recurrence {
+	case models.CountdownRecurrenceDaily:
+		days = 1
+	case models.CountdownRecurrenceWeekly:
+		days = 7
+	default:
+		return time.Time{}, false
+	}
+
+	next := schedule.ScheduledAt
+	for !next.After(now) {
+		next = next.AddDate(0, 0, days)
+	}
+	return next, true
+}
+
+// queueNextOccurrence creates the next pending occurrence of a recurring schedule after now
+func (s *CountdownService) queueNextOccurrence(schedule *models.CountdownSchedule, now time.Time) {
+	next, ok := nextOccurrence(schedule, now)
+	if !ok {
+		return
+	}
+	occurrence := &models.CountdownSchedule{
+		RoomID:          schedule.RoomID,
+		PresetID:        schedule.PresetID,
+		Label:           schedule.Label,
+		DurationMinutes: schedule.DurationMinutes,
+		ScheduledAt:     next,
+		Recurrence:      schedule.Recurrence,
+		Status:          models.CountdownSchedulePending,
+		CreatedBy:       schedule.CreatedBy,
+	}
+	if err := s.countdownRepo.CreateSchedule(occurrence); err != nil {
+		log.Printf("Error queueing next occurrence of countdown schedule %d: %v", schedule.ID, err)
+	}
+}
+
+// RecordEvent stores a countdown event, logging failures, and queues it for webhook subscribers
+func (s *CountdownService) RecordEvent(roomID uint, scheduleID *uint, event, label string, durationSeconds int, userID *uint, at time.Time) {
+	record := &models.CountdownEvent{
+		RoomID:          roomID,
+		ScheduleID:      scheduleID,
+		Event:           event,
+		Label:           label,
+		DurationSeconds: durationSeconds,
+		OccurredAt:      at,
+		UserID:          userID,
+	}
+	if err := s.countdownRepo.CreateEvent(record); err != nil {
+		log.Printf("Error recording countdown %s event for room_id=%d: %v", event, roomID, err)
+	}
+	if s.webhookService != nil {
+		s.webhookService.Publish(models.WebhookEventCountdownTimer, &roomID, record, at)
+	}
+}
+
+// RecordCountdownEnd records how a running countdown ended when it is stopped, reset or found expired
+// A countdown past its target time expired at that time; otherwise staff completed it early
+func (s *CountdownService) RecordCountdownEnd(state *models.TheaterLiveState, userID *uint, now time.Time) {
+	event, at, ok := countdownEnd(state, now)
+	if !ok {
+		return
+	}
+	if event == models.CountdownEventExpired {
+		// Nobody stopped it, the countdown simply ran out
+		userID = nil
+	}
+	s.RecordEvent(*state.RoomID, state.CdScheduleID, event, state.CdLabel, state.CdDurationSeconds, userID, at)
+}
+
+// countdownEnd returns the event that ends a countdown and when it happened
+// The boolean is false for countdowns that are not running or belong to a legacy room without a room_id
+func countdownEnd(state *models.TheaterLiveState, now time.Time) (string, time.Time, bool) {
+	if state.RoomID == nil || !state.CdIsRunning {
+		return "", time.Time{}, false
+	}
+	if state.CdTargetTime != nil && !now.Before(*state.CdTargetTime) {
+		return models.CountdownEventExpired, *state.CdTargetTime, true
+	}
+	return models.CountdownEventCompleted, now, true
+}
+
+// visibleHospitalIDs returns the hospitals a non-admin user may see (nil = no restriction)
+func (s *CountdownService) visibleHospitalIDs(userID uint, role string) ([]uint, error) {
+	if role == models.UserRoleSuperAdmin {
+		return nil, nil
+	}
+	hospitalIDs, err := s.userHospitalRepo.GetUserHospitals(userID)
+	if err != nil {
+		return nil, err
+	}
+	if hospitalIDs == nil {
+		hospitalIDs = []uint{}
+	}
+	return hospitalIDs, nil
+}
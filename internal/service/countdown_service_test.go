@@ -0,0 +1,139 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+)
+
+func TestScheduleMissed(t *testing.T) {
+	scheduledAt := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	schedule := &models.CountdownSchedule{ScheduledAt: scheduledAt, DurationMinutes: 30}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{name: "due now", now: scheduledAt},
+		{name: "busy room still has time left", now: scheduledAt.Add(29 * time.Minute)},
+		{name: "whole duration has passed", now: scheduledAt.Add(30 * time.Minute), want: true},
+		{name: "long after", now: scheduledAt.Add(24 * time.Hour), want: true},
+	}
+
+	for _, tt := range tests {
+		if got := scheduleMissed(schedule, tt.now); got != tt.want {
+			t.Errorf("%s: missed = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	scheduledAt := time.Date(2026, 1, 1, 8, 0, 0, 0, jakarta)
+
+	tests := []struct {
+		name       string
+		recurrence string
+		now        time.Time
+		want       time.Time // Zero when no occurrence is queued
+	}{
+		{name: "one-off schedule", recurrence: models.CountdownRecurrenceNone, now: scheduledAt},
+		{name: "daily after starting on time", recurrence: models.CountdownRecurrenceDaily, now: scheduledAt, want: scheduledAt.AddDate(0, 0, 1)},
+		{name: "weekly after starting on time", recurrence: models.CountdownRecurrenceWeekly, now: scheduledAt, want: scheduledAt.AddDate(0, 0, 7)},
+		{name: "daily started late the same day", recurrence: models.CountdownRecurrenceDaily, now: scheduledAt.Add(20 * time.Minute), want: scheduledAt.AddDate(0, 0, 1)},
+		{name: "daily after downtime skips passed days", recurrence: models.CountdownRecurrenceDaily, now: scheduledAt.AddDate(0, 0, 2).Add(time.Hour), want: scheduledAt.AddDate(0, 0, 3)},
+		{name: "daily exactly at a later occurrence moves past it", recurrence: models.CountdownRecurrenceDaily, now: scheduledAt.AddDate(0, 0, 2), want: scheduledAt.AddDate(0, 0, 3)},
+		{name: "weekly after downtime keeps the weekday", recurrence: models.CountdownRecurrenceWeekly, now: scheduledAt.AddDate(0, 0, 10), want: scheduledAt.AddDate(0, 0, 14)},
+	}
+
+	for _, tt := range tests {
+		schedule := &models.CountdownSchedule{ScheduledAt: scheduledAt, Recurrence: tt.recurrence}
+		got, ok := nextOccurrence(schedule, tt.now)
+		if ok != !tt.want.IsZero() {
+			t.Errorf("%s: queued = %v, want %v", tt.name, ok, !tt.want.IsZero())
+			continue
+		}
+		if ok && !got.Equal(tt.want) {
+			t.Errorf("%s: next = %v, want %v", tt.name, got, tt.want)
+		}
+		if ok && (got.Hour() != 8 || got.Minute() != 0) {
+			t.Errorf("%s: next = %v, want the schedule's local time of day", tt.name, got)
+		}
+	}
+}
+
+func TestCountdownEnd(t *testing.T) {
+	roomID := uint(3)
+	target := time.Date(2026, 1, 1, 8, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		state     models.TheaterLiveState
+		now       time.Time
+		wantEvent string // Empty when no event is recorded
+		wantAt    time.Time
+	}{
+		{
+			name:      "expired when found by a late worker run",
+			state:     models.TheaterLiveState{RoomID: &roomID, CdIsRunning: true, CdTargetTime: &target},
+			now:       target.Add(7 * time.Second),
+			wantEvent: models.CountdownEventExpired,
+			wantAt:    target,
+		},
+		{
+			name:      "expired exactly at the target time",
+			state:     models.TheaterLiveState{RoomID: &roomID, CdIsRunning: true, CdTargetTime: &target},
+			now:       target,
+			wantEvent: models.CountdownEventExpired,
+			wantAt:    target,
+		},
+		{
+			name:      "stopped early by staff",
+			state:     models.TheaterLiveState{RoomID: &roomID, CdIsRunning: true, CdTargetTime: &target},
+			now:       target.Add(-time.Minute),
+			wantEvent: models.CountdownEventCompleted,
+			wantAt:    target.Add(-time.Minute),
+		},
+		{
+			name:  "not running",
+			state: models.TheaterLiveState{RoomID: &roomID, CdTargetTime: &target},
+			now:   target.Add(time.Minute),
+		},
+		{
+			name:  "legacy room without a room_id",
+			state: models.TheaterLiveState{CdIsRunning: true, CdTargetTime: &target},
+			now:   target.Add(time.Minute),
+		},
+	}
+
+	for _, tt := range tests {
+		event, at, ok := countdownEnd(&tt.state, tt.now)
+		if ok != (tt.wantEvent != "") || event != tt.wantEvent {
+			t.Errorf("%s: got event %q (%v), want %q", tt.name, event, ok, tt.wantEvent)
+			continue
+		}
+		if ok && !at.Equal(tt.wantAt) {
+			t.Errorf("%s: at %v, want %v", tt.name, at, tt.wantAt)
+		}
+	}
+}
+
+func TestCountdownExpiredEventUsesTargetTime(t *testing.T) {
+	roomID, scheduleID := uint(3), uint(9)
+	target := time.Date(2026, 1, 1, 8, 30, 0, 0, time.UTC)
+	state := &models.TheaterLiveState{RoomID: &roomID, RoomName: "OT-03", CdLabel: "Cleaning", CdScheduleID: &scheduleID, CdDurationSeconds: 1800}
+
+	event := countdownExpiredEvent(state, target)
+	if event.Type != "countdown_expired" || event.RoomID == nil || *event.RoomID != roomID {
+		t.Fatalf("got %s event for room %v, want countdown_expired for room %d", event.Type, event.RoomID, roomID)
+	}
+	if !event.At.Equal(target) {
+		t.Errorf("event at %v, want the target time %v", event.At, target)
+	}
+	data := event.Data.(map[string]interface{})
+	if data["label"] != "Cleaning" || data["schedule_id"] != &scheduleID || data["duration_seconds"] != 1800 {
+		t.Errorf("event data %v does not describe the countdown", data)
+	}
+}
@@ -1,17 +1,29 @@
 package service
 
 import (
-	"crypto/rand"
-	"encoding/base64"
+	"context"
 	"errors"
 	"fmt"
+	"log"
+	"time"
 
+	"iot-backend-room-monitoring/internal/metrics"
 	"iot-backend-room-monitoring/internal/models"
 	"iot-backend-room-monitoring/internal/repository"
+	"iot-backend-room-monitoring/pkg/utils"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	// MaxAPIKeyRotationOverlap is the longest an old key may keep working after rotation
+	MaxAPIKeyRotationOverlap = 30 * 24 * time.Hour
+	// apiKeyUsageWriteInterval throttles last_used_at updates per key
+	apiKeyUsageWriteInterval = time.Minute
+	// apiKeyExpiryCheckInterval is how often expired keys are deactivated
+	apiKeyExpiryCheckInterval = time.Minute
+)
+
 type DeviceAPIKeyService struct {
 	apiKeyRepo *repository.DeviceAPIKeyRepository
 	roomRepo   *repository.RoomRepository
@@ -32,35 +44,21 @@ func NewDeviceAPIKeyService(
 
 // GenerateAPIKey generates a new API key for a room
 // Returns the plain-text key (only shown once) and stores the hashed version
-func (s *DeviceAPIKeyService) GenerateAPIKey(roomID uint, description string, userID *uint) (*models.DeviceAPIKeyResponse, error) {
+// expiresAt is optional; a nil value creates a key that never expires
+func (s *DeviceAPIKeyService) GenerateAPIKey(roomID uint, description string, expiresAt *time.Time, userID *uint) (*models.DeviceAPIKeyResponse, error) {
 	// Verify room exists
 	_, err := s.roomRepo.GetRoomByID(roomID)
 	if err != nil {
 		return nil, fmt.Errorf("room not found: %w", err)
 	}
 
-	// Generate a random 32-byte key
-	keyBytes := make([]byte, 32)
-	_, err = rand.Read(keyBytes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate random key: %w", err)
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, errors.New("expires_at must be in the future")
 	}
 
-	// Encode to base64 for easier transmission
-	plainKey := base64.URLEncoding.EncodeToString(keyBytes)
-
-	// Hash the key for storage
-	hashedKey, err := bcrypt.GenerateFromPassword([]byte(plainKey), bcrypt.DefaultCost)
+	apiKey, plainKey, err := newDeviceAPIKey(roomID, description, expiresAt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to hash API key: %w", err)
-	}
-
-	// Create the API key record
-	apiKey := &models.DeviceAPIKey{
-		RoomID:      roomID,
-		APIKeyHash:  string(hashedKey),
-		IsActive:    true,
-		Description: description,
+		return nil, err
 	}
 
 	if err := s.apiKeyRepo.CreateAPIKey(apiKey); err != nil {
@@ -69,57 +67,222 @@ func (s *DeviceAPIKeyService) GenerateAPIKey(roomID uint, description string, us
 
 	// Audit log
 	if userID != nil {
-		details := fmt.Sprintf("Generated API key for room_id: %d, description: %s", roomID, description)
+		details := fmt.Sprintf("Generated API key ID: %d for room_id: %d, description: %s", apiKey.ID, roomID, description)
 		_ = s.auditRepo.CreateAuditLog(userID, "api_key_generate", details)
 	}
 
 	// Return the plain key (only time it will be shown)
-	return &models.DeviceAPIKeyResponse{
-		ID:          apiKey.ID,
-		RoomID:      apiKey.RoomID,
-		APIKey:      plainKey, // Plain text key
-		CreatedAt:   apiKey.CreatedAt,
-		ExpiresAt:   apiKey.ExpiresAt,
-		IsActive:    apiKey.IsActive,
-		Description: apiKey.Description,
-	}, nil
+	return apiKeyResponse(apiKey, plainKey), nil
 }
 
-// ValidateAPIKey validates a plain-text API key for a specific room
-func (s *DeviceAPIKeyService) ValidateAPIKey(plainKey string, roomID uint) (bool, error) {
-	if plainKey == "" {
-		return false, errors.New("API key is required")
+// RotateAPIKey issues a successor for a key and lets the old key keep working for the overlap window
+// This gives field engineers time to flash the new key before the old one is deactivated
+func (s *DeviceAPIKeyService) RotateAPIKey(roomID uint, keyID uint, overlap time.Duration, description string, userID uint) (*models.DeviceAPIKeyResponse, error) {
+	if overlap < 0 || overlap > MaxAPIKeyRotationOverlap {
+		return nil, fmt.Errorf("overlap must be between 0 and %v", MaxAPIKeyRotationOverlap)
 	}
 
-	// Get all active API keys for the room
-	keys, err := s.apiKeyRepo.GetAPIKeysByRoomID(roomID)
+	old, err := s.getRoomAPIKey(roomID, keyID)
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	if !apiKeyUsable(old) {
+		return nil, errors.New("API key is already inactive or expired")
+	}
+
+	if description == "" {
+		description = old.Description
+	}
+	successor, plainKey, err := newDeviceAPIKey(roomID, description, nil)
+	if err != nil {
+		return nil, err
+	}
+	successor.ReplacesKeyID = &old.ID
+
+	if err := s.apiKeyRepo.CreateAPIKey(successor); err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	// Shorten the old key's lifetime to the overlap window, never extend it
+	oldExpiresAt := time.Now().Add(overlap)
+	if old.ExpiresAt == nil || oldExpiresAt.Before(*old.ExpiresAt) {
+		if err := s.apiKeyRepo.UpdateAPIKeyExpiry(old.ID, oldExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to update API key expiry: %w", err)
+		}
+	}
+
+	details := fmt.Sprintf("Rotated API key ID: %d to new key ID: %d for room_id: %d, old key expires at %s",
+		old.ID, successor.ID, roomID, oldExpiresAt.Format(time.RFC3339))
+	_ = s.auditRepo.CreateAuditLog(&userID, "api_key_rotate", details)
+
+	return apiKeyResponse(successor, plainKey), nil
+}
+
+// RecordAPIKeyUse stores the last use time and source address of an authenticated key
+// Writes are throttled so devices reporting every second don't cause a write per reading
+func (s *DeviceAPIKeyService) RecordAPIKeyUse(key *models.DeviceAPIKey, ip string) {
+	now := time.Now()
+	if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < apiKeyUsageWriteInterval && (ip == "" || ip == key.LastUsedIP) {
+		return
+	}
+	if err := s.apiKeyRepo.UpdateAPIKeyUsage(key.ID, now, ip); err != nil {
+		log.Printf("Error recording use of API key %d: %v", key.ID, err)
+	}
+}
+
+// StartExpiryJob periodically deactivates expired keys until the context is cancelled
+func (s *DeviceAPIKeyService) StartExpiryJob(ctx context.Context) {
+	ticker := time.NewTicker(apiKeyExpiryCheckInterval)
+	defer ticker.Stop()
+
+	s.DeactivateExpiredKeys(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.DeactivateExpiredKeys(now)
+		}
+	}
+}
+
+// DeactivateExpiredKeys deactivates active keys whose expiry has passed and audits each one
+func (s *DeviceAPIKeyService) DeactivateExpiredKeys(now time.Time) {
+	keys, err := s.apiKeyRepo.GetExpiredActiveAPIKeys(now)
+	if err != nil {
+		log.Printf("Error fetching expired API keys: %v", err)
+		return
 	}
 
-	// Try to match the plain key against stored hashes
 	for _, key := range keys {
-		if !key.IsActive {
+		if err := s.apiKeyRepo.RevokeAPIKey(key.ID); err != nil {
+			log.Printf("Error deactivating expired API key %d: %v", key.ID, err)
 			continue
 		}
+		details := fmt.Sprintf("Deactivated expired API key ID: %d for room_id: %d, expired at %s",
+			key.ID, key.RoomID, key.ExpiresAt.Format(time.RFC3339))
+		_ = s.auditRepo.CreateAuditLog(nil, "api_key_expire", details)
+		log.Printf("Deactivated expired API key %d for room %d", key.ID, key.RoomID)
+	}
+}
 
-		// Check expiration
-		if key.ExpiresAt != nil && key.ExpiresAt.Before(key.CreatedAt) {
+// ValidateAPIKey validates a plain-text API key for a specific room
+func (s *DeviceAPIKeyService) ValidateAPIKey(plainKey string, roomID uint) (bool, error) {
+	if _, err := s.AuthenticateAPIKey(plainKey, roomID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// AuthenticateAPIKey returns the room's API key matching a plain-text key
+// Keys with a key ID prefix are found by index and checked with one HMAC;
+// legacy keys fall back to comparing against the room's bcrypt hashes
+func (s *DeviceAPIKeyService) AuthenticateAPIKey(plainKey string, roomID uint) (*models.DeviceAPIKey, error) {
+	key, err := s.authenticateAPIKey(plainKey, roomID)
+	if err != nil {
+		reason := metrics.AuthError
+		switch err.Error() {
+		case "API key is required":
+			reason = metrics.AuthMissingKey
+		case "invalid API key":
+			reason = metrics.AuthInvalidKey
+		}
+		metrics.APIKeyAuthFailures.WithLabelValues(reason).Inc()
+	}
+	return key, err
+}
+
+func (s *DeviceAPIKeyService) authenticateAPIKey(plainKey string, roomID uint) (*models.DeviceAPIKey, error) {
+	if plainKey == "" {
+		return nil, errors.New("API key is required")
+	}
+
+	if keyID, ok := utils.ParseAPIKeyID(plainKey); ok {
+		key, err := s.apiKeyRepo.GetAPIKeyByKeyID(keyID)
+		if err != nil {
+			if err.Error() == "API key not found" {
+				return nil, errors.New("invalid API key")
+			}
+			return nil, err
+		}
+		if key.RoomID != roomID || !apiKeyUsable(key) || !utils.CompareAPIKey(key.APIKeyHash, plainKey) {
+			return nil, errors.New("invalid API key")
+		}
+		return key, nil
+	}
+
+	// Legacy keys: try to match the plain key against the room's stored bcrypt hashes
+	keys, err := s.apiKeyRepo.GetLegacyAPIKeysByRoomID(roomID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		if !apiKeyUsable(&keys[i]) {
 			continue
 		}
 
 		// Compare the plain key with the hashed key
-		err := bcrypt.CompareHashAndPassword([]byte(key.APIKeyHash), []byte(plainKey))
+		err := bcrypt.CompareHashAndPassword([]byte(keys[i].APIKeyHash), []byte(plainKey))
 		if err == nil {
 			// Key matches!
-			return true, nil
+			return &keys[i], nil
 		}
 	}
 
-	return false, errors.New("invalid API key")
+	return nil, errors.New("invalid API key")
+}
+
+// apiKeyUsable reports whether a key is active and not expired
+// Expiry is checked here as well as by the expiry job, which only runs periodically
+func apiKeyUsable(key *models.DeviceAPIKey) bool {
+	if !key.IsActive {
+		return false
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()) {
+		return false
+	}
+	return true
+}
+
+// apiKeyResponse converts a stored key into its API representation
+// plainKey is only set right after generation
+func apiKeyResponse(key *models.DeviceAPIKey, plainKey string) *models.DeviceAPIKeyResponse {
+	return &models.DeviceAPIKeyResponse{
+		ID:            key.ID,
+		RoomID:        key.RoomID,
+		KeyID:         key.KeyID,
+		HashScheme:    key.HashScheme,
+		APIKey:        plainKey,
+		CreatedAt:     key.CreatedAt,
+		ExpiresAt:     key.ExpiresAt,
+		IsActive:      key.IsActive,
+		Description:   key.Description,
+		ReplacesKeyID: key.ReplacesKeyID,
+		LastUsedAt:    key.LastUsedAt,
+		LastUsedIP:    key.LastUsedIP,
+	}
 }
 
-// GetAPIKeysByRoomID retrieves all API keys for a room (admin only)
+// newDeviceAPIKey builds an API key record with a freshly generated key
+// Returns the record (not yet stored) and the plain-text key
+func newDeviceAPIKey(roomID uint, description string, expiresAt *time.Time) (*models.DeviceAPIKey, string, error) {
+	plainKey, keyID, err := utils.GenerateAPIKey()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate random key: %w", err)
+	}
+
+	return &models.DeviceAPIKey{
+		RoomID:      roomID,
+		KeyID:       &keyID,
+		APIKeyHash:  utils.HashAPIKey(plainKey),
+		HashScheme:  utils.APIKeySchemeHMAC,
+		ExpiresAt:   expiresAt,
+		IsActive:    true,
+		Description: description,
+	}, plainKey, nil
+}
+
+// GetAPIKeysByRoomID retrieves all API keys for a room (hospital admins of the room's hospital)
 func (s *DeviceAPIKeyService) GetAPIKeysByRoomID(roomID uint, userID uint) ([]models.DeviceAPIKeyResponse, error) {
 	// Verify room exists
 	_, err := s.roomRepo.GetRoomByID(roomID)
@@ -134,25 +297,18 @@ func (s *DeviceAPIKeyService) GetAPIKeysByRoomID(roomID uint, userID uint) ([]mo
 
 	// Convert to response format (without plain keys)
 	responses := make([]models.DeviceAPIKeyResponse, len(keys))
-	for i, key := range keys {
-		responses[i] = models.DeviceAPIKeyResponse{
-			ID:          key.ID,
-			RoomID:      key.RoomID,
-			CreatedAt:   key.CreatedAt,
-			ExpiresAt:   key.ExpiresAt,
-			IsActive:    key.IsActive,
-			Description: key.Description,
-			// APIKey is intentionally omitted (never shown after creation)
-		}
+	for i := range keys {
+		// APIKey is intentionally omitted (never shown after creation)
+		responses[i] = *apiKeyResponse(&keys[i], "")
 	}
 
 	return responses, nil
 }
 
-// RevokeAPIKey revokes (deactivates) an API key (admin only)
-func (s *DeviceAPIKeyService) RevokeAPIKey(keyID uint, userID uint) error {
+// RevokeAPIKey revokes (deactivates) an API key of a room (hospital admins of the room's hospital)
+func (s *DeviceAPIKeyService) RevokeAPIKey(roomID uint, keyID uint, userID uint) error {
 	// Get the key to verify it exists and for audit logging
-	key, err := s.apiKeyRepo.GetAPIKeyByID(keyID)
+	key, err := s.getRoomAPIKey(roomID, keyID)
 	if err != nil {
 		return err
 	}
@@ -170,10 +326,10 @@ func (s *DeviceAPIKeyService) RevokeAPIKey(keyID uint, userID uint) error {
 	return nil
 }
 
-// DeleteAPIKey permanently deletes an API key (admin only)
-func (s *DeviceAPIKeyService) DeleteAPIKey(keyID uint, userID uint) error {
+// DeleteAPIKey permanently deletes an API key of a room (hospital admins of the room's hospital)
+func (s *DeviceAPIKeyService) DeleteAPIKey(roomID uint, keyID uint, userID uint) error {
 	// Get the key to verify it exists and for audit logging
-	key, err := s.apiKeyRepo.GetAPIKeyByID(keyID)
+	key, err := s.getRoomAPIKey(roomID, keyID)
 	if err != nil {
 		return err
 	}
@@ -190,3 +346,15 @@ func (s *DeviceAPIKeyService) DeleteAPIKey(keyID uint, userID uint) error {
 
 	return nil
 }
+
+// getRoomAPIKey retrieves an API key, treating keys of other rooms as not found
+func (s *DeviceAPIKeyService) getRoomAPIKey(roomID uint, keyID uint) (*models.DeviceAPIKey, error) {
+	key, err := s.apiKeyRepo.GetAPIKeyByID(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key.RoomID != roomID {
+		return nil, errors.New("API key not found")
+	}
+	return key, nil
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+)
+
+const (
+	// defaultReportingIntervalSeconds is used for rooms without a stored config
+	defaultReportingIntervalSeconds = 1
+	// maxReportingIntervalSeconds keeps devices well inside the staleness threshold
+	maxReportingIntervalSeconds = 3600
+	// defaultCommandTTL is how long a command waits for the device before it expires
+	defaultCommandTTL = time.Hour
+	// maxCommandTTL is the longest a command may wait for the device
+	maxCommandTTL = 7 * 24 * time.Hour
+	// deviceCommandHistoryLimit caps the commands listed for a room
+	deviceCommandHistoryLimit = 100
+)
+
+type DeviceConfigService struct {
+	configRepo          *repository.DeviceConfigRepository
+	calibrationRepo     *repository.CalibrationRepository
+	roomRepo            *repository.RoomRepository
+	userHospitalRepo    *repository.UserHospitalRepository
+	auditRepo           *repository.AuditRepository
+	sensorSchemaService *SensorSchemaService
+}
+
+func NewDeviceConfigService(
+	configRepo *repository.DeviceConfigRepository,
+	calibrationRepo *repository.CalibrationRepository,
+	roomRepo *repository.RoomRepository,
+	userHospitalRepo *repository.UserHospitalRepository,
+	auditRepo *repository.AuditRepository,
+	sensorSchemaService *SensorSchemaService,
+) *DeviceConfigService {
+	return &DeviceConfigService{
+		configRepo:          configRepo,
+		calibrationRepo:     calibrationRepo,
+		roomRepo:            roomRepo,
+		userHospitalRepo:    userHospitalRepo,
+		auditRepo:           auditRepo,
+		sensorSchemaService: sensorSchemaService,
+	}
+}
+
+// UpdateDeviceConfigRequest is the new device config of a room
+// Omitted fields keep their current value
+type UpdateDeviceConfigRequest struct {
+	ReportingIntervalSeconds *int                 `json:"reporting_interval_seconds"`
+	CalibrationOffsets       *models.SensorValues `json:"calibration_offsets"`
+	EnabledSensors           *[]string            `json:"enabled_sensors"`
+}
+
+// CreateDeviceCommandRequest queues a command for a room's device
+type CreateDeviceCommandRequest struct {
+	Command          string `json:"command" binding:"required"`
+	ExpiresInMinutes *int   `json:"expires_in_minutes"` // Default 60
+}
+
+// AcknowledgeDeviceCommandRequest is a device's report on a delivered command
+type AcknowledgeDeviceCommandRequest struct {
+	Status string `json:"status" binding:"required"` // "completed" or "failed"
+	Result string `json:"result" binding:"max=255"`
+}
+
+// GetDeviceConfig returns the config a room's device should apply
+// Rooms that were never configured get the defaults with version 0
+func (s *DeviceConfigService) GetDeviceConfig(roomID uint) (*models.DeviceConfig, error) {
+	config, err := s.configRepo.GetConfigByRoomID(roomID)
+	if err == nil {
+		return config, nil
+	}
+	if err.Error() != "device config not found" {
+		return nil, fmt.Errorf("failed to fetch device config: %w", err)
+	}
+	return defaultDeviceConfig(roomID, s.sensorSchemaService.SchemaForRoom(&roomID)), nil
+}
+
+// GetDeviceConfigForUser returns a room's device config with access control
+func (s *DeviceConfigService) GetDeviceConfigForUser(roomID uint, userID uint, role string) (*models.DeviceConfig, error) {
+	if _, err := checkRoomAccess(s.roomRepo, s.userHospitalRepo, roomID, userID, role); err != nil {
+		return nil, err
+	}
+	return s.GetDeviceConfig(roomID)
+}
+
+// UpdateDeviceConfig changes a room's device config and bumps its version (hospital admins of the room's hospital)
+func (s *DeviceConfigService) UpdateDeviceConfig(roomID uint, req *UpdateDeviceConfigRequest, userID uint) (*models.DeviceConfig, error) {
+	room, err := s.roomRepo.GetRoomByID(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := s.GetDeviceConfig(roomID)
+	if err != nil {
+		return nil, err
+	}
+	previousVersion := config.Version
+
+	if req.ReportingIntervalSeconds != nil {
+		config.ReportingIntervalSeconds = *req.ReportingIntervalSeconds
+	}
+	if req.CalibrationOffsets != nil {
+		config.CalibrationOffsets = *req.CalibrationOffsets
+	}
+	if req.EnabledSensors != nil {
+		config.EnabledSensors = *req.EnabledSensors
+	}
+	if err := validateDeviceConfig(config, s.sensorSchemaService.SchemaFor(room.RoomType)); err != nil {
+		return nil, err
+	}
+	if req.CalibrationOffsets != nil {
+		if err := s.checkCalibrationProfiles(roomID, config.CalibrationOffsets); err != nil {
+			return nil, err
+		}
+	}
+
+	config.Version = previousVersion + 1
+	config.UpdatedBy = &userID
+	if previousVersion == 0 {
+		err = s.configRepo.CreateConfig(config)
+	} else {
+		err = s.configRepo.UpdateConfig(config, previousVersion)
+	}
+	if err != nil {
+		if err.Error() == "device config was changed by another update" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update device config: %w", err)
+	}
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Updated device config for room_id: %d to version %d", roomID, config.Version)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "device_config_update", details)
+
+	return config, nil
+}
+
+// checkCalibrationProfiles refuses device offsets for fields a server calibration profile of the room already corrects
+// A field is calibrated in one place only, otherwise the reading would be corrected twice
+func (s *DeviceConfigService) checkCalibrationProfiles(roomID uint, offsets models.SensorValues) error {
+	profiles, err := s.calibrationRepo.GetProfilesByRoomID(roomID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch calibration profiles: %w", err)
+	}
+	for _, profile := range profiles {
+		if field, ok := doubleCorrectedField(profile.Fields, offsets); ok {
+			return fmt.Errorf("%s is already corrected by calibration profile %d; remove it from the profile first", field, profile.ID)
+		}
+	}
+	return nil
+}
+
+// CreateCommand queues a command for a room's device (hospital admins of the room's hospital)
+func (s *DeviceConfigService) CreateCommand(roomID uint, req *CreateDeviceCommandRequest, userID uint) (*models.DeviceCommand, error) {
+	if _, err := s.roomRepo.GetRoomByID(roomID); err != nil {
+		return nil, err
+	}
+
+	command := strings.ToLower(strings.TrimSpace(req.Command))
+	switch command {
+	case models.DeviceCommandReboot, models.DeviceCommandRecalibrate, models.DeviceCommandIdentify:
+	default:
+		return nil, errors.New("invalid command: must be 'reboot', 'recalibrate', or 'identify'")
+	}
+
+	ttl := defaultCommandTTL
+	if req.ExpiresInMinutes != nil {
+		ttl = time.Duration(*req.ExpiresInMinutes) * time.Minute
+		if ttl <= 0 || ttl > maxCommandTTL {
+			return nil, fmt.Errorf("expires_in_minutes must be between 1 and %d", int(maxCommandTTL/time.Minute))
+		}
+	}
+
+	cmd := &models.DeviceCommand{
+		RoomID:    roomID,
+		Command:   command,
+		State:     models.DeviceCommandPending,
+		CreatedBy: &userID,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.configRepo.CreateCommand(cmd); err != nil {
+		return nil, fmt.Errorf("failed to queue command: %w", err)
+	}
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Queued device command %s (ID: %d) for room_id: %d", command, cmd.ID, roomID)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "device_command_create", details)
+
+	return cmd, nil
+}
+
+// GetCommands lists the recent commands of a room with access control
+func (s *DeviceConfigService) GetCommands(roomID uint, userID uint, role string) ([]models.DeviceCommand, error) {
+	if _, err := checkRoomAccess(s.roomRepo, s.userHospitalRepo, roomID, userID, role); err != nil {
+		return nil, err
+	}
+	commands, err := s.configRepo.GetCommandsByRoomID(roomID, deviceCommandHistoryLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch commands: %w", err)
+	}
+	return commands, nil
+}
+
+// FetchCommands returns the commands a room's device has not acknowledged yet
+// A device that crashed before acknowledging gets its delivered commands again, up to their delivery limit,
+// so it must skip IDs it already ran
+func (s *DeviceConfigService) FetchCommands(roomID uint) ([]models.DeviceCommand, error) {
+	commands, err := s.configRepo.DeliverCommands(roomID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch commands: %w", err)
+	}
+	return commands, nil
+}
+
+// AcknowledgeCommand records a device's result for a delivered command
+func (s *DeviceConfigService) AcknowledgeCommand(roomID uint, commandID uint, req *AcknowledgeDeviceCommandRequest) (*models.DeviceCommand, error) {
+	if req.Status != models.DeviceCommandCompleted && req.Status != models.DeviceCommandFailed {
+		return nil, errors.New("invalid status: must be 'completed' or 'failed'")
+	}
+
+	command, err := s.configRepo.GetCommandByID(roomID, commandID)
+	if err != nil {
+		return nil, err
+	}
+	switch command.State {
+	case models.DeviceCommandPending, models.DeviceCommandDelivered:
+	case models.DeviceCommandExpired:
+		return nil, errors.New("command has expired")
+	default:
+		return nil, errors.New("command is already acknowledged")
+	}
+
+	now := time.Now()
+	if command.DeliveredAt == nil {
+		command.DeliveredAt = &now
+	}
+	command.State = req.Status
+	command.Result = req.Result
+	command.AcknowledgedAt = &now
+	if err := s.configRepo.UpdateCommand(command); err != nil {
+		return nil, fmt.Errorf("failed to acknowledge command: %w", err)
+	}
+
+	return command, nil
+}
+
+// defaultDeviceConfig is the config of a room that was never configured
+// Every sensor of the room's schema is enabled
+func defaultDeviceConfig(roomID uint, schema []models.SensorDefinition) *models.DeviceConfig {
+	return &models.DeviceConfig{
+		RoomID:                   roomID,
+		Version:                  0,
+		ReportingIntervalSeconds: defaultReportingIntervalSeconds,
+		CalibrationOffsets:       models.SensorValues{},
+		EnabledSensors:           models.SensorNameList(roomSensorNames(schema)),
+	}
+}
+
+// validateDeviceConfig checks the interval and that offsets and enabled sensors name sensors of the room's schema
+func validateDeviceConfig(config *models.DeviceConfig, schema []models.SensorDefinition) error {
+	if config.ReportingIntervalSeconds < 1 || config.ReportingIntervalSeconds > maxReportingIntervalSeconds {
+		return fmt.Errorf("reporting_interval_seconds must be between 1 and %d", maxReportingIntervalSeconds)
+	}
+	for sensor := range config.CalibrationOffsets {
+		if !isRoomSensor(schema, sensor) {
+			return fmt.Errorf("unknown sensor in calibration_offsets: %s", sensor)
+		}
+	}
+	seen := make(map[string]bool, len(config.EnabledSensors))
+	enabled := make(models.SensorNameList, 0, len(config.EnabledSensors))
+	for _, sensor := range config.EnabledSensors {
+		if !isRoomSensor(schema, sensor) {
+			return fmt.Errorf("unknown sensor in enabled_sensors: %s", sensor)
+		}
+		if !seen[sensor] {
+			seen[sensor] = true
+			enabled = append(enabled, sensor)
+		}
+	}
+	config.EnabledSensors = enabled
+	return nil
+}
@@ -0,0 +1,379 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"iot-backend-room-monitoring/internal/config"
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+)
+
+// deviceHeartbeatWriteInterval throttles last_seen_at writes for devices reporting every second
+// Must stay well below the configured offline timeout
+const deviceHeartbeatWriteInterval = 10 * time.Second
+
+var macAddressPattern = regexp.MustCompile(`^([0-9A-F]{2}:){5}[0-9A-F]{2}$`)
+
+// DeviceHeartbeat is the device information reported alongside telemetry
+type DeviceHeartbeat struct {
+	MacAddress      string
+	FirmwareVersion string
+	UptimeSeconds   *int64
+	IPAddress       string
+}
+
+// DeviceHealthSummary lists devices with their online/offline counts
+type DeviceHealthSummary struct {
+	Devices []models.Device `json:"devices"`
+	Total   int             `json:"total"`
+	Online  int             `json:"online"`
+	Offline int             `json:"offline"`
+}
+
+type DeviceService struct {
+	deviceRepo       *repository.DeviceRepository
+	apiKeyRepo       *repository.DeviceAPIKeyRepository
+	roomRepo         *repository.RoomRepository
+	userHospitalRepo *repository.UserHospitalRepository
+	offlineAfter     time.Duration
+
+	mu           sync.Mutex
+	byAPIKey     map[uint]*models.Device // Last known device per authenticating key
+	lastWrite    map[uint]time.Time      // Last persisted heartbeat per device ID
+	macConflicts map[uint]string         // MAC a key reported but another room's device holds
+}
+
+func NewDeviceService(
+	deviceRepo *repository.DeviceRepository,
+	apiKeyRepo *repository.DeviceAPIKeyRepository,
+	roomRepo *repository.RoomRepository,
+	userHospitalRepo *repository.UserHospitalRepository,
+	cfg config.DeviceConfig,
+) *DeviceService {
+	return &DeviceService{
+		deviceRepo:       deviceRepo,
+		apiKeyRepo:       apiKeyRepo,
+		roomRepo:         roomRepo,
+		userHospitalRepo: userHospitalRepo,
+		offlineAfter:     cfg.OfflineAfter,
+		byAPIKey:         make(map[uint]*models.Device),
+		lastWrite:        make(map[uint]time.Time),
+		macConflicts:     make(map[uint]string),
+	}
+}
+
+// RecordHeartbeat registers or updates the device behind a telemetry call
+// Devices are matched by MAC address when reported, otherwise by the API key they use
+// (following rotations, so a device keeps its identity when its key is replaced)
+// Database lookups and writes happen outside s.mu, which only guards the in-memory cache,
+// so uploads from different rooms never wait on each other
+func (s *DeviceService) RecordHeartbeat(roomID uint, apiKeyID uint, heartbeat DeviceHeartbeat, at time.Time) {
+	mac := normalizeMacAddress(heartbeat.MacAddress)
+
+	err := s.recordHeartbeat(roomID, apiKeyID, mac, heartbeat, at)
+	if err != nil && err.Error() == "device already registered" {
+		// A concurrent first heartbeat registered the device; the retry finds and updates it
+		err = s.recordHeartbeat(roomID, apiKeyID, mac, heartbeat, at)
+	}
+	if err != nil {
+		log.Printf("Error saving device for room %d: %v", roomID, err)
+	}
+}
+
+// recordHeartbeat applies a heartbeat to the cached or stored device, registering it if unknown
+func (s *DeviceService) recordHeartbeat(roomID uint, apiKeyID uint, mac string, heartbeat DeviceHeartbeat, at time.Time) error {
+	// On a cache hit the device already holds the reported MAC, or may not claim it
+	claimMAC := ""
+	device, found := s.cachedByAPIKey(apiKeyID, mac)
+	if !found {
+		known, claimable, err := s.findDevice(roomID, apiKeyID, mac)
+		if err != nil {
+			return err
+		}
+		claimMAC = claimable
+		if known != nil {
+			device, found = *known, true
+		}
+	}
+	isNew := !found
+
+	// Identity or status changes are written immediately, plain heartbeats are throttled
+	keyChanged := isNew || device.APIKeyID == nil || *device.APIKeyID != apiKeyID
+	changed := keyChanged || device.RoomID != roomID || device.Status != models.DeviceStatusOnline
+	if device.Status == models.DeviceStatusOffline {
+		log.Printf("Device %d in room %d is back online", device.ID, roomID)
+	}
+
+	keyID := apiKeyID
+	device.RoomID = roomID
+	device.APIKeyID = &keyID
+	device.Status = models.DeviceStatusOnline
+	seenAt := at
+	device.LastSeenAt = &seenAt
+	if claimMAC != "" && (device.MacAddress == nil || *device.MacAddress != claimMAC) {
+		device.MacAddress = &claimMAC
+		changed = true
+	}
+	if heartbeat.FirmwareVersion != "" && heartbeat.FirmwareVersion != device.FirmwareVersion {
+		device.FirmwareVersion = heartbeat.FirmwareVersion
+		changed = true
+	}
+	if heartbeat.IPAddress != "" && heartbeat.IPAddress != device.IPAddress {
+		device.IPAddress = heartbeat.IPAddress
+		changed = true
+	}
+	if heartbeat.UptimeSeconds != nil {
+		uptime := *heartbeat.UptimeSeconds
+		// A drop in uptime means the device rebooted
+		if device.UptimeSeconds != nil && uptime < *device.UptimeSeconds {
+			changed = true
+		}
+		device.UptimeSeconds = &uptime
+	}
+
+	s.mu.Lock()
+	throttled := !changed && at.Sub(s.lastWrite[device.ID]) < deviceHeartbeatWriteInterval
+	if throttled {
+		s.byAPIKey[apiKeyID] = &device
+	}
+	s.mu.Unlock()
+	if throttled {
+		return nil
+	}
+
+	var err error
+	// A key authenticates one device: a replacement board or a moved device takes it over.
+	// A new device without a MAC cannot be told apart from a concurrent registration of itself,
+	// so it relies on the unique key instead
+	if keyChanged && !(isNew && device.MacAddress == nil) {
+		err = s.deviceRepo.ReleaseAPIKey(apiKeyID, &device)
+	}
+	if err == nil {
+		if isNew {
+			err = s.deviceRepo.CreateDevice(&device)
+		} else {
+			err = s.deviceRepo.UpdateDevice(&device)
+		}
+	}
+	if err != nil {
+		s.mu.Lock()
+		delete(s.byAPIKey, apiKeyID)
+		s.mu.Unlock()
+		return err
+	}
+	if isNew {
+		log.Printf("Registered device %d for room %d", device.ID, roomID)
+	}
+
+	s.mu.Lock()
+	s.lastWrite[device.ID] = at
+	s.byAPIKey[apiKeyID] = &device
+	if mac != "" && claimMAC == "" && (device.MacAddress == nil || *device.MacAddress != mac) {
+		s.macConflicts[apiKeyID] = mac
+	} else {
+		delete(s.macConflicts, apiKeyID)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// cachedByAPIKey returns a copy of the cached device behind a key, unless it reported a different MAC
+// A MAC the key was refused because another room's device holds it does not count as different
+// The copy can be changed without holding s.mu
+func (s *DeviceService) cachedByAPIKey(apiKeyID uint, mac string) (models.Device, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if mac != "" && s.macConflicts[apiKeyID] == mac {
+		mac = ""
+	}
+	device := s.byAPIKey[apiKeyID]
+	if device == nil || (mac != "" && (device.MacAddress == nil || *device.MacAddress != mac)) {
+		return models.Device{}, false
+	}
+	return *device, true
+}
+
+// DeviceIDFor returns the ID of the registered device behind a telemetry call, or nil if it is unknown
+// Only a cache miss reaches the database, and it does so without holding s.mu
+func (s *DeviceService) DeviceIDFor(roomID uint, apiKeyID uint, macAddress string) *uint {
+	mac := normalizeMacAddress(macAddress)
+
+	device, found := s.cachedByAPIKey(apiKeyID, mac)
+	if !found {
+		known, _, err := s.findDevice(roomID, apiKeyID, mac)
+		if err != nil || known == nil {
+			return nil
+		}
+		device = *known
+	}
+	id := device.ID
+	return &id
+}
+
+// findDevice looks up a known device by MAC, API key, or the key it replaced
+// A MAC only matches a device of the same room or key, so a key cannot take over another room's device;
+// the returned MAC is the one the device may claim, empty when another room's device holds it
+// Returns nil without error for a device that hasn't been seen before
+func (s *DeviceService) findDevice(roomID uint, apiKeyID uint, mac string) (*models.Device, string, error) {
+	claimMAC := mac
+	if mac != "" {
+		device, err := s.deviceRepo.GetDeviceByMAC(mac)
+		if err == nil {
+			if device.RoomID == roomID || (device.APIKeyID != nil && *device.APIKeyID == apiKeyID) {
+				return device, mac, nil
+			}
+			log.Printf("Warning: API key %d of room %d reported MAC %s of device %d in room %d; MAC ignored",
+				apiKeyID, roomID, mac, device.ID, device.RoomID)
+			claimMAC = ""
+		} else if err.Error() != "device not found" {
+			return nil, "", err
+		}
+	}
+
+	device, err := s.deviceRepo.GetDeviceByAPIKeyID(apiKeyID)
+	if err == nil {
+		// A device reporting a different MAC through the same key is a replacement board
+		if claimMAC != "" && device.MacAddress != nil && *device.MacAddress != claimMAC {
+			return nil, claimMAC, nil
+		}
+		return device, claimMAC, nil
+	}
+	if err.Error() != "device not found" {
+		return nil, "", err
+	}
+
+	key, err := s.apiKeyRepo.GetAPIKeyByID(apiKeyID)
+	if err != nil || key.ReplacesKeyID == nil {
+		return nil, claimMAC, nil
+	}
+	device, err = s.deviceRepo.GetDeviceByAPIKeyID(*key.ReplacesKeyID)
+	if err == nil {
+		return device, claimMAC, nil
+	}
+	if err.Error() != "device not found" {
+		return nil, "", err
+	}
+	return nil, claimMAC, nil
+}
+
+// MarkOfflineDevices sets devices offline that have not reported within the configured silence
+// Called periodically by the background worker; returns the devices that went offline
+func (s *DeviceService) MarkOfflineDevices(now time.Time) []models.Device {
+	cutoff := now.Add(-s.offlineAfter)
+	devices, err := s.deviceRepo.GetOnlineDevicesSeenBefore(cutoff)
+	if err != nil {
+		log.Printf("Error fetching silent devices: %v", err)
+		return nil
+	}
+	if len(devices) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A heartbeat may have arrived since it was last written; trust the in-memory time
+	silent := make([]models.Device, 0, len(devices))
+	ids := make([]uint, 0, len(devices))
+	for _, device := range devices {
+		if cached := s.cachedDevice(device.ID); cached != nil && cached.LastSeenAt != nil && !cached.LastSeenAt.Before(cutoff) {
+			continue
+		}
+		silent = append(silent, device)
+		ids = append(ids, device.ID)
+	}
+
+	if err := s.deviceRepo.MarkDevicesOffline(ids, cutoff); err != nil {
+		log.Printf("Error marking devices offline: %v", err)
+		return nil
+	}
+
+	for i := range silent {
+		silent[i].Status = models.DeviceStatusOffline
+		if cached := s.cachedDevice(silent[i].ID); cached != nil {
+			cached.Status = models.DeviceStatusOffline
+		}
+		log.Printf("Device %d in room %d is offline - last seen %v", silent[i].ID, silent[i].RoomID, silent[i].LastSeenAt)
+	}
+	return silent
+}
+
+// cachedDevice finds a device in the heartbeat cache; the caller must hold s.mu
+func (s *DeviceService) cachedDevice(id uint) *models.Device {
+	for _, device := range s.byAPIKey {
+		if device.ID == id {
+			return device
+		}
+	}
+	return nil
+}
+
+// GetDevicesByHospital lists the devices of a hospital with their health
+func (s *DeviceService) GetDevicesByHospital(hospitalID uint, userID uint, role string) (*DeviceHealthSummary, error) {
+	if role != models.UserRoleSuperAdmin {
+		hasAccess, err := s.userHospitalRepo.UserHasAccessToHospital(userID, hospitalID)
+		if err != nil {
+			return nil, err
+		}
+		if !hasAccess {
+			return nil, errors.New("access denied: you don't have permission to access this hospital")
+		}
+	}
+
+	devices, err := s.deviceRepo.GetDevicesByHospitalID(hospitalID)
+	if err != nil {
+		return nil, err
+	}
+	return summarizeDevices(devices), nil
+}
+
+// GetDevicesByRoom lists the devices of a room with their health
+func (s *DeviceService) GetDevicesByRoom(roomID uint, userID uint, role string) (*DeviceHealthSummary, error) {
+	room, err := s.roomRepo.GetRoomByID(roomID)
+	if err != nil {
+		return nil, err
+	}
+	if role != models.UserRoleSuperAdmin {
+		hasAccess, err := s.userHospitalRepo.UserHasAccessToHospital(userID, room.HospitalID)
+		if err != nil {
+			return nil, err
+		}
+		if !hasAccess {
+			return nil, errors.New("access denied: you don't have permission to access this room")
+		}
+	}
+
+	devices, err := s.deviceRepo.GetDevicesByRoomID(roomID)
+	if err != nil {
+		return nil, err
+	}
+	return summarizeDevices(devices), nil
+}
+
+// summarizeDevices counts devices by status
+func summarizeDevices(devices []models.Device) *DeviceHealthSummary {
+	summary := &DeviceHealthSummary{Devices: devices, Total: len(devices)}
+	for _, device := range devices {
+		if device.Status == models.DeviceStatusOnline {
+			summary.Online++
+		} else {
+			summary.Offline++
+		}
+	}
+	return summary
+}
+
+// normalizeMacAddress returns a MAC address as upper-case colon-separated hex, or "" if invalid
+func normalizeMacAddress(mac string) string {
+	mac = strings.ToUpper(strings.TrimSpace(strings.ReplaceAll(mac, "-", ":")))
+	if !macAddressPattern.MatchString(mac) {
+		return ""
+	}
+	return mac
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"testing"
+
+	"iot-backend-room-monitoring/internal/config"
+	"iot-backend-room-monitoring/internal/models"
+)
+
+func TestCachedByAPIKeyMatchesReportedMAC(t *testing.T) {
+	s := NewDeviceService(nil, nil, nil, nil, config.DeviceConfig{})
+	mac := "AA:BB:CC:DD:EE:01"
+	s.byAPIKey[1] = &models.Device{ID: 10, MacAddress: &mac}
+	s.byAPIKey[2] = &models.Device{ID: 20}
+	s.macConflicts[2] = "AA:BB:CC:DD:EE:02"
+
+	tests := []struct {
+		name    string
+		keyID   uint
+		mac     string
+		wantHit bool
+	}{
+		{name: "same MAC", keyID: 1, mac: mac, wantHit: true},
+		{name: "no MAC reported", keyID: 1, wantHit: true},
+		{name: "different MAC is a replacement board", keyID: 1, mac: "AA:BB:CC:DD:EE:03"},
+		{name: "MAC held by another room's device", keyID: 2, mac: "AA:BB:CC:DD:EE:02", wantHit: true},
+		{name: "first MAC from a device without one", keyID: 2, mac: "AA:BB:CC:DD:EE:04"},
+		{name: "unknown key", keyID: 3},
+	}
+
+	for _, tt := range tests {
+		device, hit := s.cachedByAPIKey(tt.keyID, tt.mac)
+		if hit != tt.wantHit {
+			t.Errorf("%s: hit = %v, want %v", tt.name, hit, tt.wantHit)
+			continue
+		}
+		if hit && device.ID != s.byAPIKey[tt.keyID].ID {
+			t.Errorf("%s: got device %d, want %d", tt.name, device.ID, s.byAPIKey[tt.keyID].ID)
+		}
+	}
+}
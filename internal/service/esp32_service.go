@@ -3,23 +3,48 @@ package service
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	"iot-backend-room-monitoring/internal/metrics"
 	"iot-backend-room-monitoring/internal/models"
 	"iot-backend-room-monitoring/internal/repository"
 )
 
 type ESP32Service struct {
-	theaterRepo *repository.TheaterRepository
-	roomRepo    *repository.RoomRepository
+	theaterRepo         *repository.TheaterRepository
+	roomRepo            *repository.RoomRepository
+	historyRepo         *repository.TelemetryHistoryRepository
+	workerService       *WorkerService
+	rollupService       *RollupService
+	clockSkewService    *ClockSkewService
+	deviceService       *DeviceService
+	calibrationService  *CalibrationService
+	sensorSchemaService *SensorSchemaService
 }
 
 func NewESP32Service(
 	theaterRepo *repository.TheaterRepository,
 	roomRepo *repository.RoomRepository,
+	historyRepo *repository.TelemetryHistoryRepository,
+	workerService *WorkerService,
+	rollupService *RollupService,
+	clockSkewService *ClockSkewService,
+	deviceService *DeviceService,
+	calibrationService *CalibrationService,
+	sensorSchemaService *SensorSchemaService,
 ) *ESP32Service {
 	return &ESP32Service{
-		theaterRepo: theaterRepo,
-		roomRepo:    roomRepo,
+		theaterRepo:         theaterRepo,
+		roomRepo:            roomRepo,
+		historyRepo:         historyRepo,
+		workerService:       workerService,
+		rollupService:       rollupService,
+		clockSkewService:    clockSkewService,
+		deviceService:       deviceService,
+		calibrationService:  calibrationService,
+		sensorSchemaService: sensorSchemaService,
 	}
 }
 
@@ -38,49 +63,78 @@ type TelemetryUpdateRequest struct {
 	Vacuum        *int     `json:"vacuum"`
 	Instrument    *float64 `json:"instrument"`
 	Carbon        *float64 `json:"carbon"`
+
+	// Sensors of the room's schema without a dedicated field, keyed by sensor name
+	Sensors map[string]float64 `json:"sensors"`
+
+	// Optional, from firmware with a synchronized clock
+	DeviceTimestamp *time.Time `json:"device_timestamp"` // When the reading was taken
+	Sequence        *uint32    `json:"sequence"`         // Incrementing reading counter
+
+	// Optional device information, recorded in the device registry
+	FirmwareVersion string `json:"firmware_version"`
+	MacAddress      string `json:"mac_address"`
+	UptimeSeconds   *int64 `json:"uptime_seconds"`
 }
 
-// ValidateTelemetryData validates the telemetry data from ESP32
-func (s *ESP32Service) ValidateTelemetryData(data *TelemetryUpdateRequest) error {
-	// According to the plan, all fields are required
-	if data.Temp == nil {
-		return errors.New("temp is required")
-	}
-	if data.Humidity == nil {
-		return errors.New("humidity is required")
-	}
-	if data.RoomPressure == nil {
-		return errors.New("room_pressure is required")
-	}
-	if data.Oxygen == nil {
-		return errors.New("oxygen is required")
-	}
-	if data.Nitrous == nil {
-		return errors.New("nitrous is required")
-	}
-	if data.Air == nil {
-		return errors.New("air is required")
-	}
-	if data.Vacuum == nil {
-		return errors.New("vacuum is required")
-	}
-	if data.Instrument == nil {
-		return errors.New("instrument is required")
-	}
-	if data.Carbon == nil {
-		return errors.New("carbon is required")
-	}
+// TelemetrySource identifies who sent a telemetry call
+type TelemetrySource struct {
+	APIKeyID uint   // Key the device authenticated with
+	IP       string // Source address, empty when unknown (e.g. MQTT)
+}
 
-	// Validate ranges (optional, but good practice)
-	if *data.Temp < -50 || *data.Temp > 100 {
-		return errors.New("temperature out of valid range (-50 to 100)")
-	}
-	if *data.Humidity < 0 || *data.Humidity > 100 {
-		return errors.New("humidity out of valid range (0 to 100)")
+// MaxTelemetryBatchSize caps the number of readings accepted in one batch upload
+const MaxTelemetryBatchSize = 1000
+
+// maxTelemetryClockAhead is how far in the future a device timestamp may be before it is rejected
+const maxTelemetryClockAhead = time.Minute
+
+// TimestampedTelemetry is a single buffered reading with the time the device took it
+type TimestampedTelemetry struct {
+	RecordedAt *time.Time `json:"recorded_at"`
+	TelemetryUpdateRequest
+}
+
+// TelemetryBatchRequest represents readings a device buffered while it was offline
+type TelemetryBatchRequest struct {
+	Readings []TimestampedTelemetry `json:"readings"`
+}
+
+// TelemetryBatchResult summarizes a stored batch
+type TelemetryBatchResult struct {
+	Stored      int       `json:"stored"`
+	OldestAt    time.Time `json:"oldest_at"`
+	NewestAt    time.Time `json:"newest_at"`
+	LiveUpdated bool      `json:"live_updated"` // False when a newer live reading already existed
+}
+
+// ValidateTelemetryData validates the telemetry data from ESP32 against the sensor schema of its room type
+// Built-in sensors the schema does not define are accepted and dropped before storage
+func (s *ESP32Service) ValidateTelemetryData(data *TelemetryUpdateRequest, schema []models.SensorDefinition) error {
+	for name := range data.Sensors {
+		if isBuiltinSensor(name) {
+			return fmt.Errorf("%s must be sent as a top-level field", name)
+		}
+		if findSensorDefinition(schema, name) == nil {
+			return fmt.Errorf("unknown sensor: %s", name)
+		}
 	}
-	if *data.RoomPressure < 0 {
-		return errors.New("room_pressure cannot be negative")
+
+	values := telemetrySensorValues(data)
+	for i := range schema {
+		def := &schema[i]
+		value, ok := values[def.Name]
+		if !ok {
+			if def.Required {
+				return fmt.Errorf("%s is required", def.Name)
+			}
+			continue
+		}
+		if (def.MinValue != nil && value < *def.MinValue) || (def.MaxValue != nil && value > *def.MaxValue) {
+			return fmt.Errorf("%s out of valid range (%s)", def.Name, describeSensorRange(def))
+		}
 	}
+
 	if data.RoomStatus < 0 || data.RoomStatus > 1 {
 		return errors.New("room_status must be 0 or 1")
 	}
@@ -92,22 +146,221 @@ func (s *ESP32Service) ValidateTelemetryData(data *TelemetryUpdateRequest) error
 }
 
 // UpdateTelemetry updates the telemetry data for a specific room
-func (s *ESP32Service) UpdateTelemetry(roomID uint, data *TelemetryUpdateRequest) error {
+// source identifies the sending device for clock skew tracking and the device registry
+func (s *ESP32Service) UpdateTelemetry(roomID uint, source TelemetrySource, data *TelemetryUpdateRequest) error {
+	err := s.updateTelemetry(roomID, source, data)
+	recordIngestion(roomID, 1, err)
+	return err
+}
+
+func (s *ESP32Service) updateTelemetry(roomID uint, source TelemetrySource, data *TelemetryUpdateRequest) error {
 	// Verify room exists and get room data (we need volume_ruangan from room)
 	room, err := s.roomRepo.GetRoomByID(roomID)
 	if err != nil {
 		return fmt.Errorf("room not found: %w", err)
 	}
 
-	// Validate telemetry data
-	if err := s.ValidateTelemetryData(data); err != nil {
+	// Calibrate before validating, since the valid ranges are in storage units
+	data, calibration, err := s.calibrationService.Calibrate(roomID, source, data)
+	if err != nil {
+		return fmt.Errorf("invalid telemetry data: %w", err)
+	}
+
+	// Validate telemetry data against the room type's sensor schema, then keep only its sensors
+	schema := s.sensorSchemaService.SchemaFor(room.RoomType)
+	if err := s.ValidateTelemetryData(data, schema); err != nil {
 		return fmt.Errorf("invalid telemetry data: %w", err)
 	}
+	data = restrictToSchema(data, schema)
+
+	// Millisecond precision matches the database columns, so the worker's
+	// "newer than last processed" check behaves the same for queued and polled readings
+	receivedAt := time.Now().Truncate(time.Millisecond)
+
+	telemetry, history := newTelemetryRecords(room, data, receivedAt)
+	history.CalibrationProfileID = calibration.ProfileID
+	history.UncalibratedValues = calibration.Uncalibrated
+
+	// Device timestamps are only used for timing when they agree with the receive time
+	if data.DeviceTimestamp != nil {
+		deviceTime := data.DeviceTimestamp.Truncate(time.Millisecond)
+		skew := s.clockSkewService.Observe(roomID, source.APIKeyID, deviceTime, data.Sequence, receivedAt)
+		telemetry.DeviceTimestamp, history.DeviceTimestamp = &deviceTime, &deviceTime
+		telemetry.ClockSkewMs, history.ClockSkewMs = &skew.SkewMs, &skew.SkewMs
+		telemetry.ClockSkewFlagged, history.ClockSkewFlagged = skew.Flagged, skew.Flagged
+	}
+
+	// Append the reading to the history table before overwriting the live row,
+	// so a failed insert leaves both untouched and the device can simply retry
+	if err := s.historyRepo.CreateHistory(history); err != nil {
+		return fmt.Errorf("failed to store telemetry history: %w", err)
+	}
+
+	// Update the raw telemetry table
+	if err := s.theaterRepo.UpdateRawTelemetryByRoomID(roomID, telemetry, roomSensorNames(schema)); err != nil {
+		return fmt.Errorf("failed to update telemetry: %w", err)
+	}
+
+	// Hand the reading to the background worker; if its queue is full the
+	// reconciliation poll will pick the stored row up instead
+	s.workerService.Enqueue(telemetry)
+
+	s.deviceService.RecordHeartbeat(roomID, source.APIKeyID, deviceHeartbeat(data, source), receivedAt)
+
+	// Log success (optional, could be used for monitoring)
+	fmt.Printf("Telemetry updated for room %s (ID: %d)\n", room.RoomCode, roomID)
+
+	return nil
+}
+
+// UpdateTelemetryBatch stores readings a device buffered while offline
+// Every reading is appended to history in time order; the raw telemetry row only moves
+// forward to the newest reading, and the worker replays the batch so ACH cycles are not lost
+func (s *ESP32Service) UpdateTelemetryBatch(roomID uint, source TelemetrySource, batch *TelemetryBatchRequest) (*TelemetryBatchResult, error) {
+	result, err := s.updateTelemetryBatch(roomID, source, batch)
+	recordIngestion(roomID, len(batch.Readings), err)
+	return result, err
+}
+
+func (s *ESP32Service) updateTelemetryBatch(roomID uint, source TelemetrySource, batch *TelemetryBatchRequest) (*TelemetryBatchResult, error) {
+	room, err := s.roomRepo.GetRoomByID(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("room not found: %w", err)
+	}
+
+	if len(batch.Readings) == 0 {
+		return nil, errors.New("invalid telemetry data: readings are required")
+	}
+	if len(batch.Readings) > MaxTelemetryBatchSize {
+		return nil, fmt.Errorf("invalid telemetry data: at most %d readings per batch", MaxTelemetryBatchSize)
+	}
+
+	schema := s.sensorSchemaService.SchemaFor(room.RoomType)
+	now := time.Now()
+	readings := make([]TimestampedTelemetry, len(batch.Readings))
+	calibrations := make([]CalibrationResult, len(batch.Readings))
+	for i := range batch.Readings {
+		reading := &batch.Readings[i]
+		if reading.RecordedAt == nil || reading.RecordedAt.IsZero() {
+			return nil, fmt.Errorf("invalid telemetry data: reading %d: recorded_at is required", i)
+		}
+		if reading.RecordedAt.After(now.Add(maxTelemetryClockAhead)) {
+			return nil, fmt.Errorf("invalid telemetry data: reading %d: recorded_at is in the future", i)
+		}
+		calibrated, calibration, err := s.calibrationService.Calibrate(roomID, source, &reading.TelemetryUpdateRequest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid telemetry data: reading %d: %w", i, err)
+		}
+		if err := s.ValidateTelemetryData(calibrated, schema); err != nil {
+			return nil, fmt.Errorf("invalid telemetry data: reading %d: %w", i, err)
+		}
+		readings[i] = TimestampedTelemetry{RecordedAt: reading.RecordedAt, TelemetryUpdateRequest: *restrictToSchema(calibrated, schema)}
+		calibrations[i] = calibration
+	}
+
+	// Devices may flush their buffer out of order; store and replay oldest first
+	order := make([]int, len(readings))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return readings[order[a]].RecordedAt.Before(*readings[order[b]].RecordedAt)
+	})
+
+	rawReadings := make([]models.TheaterRawTelemetry, len(readings))
+	history := make([]models.TheaterTelemetryHistory, len(readings))
+	for n, i := range order {
+		deviceTime := readings[i].RecordedAt.Truncate(time.Millisecond)
+		// A slightly fast device clock must not push the raw row ahead of correct readings,
+		// so readings are never stored later than the time they were received
+		recordedAt := deviceTime
+		if recordedAt.After(now) {
+			recordedAt = now.Truncate(time.Millisecond)
+		}
+		raw, entry := newTelemetryRecords(room, &readings[i].TelemetryUpdateRequest, recordedAt)
+		// Buffered readings are stored at device time, so there is no receive time to measure skew against
+		raw.DeviceTimestamp, entry.DeviceTimestamp = &deviceTime, &deviceTime
+		entry.CalibrationProfileID = calibrations[i].ProfileID
+		entry.UncalibratedValues = calibrations[i].Uncalibrated
+		rawReadings[n] = *raw
+		history[n] = *entry
+	}
+
+	if err := s.historyRepo.CreateHistoryBatch(history); err != nil {
+		return nil, fmt.Errorf("failed to store telemetry history: %w", err)
+	}
+
+	// Closed rollup buckets must be recomputed to include the backfilled readings
+	s.rollupService.Backfill(history[0].RecordedAt)
+
+	current, err := s.theaterRepo.GetRawTelemetryByRoomID(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update telemetry: %w", err)
+	}
+
+	// Only move the raw row forward; a late batch must not overwrite a fresher live reading
+	newest := &rawReadings[len(rawReadings)-1]
+	liveUpdated := false
+	if newest.UpdatedAt.After(current.UpdatedAt) {
+		if err := s.theaterRepo.UpdateRawTelemetryByRoomID(roomID, newest, roomSensorNames(schema)); err != nil {
+			return nil, fmt.Errorf("failed to update telemetry: %w", err)
+		}
+		liveUpdated = true
+	}
+
+	for i := range rawReadings {
+		rawReadings[i].ID = current.ID
+		rawReadings[i].RoomName = current.RoomName
+	}
+	s.workerService.EnqueueBatch(rawReadings)
+
+	// The upload itself shows the device is back; describe it with its newest reading
+	s.deviceService.RecordHeartbeat(roomID, source.APIKeyID,
+		deviceHeartbeat(&readings[order[len(order)-1]].TelemetryUpdateRequest, source), time.Now())
+
+	return &TelemetryBatchResult{
+		Stored:      len(history),
+		OldestAt:    history[0].RecordedAt,
+		NewestAt:    history[len(history)-1].RecordedAt,
+		LiveUpdated: liveUpdated,
+	}, nil
+}
+
+// recordIngestion counts an upload's readings as ingested, or the upload as rejected with the reason of its error
+func recordIngestion(roomID uint, readings int, err error) {
+	room := metrics.RoomLabel(roomID)
+	if err == nil {
+		metrics.TelemetryIngested.WithLabelValues(room).Add(float64(readings))
+		return
+	}
 
-	// Convert request to model
-	// Note: VolumeRuangan comes from the room data, not from ESP32
+	reason := metrics.RejectStorage
+	switch {
+	case strings.HasPrefix(err.Error(), "room not found"):
+		reason = metrics.RejectUnknownRoom
+	case strings.HasPrefix(err.Error(), "invalid telemetry data"):
+		reason = metrics.RejectInvalid
+	}
+	metrics.TelemetryRejected.WithLabelValues(room, reason).Inc()
+}
+
+// deviceHeartbeat extracts the device registry information from a telemetry call
+func deviceHeartbeat(data *TelemetryUpdateRequest, source TelemetrySource) DeviceHeartbeat {
+	return DeviceHeartbeat{
+		MacAddress:      data.MacAddress,
+		FirmwareVersion: data.FirmwareVersion,
+		UptimeSeconds:   data.UptimeSeconds,
+		IPAddress:       source.IP,
+	}
+}
+
+// newTelemetryRecords converts a device reading into its raw telemetry and history rows
+// Note: VolumeRuangan comes from the room data, not from ESP32
+func newTelemetryRecords(room *models.Room, data *TelemetryUpdateRequest, recordedAt time.Time) (*models.TheaterRawTelemetry, *models.TheaterTelemetryHistory) {
+	roomID := room.ID
 	telemetry := &models.TheaterRawTelemetry{
 		RoomID:        &roomID,
+		UpdatedAt:     recordedAt,
 		Temp:          data.Temp,
 		Humidity:      data.Humidity,
 		RoomPressure:  data.RoomPressure,
@@ -121,18 +374,29 @@ func (s *ESP32Service) UpdateTelemetry(roomID uint, data *TelemetryUpdateRequest
 		Vacuum:        data.Vacuum,
 		Instrument:    data.Instrument,
 		Carbon:        data.Carbon,
+		ExtraSensors:  models.SensorValues(data.Sensors),
+		Sequence:      data.Sequence,
 	}
-
-	// Update the raw telemetry table
-	// This will trigger the background worker to process the new data
-	if err := s.theaterRepo.UpdateRawTelemetryByRoomID(roomID, telemetry); err != nil {
-		return fmt.Errorf("failed to update telemetry: %w", err)
+	history := &models.TheaterTelemetryHistory{
+		RoomID:        roomID,
+		RecordedAt:    recordedAt,
+		Temp:          data.Temp,
+		Humidity:      data.Humidity,
+		RoomPressure:  data.RoomPressure,
+		RoomStatus:    data.RoomStatus,
+		LajuAliranAhu: data.LajuAliranAhu,
+		VolumeRuangan: room.VolumeRuangan,
+		LogicAhu:      data.LogicAhu,
+		Oxygen:        data.Oxygen,
+		Nitrous:       data.Nitrous,
+		Air:           data.Air,
+		Vacuum:        data.Vacuum,
+		Instrument:    data.Instrument,
+		Carbon:        data.Carbon,
+		ExtraSensors:  models.SensorValues(data.Sensors),
+		Sequence:      data.Sequence,
 	}
-
-	// Log success (optional, could be used for monitoring)
-	fmt.Printf("Telemetry updated for room %s (ID: %d)\n", room.RoomCode, roomID)
-
-	return nil
+	return telemetry, history
 }
 
 // GetRoomTelemetry retrieves the current telemetry data for a room
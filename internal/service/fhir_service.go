@@ -0,0 +1,487 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+	"iot-backend-room-monitoring/pkg/fhir"
+)
+
+// Identifier and code systems of resources exported by this backend
+const (
+	fhirSystemHospital    = "urn:iot-backend-room-monitoring:hospital"
+	fhirSystemRoom        = "urn:iot-backend-room-monitoring:room"
+	fhirSystemObservation = "urn:iot-backend-room-monitoring:observation"
+	fhirSystemSensor      = "urn:iot-backend-room-monitoring:sensor"
+	fhirSystemCategory    = "urn:iot-backend-room-monitoring:observation-category"
+)
+
+// fhirUCUMUnits maps sensor schema units to UCUM codes; other units are exported as text only
+var fhirUCUMUnits = map[string]string{
+	"C":    "Cel",
+	"°C":   "Cel",
+	"F":    "[degF]",
+	"°F":   "[degF]",
+	"%":    "%",
+	"Pa":   "Pa",
+	"kPa":  "kPa",
+	"bar":  "bar",
+	"psi":  "[psi]",
+	"ppm":  "[ppm]",
+	"ACH":  "/h",
+	"m3/h": "m3/h",
+}
+
+// fhirRoomEnvironment is the category of every exported observation
+var fhirRoomEnvironment = fhir.CodeableConcept{
+	Coding: []fhir.Coding{{System: fhirSystemCategory, Code: "room-environment", Display: "Room environment"}},
+	Text:   "Room environment",
+}
+
+// fhirACHDefinitions describe the computed air change rates, which are not part of any sensor schema
+var (
+	fhirACHTheoretical = models.SensorDefinition{Name: "ach_theoretical", Label: "Air changes per hour (AHU flow)", Unit: "ACH"}
+	fhirACHEmpirical   = models.SensorDefinition{Name: "ach_empirical", Label: "Air changes per hour (AHU cycle)", Unit: "ACH"}
+)
+
+// FHIRHistoryQuery holds the filters for a FHIR export of historical readings
+type FHIRHistoryQuery struct {
+	From       *time.Time
+	To         *time.Time
+	BundleType string
+	Page       int
+	Limit      int // Readings per page; each reading becomes one observation per reported sensor
+}
+
+// FHIRHistoryBundle is a page of historical readings rendered as a FHIR bundle
+type FHIRHistoryBundle struct {
+	Bundle  *fhir.Bundle
+	Page    int
+	Limit   int
+	Total   int64 // Readings in the period
+	HasMore bool
+}
+
+// FHIRService renders live and historical room readings as FHIR R4 Observation and Location resources
+type FHIRService struct {
+	theaterRepo         *repository.TheaterRepository
+	historyRepo         *repository.TelemetryHistoryRepository
+	roomRepo            *repository.RoomRepository
+	hospitalRepo        *repository.HospitalRepository
+	userHospitalRepo    *repository.UserHospitalRepository
+	sensorSchemaService *SensorSchemaService
+}
+
+func NewFHIRService(
+	theaterRepo *repository.TheaterRepository,
+	historyRepo *repository.TelemetryHistoryRepository,
+	roomRepo *repository.RoomRepository,
+	hospitalRepo *repository.HospitalRepository,
+	userHospitalRepo *repository.UserHospitalRepository,
+	sensorSchemaService *SensorSchemaService,
+) *FHIRService {
+	return &FHIRService{
+		theaterRepo:         theaterRepo,
+		historyRepo:         historyRepo,
+		roomRepo:            roomRepo,
+		hospitalRepo:        hospitalRepo,
+		userHospitalRepo:    userHospitalRepo,
+		sensorSchemaService: sensorSchemaService,
+	}
+}
+
+// GetRoomLiveBundle renders a room's latest readings with its Location resources
+func (s *FHIRService) GetRoomLiveBundle(roomID uint, bundleType string, userID uint, role string) (*fhir.Bundle, error) {
+	room, err := s.accessibleRoom(roomID, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateBundleType(bundleType); err != nil {
+		return nil, err
+	}
+
+	// A room without a live state has not reported yet and exports its Locations only
+	state, err := s.theaterRepo.FindLiveStateByRoomID(roomID)
+	if err != nil && err.Error() != "live state not found for room" {
+		return nil, fmt.Errorf("failed to fetch live state: %w", err)
+	}
+
+	now := time.Now()
+	bundle := fhir.NewBundle(bundleType, now)
+	addHospitalLocation(bundle, &room.Hospital)
+	addRoomLocation(bundle, room)
+	if state != nil {
+		s.addLiveObservations(bundle, room, state, now)
+	}
+	return bundle, nil
+}
+
+// GetHospitalLiveBundle renders the latest readings of every room of a hospital with their Location resources
+func (s *FHIRService) GetHospitalLiveBundle(hospitalID uint, bundleType string, userID uint, role string) (*fhir.Bundle, error) {
+	if role != models.UserRoleSuperAdmin {
+		hasAccess, err := s.userHospitalRepo.UserHasAccessToHospital(userID, hospitalID)
+		if err != nil {
+			return nil, err
+		}
+		if !hasAccess {
+			return nil, errors.New("access denied: you don't have permission to access this hospital")
+		}
+	}
+	if err := validateBundleType(bundleType); err != nil {
+		return nil, err
+	}
+
+	hospital, err := s.hospitalRepo.GetHospitalByID(hospitalID)
+	if err != nil {
+		return nil, err
+	}
+	rooms, err := s.roomRepo.GetRoomsByHospitalID(hospitalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rooms: %w", err)
+	}
+	states, err := s.theaterRepo.GetAllLiveStates()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch live states: %w", err)
+	}
+	statesByRoom := make(map[uint]*models.TheaterLiveState, len(states))
+	for i := range states {
+		if states[i].RoomID != nil {
+			statesByRoom[*states[i].RoomID] = &states[i]
+		}
+	}
+
+	now := time.Now()
+	bundle := fhir.NewBundle(bundleType, now)
+	addHospitalLocation(bundle, hospital)
+	for i := range rooms {
+		rooms[i].Hospital = *hospital
+		addRoomLocation(bundle, &rooms[i])
+	}
+	for i := range rooms {
+		if state, ok := statesByRoom[rooms[i].ID]; ok {
+			s.addLiveObservations(bundle, &rooms[i], state, now)
+		}
+	}
+	return bundle, nil
+}
+
+// GetRoomHistoryBundle renders a page of a room's historical readings, oldest first, with its Location resources
+func (s *FHIRService) GetRoomHistoryBundle(roomID uint, query FHIRHistoryQuery, userID uint, role string) (*FHIRHistoryBundle, error) {
+	room, err := s.accessibleRoom(roomID, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateBundleType(query.BundleType); err != nil {
+		return nil, err
+	}
+
+	// Default to the last 24 hours, as the telemetry history endpoint does
+	to := time.Now()
+	if query.To != nil {
+		to = *query.To
+	}
+	from := to.Add(-defaultHistoryWindow)
+	if query.From != nil {
+		from = *query.From
+	}
+	if from.After(to) {
+		return nil, errors.New("from must be before to")
+	}
+
+	page := query.Page
+	if page < 1 {
+		page = 1
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	history, total, err := s.historyRepo.GetHistoryByRoomID(roomID, from, to, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch telemetry history: %w", err)
+	}
+
+	// An AHU cycle can start before the page and end in it
+	var ahuOn bool
+	var cycleStart *time.Time
+	if len(history) > 0 {
+		ahuOn, cycleStart, err = s.historyRepo.GetAHUStateBefore(roomID, &history[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch AHU state: %w", err)
+		}
+	}
+
+	now := time.Now()
+	bundle := fhir.NewBundle(query.BundleType, now)
+	addHospitalLocation(bundle, &room.Hospital)
+	addRoomLocation(bundle, room)
+	addHistoryObservations(bundle, room, s.sensorSchemaService.SchemaFor(room.RoomType), history, ahuOn, cycleStart, now)
+
+	return &FHIRHistoryBundle{
+		Bundle:  bundle,
+		Page:    page,
+		Limit:   limit,
+		Total:   total,
+		HasMore: int64(page*limit) < total,
+	}, nil
+}
+
+// addLiveObservations adds one observation per sensor of the room's schema reported in its latest reading
+// A room that never reported has no observations
+func (s *FHIRService) addLiveObservations(bundle *fhir.Bundle, room *models.Room, state *models.TheaterLiveState, now time.Time) {
+	if state.LastProcessedAt == nil {
+		return
+	}
+	at := *state.LastProcessedAt
+
+	schema := s.sensorSchemaService.SchemaFor(room.RoomType)
+	for i := range schema {
+		if value, ok := state.SensorValues[schema[i].Name]; ok {
+			addObservation(bundle, room, &schema[i], value, at, now)
+		}
+	}
+	if room.VolumeRuangan > 0 {
+		addObservation(bundle, room, &fhirACHTheoretical, state.AchTheoretical, at, now)
+	}
+	if state.AchEmpirical > 0 {
+		addObservation(bundle, room, &fhirACHEmpirical, state.AchEmpirical, at, now)
+	}
+}
+
+// addHistoryObservations adds the observations of history readings, oldest first
+// Every reported sensor is exported, including sensors no longer in the room's schema, with the
+// theoretical air change rate of each reading and the empirical rate of each AHU cycle ending in them.
+// ahuOn and cycleStart are the AHU state before the first reading
+func addHistoryObservations(bundle *fhir.Bundle, room *models.Room, schema []models.SensorDefinition, history []models.TheaterTelemetryHistory, ahuOn bool, cycleStart *time.Time, now time.Time) {
+	empirical := historyEmpiricalACH(history, ahuOn, cycleStart)
+	for i := range history {
+		reading := &history[i]
+		values := historySensorValues(reading)
+		for _, def := range historySensorDefinitions(schema, values) {
+			addObservation(bundle, room, &def, values[def.Name], reading.RecordedAt, now)
+		}
+		if reading.VolumeRuangan > 0 {
+			ach := float64(reading.LajuAliranAhu*3600) / float64(reading.VolumeRuangan)
+			addObservation(bundle, room, &fhirACHTheoretical, ach, reading.RecordedAt, now)
+		}
+		if ach, ok := empirical[i]; ok {
+			addObservation(bundle, room, &fhirACHEmpirical, ach, reading.RecordedAt, now)
+		}
+	}
+}
+
+// addObservation adds the observation of one sensor value of a room
+// Its ID is derived from room, sensor and reading time so re-exporting a reading yields the same resource
+func addObservation(bundle *fhir.Bundle, room *models.Room, def *models.SensorDefinition, value float64, at, issued time.Time) {
+	effective := fhir.FormatInstant(at)
+	identifier := fmt.Sprintf("%d/%s/%s", room.ID, def.Name, at.UTC().Format(time.RFC3339Nano))
+	sum := sha256.Sum256([]byte(identifier))
+	id := "obs-" + hex.EncodeToString(sum[:16])
+
+	label := def.Label
+	if label == "" {
+		label = def.Name
+	}
+	quantity := &fhir.Quantity{Value: value, Unit: def.Unit}
+	if code, ok := fhirUCUMUnits[def.Unit]; ok {
+		quantity.System = fhir.SystemUCUM
+		quantity.Code = code
+	}
+
+	bundle.Add("Observation", id, &fhir.Observation{
+		ResourceType: "Observation",
+		ID:           id,
+		Identifier:   []fhir.Identifier{{System: fhirSystemObservation, Value: identifier}},
+		Status:       "final",
+		Category:     []fhir.CodeableConcept{fhirRoomEnvironment},
+		Code: fhir.CodeableConcept{
+			Coding: []fhir.Coding{{System: fhirSystemSensor, Code: def.Name, Display: label}},
+			Text:   label,
+		},
+		Subject:           &fhir.Reference{Reference: "Location/" + roomLocationID(room.ID), Display: room.RoomName},
+		EffectiveDateTime: effective,
+		Issued:            fhir.FormatInstant(issued),
+		ValueQuantity:     quantity,
+	})
+}
+
+// addHospitalLocation adds the building-level Location of a hospital
+func addHospitalLocation(bundle *fhir.Bundle, hospital *models.Hospital) {
+	id := hospitalLocationID(hospital.ID)
+	location := &fhir.Location{
+		ResourceType: "Location",
+		ID:           id,
+		Identifier:   []fhir.Identifier{{System: fhirSystemHospital, Value: hospital.Code}},
+		Status:       locationStatus(hospital.IsActive),
+		Name:         hospital.Name,
+		Mode:         "instance",
+		PhysicalType: &fhir.CodeableConcept{
+			Coding: []fhir.Coding{{System: fhir.SystemLocationPhysicalType, Code: "bu", Display: "Building"}},
+		},
+	}
+	if hospital.Address != "" || hospital.City != "" {
+		location.Address = &fhir.Address{Text: hospital.Address, City: hospital.City}
+	}
+	bundle.Add("Location", id, location)
+}
+
+// addRoomLocation adds the Location of a room, part of its hospital's Location
+// The room's hospital must be loaded
+func addRoomLocation(bundle *fhir.Bundle, room *models.Room) {
+	id := roomLocationID(room.ID)
+	bundle.Add("Location", id, &fhir.Location{
+		ResourceType: "Location",
+		ID:           id,
+		Identifier:   []fhir.Identifier{{System: fhirSystemRoom, Value: room.Hospital.Code + "/" + room.RoomCode}},
+		Status:       locationStatus(room.IsActive),
+		Name:         room.RoomName,
+		Mode:         "instance",
+		Type:         []fhir.CodeableConcept{{Text: strings.ReplaceAll(room.RoomType, "_", " ")}},
+		PhysicalType: &fhir.CodeableConcept{
+			Coding: []fhir.Coding{{System: fhir.SystemLocationPhysicalType, Code: "ro", Display: "Room"}},
+		},
+		PartOf: &fhir.Reference{Reference: "Location/" + hospitalLocationID(room.HospitalID), Display: room.Hospital.Name},
+	})
+}
+
+func hospitalLocationID(hospitalID uint) string {
+	return fmt.Sprintf("hospital-%d", hospitalID)
+}
+
+func roomLocationID(roomID uint) string {
+	return fmt.Sprintf("room-%d", roomID)
+}
+
+func locationStatus(active bool) string {
+	if active {
+		return "active"
+	}
+	return "inactive"
+}
+
+// historySensorValues returns the sensor values of a history row, including sensors without a column
+// Sensors that did not report a value are omitted
+func historySensorValues(h *models.TheaterTelemetryHistory) map[string]float64 {
+	values := make(map[string]float64)
+	floats := map[string]*float64{
+		"temp":          h.Temp,
+		"room_pressure": h.RoomPressure,
+		"oxygen":        h.Oxygen,
+		"nitrous":       h.Nitrous,
+		"air":           h.Air,
+		"instrument":    h.Instrument,
+		"carbon":        h.Carbon,
+	}
+	for name, v := range floats {
+		if v != nil {
+			values[name] = *v
+		}
+	}
+	if h.Humidity != nil {
+		values["humidity"] = float64(*h.Humidity)
+	}
+	if h.Vacuum != nil {
+		values["vacuum"] = float64(*h.Vacuum)
+	}
+	for name, v := range h.ExtraSensors {
+		values[name] = v
+	}
+	return values
+}
+
+// historySensorDefinitions returns the definitions of the sensors in a history row's values
+// Sensors of the room's schema come first in schema order; sensors no longer in the schema follow by name,
+// described by their built-in definition when they have a dedicated column
+func historySensorDefinitions(schema []models.SensorDefinition, values map[string]float64) []models.SensorDefinition {
+	defs := make([]models.SensorDefinition, 0, len(values))
+	inSchema := make(map[string]bool, len(schema))
+	for _, def := range schema {
+		inSchema[def.Name] = true
+		if _, ok := values[def.Name]; ok {
+			defs = append(defs, def)
+		}
+	}
+
+	var others []string
+	for name := range values {
+		if !inSchema[name] {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	for _, name := range others {
+		if builtin := findSensorDefinition(builtinSensors, name); builtin != nil {
+			defs = append(defs, *builtin)
+		} else {
+			defs = append(defs, models.SensorDefinition{Name: name})
+		}
+	}
+	return defs
+}
+
+// historyEmpiricalACH returns the empirical air change rate of each AHU cycle ending in the readings, by reading index
+// A cycle runs from the reading where logic_ahu turns on to the one where it turns off, as the worker times it live.
+// ahuOn and cycleStart are the AHU state before the first reading
+func historyEmpiricalACH(history []models.TheaterTelemetryHistory, ahuOn bool, cycleStart *time.Time) map[int]float64 {
+	rates := make(map[int]float64)
+	for i := range history {
+		reading := &history[i]
+		switch {
+		case !ahuOn && reading.LogicAhu == 1:
+			ahuOn = true
+			started := reading.RecordedAt
+			cycleStart = &started
+		case ahuOn && reading.LogicAhu == 0:
+			if cycleStart != nil {
+				if duration := reading.RecordedAt.Sub(*cycleStart).Seconds(); duration > 0 {
+					rates[i] = 3600 / duration
+				}
+			}
+			ahuOn = false
+			cycleStart = nil
+		}
+	}
+	return rates
+}
+
+// validateBundleType checks a requested bundle type
+func validateBundleType(bundleType string) error {
+	if bundleType != fhir.BundleTypeCollection && bundleType != fhir.BundleTypeTransaction {
+		return errors.New("invalid bundle type: must be 'collection' or 'transaction'")
+	}
+	return nil
+}
+
+// accessibleRoom loads a room with its hospital, checking the user's access to it
+func (s *FHIRService) accessibleRoom(roomID uint, userID uint, role string) (*models.Room, error) {
+	room, err := s.roomRepo.GetRoomWithHospital(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Admin users have access to all rooms
+	if role == models.UserRoleSuperAdmin {
+		return room, nil
+	}
+
+	hasAccess, err := s.userHospitalRepo.UserHasAccessToHospital(userID, room.HospitalID)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, errors.New("access denied: you don't have permission to access this room")
+	}
+
+	return room, nil
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/pkg/fhir"
+)
+
+func intValue(v int) *int { return &v }
+
+func testFHIRRoom() *models.Room {
+	return &models.Room{
+		ID:         3,
+		HospitalID: 1,
+		RoomCode:   "OT-1",
+		RoomName:   "Operating Theater 1",
+		RoomType:   "operating_theater",
+		IsActive:   true,
+		Hospital:   models.Hospital{ID: 1, Code: "RSUD01", Name: "RSUD Kota", IsActive: true},
+	}
+}
+
+func TestHistorySensorValuesKeepsEveryReportedSensor(t *testing.T) {
+	reading := &models.TheaterTelemetryHistory{
+		Temp:         floatPtr(21.5),
+		Humidity:     intValue(48),
+		Vacuum:       intValue(-60),
+		Oxygen:       floatPtr(410),
+		ExtraSensors: models.SensorValues{"co2": 620, "pm25": 4},
+	}
+
+	values := historySensorValues(reading)
+	want := map[string]float64{"temp": 21.5, "humidity": 48, "vacuum": -60, "oxygen": 410, "co2": 620, "pm25": 4}
+	if len(values) != len(want) {
+		t.Errorf("got %d values %v, want %v", len(values), values, want)
+	}
+	for name, value := range want {
+		if got, ok := values[name]; !ok || got != value {
+			t.Errorf("values[%s] = %v, %v, want %v", name, got, ok, value)
+		}
+	}
+	if _, ok := values["nitrous"]; ok {
+		t.Error("unreported nitrous was exported")
+	}
+}
+
+func TestHistorySensorDefinitionsIncludesSensorsOutsideSchema(t *testing.T) {
+	schema := []models.SensorDefinition{
+		{Name: "humidity", Label: "Humidity", Unit: "%"},
+		{Name: "temp", Label: "Temperature", Unit: "C"},
+		{Name: "co2", Label: "CO2", Unit: "ppm"},
+	}
+	values := map[string]float64{"temp": 21, "humidity": 50, "oxygen": 400, "pm25": 3, "co2": 600}
+
+	defs := historySensorDefinitions(schema, values)
+	var names []string
+	for _, def := range defs {
+		names = append(names, def.Name)
+	}
+	want := []string{"humidity", "temp", "co2", "oxygen", "pm25"}
+	if len(names) != len(want) {
+		t.Fatalf("definitions = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("definitions = %v, want %v", names, want)
+		}
+	}
+	// A removed built-in sensor keeps its unit; an unknown one is exported by name
+	if defs[3].Unit != "kPa" {
+		t.Errorf("oxygen unit = %q, want the built-in kPa", defs[3].Unit)
+	}
+	if defs[4].Label != "" || defs[4].Unit != "" {
+		t.Errorf("pm25 definition = %+v, want name only", defs[4])
+	}
+}
+
+func TestHistoryEmpiricalACH(t *testing.T) {
+	base := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	reading := func(offset time.Duration, logic int) models.TheaterTelemetryHistory {
+		return models.TheaterTelemetryHistory{RecordedAt: base.Add(offset), LogicAhu: logic}
+	}
+
+	tests := []struct {
+		name       string
+		history    []models.TheaterTelemetryHistory
+		ahuOn      bool
+		cycleStart *time.Time
+		want       map[int]float64
+	}{
+		{
+			name:    "cycle within the page",
+			history: []models.TheaterTelemetryHistory{reading(0, 0), reading(time.Minute, 1), reading(2*time.Minute, 1), reading(4*time.Minute, 0)},
+			want:    map[int]float64{3: 20}, // 3 minute cycle
+		},
+		{
+			name:       "cycle started before the page",
+			history:    []models.TheaterTelemetryHistory{reading(time.Minute, 1), reading(2*time.Minute, 0)},
+			ahuOn:      true,
+			cycleStart: &base,
+			want:       map[int]float64{1: 30}, // 2 minute cycle
+		},
+		{
+			name:    "on before the page without a known start",
+			history: []models.TheaterTelemetryHistory{reading(time.Minute, 1), reading(2*time.Minute, 0)},
+			ahuOn:   true,
+			want:    map[int]float64{},
+		},
+		{
+			name:    "cycle still running",
+			history: []models.TheaterTelemetryHistory{reading(0, 0), reading(time.Minute, 1)},
+			want:    map[int]float64{},
+		},
+	}
+
+	for _, tt := range tests {
+		got := historyEmpiricalACH(tt.history, tt.ahuOn, tt.cycleStart)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i, rate := range tt.want {
+			if math.Abs(got[i]-rate) > 1e-9 {
+				t.Errorf("%s: rate at reading %d = %v, want %v", tt.name, i, got[i], rate)
+			}
+		}
+	}
+}
+
+func TestHistoryBundleStructure(t *testing.T) {
+	room := testFHIRRoom()
+	base := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	schema := []models.SensorDefinition{{Name: "temp", Label: "Temperature", Unit: "C"}}
+	history := []models.TheaterTelemetryHistory{
+		{RoomID: room.ID, RecordedAt: base, Temp: floatPtr(21), LogicAhu: 1, LajuAliranAhu: 2, VolumeRuangan: 120},
+		{RoomID: room.ID, RecordedAt: base.Add(2 * time.Minute), Temp: floatPtr(21.4), ExtraSensors: models.SensorValues{"co2": 640}},
+	}
+
+	bundle := fhir.NewBundle(fhir.BundleTypeTransaction, base.Add(time.Hour))
+	addHospitalLocation(bundle, &room.Hospital)
+	addRoomLocation(bundle, room)
+	addHistoryObservations(bundle, room, schema, history, false, nil, base.Add(time.Hour))
+
+	encoded, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded struct {
+		ResourceType string `json:"resourceType"`
+		Type         string `json:"type"`
+		Entry        []struct {
+			Resource struct {
+				ResourceType string `json:"resourceType"`
+				ID           string `json:"id"`
+				Code         struct {
+					Coding []struct {
+						Code string `json:"code"`
+					} `json:"coding"`
+				} `json:"code"`
+				Subject struct {
+					Reference string `json:"reference"`
+				} `json:"subject"`
+				EffectiveDateTime string `json:"effectiveDateTime"`
+			} `json:"resource"`
+			Request *struct {
+				Method string `json:"method"`
+				URL    string `json:"url"`
+			} `json:"request"`
+		} `json:"entry"`
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if decoded.ResourceType != "Bundle" || decoded.Type != fhir.BundleTypeTransaction {
+		t.Errorf("bundle is %s/%s, want Bundle/transaction", decoded.ResourceType, decoded.Type)
+	}
+
+	// Locations, then per reading its sensors and air change rates
+	want := []struct{ resourceType, code, effective string }{
+		{"Location", "", ""},
+		{"Location", "", ""},
+		{"Observation", "temp", "2026-03-01T08:00:00Z"},
+		{"Observation", "ach_theoretical", "2026-03-01T08:00:00Z"},
+		{"Observation", "temp", "2026-03-01T08:02:00Z"},
+		{"Observation", "co2", "2026-03-01T08:02:00Z"},
+		{"Observation", "ach_empirical", "2026-03-01T08:02:00Z"},
+	}
+	if len(decoded.Entry) != len(want) {
+		t.Fatalf("bundle has %d entries, want %d", len(decoded.Entry), len(want))
+	}
+
+	seen := make(map[string]bool)
+	for i, entry := range decoded.Entry {
+		resource := entry.Resource
+		if resource.ResourceType != want[i].resourceType {
+			t.Errorf("entry %d is a %s, want %s", i, resource.ResourceType, want[i].resourceType)
+		}
+		if entry.Request == nil {
+			t.Errorf("entry %d has no request", i)
+		} else if entry.Request.Method != "PUT" || entry.Request.URL != resource.ResourceType+"/"+resource.ID {
+			t.Errorf("entry %d request = %s %s, want PUT %s/%s", i, entry.Request.Method, entry.Request.URL, resource.ResourceType, resource.ID)
+		}
+		if seen[resource.ID] {
+			t.Errorf("entry %d repeats resource ID %s", i, resource.ID)
+		}
+		seen[resource.ID] = true
+
+		if resource.ResourceType != "Observation" {
+			continue
+		}
+		if len(resource.Code.Coding) == 0 || resource.Code.Coding[0].Code != want[i].code {
+			t.Errorf("entry %d code = %+v, want %s", i, resource.Code.Coding, want[i].code)
+		}
+		if resource.Subject.Reference != "Location/room-3" {
+			t.Errorf("entry %d subject = %s, want Location/room-3", i, resource.Subject.Reference)
+		}
+		if resource.EffectiveDateTime != want[i].effective {
+			t.Errorf("entry %d effective = %s, want %s", i, resource.EffectiveDateTime, want[i].effective)
+		}
+	}
+}
+
+func TestCollectionBundleHasNoRequests(t *testing.T) {
+	room := testFHIRRoom()
+	at := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	history := []models.TheaterTelemetryHistory{{RoomID: room.ID, RecordedAt: at, Temp: floatPtr(21)}}
+
+	bundle := fhir.NewBundle(fhir.BundleTypeCollection, at)
+	addRoomLocation(bundle, room)
+	addHistoryObservations(bundle, room, nil, history, false, nil, at)
+
+	if len(bundle.Entry) != 2 {
+		t.Fatalf("bundle has %d entries, want the room and one observation", len(bundle.Entry))
+	}
+	for i, entry := range bundle.Entry {
+		if entry.Request != nil {
+			t.Errorf("collection entry %d has a request", i)
+		}
+	}
+}
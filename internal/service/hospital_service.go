@@ -3,6 +3,8 @@ package service
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"iot-backend-room-monitoring/internal/models"
 	"iot-backend-room-monitoring/internal/repository"
@@ -12,24 +14,40 @@ type HospitalService struct {
 	hospitalRepo     *repository.HospitalRepository
 	userHospitalRepo *repository.UserHospitalRepository
 	auditRepo        *repository.AuditRepository
+	userRepo         *repository.UserRepository
+
+	// Serializes membership changes so two of them cannot both remove a hospital's last admin
+	membershipMu sync.Mutex
+}
+
+// HospitalMember is a user assigned to a hospital with their hospital role
+type HospitalMember struct {
+	UserID     uint      `json:"user_id"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email,omitempty"`
+	UserRole   string    `json:"user_role"` // admin = super admin
+	Role       string    `json:"role"`      // Role within the hospital
+	AssignedAt time.Time `json:"assigned_at"`
 }
 
 func NewHospitalService(
 	hospitalRepo *repository.HospitalRepository,
 	userHospitalRepo *repository.UserHospitalRepository,
 	auditRepo *repository.AuditRepository,
+	userRepo *repository.UserRepository,
 ) *HospitalService {
 	return &HospitalService{
 		hospitalRepo:     hospitalRepo,
 		userHospitalRepo: userHospitalRepo,
 		auditRepo:        auditRepo,
+		userRepo:         userRepo,
 	}
 }
 
 // GetAllHospitals retrieves hospitals based on user role
 // Admin users see all hospitals, regular users see only assigned hospitals
 func (s *HospitalService) GetAllHospitals(userID uint, role string) ([]models.Hospital, error) {
-	if role == "admin" {
+	if role == models.UserRoleSuperAdmin {
 		return s.hospitalRepo.GetAllHospitals()
 	}
 	return s.hospitalRepo.GetHospitalsByUserID(userID)
@@ -38,7 +56,7 @@ func (s *HospitalService) GetAllHospitals(userID uint, role string) ([]models.Ho
 // GetHospitalByID retrieves a hospital by ID with access control
 func (s *HospitalService) GetHospitalByID(id uint, userID uint, role string) (*models.Hospital, error) {
 	// Admin users can access any hospital
-	if role == "admin" {
+	if role == models.UserRoleSuperAdmin {
 		return s.hospitalRepo.GetHospitalByID(id)
 	}
 
@@ -72,7 +90,7 @@ func (s *HospitalService) CreateHospital(hospital *models.Hospital, userID uint)
 	return nil
 }
 
-// UpdateHospital updates an existing hospital (admin only)
+// UpdateHospital updates an existing hospital (hospital admins of the hospital)
 func (s *HospitalService) UpdateHospital(hospital *models.Hospital, userID uint) error {
 	// Verify hospital exists
 	existing, err := s.hospitalRepo.GetHospitalByID(hospital.ID)
@@ -114,29 +132,85 @@ func (s *HospitalService) DeleteHospital(id uint, userID uint) error {
 	return nil
 }
 
-// AssignUserToHospital assigns a user to a hospital (admin only)
-func (s *HospitalService) AssignUserToHospital(userID uint, hospitalID uint, adminUserID uint) error {
-	// Verify hospital exists
-	_, err := s.hospitalRepo.GetHospitalByID(hospitalID)
+// GetHospitalMembers lists the users assigned to a hospital with their hospital roles (hospital admins of the hospital)
+func (s *HospitalService) GetHospitalMembers(hospitalID uint) ([]HospitalMember, error) {
+	if _, err := s.hospitalRepo.GetHospitalByID(hospitalID); err != nil {
+		return nil, err
+	}
+
+	assignments, err := s.userHospitalRepo.GetHospitalMembers(hospitalID)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to fetch hospital users: %w", err)
+	}
+
+	members := make([]HospitalMember, len(assignments))
+	for i, a := range assignments {
+		members[i] = HospitalMember{
+			UserID:     a.UserID,
+			Username:   a.User.Username,
+			Email:      a.User.Email,
+			UserRole:   a.User.Role,
+			Role:       a.Role,
+			AssignedAt: a.CreatedAt,
+		}
+	}
+	return members, nil
+}
+
+// AssignUserToHospital assigns a user to a hospital with a hospital role, replacing any previous role
+// (hospital admins of the hospital)
+func (s *HospitalService) AssignUserToHospital(userID uint, hospitalID uint, role string, adminUserID uint, adminRole string) (*HospitalMember, error) {
+	if !models.IsHospitalRole(role) {
+		return nil, errors.New("invalid role: must be viewer, operator or hospital_admin")
+	}
+
+	s.membershipMu.Lock()
+	defer s.membershipMu.Unlock()
+
+	change, err := s.loadMembershipChange(userID, hospitalID, adminRole)
+	if err != nil {
+		return nil, err
+	}
+	change.NewRole = role
+	if err := checkMembershipChange(change); err != nil {
+		return nil, err
 	}
 
 	// Assign user to hospital
-	if err := s.userHospitalRepo.AssignUserToHospital(userID, hospitalID); err != nil {
-		return fmt.Errorf("failed to assign user to hospital: %w", err)
+	assignment, err := s.userHospitalRepo.SetUserHospitalRole(userID, hospitalID, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign user to hospital: %w", err)
 	}
 
 	// Audit log
 	adminUserIDPtr := &adminUserID
-	details := fmt.Sprintf("Assigned user ID %d to hospital ID %d", userID, hospitalID)
+	details := fmt.Sprintf("Assigned user ID %d to hospital ID %d as %s", userID, hospitalID, role)
 	_ = s.auditRepo.CreateAuditLog(adminUserIDPtr, "user_hospital_assign", details)
 
-	return nil
+	user := change.Target
+	return &HospitalMember{
+		UserID:     user.ID,
+		Username:   user.Username,
+		Email:      user.Email,
+		UserRole:   user.Role,
+		Role:       assignment.Role,
+		AssignedAt: assignment.CreatedAt,
+	}, nil
 }
 
-// RemoveUserFromHospital removes a user's access to a hospital (admin only)
-func (s *HospitalService) RemoveUserFromHospital(userID uint, hospitalID uint, adminUserID uint) error {
+// RemoveUserFromHospital removes a user's access to a hospital (hospital admins of the hospital)
+func (s *HospitalService) RemoveUserFromHospital(userID uint, hospitalID uint, adminUserID uint, adminRole string) error {
+	s.membershipMu.Lock()
+	defer s.membershipMu.Unlock()
+
+	change, err := s.loadMembershipChange(userID, hospitalID, adminRole)
+	if err != nil {
+		return err
+	}
+	if err := checkMembershipChange(change); err != nil {
+		return err
+	}
+
 	// Remove assignment
 	if err := s.userHospitalRepo.RemoveUserFromHospital(userID, hospitalID); err != nil {
 		return fmt.Errorf("failed to remove user from hospital: %w", err)
@@ -150,10 +224,73 @@ func (s *HospitalService) RemoveUserFromHospital(userID uint, hospitalID uint, a
 	return nil
 }
 
+// membershipChange is a change to a user's role in a hospital, with what the guards need to judge it
+type membershipChange struct {
+	ActorIsSuperAdmin bool
+	Target            *models.User
+	CurrentRole       string // Target's role in the hospital, empty when not a member
+	TargetHospitals   int    // Hospitals the target is a member of
+	NewRole           string // Empty for a removal
+	HospitalAdmins    int64  // Members of the hospital holding hospital_admin
+}
+
+// loadMembershipChange looks up the target user and hospital state for a membership change
+func (s *HospitalService) loadMembershipChange(userID, hospitalID uint, adminRole string) (membershipChange, error) {
+	change := membershipChange{ActorIsSuperAdmin: adminRole == models.UserRoleSuperAdmin}
+
+	if _, err := s.hospitalRepo.GetHospitalByID(hospitalID); err != nil {
+		return change, err
+	}
+	user, err := s.userRepo.FindUserByID(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return change, err
+		}
+		return change, fmt.Errorf("failed to get user: %w", err)
+	}
+	change.Target = user
+
+	if change.CurrentRole, err = s.userHospitalRepo.GetUserHospitalRole(userID, hospitalID); err != nil {
+		return change, fmt.Errorf("failed to get hospital role: %w", err)
+	}
+	hospitalIDs, err := s.userHospitalRepo.GetUserHospitals(userID)
+	if err != nil {
+		return change, fmt.Errorf("failed to get user hospitals: %w", err)
+	}
+	change.TargetHospitals = len(hospitalIDs)
+	if change.HospitalAdmins, err = s.userHospitalRepo.CountHospitalRole(hospitalID, models.HospitalRoleHospitalAdmin); err != nil {
+		return change, fmt.Errorf("failed to count hospital admins: %w", err)
+	}
+
+	return change, nil
+}
+
+// checkMembershipChange applies the guards on changing a user's role in a hospital
+// Hospital admins can only change their own members and enrol users not yet in any hospital;
+// any other user is reported as not found so user IDs cannot be probed
+// Super admin accounts are never hospital members, and a hospital always keeps one hospital admin
+func checkMembershipChange(change membershipChange) error {
+	enrollable := change.NewRole != "" && change.TargetHospitals == 0 && change.Target.Role != models.UserRoleSuperAdmin
+	if !change.ActorIsSuperAdmin && change.CurrentRole == "" && !enrollable {
+		return errors.New("user not found")
+	}
+	if change.Target.Role == models.UserRoleSuperAdmin {
+		return errors.New("access denied: super admin accounts have access to every hospital and cannot be assigned hospital roles")
+	}
+	if change.NewRole == "" && change.CurrentRole == "" {
+		return errors.New("user is not a member of this hospital")
+	}
+	if change.CurrentRole == models.HospitalRoleHospitalAdmin && change.NewRole != models.HospitalRoleHospitalAdmin &&
+		change.HospitalAdmins <= 1 {
+		return errors.New("cannot remove or demote the last hospital admin of a hospital")
+	}
+	return nil
+}
+
 // CheckUserHospitalAccess checks if a user has access to a hospital
 func (s *HospitalService) CheckUserHospitalAccess(userID uint, hospitalID uint, role string) error {
 	// Admin users have access to all hospitals
-	if role == "admin" {
+	if role == models.UserRoleSuperAdmin {
 		return nil
 	}
 
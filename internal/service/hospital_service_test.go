@@ -0,0 +1,97 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"iot-backend-room-monitoring/internal/models"
+)
+
+func TestCheckMembershipChange(t *testing.T) {
+	user := &models.User{ID: 7, Role: models.UserRoleUser}
+	superAdmin := &models.User{ID: 1, Role: models.UserRoleSuperAdmin}
+
+	tests := []struct {
+		name    string
+		change  membershipChange
+		wantErr string // Error prefix, empty when the change is allowed
+	}{
+		{
+			name:   "hospital admin changes a member's role",
+			change: membershipChange{Target: user, CurrentRole: models.HospitalRoleViewer, TargetHospitals: 1, NewRole: models.HospitalRoleOperator, HospitalAdmins: 1},
+		},
+		{
+			name:   "hospital admin enrols a user without a hospital",
+			change: membershipChange{Target: user, NewRole: models.HospitalRoleViewer, HospitalAdmins: 1},
+		},
+		{
+			name:    "hospital admin cannot enrol a member of another hospital",
+			change:  membershipChange{Target: user, TargetHospitals: 1, NewRole: models.HospitalRoleViewer, HospitalAdmins: 1},
+			wantErr: "user not found",
+		},
+		{
+			name:    "hospital admin cannot probe for super admins",
+			change:  membershipChange{Target: superAdmin, NewRole: models.HospitalRoleViewer, HospitalAdmins: 1},
+			wantErr: "user not found",
+		},
+		{
+			name:    "hospital admin cannot remove a non-member",
+			change:  membershipChange{Target: user, HospitalAdmins: 1},
+			wantErr: "user not found",
+		},
+		{
+			name:    "hospital admin cannot change a super admin member",
+			change:  membershipChange{Target: superAdmin, CurrentRole: models.HospitalRoleViewer, TargetHospitals: 3, NewRole: models.HospitalRoleOperator, HospitalAdmins: 1},
+			wantErr: "access denied",
+		},
+		{
+			name:   "super admin enrols a member of another hospital",
+			change: membershipChange{ActorIsSuperAdmin: true, Target: user, TargetHospitals: 2, NewRole: models.HospitalRoleViewer, HospitalAdmins: 1},
+		},
+		{
+			name:    "super admin removing a non-member is told so",
+			change:  membershipChange{ActorIsSuperAdmin: true, Target: user, TargetHospitals: 1, HospitalAdmins: 1},
+			wantErr: "user is not a member",
+		},
+		{
+			name:    "super admin accounts cannot be assigned",
+			change:  membershipChange{ActorIsSuperAdmin: true, Target: superAdmin, NewRole: models.HospitalRoleHospitalAdmin, HospitalAdmins: 1},
+			wantErr: "access denied",
+		},
+		{
+			name:    "super admin accounts cannot be removed",
+			change:  membershipChange{ActorIsSuperAdmin: true, Target: superAdmin, CurrentRole: models.HospitalRoleViewer, TargetHospitals: 3, HospitalAdmins: 1},
+			wantErr: "access denied",
+		},
+		{
+			name:    "last hospital admin cannot be demoted",
+			change:  membershipChange{Target: user, CurrentRole: models.HospitalRoleHospitalAdmin, TargetHospitals: 1, NewRole: models.HospitalRoleOperator, HospitalAdmins: 1},
+			wantErr: "cannot remove or demote the last hospital admin",
+		},
+		{
+			name:    "last hospital admin cannot be removed, even by a super admin",
+			change:  membershipChange{ActorIsSuperAdmin: true, Target: user, CurrentRole: models.HospitalRoleHospitalAdmin, TargetHospitals: 1, HospitalAdmins: 1},
+			wantErr: "cannot remove or demote the last hospital admin",
+		},
+		{
+			name:   "hospital admin can be demoted while another remains",
+			change: membershipChange{Target: user, CurrentRole: models.HospitalRoleHospitalAdmin, TargetHospitals: 1, NewRole: models.HospitalRoleViewer, HospitalAdmins: 2},
+		},
+		{
+			name:   "last hospital admin keeps the role on reassignment",
+			change: membershipChange{Target: user, CurrentRole: models.HospitalRoleHospitalAdmin, TargetHospitals: 1, NewRole: models.HospitalRoleHospitalAdmin, HospitalAdmins: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		err := checkMembershipChange(tt.change)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: got error %v, want the change allowed", tt.name, err)
+		case tt.wantErr != "" && err == nil:
+			t.Errorf("%s: change allowed, want error %q", tt.name, tt.wantErr)
+		case tt.wantErr != "" && !strings.HasPrefix(err.Error(), tt.wantErr):
+			t.Errorf("%s: got error %q, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+)
+
+// LiveStateAnnotator fills in the computed fields of live states: data freshness, dashboard sensors and the stopwatch timeline
+// REST responses and the event stream annotate through the same annotator so both show the same state
+type LiveStateAnnotator struct {
+	stalenessService    *StalenessService
+	sensorSchemaService *SensorSchemaService
+	sessionService      *OperationSessionService
+}
+
+func NewLiveStateAnnotator(
+	stalenessService *StalenessService,
+	sensorSchemaService *SensorSchemaService,
+	sessionService *OperationSessionService,
+) *LiveStateAnnotator {
+	return &LiveStateAnnotator{
+		stalenessService:    stalenessService,
+		sensorSchemaService: sensorSchemaService,
+		sessionService:      sessionService,
+	}
+}
+
+// Annotate fills in the computed fields of a live state
+func (a *LiveStateAnnotator) Annotate(state *models.TheaterLiveState, now time.Time) {
+	a.AnnotateAll([]*models.TheaterLiveState{state}, now)
+}
+
+// AnnotateAll fills in the computed fields of several live states, loading their timelines in one query
+func (a *LiveStateAnnotator) AnnotateAll(states []*models.TheaterLiveState, now time.Time) {
+	for _, state := range states {
+		a.stalenessService.Annotate(state, now)
+		a.sensorSchemaService.Annotate(state)
+	}
+	if a.sessionService != nil {
+		a.sessionService.AnnotateTimelines(states)
+	}
+}
+
+// liveStatePointers returns pointers to the elements of a live state slice
+func liveStatePointers(states []models.TheaterLiveState) []*models.TheaterLiveState {
+	pointers := make([]*models.TheaterLiveState, len(states))
+	for i := range states {
+		pointers[i] = &states[i]
+	}
+	return pointers
+}
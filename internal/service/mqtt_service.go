@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"iot-backend-room-monitoring/internal/config"
+	"iot-backend-room-monitoring/internal/metrics"
+	"iot-backend-room-monitoring/internal/repository"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	mqttHospitalPlaceholder = "{code}"
+	mqttRoomPlaceholder     = "{room_code}"
+)
+
+// MQTTTelemetryMessage is the payload published by ESP32 devices over MQTT
+// It is the HTTP telemetry body plus the device's API key, since MQTT has no request headers
+type MQTTTelemetryMessage struct {
+	APIKey string `json:"api_key"`
+	TelemetryUpdateRequest
+}
+
+// mqttMessage is a received telemetry message waiting to be processed
+type mqttMessage struct {
+	topic   string
+	payload []byte
+}
+
+// MQTTService subscribes to device telemetry topics and feeds readings into the ESP32 ingestion path
+type MQTTService struct {
+	cfg           config.MQTTConfig
+	esp32Service  *ESP32Service
+	apiKeyService *DeviceAPIKeyService
+	hospitalRepo  *repository.HospitalRepository
+	roomRepo      *repository.RoomRepository
+
+	client mqtt.Client
+	done   <-chan struct{} // Closed when Start's context is cancelled
+
+	// Each topic always maps to the same queue, so a room's readings are processed in order
+	queues []chan mqttMessage
+}
+
+func NewMQTTService(
+	cfg config.MQTTConfig,
+	esp32Service *ESP32Service,
+	apiKeyService *DeviceAPIKeyService,
+	hospitalRepo *repository.HospitalRepository,
+	roomRepo *repository.RoomRepository,
+) *MQTTService {
+	queues := make([]chan mqttMessage, cfg.Workers)
+	for i := range queues {
+		queues[i] = make(chan mqttMessage, cfg.QueueSize)
+	}
+
+	return &MQTTService{
+		cfg:           cfg,
+		esp32Service:  esp32Service,
+		apiKeyService: apiKeyService,
+		hospitalRepo:  hospitalRepo,
+		roomRepo:      roomRepo,
+		queues:        queues,
+	}
+}
+
+// Start connects to the broker and processes telemetry messages until the context is cancelled
+// The client reconnects automatically and re-subscribes on every connection
+func (s *MQTTService) Start(ctx context.Context) {
+	filter, err := mqttSubscriptionFilter(s.cfg.TopicPattern)
+	if err != nil {
+		log.Printf("MQTT ingestion disabled: %v", err)
+		return
+	}
+
+	s.done = ctx.Done()
+	var wg sync.WaitGroup
+	for i := range s.queues {
+		wg.Add(1)
+		go func(messages chan mqttMessage) {
+			defer wg.Done()
+			s.runQueue(ctx, messages)
+		}(s.queues[i])
+	}
+	defer wg.Wait()
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(s.cfg.BrokerURL).
+		SetClientID(s.cfg.ClientID).
+		SetUsername(s.cfg.Username).
+		SetPassword(s.cfg.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10 * time.Second)
+
+	opts.SetOnConnectHandler(func(client mqtt.Client) {
+		token := client.Subscribe(filter, s.cfg.QoS, s.handleMessage)
+		token.Wait()
+		if err := token.Error(); err != nil {
+			log.Printf("Error subscribing to MQTT topic %s: %v", filter, err)
+			return
+		}
+		log.Printf("MQTT subscribed to %s on %s", filter, s.cfg.BrokerURL)
+	})
+	opts.SetConnectionLostHandler(func(client mqtt.Client, err error) {
+		log.Printf("MQTT connection lost: %v", err)
+	})
+
+	s.client = mqtt.NewClient(opts)
+	// With ConnectRetry enabled the token only completes once connected, so don't wait on it
+	s.client.Connect()
+
+	<-ctx.Done()
+	s.client.Disconnect(250)
+	log.Println("MQTT subscriber stopped")
+}
+
+// handleMessage hands a telemetry message to its topic's queue
+// It runs on the client's ordered message callback, so the key lookups and database writes happen
+// on the queue workers instead. A full queue blocks the callback, which holds back further
+// messages at the broker rather than dropping them
+func (s *MQTTService) handleMessage(client mqtt.Client, msg mqtt.Message) {
+	select {
+	case s.queueFor(msg.Topic()) <- mqttMessage{topic: msg.Topic(), payload: msg.Payload()}:
+	case <-s.done:
+	}
+}
+
+// queueFor picks the queue for a topic
+func (s *MQTTService) queueFor(topic string) chan mqttMessage {
+	h := fnv.New32a()
+	h.Write([]byte(topic))
+	return s.queues[h.Sum32()%uint32(len(s.queues))]
+}
+
+// runQueue processes queued messages until the context is cancelled
+// Messages are fire-and-forget, so failures are logged rather than returned to the device
+func (s *MQTTService) runQueue(ctx context.Context, messages chan mqttMessage) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-messages:
+			if err := s.ProcessMessage(msg.topic, msg.payload); err != nil {
+				log.Printf("Rejected MQTT telemetry on %s: %v", msg.topic, err)
+			}
+		}
+	}
+}
+
+// ProcessMessage resolves the room from the topic, validates the API key and updates telemetry
+func (s *MQTTService) ProcessMessage(topic string, payload []byte) error {
+	hospitalCode, roomCode, err := parseMQTTTopic(s.cfg.TopicPattern, topic)
+	if err != nil {
+		return err
+	}
+
+	hospital, err := s.hospitalRepo.GetHospitalByCode(hospitalCode)
+	if err != nil {
+		return fmt.Errorf("hospital %s: %w", hospitalCode, err)
+	}
+	room, err := s.roomRepo.GetRoomByCodeAndHospital(roomCode, hospital.ID)
+	if err != nil {
+		return fmt.Errorf("room %s: %w", roomCode, err)
+	}
+
+	var message MQTTTelemetryMessage
+	if err := json.Unmarshal(payload, &message); err != nil {
+		metrics.TelemetryRejected.WithLabelValues(metrics.RoomLabel(room.ID), metrics.RejectMalformed).Inc()
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	key, err := s.apiKeyService.AuthenticateAPIKey(strings.TrimSpace(message.APIKey), room.ID)
+	if err != nil {
+		return err
+	}
+	// The broker hides the device's address, so only the time is recorded
+	s.apiKeyService.RecordAPIKeyUse(key, "")
+
+	return s.esp32Service.UpdateTelemetry(room.ID, TelemetrySource{APIKeyID: key.ID}, &message.TelemetryUpdateRequest)
+}
+
+// mqttSubscriptionFilter turns the topic pattern into a broker subscription using single-level wildcards
+func mqttSubscriptionFilter(pattern string) (string, error) {
+	segments := strings.Split(pattern, "/")
+	hasHospital, hasRoom := false, false
+	for i, segment := range segments {
+		switch segment {
+		case mqttHospitalPlaceholder:
+			hasHospital = true
+			segments[i] = "+"
+		case mqttRoomPlaceholder:
+			hasRoom = true
+			segments[i] = "+"
+		}
+	}
+	if !hasHospital || !hasRoom {
+		return "", errors.New("MQTT topic pattern must contain {code} and {room_code} as whole segments")
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// parseMQTTTopic extracts the hospital and room codes from a topic matching the pattern
+func parseMQTTTopic(pattern, topic string) (string, string, error) {
+	patternSegments := strings.Split(pattern, "/")
+	topicSegments := strings.Split(topic, "/")
+	if len(patternSegments) != len(topicSegments) {
+		return "", "", errors.New("topic does not match pattern")
+	}
+
+	var hospitalCode, roomCode string
+	for i, segment := range patternSegments {
+		switch segment {
+		case mqttHospitalPlaceholder:
+			hospitalCode = topicSegments[i]
+		case mqttRoomPlaceholder:
+			roomCode = topicSegments[i]
+		default:
+			if segment != topicSegments[i] {
+				return "", "", errors.New("topic does not match pattern")
+			}
+		}
+	}
+	if hospitalCode == "" || roomCode == "" {
+		return "", "", errors.New("topic is missing hospital or room code")
+	}
+	return hospitalCode, roomCode, nil
+}
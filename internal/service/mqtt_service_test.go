@@ -0,0 +1,185 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"iot-backend-room-monitoring/internal/config"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const testTopicPattern = "hospitals/{code}/rooms/{room_code}/telemetry"
+
+func TestMQTTSubscriptionFilter(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+		wantErr bool
+	}{
+		{pattern: testTopicPattern, want: "hospitals/+/rooms/+/telemetry"},
+		{pattern: "{room_code}/{code}", want: "+/+"},
+		{pattern: "hospitals/{code}/telemetry", wantErr: true},
+		{pattern: "hospitals/rooms/{room_code}", wantErr: true},
+		{pattern: "hospitals/x{code}/rooms/{room_code}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := mqttSubscriptionFilter(tt.pattern)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("mqttSubscriptionFilter(%q) = %q, want error", tt.pattern, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("mqttSubscriptionFilter(%q) returned error: %v", tt.pattern, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("mqttSubscriptionFilter(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestParseMQTTTopic(t *testing.T) {
+	tests := []struct {
+		topic        string
+		wantHospital string
+		wantRoom     string
+		wantErr      bool
+	}{
+		{topic: "hospitals/RSUD01/rooms/OT-1/telemetry", wantHospital: "RSUD01", wantRoom: "OT-1"},
+		{topic: "hospitals/RSUD01/rooms/OT-1/status", wantErr: true},
+		{topic: "hospitals/RSUD01/rooms/OT-1", wantErr: true},
+		{topic: "hospitals/RSUD01/rooms/OT-1/telemetry/extra", wantErr: true},
+		{topic: "clinics/RSUD01/rooms/OT-1/telemetry", wantErr: true},
+		{topic: "hospitals//rooms/OT-1/telemetry", wantErr: true},
+		{topic: "hospitals/RSUD01/rooms//telemetry", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		hospital, room, err := parseMQTTTopic(testTopicPattern, tt.topic)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseMQTTTopic(%q) = %q, %q, want error", tt.topic, hospital, room)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseMQTTTopic(%q) returned error: %v", tt.topic, err)
+			continue
+		}
+		if hospital != tt.wantHospital || room != tt.wantRoom {
+			t.Errorf("parseMQTTTopic(%q) = %q, %q, want %q, %q", tt.topic, hospital, room, tt.wantHospital, tt.wantRoom)
+		}
+	}
+}
+
+func TestMQTTTelemetryMessageDecodesFlatPayload(t *testing.T) {
+	payload := []byte(`{
+		"api_key": "key_abc.secret",
+		"temp": 21.5,
+		"humidity": 48,
+		"room_pressure": 12.5,
+		"logic_ahu": 1,
+		"oxygen": 410,
+		"sensors": {"co2": 620},
+		"sequence": 7
+	}`)
+
+	var message MQTTTelemetryMessage
+	if err := json.Unmarshal(payload, &message); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if message.APIKey != "key_abc.secret" {
+		t.Errorf("APIKey = %q, want key_abc.secret", message.APIKey)
+	}
+	if message.Temp == nil || *message.Temp != 21.5 {
+		t.Errorf("Temp = %v, want 21.5", message.Temp)
+	}
+	if message.Humidity == nil || *message.Humidity != 48 {
+		t.Errorf("Humidity = %v, want 48", message.Humidity)
+	}
+	if message.LogicAhu != 1 {
+		t.Errorf("LogicAhu = %d, want 1", message.LogicAhu)
+	}
+	if message.Nitrous != nil {
+		t.Errorf("Nitrous = %v, want nil for an unreported sensor", *message.Nitrous)
+	}
+	if message.Sensors["co2"] != 620 {
+		t.Errorf("Sensors[co2] = %v, want 620", message.Sensors["co2"])
+	}
+	if message.Sequence == nil || *message.Sequence != 7 {
+		t.Errorf("Sequence = %v, want 7", message.Sequence)
+	}
+}
+
+func TestProcessMessageRejectsTopicOutsidePattern(t *testing.T) {
+	// The topic is checked before any lookup, so no repositories are needed
+	s := NewMQTTService(config.MQTTConfig{TopicPattern: testTopicPattern}, nil, nil, nil, nil)
+
+	for _, topic := range []string{
+		"hospitals/RSUD01/rooms/OT-1/commands",
+		"hospitals/RSUD01/telemetry",
+		"",
+	} {
+		if err := s.ProcessMessage(topic, []byte(`{"api_key":"x"}`)); err == nil {
+			t.Errorf("ProcessMessage(%q) succeeded, want error", topic)
+		}
+	}
+}
+
+func TestQueueForKeepsTopicsOnOneQueue(t *testing.T) {
+	s := NewMQTTService(config.MQTTConfig{TopicPattern: testTopicPattern, Workers: 4, QueueSize: 1}, nil, nil, nil, nil)
+
+	used := make(map[chan mqttMessage]bool)
+	for i := 1; i <= 20; i++ {
+		topic := fmt.Sprintf("hospitals/RSUD01/rooms/OT-%d/telemetry", i)
+		if s.queueFor(topic) != s.queueFor(topic) {
+			t.Fatalf("%s mapped to different queues", topic)
+		}
+		used[s.queueFor(topic)] = true
+	}
+	if len(used) < 2 {
+		t.Error("20 rooms all went to one queue, want them spread over the workers")
+	}
+}
+
+// fakeMQTTMessage is a received message for driving the subscription callback
+type fakeMQTTMessage struct {
+	mqtt.Message
+	topic   string
+	payload []byte
+}
+
+func (m fakeMQTTMessage) Topic() string   { return m.topic }
+func (m fakeMQTTMessage) Payload() []byte { return m.payload }
+
+func TestHandleMessageReturnsAfterShutdown(t *testing.T) {
+	s := NewMQTTService(config.MQTTConfig{TopicPattern: testTopicPattern, Workers: 1, QueueSize: 1}, nil, nil, nil, nil)
+	done := make(chan struct{})
+	s.done = done
+	msg := fakeMQTTMessage{topic: "hospitals/RSUD01/rooms/OT-1/telemetry", payload: []byte(`{}`)}
+
+	s.handleMessage(nil, msg)
+	if got := len(s.queues[0]); got != 1 {
+		t.Fatalf("queued %d messages, want 1", got)
+	}
+
+	// The queue is full and nobody drains it; once stopped the callback must not hang
+	close(done)
+	returned := make(chan struct{})
+	go func() {
+		s.handleMessage(nil, msg)
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("handleMessage blocked on a full queue after shutdown")
+	}
+}
@@ -0,0 +1,511 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"iot-backend-room-monitoring/internal/config"
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+)
+
+// notificationDeliveryInterval is how often the delivery job looks for due notifications
+const notificationDeliveryInterval = 5 * time.Second
+
+// notificationDeliveryBatch is the number of due notifications attempted per tick
+const notificationDeliveryBatch = 50
+
+// notificationPruneInterval is how often notifications past the retention period are deleted
+const notificationPruneInterval = time.Hour
+
+// notificationPruneBatch is the number of notifications deleted per statement
+const notificationPruneBatch = 1000
+
+// notificationDeliveryLease is how long a claimed notification is held before another attempt may send it
+// It covers a slow SMTP exchange so the email isn't sent twice
+const notificationDeliveryLease = 2 * time.Minute
+
+// notificationEventTypes lists the events a channel can subscribe to
+var notificationEventTypes = []string{
+	models.NotificationCountdownExpired,
+	models.NotificationCountdownMissed,
+}
+
+// Notification is a room event sent to notification channels, queued as JSON until delivered
+type Notification struct {
+	Event      string                 `json:"event"`
+	RoomID     uint                   `json:"room_id"`
+	RoomCode   string                 `json:"room_code"`
+	RoomName   string                 `json:"room_name"`
+	HospitalID uint                   `json:"hospital_id"`
+	Subject    string                 `json:"subject"`
+	Message    string                 `json:"message"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	At         time.Time              `json:"at"`
+}
+
+// NotificationChannelRequest is the body for creating or updating a notification channel
+type NotificationChannelRequest struct {
+	HospitalID          *uint    `json:"hospital_id"`
+	Name                string   `json:"name" binding:"required,max=100"`
+	Type                string   `json:"type" binding:"required,oneof=email"` // Webhooks are webhook subscriptions
+	Recipients          []string `json:"recipients" binding:"omitempty,dive,email"`
+	NotifyHospitalStaff *bool    `json:"notify_hospital_staff"` // Defaults to true
+	EventTypes          []string `json:"event_types"`           // Empty = every event
+	IsActive            *bool    `json:"is_active"`             // Defaults to true
+}
+
+// Mailer sends plain text email
+type Mailer interface {
+	Send(to []string, subject, body string) error
+}
+
+// NewMailer returns an SMTP mailer, or a stand-in that logs emails when no SMTP host is configured
+func NewMailer(cfg config.NotificationConfig) Mailer {
+	if cfg.SMTPHost == "" {
+		return logMailer{}
+	}
+	return &smtpMailer{cfg: cfg}
+}
+
+// smtpMailer sends email through the configured SMTP server
+type smtpMailer struct {
+	cfg config.NotificationConfig
+}
+
+func (m *smtpMailer) Send(to []string, subject, body string) error {
+	var auth smtp.Auth
+	if m.cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", m.cfg.SMTPUsername, m.cfg.SMTPPassword, m.cfg.SMTPHost)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.cfg.SMTPFrom)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(body)
+
+	return smtp.SendMail(m.cfg.SMTPHost+":"+m.cfg.SMTPPort, auth, m.cfg.SMTPFrom, to, msg.Bytes())
+}
+
+// logMailer is the SMTP stand-in for development: it only logs the email
+type logMailer struct{}
+
+func (logMailer) Send(to []string, subject, body string) error {
+	log.Printf("[email] to=%s subject=%q body=%q", strings.Join(to, ","), subject, body)
+	return nil
+}
+
+// NotificationService manages notification channels and delivers room notifications to them
+type NotificationService struct {
+	notificationRepo *repository.NotificationRepository
+	userHospitalRepo *repository.UserHospitalRepository
+	auditRepo        *repository.AuditRepository
+	mailer           Mailer
+	cfg              config.NotificationConfig
+}
+
+func NewNotificationService(
+	notificationRepo *repository.NotificationRepository,
+	userHospitalRepo *repository.UserHospitalRepository,
+	auditRepo *repository.AuditRepository,
+	mailer Mailer,
+	cfg config.NotificationConfig,
+) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		userHospitalRepo: userHospitalRepo,
+		auditRepo:        auditRepo,
+		mailer:           mailer,
+		cfg:              cfg,
+	}
+}
+
+// Notify queues a notification for every active channel of the room's hospital subscribed to its event
+// Delivery happens on the delivery job's next tick, with retries; failures to queue are logged
+func (s *NotificationService) Notify(notification Notification) {
+	if notification.At.IsZero() {
+		notification.At = time.Now()
+	}
+	channels, err := s.notificationRepo.GetActiveChannelsForHospital(notification.HospitalID)
+	if err != nil {
+		log.Printf("Error loading notification channels for hospital_id=%d: %v", notification.HospitalID, err)
+		return
+	}
+
+	var payload []byte
+	deliveries := make([]models.NotificationDelivery, 0, len(channels))
+	for i := range channels {
+		if !channelWantsEvent(&channels[i], notification.Event) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(notification); err != nil {
+				log.Printf("Error encoding %s notification: %v", notification.Event, err)
+				return
+			}
+		}
+		delivery := models.NotificationDelivery{
+			ChannelID:     channels[i].ID,
+			EventType:     notification.Event,
+			Payload:       string(payload),
+			Status:        models.NotificationDeliveryPending,
+			NextAttemptAt: time.Now(),
+		}
+		if notification.RoomID != 0 {
+			roomID := notification.RoomID
+			delivery.RoomID = &roomID
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := s.notificationRepo.CreateDeliveries(deliveries); err != nil {
+		log.Printf("Error queueing %s notifications: %v", notification.Event, err)
+	}
+}
+
+// StartDeliveryJob periodically delivers queued notifications and prunes old ones until the context is cancelled
+func (s *NotificationService) StartDeliveryJob(ctx context.Context) {
+	ticker := time.NewTicker(notificationDeliveryInterval)
+	defer ticker.Stop()
+	pruneTicker := time.NewTicker(notificationPruneInterval)
+	defer pruneTicker.Stop()
+
+	s.DeliverDue(time.Now())
+	s.PruneDeliveries(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.DeliverDue(now)
+		case now := <-pruneTicker.C:
+			s.PruneDeliveries(now)
+		}
+	}
+}
+
+// PruneDeliveries deletes delivered and failed notifications older than the retention period
+func (s *NotificationService) PruneDeliveries(now time.Time) {
+	if s.cfg.Retention <= 0 {
+		return
+	}
+	cutoff := now.Add(-s.cfg.Retention)
+
+	var total int64
+	for {
+		deleted, err := s.notificationRepo.DeleteFinishedDeliveriesBefore(cutoff, notificationPruneBatch)
+		if err != nil {
+			log.Printf("Error pruning notification deliveries: %v", err)
+			break
+		}
+		total += deleted
+		if deleted < notificationPruneBatch {
+			break
+		}
+	}
+	if total > 0 {
+		log.Printf("Pruned %d notification deliveries older than %s", total, cutoff.Format(time.RFC3339))
+	}
+}
+
+// DeliverDue attempts every pending notification whose next attempt is due
+func (s *NotificationService) DeliverDue(now time.Time) {
+	deliveries, err := s.notificationRepo.GetDueDeliveries(now, notificationDeliveryBatch)
+	if err != nil {
+		log.Printf("Error fetching due notifications: %v", err)
+		return
+	}
+
+	leaseUntil := now.Add(notificationDeliveryLease)
+
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		claimed, err := s.notificationRepo.ClaimDelivery(delivery.ID, now, leaseUntil)
+		if err != nil {
+			log.Printf("Error claiming notification delivery %d: %v", delivery.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.attempt(delivery)
+		}()
+	}
+	wg.Wait()
+}
+
+// attempt sends a queued notification once and records the outcome, scheduling a retry on failure
+func (s *NotificationService) attempt(delivery *models.NotificationDelivery) {
+	channelActive := delivery.Channel != nil && delivery.Channel.IsActive
+
+	var err error
+	if !channelActive {
+		err = errors.New("channel is inactive")
+	} else {
+		var notification Notification
+		if err = json.Unmarshal([]byte(delivery.Payload), &notification); err == nil {
+			err = s.deliver(delivery.Channel, &notification)
+		}
+	}
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+
+	switch {
+	case err == nil:
+		delivery.Status = models.NotificationDeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= s.cfg.MaxAttempts || !channelActive:
+		delivery.Status = models.NotificationDeliveryFailed
+		delivery.LastError = truncate(err.Error(), 500)
+		log.Printf("Notification delivery %d (%s) to channel %d failed after %d attempts: %v",
+			delivery.ID, delivery.EventType, delivery.ChannelID, delivery.Attempts, err)
+	default:
+		delivery.NextAttemptAt = now.Add(backoffDelay(delivery.Attempts, s.cfg.RetryBaseDelay, s.cfg.RetryMaxDelay))
+		delivery.LastError = truncate(err.Error(), 500)
+	}
+
+	if err := s.notificationRepo.UpdateDelivery(delivery); err != nil {
+		log.Printf("Error saving notification delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// backoffDelay is the exponential backoff after the given number of failed attempts
+// The base delay doubles after each further failure, up to maxDelay
+func backoffDelay(attempts int, base, maxDelay time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// deliver sends a notification through one channel
+func (s *NotificationService) deliver(channel *models.NotificationChannel, notification *Notification) error {
+	switch channel.Type {
+	case models.NotificationChannelEmail:
+		recipients, err := s.emailRecipients(channel, notification.HospitalID)
+		if err != nil {
+			return err
+		}
+		if len(recipients) == 0 {
+			return nil
+		}
+		return s.mailer.Send(recipients, notification.Subject, notification.Message)
+	}
+	return fmt.Errorf("unknown channel type %q", channel.Type)
+}
+
+// emailRecipients combines a channel's extra recipients with the hospital's staff
+func (s *NotificationService) emailRecipients(channel *models.NotificationChannel, hospitalID uint) ([]string, error) {
+	seen := make(map[string]bool)
+	var recipients []string
+	add := func(address string) {
+		address = strings.TrimSpace(address)
+		key := strings.ToLower(address)
+		if address != "" && !seen[key] {
+			seen[key] = true
+			recipients = append(recipients, address)
+		}
+	}
+
+	for _, address := range strings.Split(channel.Recipients, ",") {
+		add(address)
+	}
+	if channel.NotifyHospitalStaff {
+		staff, err := s.userHospitalRepo.GetHospitalUserEmails(hospitalID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch hospital staff: %w", err)
+		}
+		for _, address := range staff {
+			add(address)
+		}
+	}
+	return recipients, nil
+}
+
+// channelWantsEvent reports whether a channel subscribes to an event type
+func channelWantsEvent(channel *models.NotificationChannel, event string) bool {
+	return commaListContains(channel.EventTypes, event)
+}
+
+// commaListContains reports whether a comma separated event type list includes an event
+// An empty list includes every event
+func commaListContains(list, event string) bool {
+	if strings.TrimSpace(list) == "" {
+		return true
+	}
+	for _, eventType := range strings.Split(list, ",") {
+		if strings.TrimSpace(eventType) == event {
+			return true
+		}
+	}
+	return false
+}
+
+// GetChannels lists every notification channel (admin only)
+func (s *NotificationService) GetChannels() ([]models.NotificationChannel, error) {
+	channels, err := s.notificationRepo.GetChannels()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch notification channels: %w", err)
+	}
+	return channels, nil
+}
+
+// CreateChannel creates a notification channel (admin only)
+func (s *NotificationService) CreateChannel(req *NotificationChannelRequest, userID uint) (*models.NotificationChannel, error) {
+	channel := &models.NotificationChannel{}
+	if err := applyChannelRequest(channel, req); err != nil {
+		return nil, err
+	}
+	if err := s.notificationRepo.CreateChannel(channel); err != nil {
+		return nil, fmt.Errorf("failed to create notification channel: %w", err)
+	}
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Created %s notification channel ID: %d %q", channel.Type, channel.ID, channel.Name)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "notification_channel_create", details)
+
+	return channel, nil
+}
+
+// UpdateChannel updates a notification channel (admin only)
+func (s *NotificationService) UpdateChannel(channelID uint, req *NotificationChannelRequest, userID uint) (*models.NotificationChannel, error) {
+	channel, err := s.notificationRepo.GetChannelByID(channelID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyChannelRequest(channel, req); err != nil {
+		return nil, err
+	}
+	if err := s.notificationRepo.UpdateChannel(channel); err != nil {
+		return nil, fmt.Errorf("failed to update notification channel: %w", err)
+	}
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Updated %s notification channel ID: %d %q", channel.Type, channel.ID, channel.Name)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "notification_channel_update", details)
+
+	return channel, nil
+}
+
+// DeleteChannel deletes a notification channel (admin only)
+func (s *NotificationService) DeleteChannel(channelID uint, userID uint) error {
+	channel, err := s.notificationRepo.GetChannelByID(channelID)
+	if err != nil {
+		return err
+	}
+	if err := s.notificationRepo.DeleteChannel(channel.ID); err != nil {
+		return fmt.Errorf("failed to delete notification channel: %w", err)
+	}
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Deleted %s notification channel ID: %d %q", channel.Type, channel.ID, channel.Name)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "notification_channel_delete", details)
+
+	return nil
+}
+
+// TestChannel sends a test notification through a channel and reports the outcome (admin only)
+// A channel without a hospital filter has no staff to email, so only its extra recipients get the test
+func (s *NotificationService) TestChannel(channelID uint, userID uint) error {
+	channel, err := s.notificationRepo.GetChannelByID(channelID)
+	if err != nil {
+		return err
+	}
+
+	notification := &Notification{
+		Event:   "test",
+		Subject: "Test notification",
+		Message: fmt.Sprintf("This is a test of notification channel %q.", channel.Name),
+		At:      time.Now(),
+	}
+	if channel.HospitalID != nil {
+		notification.HospitalID = *channel.HospitalID
+	} else {
+		channel.NotifyHospitalStaff = false
+	}
+
+	if err := s.deliver(channel, notification); err != nil {
+		return fmt.Errorf("test notification failed: %v", err)
+	}
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Sent test notification through channel ID: %d %q", channel.ID, channel.Name)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "notification_channel_test", details)
+
+	return nil
+}
+
+// applyChannelRequest validates a channel request and copies it onto the channel
+func applyChannelRequest(channel *models.NotificationChannel, req *NotificationChannelRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return errors.New("channel name is required")
+	}
+
+	notifyStaff := req.NotifyHospitalStaff == nil || *req.NotifyHospitalStaff
+	if req.Type == models.NotificationChannelEmail && len(req.Recipients) == 0 && !notifyStaff {
+		return errors.New("email channels need recipients or notify_hospital_staff")
+	}
+	for _, eventType := range req.EventTypes {
+		if !isNotificationEventType(eventType) {
+			return fmt.Errorf("unknown event type %q, must be one of: %s", eventType, strings.Join(notificationEventTypes, ", "))
+		}
+	}
+
+	channel.HospitalID = req.HospitalID
+	channel.Name = name
+	channel.Type = req.Type
+	channel.Recipients = ""
+	channel.NotifyHospitalStaff = false
+	switch req.Type {
+	case models.NotificationChannelEmail:
+		channel.Recipients = strings.Join(req.Recipients, ",")
+		channel.NotifyHospitalStaff = notifyStaff
+	}
+	channel.EventTypes = strings.Join(req.EventTypes, ",")
+	channel.IsActive = req.IsActive == nil || *req.IsActive
+	return nil
+}
+
+func isNotificationEventType(eventType string) bool {
+	for _, known := range notificationEventTypes {
+		if known == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate shortens a string to at most n bytes without splitting a UTF-8 character
+func truncate(value string, n int) string {
+	if len(value) <= n {
+		return value
+	}
+	for n > 0 && !utf8.RuneStart(value[n]) {
+		n--
+	}
+	return value[:n]
+}
@@ -0,0 +1,512 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+)
+
+// defaultUtilizationWindow is the utilization period when no from is given
+const defaultUtilizationWindow = 7 * 24 * time.Hour
+
+// maxUtilizationWindow caps the period of a utilization summary
+const maxUtilizationWindow = 366 * 24 * time.Hour
+
+// RoomUtilization summarizes how long a room's operation stopwatch ran during a period
+type RoomUtilization struct {
+	RoomID                uint      `json:"room_id"`
+	RoomCode              string    `json:"room_code"`
+	RoomName              string    `json:"room_name"`
+	From                  time.Time `json:"from"`
+	To                    time.Time `json:"to"`
+	WindowSeconds         int64     `json:"window_seconds"`
+	OperatingSeconds      int64     `json:"operating_seconds"` // Stopwatch running time inside the period
+	UtilizationPercent    float64   `json:"utilization_percent"`
+	SessionCount          int       `json:"session_count"`           // Sessions that ran during the period
+	CompletedSessionCount int       `json:"completed_session_count"` // Of those, sessions already reset
+	AverageSessionSeconds int64     `json:"average_session_seconds"` // Over completed sessions
+	LongestSessionSeconds int64     `json:"longest_session_seconds"` // Over completed sessions
+}
+
+// HospitalUtilization summarizes the utilization of every room of a hospital
+type HospitalUtilization struct {
+	HospitalID         uint              `json:"hospital_id"`
+	From               time.Time         `json:"from"`
+	To                 time.Time         `json:"to"`
+	OperatingSeconds   int64             `json:"operating_seconds"`
+	UtilizationPercent float64           `json:"utilization_percent"` // Average over rooms
+	SessionCount       int               `json:"session_count"`
+	Rooms              []RoomUtilization `json:"rooms"`
+}
+
+// UpdateOperationSessionRequest is the body for editing an operation session
+type UpdateOperationSessionRequest struct {
+	ProcedureLabel string `json:"procedure_label" binding:"max=255"`
+}
+
+// OperationSessionService records operation sessions from stopwatch actions and reports on them
+type OperationSessionService struct {
+	sessionRepo      *repository.OperationSessionRepository
+	roomRepo         *repository.RoomRepository
+	userHospitalRepo *repository.UserHospitalRepository
+	auditRepo        *repository.AuditRepository
+
+	// Serializes stopwatch transitions so a room never gets two open sessions
+	mu sync.Mutex
+}
+
+func NewOperationSessionService(
+	sessionRepo *repository.OperationSessionRepository,
+	roomRepo *repository.RoomRepository,
+	userHospitalRepo *repository.UserHospitalRepository,
+	auditRepo *repository.AuditRepository,
+) *OperationSessionService {
+	return &OperationSessionService{
+		sessionRepo:      sessionRepo,
+		roomRepo:         roomRepo,
+		userHospitalRepo: userHospitalRepo,
+		auditRepo:        auditRepo,
+	}
+}
+
+// RecordStart opens a session with a start marker when the stopwatch starts
+// An open session left over from an out-of-step stopwatch is resumed instead
+func (s *OperationSessionService) RecordStart(roomID uint, procedureLabel string, userID uint, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.openSession(roomID)
+	if !ok {
+		return
+	}
+
+	if session == nil {
+		session = &models.OperationSession{
+			RoomID:         roomID,
+			ProcedureLabel: procedureLabel,
+			Status:         models.OperationSessionRunning,
+			StartedAt:      at,
+			StartedBy:      &userID,
+			Intervals:      []models.OperationInterval{{StartedAt: at, StartedBy: &userID}},
+			Markers: []models.OperationMarker{{
+				RoomID:     roomID,
+				Type:       models.OperationMarkerStart,
+				OccurredAt: at,
+				UserID:     &userID,
+			}},
+		}
+		if err := s.sessionRepo.CreateSession(session); err != nil {
+			log.Printf("Error creating operation session for room_id=%d: %v", roomID, err)
+		}
+		return
+	}
+
+	if procedureLabel != "" {
+		session.ProcedureLabel = procedureLabel
+	}
+	s.resume(session, "", userID, at)
+}
+
+// RecordResume resumes the paused session of a room with a resume marker
+func (s *OperationSessionService) RecordResume(roomID uint, reason string, userID uint, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.openSession(roomID)
+	if !ok || session == nil {
+		return
+	}
+	s.resume(session, reason, userID, at)
+}
+
+// resume opens a new interval on a paused session
+// Already running means the stopwatch and session got out of step; keep the open interval
+func (s *OperationSessionService) resume(session *models.OperationSession, reason string, userID uint, at time.Time) {
+	if session.Status == models.OperationSessionRunning {
+		return
+	}
+
+	session.Status = models.OperationSessionRunning
+	interval := &models.OperationInterval{StartedAt: at, StartedBy: &userID}
+	marker := newOperationMarker(session, models.OperationMarkerResume, "", reason, userID, at)
+	if err := s.sessionRepo.SaveSession(session, interval, marker); err != nil {
+		log.Printf("Error resuming operation session %d: %v", session.ID, err)
+	}
+}
+
+// RecordPause closes the running interval when the stopwatch is paused, with a pause marker
+func (s *OperationSessionService) RecordPause(roomID uint, reason string, userID uint, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.openSession(roomID)
+	if !ok || session == nil || session.Status != models.OperationSessionRunning {
+		return
+	}
+
+	interval := closeOpenInterval(session, userID, at)
+	session.Status = models.OperationSessionPaused
+	marker := newOperationMarker(session, models.OperationMarkerPause, "", reason, userID, at)
+	if err := s.sessionRepo.SaveSession(session, interval, marker); err != nil {
+		log.Printf("Error pausing operation session %d: %v", session.ID, err)
+	}
+}
+
+// RecordStop completes the open session with a stop marker when the stopwatch is stopped or reset
+func (s *OperationSessionService) RecordStop(roomID uint, reason string, userID uint, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.openSession(roomID)
+	if !ok || session == nil {
+		return
+	}
+
+	var interval *models.OperationInterval
+	if session.Status == models.OperationSessionRunning {
+		interval = closeOpenInterval(session, userID, at)
+	}
+	session.Status = models.OperationSessionCompleted
+	session.EndedAt = &at
+	session.StoppedBy = &userID
+	marker := newOperationMarker(session, models.OperationMarkerStop, "", reason, userID, at)
+	if err := s.sessionRepo.SaveSession(session, interval, marker); err != nil {
+		log.Printf("Error completing operation session %d: %v", session.ID, err)
+	}
+}
+
+// RecordLap adds a named lap marker to the open session of a room
+func (s *OperationSessionService) RecordLap(roomID uint, name string, userID uint, at time.Time) (*models.OperationMarker, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, err := s.sessionRepo.GetOpenSessionByRoomID(roomID)
+	if err != nil {
+		if err.Error() == "operation session not found" {
+			return nil, errors.New("no operation in progress for this room")
+		}
+		return nil, fmt.Errorf("failed to load operation session: %w", err)
+	}
+
+	marker := newOperationMarker(session, models.OperationMarkerLap, name, "", userID, at)
+	if err := s.sessionRepo.SaveSession(session, nil, marker); err != nil {
+		return nil, fmt.Errorf("failed to record lap marker: %w", err)
+	}
+	return marker, nil
+}
+
+// AnnotateTimelines sets the markers of each room's latest session on the live states whose stopwatch is in use
+// The sessions of every room are loaded in one query
+func (s *OperationSessionService) AnnotateTimelines(states []*models.TheaterLiveState) {
+	var roomIDs []uint
+	for _, state := range states {
+		if stopwatchInUse(state) {
+			roomIDs = append(roomIDs, *state.RoomID)
+		}
+	}
+	if len(roomIDs) == 0 {
+		return
+	}
+
+	sessions, err := s.sessionRepo.GetLatestSessionsByRoomIDs(roomIDs)
+	if err != nil {
+		log.Printf("Error loading operation timelines for %d rooms: %v", len(roomIDs), err)
+		return
+	}
+	// Sessions are in ID order, so a later session started in the same instant wins
+	latest := make(map[uint]*models.OperationSession, len(sessions))
+	for i := range sessions {
+		latest[sessions[i].RoomID] = &sessions[i]
+	}
+
+	for _, state := range states {
+		if !stopwatchInUse(state) {
+			continue
+		}
+		if session, ok := latest[*state.RoomID]; ok {
+			state.OpSessionID = &session.ID
+			state.OpMarkers = session.Markers
+		}
+	}
+}
+
+// stopwatchInUse reports whether a room's operation stopwatch is running, paused or holds a reading
+func stopwatchInUse(state *models.TheaterLiveState) bool {
+	return state.RoomID != nil && (state.OpIsRunning || state.OpIsPaused || state.OpAccumulatedSeconds != 0)
+}
+
+// openSession loads the open session of a room; ok is false when the lookup failed
+func (s *OperationSessionService) openSession(roomID uint) (*models.OperationSession, bool) {
+	session, err := s.sessionRepo.GetOpenSessionByRoomID(roomID)
+	if err != nil {
+		if err.Error() == "operation session not found" {
+			return nil, true
+		}
+		log.Printf("Error loading open operation session for room_id=%d: %v", roomID, err)
+		return nil, false
+	}
+	return session, true
+}
+
+// closeOpenInterval ends the running interval of a session and adds it to the session total
+// Returns nil if no interval is open
+func closeOpenInterval(session *models.OperationSession, userID uint, at time.Time) *models.OperationInterval {
+	for i := range session.Intervals {
+		interval := &session.Intervals[i]
+		if interval.EndedAt != nil {
+			continue
+		}
+		interval.EndedAt = &at
+		interval.StoppedBy = &userID
+		interval.DurationSeconds = int(at.Sub(interval.StartedAt).Seconds())
+		if interval.DurationSeconds < 0 {
+			interval.DurationSeconds = 0
+		}
+		session.TotalSeconds += interval.DurationSeconds
+		return interval
+	}
+	return nil
+}
+
+// newOperationMarker builds a timeline marker holding the session's stopwatch reading at the given time
+func newOperationMarker(session *models.OperationSession, markerType, name, reason string, userID uint, at time.Time) *models.OperationMarker {
+	annotateElapsed(session, at)
+	return &models.OperationMarker{
+		RoomID:         session.RoomID,
+		Type:           markerType,
+		Name:           name,
+		Reason:         reason,
+		ElapsedSeconds: session.ElapsedSeconds,
+		OccurredAt:     at,
+		UserID:         &userID,
+	}
+}
+
+// annotateElapsed sets the elapsed time of a session as of now
+func annotateElapsed(session *models.OperationSession, now time.Time) {
+	session.ElapsedSeconds = session.TotalSeconds
+	for _, interval := range session.Intervals {
+		if interval.EndedAt == nil && now.After(interval.StartedAt) {
+			session.ElapsedSeconds += int(now.Sub(interval.StartedAt).Seconds())
+		}
+	}
+}
+
+// GetSessions lists operation sessions visible to the user
+func (s *OperationSessionService) GetSessions(filter repository.OperationSessionFilter, userID uint, role string) ([]models.OperationSession, int64, error) {
+	if role != models.UserRoleSuperAdmin {
+		hospitalIDs, err := s.userHospitalRepo.GetUserHospitals(userID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if hospitalIDs == nil {
+			hospitalIDs = []uint{}
+		}
+		filter.HospitalIDs = hospitalIDs
+	}
+
+	sessions, total, err := s.sessionRepo.GetSessions(filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch operation sessions: %w", err)
+	}
+	now := time.Now()
+	for i := range sessions {
+		annotateElapsed(&sessions[i], now)
+	}
+	return sessions, total, nil
+}
+
+// GetSession retrieves one operation session with access control
+func (s *OperationSessionService) GetSession(sessionID uint, userID uint, role string) (*models.OperationSession, error) {
+	session, err := s.sessionRepo.GetSessionByID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkRoomAccess(s.roomRepo, s.userHospitalRepo, session.RoomID, userID, role); err != nil {
+		return nil, err
+	}
+	annotateElapsed(session, time.Now())
+	return session, nil
+}
+
+// UpdateSession changes the procedure label of a session (operators of the room's hospital)
+func (s *OperationSessionService) UpdateSession(sessionID uint, req *UpdateOperationSessionRequest, userID uint, role string) (*models.OperationSession, error) {
+	session, err := s.sessionRepo.GetSessionByID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkRoomPermission(s.roomRepo, s.userHospitalRepo, session.RoomID, userID, role, models.PermissionOperateTimers); err != nil {
+		return nil, err
+	}
+
+	if err := s.sessionRepo.UpdateProcedureLabel(session.ID, req.ProcedureLabel); err != nil {
+		return nil, fmt.Errorf("failed to update operation session: %w", err)
+	}
+	session.ProcedureLabel = req.ProcedureLabel
+	annotateElapsed(session, time.Now())
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Updated operation session ID: %d procedure label to %q", session.ID, req.ProcedureLabel)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "operation_session_update", details)
+
+	return session, nil
+}
+
+// GetRoomUtilization summarizes stopwatch use of a room during [from, to) with access control
+func (s *OperationSessionService) GetRoomUtilization(roomID uint, from, to *time.Time, userID uint, role string) (*RoomUtilization, error) {
+	if _, err := checkRoomAccess(s.roomRepo, s.userHospitalRepo, roomID, userID, role); err != nil {
+		return nil, err
+	}
+	start, end, err := utilizationWindow(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	room, err := s.roomRepo.GetRoomByID(roomID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.sessionRepo.GetSessionsOverlapping([]uint{roomID}, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch operation sessions: %w", err)
+	}
+
+	utilization := summarizeUtilization(room, sessions, start, end, time.Now())
+	return &utilization, nil
+}
+
+// GetHospitalUtilization summarizes stopwatch use of every room of a hospital during [from, to)
+func (s *OperationSessionService) GetHospitalUtilization(hospitalID uint, from, to *time.Time, userID uint, role string) (*HospitalUtilization, error) {
+	if role != models.UserRoleSuperAdmin {
+		hasAccess, err := s.userHospitalRepo.UserHasAccessToHospital(userID, hospitalID)
+		if err != nil {
+			return nil, err
+		}
+		if !hasAccess {
+			return nil, errors.New("access denied: you don't have permission to access this hospital")
+		}
+	}
+	start, end, err := utilizationWindow(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	rooms, err := s.roomRepo.GetRoomsByHospitalID(hospitalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rooms: %w", err)
+	}
+	roomIDs := make([]uint, len(rooms))
+	for i := range rooms {
+		roomIDs[i] = rooms[i].ID
+	}
+	sessions, err := s.sessionRepo.GetSessionsOverlapping(roomIDs, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch operation sessions: %w", err)
+	}
+
+	byRoom := make(map[uint][]models.OperationSession, len(rooms))
+	for _, session := range sessions {
+		byRoom[session.RoomID] = append(byRoom[session.RoomID], session)
+	}
+
+	now := time.Now()
+	result := &HospitalUtilization{
+		HospitalID: hospitalID,
+		From:       start,
+		To:         end,
+		Rooms:      make([]RoomUtilization, len(rooms)),
+	}
+	for i := range rooms {
+		room := summarizeUtilization(&rooms[i], byRoom[rooms[i].ID], start, end, now)
+		result.Rooms[i] = room
+		result.OperatingSeconds += room.OperatingSeconds
+		result.SessionCount += room.SessionCount
+		result.UtilizationPercent += room.UtilizationPercent
+	}
+	if len(rooms) > 0 {
+		result.UtilizationPercent = roundPercent(result.UtilizationPercent / float64(len(rooms)))
+	}
+	return result, nil
+}
+
+// utilizationWindow resolves the requested period, defaulting to the last 7 days
+func utilizationWindow(from, to *time.Time) (time.Time, time.Time, error) {
+	end := time.Now()
+	if to != nil {
+		end = *to
+	}
+	start := end.Add(-defaultUtilizationWindow)
+	if from != nil {
+		start = *from
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, errors.New("from must be before to")
+	}
+	if end.Sub(start) > maxUtilizationWindow {
+		return time.Time{}, time.Time{}, errors.New("period cannot be longer than 366 days")
+	}
+	return start, end, nil
+}
+
+// summarizeUtilization adds up the running time of a room's sessions that falls inside [from, to)
+// Open intervals count up to now
+func summarizeUtilization(room *models.Room, sessions []models.OperationSession, from, to, now time.Time) RoomUtilization {
+	utilization := RoomUtilization{
+		RoomID:        room.ID,
+		RoomCode:      room.RoomCode,
+		RoomName:      room.RoomName,
+		From:          from,
+		To:            to,
+		WindowSeconds: int64(to.Sub(from) / time.Second),
+		SessionCount:  len(sessions),
+	}
+
+	var operating time.Duration
+	var completedTotal int64
+	for _, session := range sessions {
+		for _, interval := range session.Intervals {
+			end := now
+			if interval.EndedAt != nil {
+				end = *interval.EndedAt
+			}
+			start := interval.StartedAt
+			if start.Before(from) {
+				start = from
+			}
+			if end.After(to) {
+				end = to
+			}
+			if end.After(start) {
+				operating += end.Sub(start)
+			}
+		}
+
+		if session.Status == models.OperationSessionCompleted {
+			utilization.CompletedSessionCount++
+			completedTotal += int64(session.TotalSeconds)
+			if int64(session.TotalSeconds) > utilization.LongestSessionSeconds {
+				utilization.LongestSessionSeconds = int64(session.TotalSeconds)
+			}
+		}
+	}
+
+	utilization.OperatingSeconds = int64(operating / time.Second)
+	if utilization.WindowSeconds > 0 {
+		utilization.UtilizationPercent = roundPercent(float64(utilization.OperatingSeconds) * 100 / float64(utilization.WindowSeconds))
+	}
+	if utilization.CompletedSessionCount > 0 {
+		utilization.AverageSessionSeconds = completedTotal / int64(utilization.CompletedSessionCount)
+	}
+	return utilization
+}
+
+// roundPercent rounds a percentage to two decimals
+func roundPercent(percent float64) float64 {
+	return math.Round(percent*100) / 100
+}
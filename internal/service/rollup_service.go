@@ -0,0 +1,341 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+)
+
+const (
+	// rollupSettleDelay keeps a bucket open a little after it ends so in-flight inserts land first
+	rollupSettleDelay = 5 * time.Second
+	// rollupMaxCatchUp bounds how much history a single run aggregates after downtime
+	rollupMaxCatchUp = 6 * time.Hour
+	// rollupHistoryPageSize bounds how many raw readings are held in memory at once
+	rollupHistoryPageSize = 5000
+)
+
+// rollupResolution describes one aggregation level
+// Coarser levels are derived from the 1m rollups instead of re-reading raw history
+type rollupResolution struct {
+	Name     string
+	Duration time.Duration
+	Source   string // "" = raw history, otherwise the finer resolution to combine
+}
+
+var rollupResolutions = []rollupResolution{
+	{Name: "1m", Duration: time.Minute},
+	{Name: "15m", Duration: 15 * time.Minute, Source: "1m"},
+	{Name: "1h", Duration: time.Hour, Source: "1m"},
+}
+
+// rollupControlFields are the telemetry control fields aggregated alongside a room's schema sensors
+// volume_ruangan is a constant room property and is not rolled up
+var rollupControlFields = []string{"room_status", "laju_aliran_ahu", "logic_ahu"}
+
+type RollupService struct {
+	historyRepo *repository.TelemetryHistoryRepository
+	rollupRepo  *repository.TelemetryRollupRepository
+
+	// watermarks holds, per resolution, the start of the first bucket not yet aggregated
+	watermarks map[string]time.Time
+
+	// backfillFrom is the oldest reading inserted behind the watermarks since the last run
+	backfillMu   sync.Mutex
+	backfillFrom *time.Time
+}
+
+func NewRollupService(
+	historyRepo *repository.TelemetryHistoryRepository,
+	rollupRepo *repository.TelemetryRollupRepository,
+) *RollupService {
+	return &RollupService{
+		historyRepo: historyRepo,
+		rollupRepo:  rollupRepo,
+		watermarks:  make(map[string]time.Time),
+	}
+}
+
+// rollupAccumulator collects min/max/sum/count for one (room, bucket, sensor)
+type rollupAccumulator struct {
+	min, max, sum float64
+	count         int
+}
+
+func (a *rollupAccumulator) add(min, max, sum float64, count int) {
+	if a.count == 0 || min < a.min {
+		a.min = min
+	}
+	if a.count == 0 || max > a.max {
+		a.max = max
+	}
+	a.sum += sum
+	a.count += count
+}
+
+type rollupKey struct {
+	roomID uint
+	bucket time.Time
+	sensor string
+}
+
+// Backfill marks history from the given time as changed, so closed buckets are recomputed
+// Safe to call from request handlers; the work happens on the next Run
+func (s *RollupService) Backfill(from time.Time) {
+	s.backfillMu.Lock()
+	defer s.backfillMu.Unlock()
+	if s.backfillFrom == nil || from.Before(*s.backfillFrom) {
+		s.backfillFrom = &from
+	}
+}
+
+// Run aggregates every bucket that has closed since the previous run
+// Called periodically by the background worker; it is not safe for concurrent use
+func (s *RollupService) Run(now time.Time) {
+	s.applyBackfill()
+	for _, res := range rollupResolutions {
+		if err := s.runResolution(res, now); err != nil {
+			log.Printf("Error computing %s telemetry rollups: %v", res.Name, err)
+		}
+	}
+}
+
+// runResolution aggregates the closed buckets of a single resolution
+func (s *RollupService) runResolution(res rollupResolution, now time.Time) error {
+	start, ok, err := s.watermark(res)
+	if err != nil || !ok {
+		return err
+	}
+
+	end, ok := s.window(res, start, now)
+	if !ok {
+		return nil
+	}
+
+	var accumulators map[rollupKey]*rollupAccumulator
+	if res.Source == "" {
+		accumulators, err = s.aggregateHistory(res, start, end)
+	} else {
+		accumulators, err = s.aggregateRollups(res, start, end)
+	}
+	if err != nil {
+		return err
+	}
+
+	rollups := make([]models.TelemetryRollup, 0, len(accumulators))
+	for key, acc := range accumulators {
+		rollups = append(rollups, models.TelemetryRollup{
+			RoomID:      key.roomID,
+			Resolution:  res.Name,
+			BucketStart: key.bucket,
+			Sensor:      key.sensor,
+			MinValue:    acc.min,
+			MaxValue:    acc.max,
+			AvgValue:    acc.sum / float64(acc.count),
+			SampleCount: acc.count,
+		})
+	}
+	if err := s.rollupRepo.UpsertRollups(rollups); err != nil {
+		return fmt.Errorf("failed to store rollups: %w", err)
+	}
+
+	s.watermarks[res.Name] = end
+	return nil
+}
+
+// window returns the end of the closed buckets a run can aggregate from start
+// The boolean is false when no bucket has closed since start
+func (s *RollupService) window(res rollupResolution, start, now time.Time) (time.Time, bool) {
+	// Only aggregate buckets that have fully closed
+	end := now.Add(-rollupSettleDelay).Truncate(res.Duration)
+	if res.Source != "" {
+		// Derived levels can't run ahead of their source level
+		if sourceMark, ok := s.watermarks[res.Source]; ok && sourceMark.Before(end) {
+			end = sourceMark.Truncate(res.Duration)
+		}
+	}
+	if limit := start.Add(rollupMaxCatchUp); end.After(limit) {
+		end = limit.Truncate(res.Duration)
+	}
+	return end, end.After(start)
+}
+
+// applyBackfill rewinds watermarks that are past a pending backfill
+// Recomputed buckets are upserted, so rewinding never duplicates rollups
+func (s *RollupService) applyBackfill() {
+	s.backfillMu.Lock()
+	from := s.backfillFrom
+	s.backfillFrom = nil
+	s.backfillMu.Unlock()
+	if from == nil {
+		return
+	}
+
+	for _, res := range rollupResolutions {
+		start := from.Truncate(res.Duration)
+		if mark, ok := s.watermarks[res.Name]; ok && start.Before(mark) {
+			s.watermarks[res.Name] = start
+		}
+	}
+}
+
+// aggregateHistory builds buckets directly from raw history readings
+// History is read in pages so a long catch-up across many rooms stays bounded in memory
+func (s *RollupService) aggregateHistory(res rollupResolution, start, end time.Time) (map[rollupKey]*rollupAccumulator, error) {
+	accumulators := make(map[rollupKey]*rollupAccumulator)
+	var afterID uint
+	for {
+		page, err := s.historyRepo.GetHistoryPageInRange(start, end, afterID, rollupHistoryPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch telemetry history: %w", err)
+		}
+		for i := range page {
+			accumulateHistory(accumulators, res, &page[i])
+		}
+		if len(page) < rollupHistoryPageSize {
+			return accumulators, nil
+		}
+		afterID = page[len(page)-1].ID
+	}
+}
+
+// accumulateHistory adds every reported sensor of one reading to its bucket
+// Ingestion only stores the sensors of the room's schema, so whatever was reported is rolled up
+func accumulateHistory(accumulators map[rollupKey]*rollupAccumulator, res rollupResolution, h *models.TheaterTelemetryHistory) {
+	bucket := h.RecordedAt.Truncate(res.Duration)
+	add := func(sensor string, value float64) {
+		key := rollupKey{roomID: h.RoomID, bucket: bucket, sensor: sensor}
+		acc, exists := accumulators[key]
+		if !exists {
+			acc = &rollupAccumulator{}
+			accumulators[key] = acc
+		}
+		acc.add(value, value, value, 1)
+	}
+
+	for _, def := range builtinSensors {
+		if value, ok := historyFieldFloat(h, def.Name); ok {
+			add(def.Name, value)
+		}
+	}
+	for _, field := range rollupControlFields {
+		if value, ok := historyFieldFloat(h, field); ok {
+			add(field, value)
+		}
+	}
+	for sensor, value := range h.ExtraSensors {
+		add(sensor, value)
+	}
+}
+
+// aggregateRollups combines finer rollups into coarser buckets
+// Averages are weighted by sample count so the result matches aggregating raw history
+func (s *RollupService) aggregateRollups(res rollupResolution, start, end time.Time) (map[rollupKey]*rollupAccumulator, error) {
+	source, err := s.rollupRepo.GetRollupsInRange(res.Source, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s rollups: %w", res.Source, err)
+	}
+	return combineRollups(res, source), nil
+}
+
+// combineRollups adds finer rollups into the coarser buckets of a resolution
+func combineRollups(res rollupResolution, source []models.TelemetryRollup) map[rollupKey]*rollupAccumulator {
+	accumulators := make(map[rollupKey]*rollupAccumulator)
+	for _, r := range source {
+		key := rollupKey{roomID: r.RoomID, bucket: r.BucketStart.Truncate(res.Duration), sensor: r.Sensor}
+		acc, exists := accumulators[key]
+		if !exists {
+			acc = &rollupAccumulator{}
+			accumulators[key] = acc
+		}
+		acc.add(r.MinValue, r.MaxValue, r.AvgValue*float64(r.SampleCount), r.SampleCount)
+	}
+	return accumulators
+}
+
+// watermark returns the first bucket start that still needs aggregating for a resolution
+// On first use it resumes after the newest stored bucket, or from the oldest history reading
+func (s *RollupService) watermark(res rollupResolution) (time.Time, bool, error) {
+	if mark, ok := s.watermarks[res.Name]; ok {
+		return mark, true, nil
+	}
+
+	latest, err := s.rollupRepo.GetLatestBucketStart(res.Name)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to load rollup watermark: %w", err)
+	}
+	if latest != nil {
+		mark := latest.Add(res.Duration)
+		s.watermarks[res.Name] = mark
+		return mark, true, nil
+	}
+
+	earliest, err := s.historyRepo.GetEarliestRecordedAt()
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to load earliest history reading: %w", err)
+	}
+	if earliest == nil {
+		// Nothing recorded yet, try again on the next run
+		return time.Time{}, false, nil
+	}
+	mark := earliest.Truncate(res.Duration)
+	s.watermarks[res.Name] = mark
+	return mark, true, nil
+}
+
+// historyFieldFloat returns a numeric telemetry field from a history row
+// The boolean is false when the sensor did not report a value
+func historyFieldFloat(h *models.TheaterTelemetryHistory, field string) (float64, bool) {
+	switch v := historyFieldValue(h, field).(type) {
+	case *float64:
+		if v == nil {
+			return 0, false
+		}
+		return *v, true
+	case *int:
+		if v == nil {
+			return 0, false
+		}
+		return float64(*v), true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// roomSensorNames lists the telemetry fields of a room with the given schema: its sensors, then the control fields
+func roomSensorNames(schema []models.SensorDefinition) []string {
+	names := make([]string, 0, len(schema)+len(rollupControlFields))
+	for _, def := range schema {
+		names = append(names, def.Name)
+	}
+	return append(names, rollupControlFields...)
+}
+
+// isRoomSensor reports whether a telemetry field is a sensor of the given schema or a control field
+// These are the fields that are rolled up and can carry alarm rules and device calibration
+func isRoomSensor(schema []models.SensorDefinition, field string) bool {
+	if findSensorDefinition(schema, field) != nil {
+		return true
+	}
+	for _, control := range rollupControlFields {
+		if control == field {
+			return true
+		}
+	}
+	return false
+}
+
+// rollupResolutionByName looks up a rollup resolution by its name
+func rollupResolutionByName(name string) (rollupResolution, bool) {
+	for _, res := range rollupResolutions {
+		if res.Name == name {
+			return res, true
+		}
+	}
+	return rollupResolution{}, false
+}
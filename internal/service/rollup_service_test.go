@@ -0,0 +1,175 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+)
+
+func TestRollupWindow(t *testing.T) {
+	minute, _ := rollupResolutionByName("1m")
+	quarter, _ := rollupResolutionByName("15m")
+	base := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		res         rollupResolution
+		start       time.Time
+		now         time.Time
+		sourceMarks map[string]time.Time
+		wantEnd     time.Time
+		wantOK      bool
+	}{
+		{
+			name:    "bucket still settling is left open",
+			res:     minute,
+			start:   base.Add(4 * time.Minute),
+			now:     base.Add(5*time.Minute + 3*time.Second),
+			wantEnd: base.Add(4 * time.Minute),
+		},
+		{
+			name:    "bucket closes once the settle delay has passed",
+			res:     minute,
+			start:   base.Add(4 * time.Minute),
+			now:     base.Add(5*time.Minute + rollupSettleDelay),
+			wantEnd: base.Add(5 * time.Minute),
+			wantOK:  true,
+		},
+		{
+			name:    "catch-up after downtime is bounded",
+			res:     minute,
+			start:   base,
+			now:     base.Add(24 * time.Hour),
+			wantEnd: base.Add(rollupMaxCatchUp),
+			wantOK:  true,
+		},
+		{
+			name:        "derived level stops at its source watermark",
+			res:         quarter,
+			start:       base,
+			now:         base.Add(time.Hour),
+			sourceMarks: map[string]time.Time{"1m": base.Add(37 * time.Minute)},
+			wantEnd:     base.Add(30 * time.Minute),
+			wantOK:      true,
+		},
+		{
+			name:        "derived level waits until its source fills a bucket",
+			res:         quarter,
+			start:       base,
+			now:         base.Add(time.Hour),
+			sourceMarks: map[string]time.Time{"1m": base.Add(14 * time.Minute)},
+			wantEnd:     base,
+		},
+	}
+
+	for _, tt := range tests {
+		s := NewRollupService(nil, nil)
+		for name, mark := range tt.sourceMarks {
+			s.watermarks[name] = mark
+		}
+		end, ok := s.window(tt.res, tt.start, tt.now)
+		if ok != tt.wantOK || !end.Equal(tt.wantEnd) {
+			t.Errorf("%s: got %v (%v), want %v (%v)", tt.name, end.Format(time.TimeOnly), ok, tt.wantEnd.Format(time.TimeOnly), tt.wantOK)
+		}
+	}
+}
+
+func TestRollupBackfillRewindsWatermarks(t *testing.T) {
+	base := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	s := NewRollupService(nil, nil)
+	s.watermarks["1m"] = base.Add(5 * time.Minute)
+	s.watermarks["15m"] = base
+
+	// Later backfills never move a watermark forward
+	s.Backfill(base.Add(-28*time.Minute - 40*time.Second))
+	s.Backfill(base.Add(3 * time.Minute))
+	s.applyBackfill()
+
+	if want := base.Add(-29 * time.Minute); !s.watermarks["1m"].Equal(want) {
+		t.Errorf("1m watermark: got %v, want %v", s.watermarks["1m"], want)
+	}
+	if want := base.Add(-30 * time.Minute); !s.watermarks["15m"].Equal(want) {
+		t.Errorf("15m watermark: got %v, want %v", s.watermarks["15m"], want)
+	}
+	if _, ok := s.watermarks["1h"]; ok {
+		t.Error("1h watermark set by a backfill, want it left to load from the database")
+	}
+
+	// A backfill is applied once
+	s.watermarks["1m"] = base
+	s.applyBackfill()
+	if !s.watermarks["1m"].Equal(base) {
+		t.Errorf("1m watermark rewound again to %v", s.watermarks["1m"])
+	}
+}
+
+func TestAccumulateHistoryBuckets(t *testing.T) {
+	minute, _ := rollupResolutionByName("1m")
+	base := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	temps := []float64{20, 22, 30}
+	humidity := 40
+
+	history := []models.TheaterTelemetryHistory{
+		{RoomID: 1, RecordedAt: base, Temp: &temps[0], Humidity: &humidity},
+		{RoomID: 1, RecordedAt: base.Add(time.Minute - time.Millisecond), Temp: &temps[1]},
+		{RoomID: 1, RecordedAt: base.Add(time.Minute), Temp: &temps[2]},
+		{RoomID: 2, RecordedAt: base.Add(30 * time.Second), Temp: &temps[2]},
+	}
+	accumulators := make(map[rollupKey]*rollupAccumulator)
+	for i := range history {
+		accumulateHistory(accumulators, minute, &history[i])
+	}
+
+	tests := []struct {
+		key       rollupKey
+		min, max  float64
+		avg       float64
+		count     int
+		wantFound bool
+	}{
+		{key: rollupKey{roomID: 1, bucket: base, sensor: "temp"}, min: 20, max: 22, avg: 21, count: 2, wantFound: true},
+		{key: rollupKey{roomID: 1, bucket: base.Add(time.Minute), sensor: "temp"}, min: 30, max: 30, avg: 30, count: 1, wantFound: true},
+		{key: rollupKey{roomID: 1, bucket: base, sensor: "humidity"}, min: 40, max: 40, avg: 40, count: 1, wantFound: true},
+		{key: rollupKey{roomID: 1, bucket: base, sensor: "room_status"}, min: 0, max: 0, avg: 0, count: 2, wantFound: true},
+		{key: rollupKey{roomID: 2, bucket: base, sensor: "temp"}, min: 30, max: 30, avg: 30, count: 1, wantFound: true},
+		{key: rollupKey{roomID: 1, bucket: base.Add(time.Minute), sensor: "humidity"}},
+	}
+	for _, tt := range tests {
+		acc, found := accumulators[tt.key]
+		if found != tt.wantFound {
+			t.Errorf("room %d %s at %s: found = %v, want %v", tt.key.roomID, tt.key.sensor, tt.key.bucket.Format(time.TimeOnly), found, tt.wantFound)
+			continue
+		}
+		if !found {
+			continue
+		}
+		if acc.min != tt.min || acc.max != tt.max || acc.sum/float64(acc.count) != tt.avg || acc.count != tt.count {
+			t.Errorf("room %d %s at %s: got min %g max %g avg %g count %d, want %g %g %g %d",
+				tt.key.roomID, tt.key.sensor, tt.key.bucket.Format(time.TimeOnly),
+				acc.min, acc.max, acc.sum/float64(acc.count), acc.count, tt.min, tt.max, tt.avg, tt.count)
+		}
+	}
+}
+
+func TestCombineRollupsWeightsBySampleCount(t *testing.T) {
+	quarter, _ := rollupResolutionByName("15m")
+	base := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	accumulators := combineRollups(quarter, []models.TelemetryRollup{
+		{RoomID: 1, BucketStart: base, Sensor: "temp", MinValue: 19, MaxValue: 21, AvgValue: 20, SampleCount: 3},
+		{RoomID: 1, BucketStart: base.Add(14 * time.Minute), Sensor: "temp", MinValue: 24, MaxValue: 24, AvgValue: 24, SampleCount: 1},
+		{RoomID: 1, BucketStart: base.Add(15 * time.Minute), Sensor: "temp", MinValue: 30, MaxValue: 30, AvgValue: 30, SampleCount: 1},
+	})
+
+	acc := accumulators[rollupKey{roomID: 1, bucket: base, sensor: "temp"}]
+	if acc == nil {
+		t.Fatal("no 10:00 bucket")
+	}
+	if acc.min != 19 || acc.max != 24 || acc.count != 4 || acc.sum/float64(acc.count) != 21 {
+		t.Errorf("10:00 bucket: got min %g max %g avg %g count %d, want 19 24 21 4", acc.min, acc.max, acc.sum/float64(acc.count), acc.count)
+	}
+	if next := accumulators[rollupKey{roomID: 1, bucket: base.Add(15 * time.Minute), sensor: "temp"}]; next == nil || next.count != 1 {
+		t.Error("10:15 rollup was not kept in its own bucket")
+	}
+}
@@ -1,15 +1,11 @@
 package service
 
 import (
-	"crypto/rand"
-	"encoding/base64"
 	"errors"
 	"fmt"
 
 	"iot-backend-room-monitoring/internal/models"
 	"iot-backend-room-monitoring/internal/repository"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 type RoomService struct {
@@ -19,6 +15,7 @@ type RoomService struct {
 	auditRepo        *repository.AuditRepository
 	theaterRepo      *repository.TheaterRepository
 	apiKeyRepo       *repository.DeviceAPIKeyRepository
+	streamService    *StreamService
 }
 
 func NewRoomService(
@@ -28,6 +25,7 @@ func NewRoomService(
 	auditRepo *repository.AuditRepository,
 	theaterRepo *repository.TheaterRepository,
 	apiKeyRepo *repository.DeviceAPIKeyRepository,
+	streamService *StreamService,
 ) *RoomService {
 	return &RoomService{
 		roomRepo:         roomRepo,
@@ -36,6 +34,7 @@ func NewRoomService(
 		auditRepo:        auditRepo,
 		theaterRepo:      theaterRepo,
 		apiKeyRepo:       apiKeyRepo,
+		streamService:    streamService,
 	}
 }
 
@@ -73,14 +72,21 @@ type CreateRoomResponse struct {
 	TelemetryWarnings []string                `json:"telemetry_warnings,omitempty"`
 }
 
-// CreateRoom creates a new room (admin only)
+// CreateRoom creates a new room (hospital admins of the room's hospital)
 // Automatically initializes telemetry tables and generates an API key
-func (s *RoomService) CreateRoom(room *models.Room, userID uint) (*CreateRoomResponse, error) {
+func (s *RoomService) CreateRoom(room *models.Room, userID uint, role string) (*CreateRoomResponse, error) {
+	if err := validateStaleAfter(room); err != nil {
+		return nil, err
+	}
+
 	// Verify hospital exists
 	_, err := s.hospitalRepo.GetHospitalByID(room.HospitalID)
 	if err != nil {
 		return nil, fmt.Errorf("hospital not found: %w", err)
 	}
+	if err := s.checkHospitalPermission(room.HospitalID, userID, role, models.PermissionManageRooms); err != nil {
+		return nil, err
+	}
 
 	// Create the room
 	if err := s.roomRepo.CreateRoom(room); err != nil {
@@ -129,28 +135,9 @@ func (s *RoomService) CreateRoom(room *models.Room, userID uint) (*CreateRoomRes
 
 // generateAPIKeyForRoom is a helper method to generate an API key for a room
 func (s *RoomService) generateAPIKeyForRoom(roomID uint, description string, userID *uint) (*models.DeviceAPIKeyResponse, error) {
-	// Generate a random 32-byte key
-	keyBytes := make([]byte, 32)
-	_, err := rand.Read(keyBytes)
+	apiKey, plainKey, err := newDeviceAPIKey(roomID, description, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate random key: %w", err)
-	}
-
-	// Encode to base64 for easier transmission
-	plainKey := base64.URLEncoding.EncodeToString(keyBytes)
-
-	// Hash the key for storage
-	hashedKey, err := bcrypt.GenerateFromPassword([]byte(plainKey), bcrypt.DefaultCost)
-	if err != nil {
-		return nil, fmt.Errorf("failed to hash API key: %w", err)
-	}
-
-	// Create the API key record
-	apiKey := &models.DeviceAPIKey{
-		RoomID:      roomID,
-		APIKeyHash:  string(hashedKey),
-		IsActive:    true,
-		Description: description,
+		return nil, err
 	}
 
 	if err := s.apiKeyRepo.CreateAPIKey(apiKey); err != nil {
@@ -158,37 +145,39 @@ func (s *RoomService) generateAPIKeyForRoom(roomID uint, description string, use
 	}
 
 	// Return the plain key (only time it will be shown)
-	return &models.DeviceAPIKeyResponse{
-		ID:          apiKey.ID,
-		RoomID:      apiKey.RoomID,
-		APIKey:      plainKey, // Plain text key
-		CreatedAt:   apiKey.CreatedAt,
-		ExpiresAt:   apiKey.ExpiresAt,
-		IsActive:    apiKey.IsActive,
-		Description: apiKey.Description,
-	}, nil
+	return apiKeyResponse(apiKey, plainKey), nil
 }
 
-// UpdateRoom updates an existing room (admin only)
-func (s *RoomService) UpdateRoom(room *models.Room, userID uint) error {
+// UpdateRoom updates an existing room (hospital admins of the room's hospital)
+func (s *RoomService) UpdateRoom(room *models.Room, userID uint, role string) error {
 	// Verify room exists
 	existing, err := s.roomRepo.GetRoomByID(room.ID)
 	if err != nil {
 		return err
 	}
 
-	// Verify hospital exists if hospital_id is being changed
+	if err := validateStaleAfter(room); err != nil {
+		return err
+	}
+
+	// Verify hospital exists if hospital_id is being changed, and that the user may move rooms into it
 	if room.HospitalID != existing.HospitalID {
 		_, err := s.hospitalRepo.GetHospitalByID(room.HospitalID)
 		if err != nil {
 			return fmt.Errorf("hospital not found: %w", err)
 		}
+		if err := s.checkHospitalPermission(room.HospitalID, userID, role, models.PermissionManageRooms); err != nil {
+			return err
+		}
 	}
 
 	// Update the room
 	if err := s.roomRepo.UpdateRoom(room); err != nil {
 		return fmt.Errorf("failed to update room: %w", err)
 	}
+	if room.HospitalID != existing.HospitalID {
+		s.streamService.ForgetRoom(room.ID)
+	}
 
 	// Audit log
 	userIDPtr := &userID
@@ -198,7 +187,7 @@ func (s *RoomService) UpdateRoom(room *models.Room, userID uint) error {
 	return nil
 }
 
-// DeleteRoom soft deletes a room (admin only)
+// DeleteRoom soft deletes a room (hospital admins of the room's hospital)
 func (s *RoomService) DeleteRoom(roomID uint, userID uint) error {
 	// Verify room exists
 	room, err := s.roomRepo.GetRoomByID(roomID)
@@ -210,6 +199,7 @@ func (s *RoomService) DeleteRoom(roomID uint, userID uint) error {
 	if err := s.roomRepo.SoftDeleteRoom(roomID); err != nil {
 		return fmt.Errorf("failed to delete room: %w", err)
 	}
+	s.streamService.ForgetRoom(roomID)
 
 	// Audit log
 	userIDPtr := &userID
@@ -221,7 +211,7 @@ func (s *RoomService) DeleteRoom(roomID uint, userID uint) error {
 
 // GetAllRoomsByUser retrieves all rooms accessible by a user
 func (s *RoomService) GetAllRoomsByUser(userID uint, role string) ([]models.Room, error) {
-	if role == "admin" {
+	if role == models.UserRoleSuperAdmin {
 		return s.roomRepo.GetAllRooms()
 	}
 	return s.roomRepo.GetRoomsByUserID(userID)
@@ -229,25 +219,68 @@ func (s *RoomService) GetAllRoomsByUser(userID uint, role string) ([]models.Room
 
 // CheckUserRoomAccess checks if a user has access to a specific room
 func (s *RoomService) CheckUserRoomAccess(roomID uint, userID uint, role string) error {
+	_, err := checkRoomAccess(s.roomRepo, s.userHospitalRepo, roomID, userID, role)
+	return err
+}
+
+// checkRoomAccess loads a room and checks the user has access to its hospital
+// Services reading a single room share it so the rule lives in one place
+func checkRoomAccess(
+	roomRepo *repository.RoomRepository,
+	userHospitalRepo *repository.UserHospitalRepository,
+	roomID uint, userID uint, role string,
+) (*models.Room, error) {
+	room, err := roomRepo.GetRoomByID(roomID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Admin users have access to all rooms
-	if role == "admin" {
-		return nil
+	if role == models.UserRoleSuperAdmin {
+		return room, nil
 	}
 
-	// Get room to find its hospital
-	room, err := s.roomRepo.GetRoomByID(roomID)
+	hasAccess, err := userHospitalRepo.UserHasAccessToHospital(userID, room.HospitalID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, errors.New("access denied: you don't have permission to access this room")
 	}
 
-	// Check hospital access
-	return s.checkHospitalAccess(room.HospitalID, userID, role)
+	return room, nil
+}
+
+// checkRoomPermission loads a room and checks the user's role in its hospital grants a permission
+func checkRoomPermission(
+	roomRepo *repository.RoomRepository,
+	userHospitalRepo *repository.UserHospitalRepository,
+	roomID uint, userID uint, role string, permission models.Permission,
+) (*models.Room, error) {
+	room, err := roomRepo.GetRoomByID(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	if role == models.UserRoleSuperAdmin {
+		return room, nil
+	}
+
+	allowed, err := userHospitalRepo.UserHasHospitalPermission(userID, room.HospitalID, permission)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, errors.New("access denied: your role in this room's hospital does not grant " + string(permission))
+	}
+
+	return room, nil
 }
 
 // checkHospitalAccess is a helper method to verify hospital access
 func (s *RoomService) checkHospitalAccess(hospitalID uint, userID uint, role string) error {
 	// Admin users have access to all hospitals
-	if role == "admin" {
+	if role == models.UserRoleSuperAdmin {
 		return nil
 	}
 
@@ -262,3 +295,28 @@ func (s *RoomService) checkHospitalAccess(hospitalID uint, userID uint, role str
 
 	return nil
 }
+
+// checkHospitalPermission verifies the user's role in a hospital grants a permission
+func (s *RoomService) checkHospitalPermission(hospitalID uint, userID uint, role string, permission models.Permission) error {
+	if role == models.UserRoleSuperAdmin {
+		return nil
+	}
+
+	allowed, err := s.userHospitalRepo.UserHasHospitalPermission(userID, hospitalID, permission)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errors.New("access denied: you don't have permission to manage this hospital's rooms")
+	}
+
+	return nil
+}
+
+// validateStaleAfter checks the room's optional staleness threshold
+func validateStaleAfter(room *models.Room) error {
+	if room.StaleAfterSeconds != nil && *room.StaleAfterSeconds <= 0 {
+		return errors.New("stale_after_seconds must be greater than 0")
+	}
+	return nil
+}
@@ -0,0 +1,414 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+)
+
+// sensorSchemaCacheTTL controls how often ingestion reloads sensor schemas and room types
+const sensorSchemaCacheTTL = 30 * time.Second
+
+// legacyRoomType is assumed for legacy rooms identified only by room_name
+const legacyRoomType = "operating_theater"
+
+// sensorSchemaRoomTypes lists the room types a sensor schema can be defined for
+var sensorSchemaRoomTypes = []string{"operating_theater", "icu", "isolation", "general"}
+
+// builtinSensors is the schema of room types without sensor definitions
+// Each of these sensors has a dedicated telemetry column and is stored in the listed unit
+var builtinSensors = []models.SensorDefinition{
+	{Name: "temp", Label: "Temperature", Unit: "C", Required: true, MinValue: floatPtr(-50), MaxValue: floatPtr(100)},
+	{Name: "humidity", Label: "Humidity", Unit: "%", Required: true, MinValue: floatPtr(0), MaxValue: floatPtr(100)},
+	{Name: "room_pressure", Label: "Room Pressure", Unit: "Pa", Required: true, MinValue: floatPtr(0)},
+	{Name: "oxygen", Label: "Oxygen", Unit: "kPa", Required: true},
+	{Name: "nitrous", Label: "Nitrous Oxide", Unit: "kPa", Required: true},
+	{Name: "air", Label: "Medical Air", Unit: "kPa", Required: true},
+	{Name: "vacuum", Label: "Vacuum", Unit: "kPa", Required: true},
+	{Name: "instrument", Label: "Instrument Air", Unit: "kPa", Required: true},
+	{Name: "carbon", Label: "Carbon Dioxide", Unit: "kPa", Required: true},
+}
+
+// reservedSensorNames are telemetry control fields that cannot be defined as sensors
+var reservedSensorNames = map[string]bool{
+	"room_status":     true,
+	"laju_aliran_ahu": true,
+	"logic_ahu":       true,
+	"volume_ruangan":  true,
+}
+
+// sensorNamePattern restricts sensor names to lowercase identifiers
+var sensorNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// SensorDefinitionInput describes one sensor of a schema; list order is the dashboard order
+type SensorDefinitionInput struct {
+	Name     string   `json:"name" binding:"required,max=50"`
+	Label    string   `json:"label" binding:"max=100"`
+	Unit     string   `json:"unit" binding:"max=20"` // Built-in sensors must use their storage unit
+	Required bool     `json:"required"`
+	MinValue *float64 `json:"min_value"`
+	MaxValue *float64 `json:"max_value"`
+}
+
+// SaveSensorSchemaRequest is the body for replacing the sensor schema of a room type
+type SaveSensorSchemaRequest struct {
+	Sensors []SensorDefinitionInput `json:"sensors" binding:"required,dive"`
+}
+
+// RoomTypeSensorSchema is the effective sensor schema of a room type
+type RoomTypeSensorSchema struct {
+	RoomType string                    `json:"room_type"`
+	Custom   bool                      `json:"custom"` // False when the built-in schema applies
+	Sensors  []models.SensorDefinition `json:"sensors"`
+}
+
+type SensorSchemaService struct {
+	schemaRepo *repository.SensorSchemaRepository
+	roomRepo   *repository.RoomRepository
+	auditRepo  *repository.AuditRepository
+
+	mu        sync.Mutex
+	schemas   map[string][]models.SensorDefinition // Defined schemas by room type
+	roomTypes map[uint]string                      // Room type of every active room
+	loadedAt  time.Time
+}
+
+func NewSensorSchemaService(
+	schemaRepo *repository.SensorSchemaRepository,
+	roomRepo *repository.RoomRepository,
+	auditRepo *repository.AuditRepository,
+) *SensorSchemaService {
+	return &SensorSchemaService{
+		schemaRepo: schemaRepo,
+		roomRepo:   roomRepo,
+		auditRepo:  auditRepo,
+		schemas:    make(map[string][]models.SensorDefinition),
+		roomTypes:  make(map[uint]string),
+	}
+}
+
+// SchemaFor returns the sensor schema of a room type, falling back to the built-in schema
+func (s *SensorSchemaService) SchemaFor(roomType string) []models.SensorDefinition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+	if schema, ok := s.schemas[roomType]; ok {
+		return schema
+	}
+	return builtinSensors
+}
+
+// SchemaForRoom returns the sensor schema of a room; legacy rooms without a room_id use the default room type
+func (s *SensorSchemaService) SchemaForRoom(roomID *uint) []models.SensorDefinition {
+	roomType := legacyRoomType
+	if roomID != nil {
+		s.mu.Lock()
+		s.refresh()
+		if t, ok := s.roomTypes[*roomID]; ok {
+			roomType = t
+		}
+		s.mu.Unlock()
+	}
+	return s.SchemaFor(roomType)
+}
+
+// Annotate lists a live state's latest value of every sensor in its room's schema, in display order
+func (s *SensorSchemaService) Annotate(state *models.TheaterLiveState) {
+	schema := s.SchemaForRoom(state.RoomID)
+	readings := make([]models.SensorReading, len(schema))
+	for i, def := range schema {
+		readings[i] = models.SensorReading{
+			Name:     def.Name,
+			Label:    def.Label,
+			Unit:     def.Unit,
+			Required: def.Required,
+		}
+		if value, ok := state.SensorValues[def.Name]; ok {
+			readings[i].Value = &value
+		}
+	}
+	state.SensorReadings = readings
+}
+
+// refresh reloads schemas and room types once the cache has expired; the caller must hold s.mu
+// On error the previous data is kept until the next reload
+func (s *SensorSchemaService) refresh() {
+	if time.Since(s.loadedAt) < sensorSchemaCacheTTL {
+		return
+	}
+	s.loadedAt = time.Now()
+
+	definitions, err := s.schemaRepo.GetAllDefinitions()
+	if err != nil {
+		log.Printf("Error loading sensor schemas: %v", err)
+		return
+	}
+	rooms, err := s.roomRepo.GetAllRooms()
+	if err != nil {
+		log.Printf("Error loading room types for sensor schemas: %v", err)
+		return
+	}
+
+	schemas := make(map[string][]models.SensorDefinition)
+	for _, def := range definitions {
+		schemas[def.RoomType] = append(schemas[def.RoomType], def)
+	}
+	types := make(map[uint]string, len(rooms))
+	for _, room := range rooms {
+		types[room.ID] = room.RoomType
+	}
+	s.schemas = schemas
+	s.roomTypes = types
+}
+
+// invalidate forces the next lookup to reload schemas
+func (s *SensorSchemaService) invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+// GetSchemas returns the effective sensor schema of every room type
+func (s *SensorSchemaService) GetSchemas() []RoomTypeSensorSchema {
+	schemas := make([]RoomTypeSensorSchema, len(sensorSchemaRoomTypes))
+	for i, roomType := range sensorSchemaRoomTypes {
+		schemas[i] = s.schemaOf(roomType)
+	}
+	return schemas
+}
+
+// GetSchema returns the effective sensor schema of one room type
+func (s *SensorSchemaService) GetSchema(roomType string) (*RoomTypeSensorSchema, error) {
+	if !isRoomType(roomType) {
+		return nil, errors.New("room type not found")
+	}
+	schema := s.schemaOf(roomType)
+	return &schema, nil
+}
+
+// schemaOf describes the schema currently applied to a room type
+func (s *SensorSchemaService) schemaOf(roomType string) RoomTypeSensorSchema {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+	if defined, ok := s.schemas[roomType]; ok {
+		return RoomTypeSensorSchema{RoomType: roomType, Custom: true, Sensors: defined}
+	}
+	defaults := make([]models.SensorDefinition, len(builtinSensors))
+	for i, def := range builtinSensors {
+		def.RoomType = roomType
+		def.SortOrder = i
+		defaults[i] = def
+	}
+	return RoomTypeSensorSchema{RoomType: roomType, Sensors: defaults}
+}
+
+// SaveSchema replaces the sensor schema of a room type (admin only)
+func (s *SensorSchemaService) SaveSchema(roomType string, req *SaveSensorSchemaRequest, userID uint) (*RoomTypeSensorSchema, error) {
+	if !isRoomType(roomType) {
+		return nil, errors.New("room type not found")
+	}
+	definitions, err := newSensorDefinitions(roomType, req.Sensors)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.schemaRepo.ReplaceDefinitions(roomType, definitions); err != nil {
+		return nil, fmt.Errorf("failed to save sensor schema: %w", err)
+	}
+	s.invalidate()
+
+	names := make([]string, len(definitions))
+	for i, def := range definitions {
+		names[i] = def.Name
+	}
+	userIDPtr := &userID
+	details := fmt.Sprintf("Saved sensor schema for room_type: %s (%s)", roomType, strings.Join(names, ", "))
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "sensor_schema_save", details)
+
+	return &RoomTypeSensorSchema{RoomType: roomType, Custom: true, Sensors: definitions}, nil
+}
+
+// DeleteSchema removes the sensor schema of a room type so the built-in schema applies again (admin only)
+func (s *SensorSchemaService) DeleteSchema(roomType string, userID uint) error {
+	if !isRoomType(roomType) {
+		return errors.New("room type not found")
+	}
+	if err := s.schemaRepo.ReplaceDefinitions(roomType, nil); err != nil {
+		return fmt.Errorf("failed to delete sensor schema: %w", err)
+	}
+	s.invalidate()
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Deleted sensor schema for room_type: %s", roomType)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "sensor_schema_delete", details)
+
+	return nil
+}
+
+// newSensorDefinitions validates a requested schema and converts it into definitions
+// Built-in sensors keep their storage unit, and their label unless one is given
+func newSensorDefinitions(roomType string, inputs []SensorDefinitionInput) ([]models.SensorDefinition, error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("sensors are required")
+	}
+
+	seen := make(map[string]bool, len(inputs))
+	definitions := make([]models.SensorDefinition, len(inputs))
+	for i, input := range inputs {
+		if !sensorNamePattern.MatchString(input.Name) {
+			return nil, fmt.Errorf("invalid sensor name: %s", input.Name)
+		}
+		if reservedSensorNames[input.Name] {
+			return nil, fmt.Errorf("sensor name is reserved: %s", input.Name)
+		}
+		if seen[input.Name] {
+			return nil, fmt.Errorf("duplicate sensor: %s", input.Name)
+		}
+		seen[input.Name] = true
+		if input.MinValue != nil && input.MaxValue != nil && *input.MinValue > *input.MaxValue {
+			return nil, fmt.Errorf("min_value of %s must not be greater than max_value", input.Name)
+		}
+
+		def := models.SensorDefinition{
+			RoomType:  roomType,
+			Name:      input.Name,
+			Label:     input.Label,
+			Unit:      input.Unit,
+			Required:  input.Required,
+			MinValue:  input.MinValue,
+			MaxValue:  input.MaxValue,
+			SortOrder: i,
+		}
+		if builtin := findSensorDefinition(builtinSensors, input.Name); builtin != nil {
+			if def.Unit != "" && def.Unit != builtin.Unit {
+				return nil, fmt.Errorf("unit of %s must be %s", input.Name, builtin.Unit)
+			}
+			def.Unit = builtin.Unit
+			if def.Label == "" {
+				def.Label = builtin.Label
+			}
+		}
+		if def.Label == "" {
+			def.Label = def.Name
+		}
+		definitions[i] = def
+	}
+	return definitions, nil
+}
+
+// findSensorDefinition looks up a sensor of a schema by name
+func findSensorDefinition(schema []models.SensorDefinition, name string) *models.SensorDefinition {
+	for i := range schema {
+		if schema[i].Name == name {
+			return &schema[i]
+		}
+	}
+	return nil
+}
+
+// isBuiltinSensor reports whether a sensor has a dedicated telemetry column
+func isBuiltinSensor(name string) bool {
+	return findSensorDefinition(builtinSensors, name) != nil
+}
+
+// isRoomType reports whether roomType is a known room type
+func isRoomType(roomType string) bool {
+	for _, known := range sensorSchemaRoomTypes {
+		if known == roomType {
+			return true
+		}
+	}
+	return false
+}
+
+// describeSensorRange formats the valid range of a sensor for error messages
+func describeSensorRange(def *models.SensorDefinition) string {
+	switch {
+	case def.MinValue != nil && def.MaxValue != nil:
+		return fmt.Sprintf("%g to %g", *def.MinValue, *def.MaxValue)
+	case def.MinValue != nil:
+		return fmt.Sprintf("at least %g", *def.MinValue)
+	default:
+		return fmt.Sprintf("at most %g", *def.MaxValue)
+	}
+}
+
+// telemetrySensorValues returns every sensor value reported in a reading keyed by sensor name
+func telemetrySensorValues(data *TelemetryUpdateRequest) models.SensorValues {
+	values := models.SensorValues{}
+	floats := map[string]*float64{
+		"temp":          data.Temp,
+		"room_pressure": data.RoomPressure,
+		"oxygen":        data.Oxygen,
+		"nitrous":       data.Nitrous,
+		"air":           data.Air,
+		"instrument":    data.Instrument,
+		"carbon":        data.Carbon,
+	}
+	for name, v := range floats {
+		if v != nil {
+			values[name] = *v
+		}
+	}
+	if data.Humidity != nil {
+		values["humidity"] = float64(*data.Humidity)
+	}
+	if data.Vacuum != nil {
+		values["vacuum"] = float64(*data.Vacuum)
+	}
+	for name, v := range data.Sensors {
+		values[name] = v
+	}
+	return values
+}
+
+// restrictToSchema returns a copy of a reading without the sensors its room's schema does not define
+func restrictToSchema(data *TelemetryUpdateRequest, schema []models.SensorDefinition) *TelemetryUpdateRequest {
+	restricted := *data
+	defined := func(name string) bool { return findSensorDefinition(schema, name) != nil }
+
+	floats := map[string]**float64{
+		"temp":          &restricted.Temp,
+		"room_pressure": &restricted.RoomPressure,
+		"oxygen":        &restricted.Oxygen,
+		"nitrous":       &restricted.Nitrous,
+		"air":           &restricted.Air,
+		"instrument":    &restricted.Instrument,
+		"carbon":        &restricted.Carbon,
+	}
+	for name, ptr := range floats {
+		if !defined(name) {
+			*ptr = nil
+		}
+	}
+	if !defined("humidity") {
+		restricted.Humidity = nil
+	}
+	if !defined("vacuum") {
+		restricted.Vacuum = nil
+	}
+
+	restricted.Sensors = nil
+	for name, v := range data.Sensors {
+		if defined(name) {
+			if restricted.Sensors == nil {
+				restricted.Sensors = make(map[string]float64)
+			}
+			restricted.Sensors[name] = v
+		}
+	}
+	return &restricted
+}
+
+// floatPtr returns a pointer to a float literal
+func floatPtr(v float64) *float64 {
+	return &v
+}
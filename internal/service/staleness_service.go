@@ -0,0 +1,103 @@
+package service
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"iot-backend-room-monitoring/internal/config"
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+)
+
+// stalenessCacheTTL controls how often per-room thresholds are reloaded
+const stalenessCacheTTL = 30 * time.Second
+
+// StalenessService decides whether a room's live state is too old to be trusted
+type StalenessService struct {
+	roomRepo          *repository.RoomRepository
+	defaultStaleAfter time.Duration
+
+	mu         sync.Mutex
+	thresholds map[uint]time.Duration // Threshold of every active room
+	loadedAt   time.Time
+}
+
+func NewStalenessService(roomRepo *repository.RoomRepository, cfg config.TelemetryConfig) *StalenessService {
+	return &StalenessService{
+		roomRepo:          roomRepo,
+		defaultStaleAfter: cfg.StaleAfter,
+		thresholds:        make(map[uint]time.Duration),
+	}
+}
+
+// StaleAfter returns the age after which a room's live state is stale
+// Legacy rooms without a room_id use the default
+func (s *StalenessService) StaleAfter(roomID *uint) time.Duration {
+	if roomID == nil {
+		return s.defaultStaleAfter
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+	if threshold, ok := s.thresholds[*roomID]; ok {
+		return threshold
+	}
+	return s.defaultStaleAfter
+}
+
+// IsMonitored reports whether a room is active and should raise device offline alarms
+func (s *StalenessService) IsMonitored(roomID uint) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+	_, ok := s.thresholds[roomID]
+	return ok
+}
+
+// Annotate sets the data age and stale flag of a live state as of now
+// A room that never reported is stale with no data age
+func (s *StalenessService) Annotate(state *models.TheaterLiveState, now time.Time) {
+	staleAfter := s.StaleAfter(state.RoomID)
+	state.StaleAfterSeconds = int64(staleAfter / time.Second)
+
+	if state.LastProcessedAt == nil {
+		state.DataAgeSeconds = nil
+		state.IsStale = true
+		return
+	}
+
+	age := now.Sub(*state.LastProcessedAt)
+	if age < 0 {
+		age = 0
+	}
+	ageSeconds := int64(age / time.Second)
+	state.DataAgeSeconds = &ageSeconds
+	state.IsStale = age > staleAfter
+}
+
+// refresh reloads room thresholds once the cache has expired; the caller must hold s.mu
+// On error the previous thresholds are kept until the next reload
+func (s *StalenessService) refresh() {
+	if time.Since(s.loadedAt) < stalenessCacheTTL {
+		return
+	}
+	s.loadedAt = time.Now()
+
+	rooms, err := s.roomRepo.GetAllRooms()
+	if err != nil {
+		log.Printf("Error loading room staleness thresholds: %v", err)
+		return
+	}
+
+	thresholds := make(map[uint]time.Duration, len(rooms))
+	for _, room := range rooms {
+		threshold := s.defaultStaleAfter
+		if room.StaleAfterSeconds != nil && *room.StaleAfterSeconds > 0 {
+			threshold = time.Duration(*room.StaleAfterSeconds) * time.Second
+		}
+		thresholds[room.ID] = threshold
+	}
+	s.thresholds = thresholds
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+)
+
+// streamSubscriberBuffer is the number of events queued per client before new events are dropped
+const streamSubscriberBuffer = 64
+
+// StreamEvent is a single message pushed to streaming clients
+type StreamEvent struct {
+	Type       string      `json:"type"`
+	RoomID     *uint       `json:"room_id"`
+	HospitalID *uint       `json:"-"`
+	Data       interface{} `json:"data"`
+	At         time.Time   `json:"at"`
+}
+
+// StreamSubscription is a client's view of the event stream
+type StreamSubscription struct {
+	Events   <-chan StreamEvent
+	Snapshot []models.TheaterLiveState
+
+	events    chan StreamEvent
+	userID    uint
+	all       bool          // Guarded by the service's mu once subscribed
+	hospitals map[uint]bool // Guarded by the service's mu once subscribed
+	service   *StreamService
+	closeOnce sync.Once
+}
+
+// Close unsubscribes the client from the stream
+func (sub *StreamSubscription) Close() {
+	sub.closeOnce.Do(func() {
+		sub.service.unsubscribe(sub)
+	})
+}
+
+// allows reports whether the subscriber may see an event
+// Rooms without a known hospital (legacy room_name rows) are only visible to admins
+func (sub *StreamSubscription) allows(event StreamEvent) bool {
+	if sub.all {
+		return true
+	}
+	return event.HospitalID != nil && sub.hospitals[*event.HospitalID]
+}
+
+// StreamService fans live state changes out to connected streaming clients
+type StreamService struct {
+	theaterRepo      *repository.TheaterRepository
+	roomRepo         *repository.RoomRepository
+	userRepo         *repository.UserRepository
+	userHospitalRepo *repository.UserHospitalRepository
+	annotator        *LiveStateAnnotator
+
+	mu            sync.Mutex
+	subscribers   map[*StreamSubscription]bool
+	lastStates    map[uint]map[string]interface{} // Last published state by live state ID
+	roomHospitals map[uint]uint                   // Cached room_id -> hospital_id
+	roomsVersion  uint64                          // Bumped by ForgetRoom so lookups racing it are not cached
+}
+
+func NewStreamService(
+	theaterRepo *repository.TheaterRepository,
+	roomRepo *repository.RoomRepository,
+	userRepo *repository.UserRepository,
+	userHospitalRepo *repository.UserHospitalRepository,
+	annotator *LiveStateAnnotator,
+) *StreamService {
+	return &StreamService{
+		theaterRepo:      theaterRepo,
+		roomRepo:         roomRepo,
+		userRepo:         userRepo,
+		userHospitalRepo: userHospitalRepo,
+		annotator:        annotator,
+		subscribers:      make(map[*StreamSubscription]bool),
+		lastStates:       make(map[uint]map[string]interface{}),
+		roomHospitals:    make(map[uint]uint),
+	}
+}
+
+// Subscribe registers a client and returns a snapshot of the rooms it can access
+func (s *StreamService) Subscribe(userID uint, role string) (*StreamSubscription, error) {
+	hospitals, err := s.accessibleHospitals(userID, role)
+	if err != nil {
+		return nil, err
+	}
+	sub := &StreamSubscription{
+		events:    make(chan StreamEvent, streamSubscriberBuffer),
+		userID:    userID,
+		all:       role == models.UserRoleSuperAdmin,
+		hospitals: hospitals,
+		service:   s,
+	}
+	sub.Events = sub.events
+
+	// Register before reading the snapshot so no change between the two is lost
+	s.mu.Lock()
+	s.subscribers[sub] = true
+	s.mu.Unlock()
+
+	states, err := s.theaterRepo.GetAllLiveStates()
+	if err != nil {
+		sub.Close()
+		return nil, err
+	}
+	for _, state := range states {
+		if sub.allows(StreamEvent{HospitalID: s.hospitalForRoom(state.RoomID)}) {
+			sub.Snapshot = append(sub.Snapshot, state)
+		}
+	}
+	s.annotator.AnnotateAll(liveStatePointers(sub.Snapshot), time.Now())
+
+	return sub, nil
+}
+
+// RefreshAccess reloads a subscriber's role and hospitals, so an open stream follows role changes
+// and removal from a hospital; it returns an error when the user no longer exists
+func (s *StreamService) RefreshAccess(sub *StreamSubscription) error {
+	user, err := s.userRepo.FindUserByID(sub.userID)
+	if err != nil {
+		return err
+	}
+	hospitals, err := s.accessibleHospitals(user.ID, user.Role)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	sub.all = user.Role == models.UserRoleSuperAdmin
+	sub.hospitals = hospitals
+	s.mu.Unlock()
+	return nil
+}
+
+// accessibleHospitals returns the hospitals a user is assigned to; super admins need none
+func (s *StreamService) accessibleHospitals(userID uint, role string) (map[uint]bool, error) {
+	hospitals := make(map[uint]bool)
+	if role == models.UserRoleSuperAdmin {
+		return hospitals, nil
+	}
+	hospitalIDs, err := s.userHospitalRepo.GetUserHospitals(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range hospitalIDs {
+		hospitals[id] = true
+	}
+	return hospitals, nil
+}
+
+// ForgetRoom drops the cached hospital of a room after it was moved or deleted
+// Its next state is published in full, for the subscribers of the room's new hospital
+func (s *StreamService) ForgetRoom(roomID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.roomHospitals, roomID)
+	s.roomsVersion++
+	for stateID, fields := range s.lastStates {
+		if id, ok := fields["room_id"].(float64); ok && uint(id) == roomID {
+			delete(s.lastStates, stateID)
+		}
+	}
+}
+
+// unsubscribe removes a client and closes its event channel
+func (s *StreamService) unsubscribe(sub *StreamSubscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscribers[sub] {
+		delete(s.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// PublishLiveState pushes the fields of a live state that changed since it was last published
+// The state's data age, stale flag, dashboard sensors and stopwatch timeline are refreshed first
+func (s *StreamService) PublishLiveState(state *models.TheaterLiveState) {
+	s.annotator.Annotate(state, time.Now())
+	current, err := liveStateFields(state)
+	if err != nil {
+		log.Printf("Error encoding live state %d for streaming: %v", state.ID, err)
+		return
+	}
+
+	s.mu.Lock()
+	previous := s.lastStates[state.ID]
+	s.lastStates[state.ID] = current
+	s.mu.Unlock()
+
+	delta := make(map[string]interface{})
+	for key, value := range current {
+		if prev, ok := previous[key]; !ok || !reflect.DeepEqual(prev, value) {
+			delta[key] = value
+		}
+	}
+	if previous != nil && len(delta) == 0 {
+		return
+	}
+
+	// Always identify the room so clients can apply the delta
+	delta["id"] = current["id"]
+	delta["room_id"] = current["room_id"]
+	delta["room_name"] = current["room_name"]
+
+	s.Publish(StreamEvent{
+		Type:   "state",
+		RoomID: state.RoomID,
+		Data:   delta,
+	})
+}
+
+// Publish sends an event to every subscriber allowed to see it
+// Slow clients whose buffer is full miss the event rather than blocking the publisher
+func (s *StreamService) Publish(event StreamEvent) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	if event.HospitalID == nil {
+		event.HospitalID = s.hospitalForRoom(event.RoomID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subscribers {
+		if !sub.allows(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			log.Printf("Stream subscriber buffer full, dropping %s event", event.Type)
+		}
+	}
+}
+
+// hospitalForRoom resolves the hospital of a room, caching the result
+func (s *StreamService) hospitalForRoom(roomID *uint) *uint {
+	if roomID == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	hospitalID, ok := s.roomHospitals[*roomID]
+	version := s.roomsVersion
+	s.mu.Unlock()
+	if ok {
+		return &hospitalID
+	}
+
+	room, err := s.roomRepo.GetRoomByID(*roomID)
+	if err != nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	if s.roomsVersion == version {
+		s.roomHospitals[*roomID] = room.HospitalID
+	}
+	s.mu.Unlock()
+	return &room.HospitalID
+}
+
+// liveStateFields converts a live state into its JSON field map, without relationships
+func liveStateFields(state *models.TheaterLiveState) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "room")
+	return fields, nil
+}
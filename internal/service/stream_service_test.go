@@ -0,0 +1,57 @@
+package service
+
+import (
+	"testing"
+)
+
+func TestStreamPublishFiltersByHospital(t *testing.T) {
+	s := NewStreamService(nil, nil, nil, nil, nil)
+	hospitalA, hospitalB := uint(1), uint(2)
+
+	admin := &StreamSubscription{events: make(chan StreamEvent, 4), all: true, service: s}
+	member := &StreamSubscription{events: make(chan StreamEvent, 4), hospitals: map[uint]bool{hospitalA: true}, service: s}
+	s.subscribers[admin] = true
+	s.subscribers[member] = true
+
+	s.Publish(StreamEvent{Type: "state", HospitalID: &hospitalA})
+	s.Publish(StreamEvent{Type: "state", HospitalID: &hospitalB})
+
+	if got := len(admin.events); got != 2 {
+		t.Errorf("admin received %d events, want 2", got)
+	}
+	if got := len(member.events); got != 1 {
+		t.Errorf("hospital member received %d events, want only its hospital's 1", got)
+	}
+
+	// Access is swapped by RefreshAccess under the service lock; later events follow it
+	s.mu.Lock()
+	member.hospitals = map[uint]bool{}
+	s.mu.Unlock()
+	s.Publish(StreamEvent{Type: "state", HospitalID: &hospitalA})
+	if got := len(member.events); got != 1 {
+		t.Errorf("removed member received %d events, want no new ones", got)
+	}
+}
+
+func TestStreamForgetRoom(t *testing.T) {
+	s := NewStreamService(nil, nil, nil, nil, nil)
+	s.roomHospitals[5] = 1
+	s.roomHospitals[6] = 1
+	s.lastStates[10] = map[string]interface{}{"id": float64(10), "room_id": float64(5)}
+	s.lastStates[11] = map[string]interface{}{"id": float64(11), "room_id": float64(6)}
+
+	s.ForgetRoom(5)
+
+	if _, ok := s.roomHospitals[5]; ok {
+		t.Error("room 5 hospital still cached after ForgetRoom")
+	}
+	if _, ok := s.lastStates[10]; ok {
+		t.Error("room 5 last state kept, want its next state published in full")
+	}
+	if _, ok := s.roomHospitals[6]; !ok {
+		t.Error("room 6 hospital dropped, want other rooms kept")
+	}
+	if _, ok := s.lastStates[11]; !ok {
+		t.Error("room 6 last state dropped, want other rooms kept")
+	}
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+)
+
+const (
+	defaultHistoryWindow = 24 * time.Hour
+	defaultHistoryLimit  = 100
+	maxHistoryLimit      = 1000
+)
+
+// historyFields lists the telemetry fields returned when no fields= filter is given
+// Sensors of the room's schema without a dedicated column can be requested by name
+var historyFields = []string{
+	"temp", "humidity", "room_pressure", "room_status",
+	"laju_aliran_ahu", "volume_ruangan", "logic_ahu",
+	"oxygen", "nitrous", "air", "vacuum", "instrument", "carbon",
+}
+
+type TelemetryHistoryService struct {
+	historyRepo         *repository.TelemetryHistoryRepository
+	rollupRepo          *repository.TelemetryRollupRepository
+	roomRepo            *repository.RoomRepository
+	userHospitalRepo    *repository.UserHospitalRepository
+	sensorSchemaService *SensorSchemaService
+}
+
+func NewTelemetryHistoryService(
+	historyRepo *repository.TelemetryHistoryRepository,
+	rollupRepo *repository.TelemetryRollupRepository,
+	roomRepo *repository.RoomRepository,
+	userHospitalRepo *repository.UserHospitalRepository,
+	sensorSchemaService *SensorSchemaService,
+) *TelemetryHistoryService {
+	return &TelemetryHistoryService{
+		historyRepo:         historyRepo,
+		rollupRepo:          rollupRepo,
+		roomRepo:            roomRepo,
+		userHospitalRepo:    userHospitalRepo,
+		sensorSchemaService: sensorSchemaService,
+	}
+}
+
+// TelemetryHistoryQuery holds the filters for a history request
+type TelemetryHistoryQuery struct {
+	From       *time.Time
+	To         *time.Time
+	Fields     []string
+	Resolution string // raw (default), 1m, 15m, 1h or auto
+	Page       int
+	Limit      int
+}
+
+// TelemetryHistoryResult is a single page of historical readings
+// For rollup resolutions each reading holds a bucket_start and min/max/avg/sample_count per field
+type TelemetryHistoryResult struct {
+	RoomID     uint                     `json:"room_id"`
+	From       time.Time                `json:"from"`
+	To         time.Time                `json:"to"`
+	Resolution string                   `json:"resolution"`
+	Fields     []string                 `json:"fields"`
+	Readings   []map[string]interface{} `json:"readings"`
+	Count      int                      `json:"count"`
+	Total      int64                    `json:"total"`
+	Page       int                      `json:"page"`
+	Limit      int                      `json:"limit"`
+}
+
+// GetHistory retrieves historical telemetry for a room with access control
+func (s *TelemetryHistoryService) GetHistory(roomID uint, query TelemetryHistoryQuery, userID uint, role string) (*TelemetryHistoryResult, error) {
+	room, err := checkRoomAccess(s.roomRepo, s.userHospitalRepo, roomID, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	schema := s.sensorSchemaService.SchemaFor(room.RoomType)
+
+	// Default to the last 24 hours
+	to := time.Now()
+	if query.To != nil {
+		to = *query.To
+	}
+	from := to.Add(-defaultHistoryWindow)
+	if query.From != nil {
+		from = *query.From
+	}
+	if from.After(to) {
+		return nil, errors.New("from must be before to")
+	}
+
+	fields, err := normalizeHistoryFields(query.Fields, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	page := query.Page
+	if page < 1 {
+		page = 1
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	resolution, err := resolveHistoryResolution(query.Resolution, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TelemetryHistoryResult{
+		RoomID:     roomID,
+		From:       from,
+		To:         to,
+		Resolution: resolution,
+		Fields:     fields,
+		Page:       page,
+		Limit:      limit,
+	}
+
+	if resolution == "raw" {
+		result.Readings, result.Total, err = s.getRawReadings(roomID, from, to, fields, limit, (page-1)*limit)
+	} else {
+		result.Fields = filterRollupFields(fields, schema)
+		result.Readings, result.Total, err = s.getRollupReadings(roomID, resolution, from, to, result.Fields, limit, (page-1)*limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Count = len(result.Readings)
+
+	return result, nil
+}
+
+// getRawReadings returns a page of individual readings projected onto the requested fields
+func (s *TelemetryHistoryService) getRawReadings(roomID uint, from, to time.Time, fields []string, limit, offset int) ([]map[string]interface{}, int64, error) {
+	history, total, err := s.historyRepo.GetHistoryByRoomID(roomID, from, to, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch telemetry history: %w", err)
+	}
+
+	readings := make([]map[string]interface{}, len(history))
+	for i := range history {
+		reading := map[string]interface{}{
+			"recorded_at":            history[i].RecordedAt,
+			"device_timestamp":       history[i].DeviceTimestamp,
+			"sequence":               history[i].Sequence,
+			"clock_skew_ms":          history[i].ClockSkewMs,
+			"clock_skew_flagged":     history[i].ClockSkewFlagged,
+			"calibration_profile_id": history[i].CalibrationProfileID,
+			"uncalibrated_values":    history[i].UncalibratedValues,
+			"extra_sensors":          history[i].ExtraSensors,
+		}
+		for _, field := range fields {
+			reading[field] = historyFieldValue(&history[i], field)
+		}
+		readings[i] = reading
+	}
+	return readings, total, nil
+}
+
+// getRollupReadings returns a page of aggregated buckets, one entry per bucket_start
+func (s *TelemetryHistoryService) getRollupReadings(roomID uint, resolution string, from, to time.Time, fields []string, limit, offset int) ([]map[string]interface{}, int64, error) {
+	buckets, total, err := s.rollupRepo.GetRollupBuckets(roomID, resolution, from, to, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch telemetry rollups: %w", err)
+	}
+	if len(buckets) == 0 || len(fields) == 0 {
+		return []map[string]interface{}{}, total, nil
+	}
+
+	rollups, err := s.rollupRepo.GetRollupsByRoomID(roomID, resolution, buckets[0], buckets[len(buckets)-1], fields)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch telemetry rollups: %w", err)
+	}
+
+	readings := make([]map[string]interface{}, len(buckets))
+	index := make(map[int64]int, len(buckets)) // keyed by unix seconds, time.Time keys also compare location
+	for i, bucket := range buckets {
+		readings[i] = map[string]interface{}{
+			"bucket_start": bucket,
+		}
+		index[bucket.Unix()] = i
+	}
+	for _, r := range rollups {
+		i, ok := index[r.BucketStart.Unix()]
+		if !ok {
+			continue
+		}
+		readings[i][r.Sensor] = map[string]interface{}{
+			"min":          r.MinValue,
+			"max":          r.MaxValue,
+			"avg":          r.AvgValue,
+			"sample_count": r.SampleCount,
+		}
+	}
+	return readings, total, nil
+}
+
+// resolveHistoryResolution validates the requested resolution
+// Raw readings stay the default so existing clients keep their response shape;
+// "auto" picks the finest level that keeps a full range within roughly a thousand points
+func resolveHistoryResolution(requested string, from, to time.Time) (string, error) {
+	switch requested {
+	case "", "raw":
+		return "raw", nil
+	case "auto":
+		span := to.Sub(from)
+		switch {
+		case span <= 2*time.Hour:
+			return "raw", nil
+		case span <= 24*time.Hour:
+			return "1m", nil
+		case span <= 10*24*time.Hour:
+			return "15m", nil
+		default:
+			return "1h", nil
+		}
+	}
+	if _, ok := rollupResolutionByName(requested); !ok {
+		return "", errors.New("invalid resolution: must be 'raw', '1m', '15m', '1h', or 'auto'")
+	}
+	return requested, nil
+}
+
+// filterRollupFields drops fields that are not aggregated into rollups
+func filterRollupFields(fields []string, schema []models.SensorDefinition) []string {
+	filtered := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if isRoomSensor(schema, field) {
+			filtered = append(filtered, field)
+		}
+	}
+	return filtered
+}
+
+// normalizeHistoryFields validates the requested fields, defaulting to all dedicated columns
+func normalizeHistoryFields(requested []string, schema []models.SensorDefinition) ([]string, error) {
+	if len(requested) == 0 {
+		return historyFields, nil
+	}
+
+	fields := make([]string, 0, len(requested))
+	seen := make(map[string]bool)
+	for _, field := range requested {
+		field = strings.TrimSpace(field)
+		if field == "" || seen[field] {
+			continue
+		}
+		if !isHistoryField(field) && findSensorDefinition(schema, field) == nil {
+			return nil, fmt.Errorf("unknown field: %s", field)
+		}
+		seen[field] = true
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return historyFields, nil
+	}
+	return fields, nil
+}
+
+// isHistoryField reports whether field is a known telemetry field
+func isHistoryField(field string) bool {
+	for _, known := range historyFields {
+		if known == field {
+			return true
+		}
+	}
+	return false
+}
+
+// historyFieldValue returns the value of a named telemetry field from a history row
+func historyFieldValue(h *models.TheaterTelemetryHistory, field string) interface{} {
+	switch field {
+	case "temp":
+		return h.Temp
+	case "humidity":
+		return h.Humidity
+	case "room_pressure":
+		return h.RoomPressure
+	case "room_status":
+		return h.RoomStatus
+	case "laju_aliran_ahu":
+		return h.LajuAliranAhu
+	case "volume_ruangan":
+		return h.VolumeRuangan
+	case "logic_ahu":
+		return h.LogicAhu
+	case "oxygen":
+		return h.Oxygen
+	case "nitrous":
+		return h.Nitrous
+	case "air":
+		return h.Air
+	case "vacuum":
+		return h.Vacuum
+	case "instrument":
+		return h.Instrument
+	case "carbon":
+		return h.Carbon
+	}
+	if value, ok := h.ExtraSensors[field]; ok {
+		return &value
+	}
+	return nil
+}
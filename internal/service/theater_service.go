@@ -10,19 +10,34 @@ import (
 )
 
 type TheaterService struct {
-	theaterRepo      *repository.TheaterRepository
-	auditRepo        *repository.AuditRepository
-	roomRepo         *repository.RoomRepository
-	userHospitalRepo *repository.UserHospitalRepository
+	theaterRepo         *repository.TheaterRepository
+	auditRepo           *repository.AuditRepository
+	roomRepo            *repository.RoomRepository
+	userHospitalRepo    *repository.UserHospitalRepository
+	streamService       *StreamService
+	annotator           *LiveStateAnnotator
+	sessionService      *OperationSessionService
+	countdownService    *CountdownService
+	webhookService      *WebhookService
 }
 
 func NewTheaterService(
 	theaterRepo *repository.TheaterRepository,
 	auditRepo *repository.AuditRepository,
+	streamService *StreamService,
+	annotator *LiveStateAnnotator,
+	sessionService *OperationSessionService,
+	countdownService *CountdownService,
+	webhookService *WebhookService,
 ) *TheaterService {
 	return &TheaterService{
-		theaterRepo: theaterRepo,
-		auditRepo:   auditRepo,
+		theaterRepo:         theaterRepo,
+		auditRepo:           auditRepo,
+		streamService:       streamService,
+		annotator:           annotator,
+		sessionService:      sessionService,
+		countdownService:    countdownService,
+		webhookService:      webhookService,
 	}
 }
 
@@ -32,23 +47,43 @@ func NewTheaterServiceWithAccessControl(
 	auditRepo *repository.AuditRepository,
 	roomRepo *repository.RoomRepository,
 	userHospitalRepo *repository.UserHospitalRepository,
+	streamService *StreamService,
+	annotator *LiveStateAnnotator,
+	sessionService *OperationSessionService,
+	countdownService *CountdownService,
+	webhookService *WebhookService,
 ) *TheaterService {
 	return &TheaterService{
-		theaterRepo:      theaterRepo,
-		auditRepo:        auditRepo,
-		roomRepo:         roomRepo,
-		userHospitalRepo: userHospitalRepo,
+		theaterRepo:         theaterRepo,
+		auditRepo:           auditRepo,
+		roomRepo:            roomRepo,
+		userHospitalRepo:    userHospitalRepo,
+		streamService:       streamService,
+		annotator:           annotator,
+		sessionService:      sessionService,
+		countdownService:    countdownService,
+		webhookService:      webhookService,
 	}
 }
 
 // GetLiveState retrieves the live state for a room
 func (s *TheaterService) GetLiveState(roomName string) (*models.TheaterLiveState, error) {
-	return s.theaterRepo.GetLiveState(roomName)
+	state, err := s.theaterRepo.GetLiveState(roomName)
+	if err != nil {
+		return nil, err
+	}
+	s.annotator.Annotate(state, time.Now())
+	return state, nil
 }
 
 // GetAllLiveStates retrieves live states for all rooms
 func (s *TheaterService) GetAllLiveStates() ([]models.TheaterLiveState, error) {
-	return s.theaterRepo.GetAllLiveStates()
+	states, err := s.theaterRepo.GetAllLiveStates()
+	if err != nil {
+		return nil, err
+	}
+	s.annotator.AnnotateAll(liveStatePointers(states), time.Now())
+	return states, nil
 }
 
 // GetAllRooms retrieves a list of all room names
@@ -65,55 +100,40 @@ func (s *TheaterService) GetAllRooms() ([]string, error) {
 	return rooms, nil
 }
 
-// UpdateOperationTimer handles start/stop/reset actions for operation timer
-func (s *TheaterService) UpdateOperationTimer(roomName, action string, userID uint) error {
+// UpdateOperationTimer handles start/pause/resume/stop/reset/lap actions for operation timer
+// Rooms managed by room_id also get an operation session record
+func (s *TheaterService) UpdateOperationTimer(roomName, action, procedureLabel, reason, markerName string, userID uint) error {
 	// Get current state
 	state, err := s.theaterRepo.GetLiveState(roomName)
 	if err != nil {
 		return err
 	}
 
-	updates := make(map[string]interface{})
-	var auditDetails string
-
-	switch action {
-	case "start":
-		if state.OpIsRunning {
-			return errors.New("timer is already running")
-		}
-		now := time.Now()
-		updates["op_start_time"] = now
-		updates["op_is_running"] = true
-		auditDetails = fmt.Sprintf("Started operation timer for room %s", roomName)
-
-	case "stop":
-		if !state.OpIsRunning {
-			return errors.New("timer is not running")
-		}
-		if state.OpStartTime != nil {
-			elapsed := int(time.Since(*state.OpStartTime).Seconds())
-			updates["op_accumulated_seconds"] = state.OpAccumulatedSeconds + elapsed
-		}
-		updates["op_is_running"] = false
-		auditDetails = fmt.Sprintf("Stopped operation timer for room %s", roomName)
-
-	case "reset":
-		updates["op_start_time"] = nil
-		updates["op_accumulated_seconds"] = 0
-		updates["op_is_running"] = false
-		auditDetails = fmt.Sprintf("Reset operation timer for room %s", roomName)
-
-	default:
-		return errors.New("invalid action: must be 'start', 'stop', or 'reset'")
+	if action == "lap" && state.RoomID == nil {
+		return errors.New("lap markers require a room managed by room_id")
+	}
+	updates, description, err := operationTimerUpdates(state, action, reason, markerName)
+	if err != nil {
+		return err
 	}
 
 	// Update the state
-	if err := s.theaterRepo.UpdateOperationTimer(roomName, updates); err != nil {
-		return fmt.Errorf("failed to update operation timer: %w", err)
+	if len(updates) > 0 {
+		if err := s.theaterRepo.UpdateOperationTimer(roomName, updates); err != nil {
+			return fmt.Errorf("failed to update operation timer: %w", err)
+		}
+	}
+	if state.RoomID != nil {
+		if err := s.recordOperationSession(*state.RoomID, action, procedureLabel, reason, markerName, userID); err != nil {
+			return err
+		}
 	}
+	s.publishLiveStateByName(roomName)
+	s.publishOperationTimer(state.RoomID, roomName, action, procedureLabel, reason, markerName, userID)
 
 	// Log the action
 	userIDPtr := &userID
+	auditDetails := fmt.Sprintf("%s for room %s%s", description, roomName, formatTimerReason(reason))
 	_ = s.auditRepo.CreateAuditLog(userIDPtr, "timer_operation", auditDetails)
 
 	return nil
@@ -146,6 +166,8 @@ func (s *TheaterService) UpdateCountdownTimer(roomName, action string, durationM
 		updates["cd_target_time"] = targetTime
 		updates["cd_duration_seconds"] = duration * 60
 		updates["cd_is_running"] = true
+		updates["cd_label"] = ""
+		updates["cd_schedule_id"] = nil
 		auditDetails = fmt.Sprintf("Started countdown timer for room %s with duration %d minutes", roomName, duration)
 
 	case "stop":
@@ -159,6 +181,8 @@ func (s *TheaterService) UpdateCountdownTimer(roomName, action string, durationM
 		updates["cd_target_time"] = nil
 		updates["cd_duration_seconds"] = 3600
 		updates["cd_is_running"] = false
+		updates["cd_label"] = ""
+		updates["cd_schedule_id"] = nil
 		auditDetails = fmt.Sprintf("Reset countdown timer for room %s", roomName)
 
 	default:
@@ -169,6 +193,8 @@ func (s *TheaterService) UpdateCountdownTimer(roomName, action string, durationM
 	if err := s.theaterRepo.UpdateCountdownTimer(roomName, updates); err != nil {
 		return fmt.Errorf("failed to update countdown timer: %w", err)
 	}
+	s.recordCountdownEvent(state, action, updates, userID)
+	s.publishLiveStateByName(roomName)
 
 	// Log the action
 	userIDPtr := &userID
@@ -213,6 +239,7 @@ func (s *TheaterService) AdjustCountdownTimer(roomName string, minutes int, user
 	if err := s.theaterRepo.UpdateCountdownTimer(roomName, updates); err != nil {
 		return fmt.Errorf("failed to adjust countdown timer: %w", err)
 	}
+	s.publishLiveStateByName(roomName)
 
 	// Log the action
 	userIDPtr := &userID
@@ -241,11 +268,17 @@ func (s *TheaterService) GetLiveStateByRoomID(roomID uint, userID uint, role str
 		return nil, err
 	}
 
-	return s.theaterRepo.GetLiveStateByRoomID(roomID)
+	state, err := s.theaterRepo.GetLiveStateByRoomID(roomID)
+	if err != nil {
+		return nil, err
+	}
+	s.annotator.Annotate(state, time.Now())
+	return state, nil
 }
 
-// UpdateOperationTimerByRoomID handles start/stop/reset actions for operation timer by room_id
-func (s *TheaterService) UpdateOperationTimerByRoomID(roomID uint, action string, userID uint, role string) error {
+// UpdateOperationTimerByRoomID handles start/pause/resume/stop/reset/lap actions for operation timer by room_id
+// Each action is also recorded in the room's operation session
+func (s *TheaterService) UpdateOperationTimerByRoomID(roomID uint, action, procedureLabel, reason, markerName string, userID uint, role string) error {
 	// Check access control
 	if err := s.checkUserRoomAccess(roomID, userID, role); err != nil {
 		return err
@@ -257,54 +290,127 @@ func (s *TheaterService) UpdateOperationTimerByRoomID(roomID uint, action string
 		return err
 	}
 
+	updates, description, err := operationTimerUpdates(state, action, reason, markerName)
+	if err != nil {
+		return err
+	}
+
+	// Update the state
+	if len(updates) > 0 {
+		if err := s.theaterRepo.UpdateOperationTimerByRoomID(roomID, updates); err != nil {
+			return fmt.Errorf("failed to update operation timer: %w", err)
+		}
+	}
+	if err := s.recordOperationSession(roomID, action, procedureLabel, reason, markerName, userID); err != nil {
+		return err
+	}
+	s.publishLiveStateByRoomID(roomID)
+	s.publishOperationTimer(&roomID, state.RoomName, action, procedureLabel, reason, markerName, userID)
+
+	// Log the action
+	userIDPtr := &userID
+	auditDetails := fmt.Sprintf("%s for room_id %d%s", description, roomID, formatTimerReason(reason))
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "timer_operation", auditDetails)
+
+	return nil
+}
+
+// operationTimerUpdates validates a stopwatch action against the live state and returns the
+// live state fields it changes with a description for the audit log
+// The stopwatch is idle, running, paused (resumable) or stopped (final time shown until reset)
+func operationTimerUpdates(state *models.TheaterLiveState, action, reason, markerName string) (map[string]interface{}, string, error) {
 	updates := make(map[string]interface{})
-	var auditDetails string
+	now := time.Now()
 
 	switch action {
 	case "start":
 		if state.OpIsRunning {
-			return errors.New("timer is already running")
+			return nil, "", errors.New("timer is already running")
+		}
+		if state.OpIsPaused {
+			return nil, "", errors.New("timer is paused: use resume to continue")
 		}
-		now := time.Now()
 		updates["op_start_time"] = now
+		updates["op_accumulated_seconds"] = 0
 		updates["op_is_running"] = true
-		auditDetails = fmt.Sprintf("Started operation timer for room_id %d", roomID)
+		return updates, "Started operation timer", nil
 
-	case "stop":
+	case "pause":
 		if !state.OpIsRunning {
-			return errors.New("timer is not running")
+			return nil, "", errors.New("timer is not running")
 		}
-		if state.OpStartTime != nil {
-			elapsed := int(time.Since(*state.OpStartTime).Seconds())
-			updates["op_accumulated_seconds"] = state.OpAccumulatedSeconds + elapsed
+		if reason == "" {
+			return nil, "", errors.New("reason is required to pause the timer")
 		}
+		updates["op_accumulated_seconds"] = state.OpAccumulatedSeconds + runningSeconds(state, now)
 		updates["op_is_running"] = false
-		auditDetails = fmt.Sprintf("Stopped operation timer for room_id %d", roomID)
+		updates["op_is_paused"] = true
+		updates["op_pause_reason"] = reason
+		return updates, "Paused operation timer", nil
+
+	case "resume":
+		if !state.OpIsPaused {
+			return nil, "", errors.New("timer is not paused")
+		}
+		updates["op_start_time"] = now
+		updates["op_is_running"] = true
+		updates["op_is_paused"] = false
+		updates["op_pause_reason"] = ""
+		return updates, "Resumed operation timer", nil
+
+	case "stop":
+		if !state.OpIsRunning && !state.OpIsPaused {
+			return nil, "", errors.New("timer is not running")
+		}
+		if state.OpIsRunning {
+			updates["op_accumulated_seconds"] = state.OpAccumulatedSeconds + runningSeconds(state, now)
+		}
+		updates["op_is_running"] = false
+		updates["op_is_paused"] = false
+		updates["op_pause_reason"] = ""
+		return updates, "Stopped operation timer", nil
 
 	case "reset":
 		updates["op_start_time"] = nil
 		updates["op_accumulated_seconds"] = 0
 		updates["op_is_running"] = false
-		auditDetails = fmt.Sprintf("Reset operation timer for room_id %d", roomID)
+		updates["op_is_paused"] = false
+		updates["op_pause_reason"] = ""
+		return updates, "Reset operation timer", nil
+
+	case "lap":
+		if !state.OpIsRunning && !state.OpIsPaused {
+			return nil, "", errors.New("timer is not running")
+		}
+		if markerName == "" {
+			return nil, "", errors.New("name is required for a lap marker")
+		}
+		return updates, fmt.Sprintf("Recorded lap marker %q on operation timer", markerName), nil
 
 	default:
-		return errors.New("invalid action: must be 'start', 'stop', or 'reset'")
+		return nil, "", errors.New("invalid action: must be 'start', 'pause', 'resume', 'stop', 'reset' or 'lap'")
 	}
+}
 
-	// Update the state
-	if err := s.theaterRepo.UpdateOperationTimerByRoomID(roomID, updates); err != nil {
-		return fmt.Errorf("failed to update operation timer: %w", err)
+// runningSeconds returns how long the stopwatch has run since it was last started or resumed
+func runningSeconds(state *models.TheaterLiveState, now time.Time) int {
+	if state.OpStartTime == nil {
+		return 0
 	}
+	return int(now.Sub(*state.OpStartTime).Seconds())
+}
 
-	// Log the action
-	userIDPtr := &userID
-	_ = s.auditRepo.CreateAuditLog(userIDPtr, "timer_operation", auditDetails)
-
-	return nil
+// formatTimerReason appends the reason given for a stopwatch action to an audit message
+func formatTimerReason(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return fmt.Sprintf(": %s", reason)
 }
 
 // UpdateCountdownTimerByRoomID handles start/stop/reset actions for countdown timer by room_id
-func (s *TheaterService) UpdateCountdownTimerByRoomID(roomID uint, action string, durationMinutes *int, userID uint, role string) error {
+// A preset supplies the label and default duration of a started countdown
+func (s *TheaterService) UpdateCountdownTimerByRoomID(roomID uint, action string, durationMinutes *int, presetID *uint, label string, userID uint, role string) error {
 	// Check access control
 	if err := s.checkUserRoomAccess(roomID, userID, role); err != nil {
 		return err
@@ -327,6 +433,19 @@ func (s *TheaterService) UpdateCountdownTimerByRoomID(roomID uint, action string
 
 		// Default duration is 60 minutes if not specified
 		duration := 60
+		if presetID != nil {
+			if s.countdownService == nil {
+				return errors.New("countdown presets are not available")
+			}
+			presetLabel, presetMinutes, err := s.countdownService.ResolvePreset(*presetID, roomID)
+			if err != nil {
+				return err
+			}
+			if label == "" {
+				label = presetLabel
+			}
+			duration = presetMinutes
+		}
 		if durationMinutes != nil && *durationMinutes > 0 {
 			duration = *durationMinutes
 		}
@@ -335,7 +454,12 @@ func (s *TheaterService) UpdateCountdownTimerByRoomID(roomID uint, action string
 		updates["cd_target_time"] = targetTime
 		updates["cd_duration_seconds"] = duration * 60
 		updates["cd_is_running"] = true
+		updates["cd_label"] = label
+		updates["cd_schedule_id"] = nil
 		auditDetails = fmt.Sprintf("Started countdown timer for room_id %d with duration %d minutes", roomID, duration)
+		if label != "" {
+			auditDetails += fmt.Sprintf(" (%s)", label)
+		}
 
 	case "stop":
 		if !state.CdIsRunning {
@@ -348,6 +472,8 @@ func (s *TheaterService) UpdateCountdownTimerByRoomID(roomID uint, action string
 		updates["cd_target_time"] = nil
 		updates["cd_duration_seconds"] = 3600
 		updates["cd_is_running"] = false
+		updates["cd_label"] = ""
+		updates["cd_schedule_id"] = nil
 		auditDetails = fmt.Sprintf("Reset countdown timer for room_id %d", roomID)
 
 	default:
@@ -358,6 +484,8 @@ func (s *TheaterService) UpdateCountdownTimerByRoomID(roomID uint, action string
 	if err := s.theaterRepo.UpdateCountdownTimerByRoomID(roomID, updates); err != nil {
 		return fmt.Errorf("failed to update countdown timer: %w", err)
 	}
+	s.recordCountdownEvent(state, action, updates, userID)
+	s.publishLiveStateByRoomID(roomID)
 
 	// Log the action
 	userIDPtr := &userID
@@ -407,6 +535,7 @@ func (s *TheaterService) AdjustCountdownTimerByRoomID(roomID uint, minutes int,
 	if err := s.theaterRepo.UpdateCountdownTimerByRoomID(roomID, updates); err != nil {
 		return fmt.Errorf("failed to adjust countdown timer: %w", err)
 	}
+	s.publishLiveStateByRoomID(roomID)
 
 	// Log the action
 	userIDPtr := &userID
@@ -420,6 +549,89 @@ func (s *TheaterService) AdjustCountdownTimerByRoomID(roomID uint, minutes int,
 	return nil
 }
 
+// recordOperationSession applies a stopwatch action to the room's operation session
+// Only a lap marker that cannot be recorded is reported back to the caller
+func (s *TheaterService) recordOperationSession(roomID uint, action, procedureLabel, reason, markerName string, userID uint) error {
+	if s.sessionService == nil {
+		return nil
+	}
+	now := time.Now()
+	switch action {
+	case "start":
+		s.sessionService.RecordStart(roomID, procedureLabel, userID, now)
+	case "pause":
+		s.sessionService.RecordPause(roomID, reason, userID, now)
+	case "resume":
+		s.sessionService.RecordResume(roomID, reason, userID, now)
+	case "stop", "reset":
+		s.sessionService.RecordStop(roomID, reason, userID, now)
+	case "lap":
+		if _, err := s.sessionService.RecordLap(roomID, markerName, userID, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishOperationTimer queues a stopwatch action for webhook subscribers
+func (s *TheaterService) publishOperationTimer(roomID *uint, roomName, action, procedureLabel, reason, markerName string, userID uint) {
+	if s.webhookService == nil {
+		return
+	}
+	data := map[string]interface{}{
+		"action":    action,
+		"room_name": roomName,
+		"user_id":   userID,
+	}
+	if procedureLabel != "" {
+		data["procedure_label"] = procedureLabel
+	}
+	if reason != "" {
+		data["reason"] = reason
+	}
+	if markerName != "" {
+		data["name"] = markerName
+	}
+	s.webhookService.Publish(models.WebhookEventOperationTimer, roomID, data, time.Now())
+}
+
+// recordCountdownEvent logs a countdown started by staff, or how a running one ended
+// state is the live state from before the action, updates the countdown fields it applied
+func (s *TheaterService) recordCountdownEvent(state *models.TheaterLiveState, action string, updates map[string]interface{}, userID uint) {
+	if s.countdownService == nil || state.RoomID == nil {
+		return
+	}
+	now := time.Now()
+	switch action {
+	case "start":
+		label, _ := updates["cd_label"].(string)
+		durationSeconds, _ := updates["cd_duration_seconds"].(int)
+		s.countdownService.RecordEvent(*state.RoomID, nil, models.CountdownEventStarted, label, durationSeconds, &userID, now)
+	case "stop", "reset":
+		s.countdownService.RecordCountdownEnd(state, &userID, now)
+	}
+}
+
+// publishLiveStateByName pushes the latest state of a room to streaming clients (legacy room_name)
+func (s *TheaterService) publishLiveStateByName(roomName string) {
+	if s.streamService == nil {
+		return
+	}
+	if state, err := s.theaterRepo.GetLiveState(roomName); err == nil {
+		s.streamService.PublishLiveState(state)
+	}
+}
+
+// publishLiveStateByRoomID pushes the latest state of a room to streaming clients
+func (s *TheaterService) publishLiveStateByRoomID(roomID uint) {
+	if s.streamService == nil {
+		return
+	}
+	if state, err := s.theaterRepo.GetLiveStateByRoomID(roomID); err == nil {
+		s.streamService.PublishLiveState(state)
+	}
+}
+
 // checkUserRoomAccess checks if a user has access to a specific room
 func (s *TheaterService) checkUserRoomAccess(roomID uint, userID uint, role string) error {
 	// If access control dependencies are not configured, skip the check
@@ -428,7 +640,7 @@ func (s *TheaterService) checkUserRoomAccess(roomID uint, userID uint, role stri
 	}
 
 	// Admin users have access to all rooms
-	if role == "admin" {
+	if role == models.UserRoleSuperAdmin {
 		return nil
 	}
 
@@ -0,0 +1,502 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"iot-backend-room-monitoring/internal/config"
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/internal/repository"
+	"iot-backend-room-monitoring/pkg/utils"
+
+	"github.com/google/uuid"
+)
+
+// webhookDeliveryInterval is how often the delivery job looks for due deliveries
+const webhookDeliveryInterval = 5 * time.Second
+
+// webhookDeliveryBatch is the number of due deliveries attempted per tick
+const webhookDeliveryBatch = 50
+
+// webhookPruneInterval is how often deliveries past the retention period are deleted
+const webhookPruneInterval = time.Hour
+
+// webhookPruneBatch is the number of deliveries deleted per statement, keeping each delete short
+const webhookPruneBatch = 1000
+
+// webhookPublishBuffer is the number of published events waiting to be queued before publishers fall back to queueing in the background
+const webhookPublishBuffer = 256
+
+// webhookErrorBodyLimit caps how much of a failed response body is kept in the delivery log
+const webhookErrorBodyLimit = 200
+
+// webhookEventTypes lists the events a subscription can receive
+var webhookEventTypes = []string{
+	models.WebhookEventOperationTimer,
+	models.WebhookEventCountdownTimer,
+	models.WebhookEventAlarmRaised,
+	models.WebhookEventAlarmAcknowledged,
+	models.WebhookEventAlarmCleared,
+	models.WebhookEventDeviceOffline,
+}
+
+// WebhookEvent is the JSON body posted to subscribers
+// ID is shared by every delivery of the event so receivers can drop retried duplicates
+type WebhookEvent struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	RoomID     *uint       `json:"room_id"`
+	RoomCode   string      `json:"room_code,omitempty"`
+	RoomName   string      `json:"room_name,omitempty"`
+	HospitalID *uint       `json:"hospital_id"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// WebhookSubscriptionRequest is the body for creating or updating a webhook subscription
+type WebhookSubscriptionRequest struct {
+	Name       string   `json:"name" binding:"required,max=100"`
+	URL        string   `json:"url" binding:"required,url,max=500"`
+	EventTypes []string `json:"event_types"`                               // Empty = every event
+	HospitalID *uint    `json:"hospital_id"`                               // Nil = rooms of every hospital
+	Secret     string   `json:"secret" binding:"omitempty,min=16,max=100"` // Generated on create when empty, kept on update when empty
+	IsActive   *bool    `json:"is_active"`                                 // Defaults to true
+}
+
+// WebhookService queues room events for webhook subscribers and delivers them with retries
+type WebhookService struct {
+	webhookRepo *repository.WebhookRepository
+	roomRepo    *repository.RoomRepository
+	auditRepo   *repository.AuditRepository
+	httpClient  *http.Client
+	cfg         config.WebhookConfig
+
+	// Events handed over by Publish, queued for delivery by the publisher goroutine
+	published chan WebhookEvent
+}
+
+func NewWebhookService(
+	webhookRepo *repository.WebhookRepository,
+	roomRepo *repository.RoomRepository,
+	auditRepo *repository.AuditRepository,
+	cfg config.WebhookConfig,
+) *WebhookService {
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		roomRepo:    roomRepo,
+		auditRepo:   auditRepo,
+		httpClient:  &http.Client{Timeout: cfg.Timeout},
+		cfg:         cfg,
+		published:   make(chan WebhookEvent, webhookPublishBuffer),
+	}
+}
+
+// Publish hands an event over for queueing without touching the database, so callers may hold their locks
+// The data is encoded right away so later changes to it don't reach subscribers
+func (s *WebhookService) Publish(eventType string, roomID *uint, data interface{}, at time.Time) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Error encoding %s webhook event: %v", eventType, err)
+		return
+	}
+	event := WebhookEvent{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		OccurredAt: at,
+		Data:       json.RawMessage(encoded),
+	}
+	if roomID != nil {
+		id := *roomID
+		event.RoomID = &id
+	}
+
+	select {
+	case s.published <- event:
+	default:
+		// The publisher goroutine is behind; queue this event on its own rather than block or drop it
+		go s.enqueue(event)
+	}
+}
+
+// StartPublisher queues published events for delivery until the context is cancelled
+func (s *WebhookService) StartPublisher(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-s.published:
+			s.enqueue(event)
+		}
+	}
+}
+
+// enqueue queues an event for every active subscription of the room's hospital that wants it
+// Delivery happens on the delivery job's next tick; failures to queue are logged
+func (s *WebhookService) enqueue(event WebhookEvent) {
+	eventType := event.Type
+	if event.RoomID != nil {
+		room, err := s.roomRepo.GetRoomByID(*event.RoomID)
+		if err != nil {
+			log.Printf("Error loading room_id=%d for %s webhook: %v", *event.RoomID, eventType, err)
+		} else {
+			event.RoomCode = room.RoomCode
+			event.RoomName = room.RoomName
+			event.HospitalID = &room.HospitalID
+		}
+	}
+
+	subscriptions, err := s.webhookRepo.GetActiveSubscriptionsForHospital(event.HospitalID)
+	if err != nil {
+		log.Printf("Error loading webhook subscriptions for %s event: %v", eventType, err)
+		return
+	}
+
+	var payload []byte
+	deliveries := make([]models.WebhookDelivery, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		if !commaListContains(subscription.EventTypes, eventType) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				log.Printf("Error encoding %s webhook event: %v", eventType, err)
+				return
+			}
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      eventType,
+			RoomID:         event.RoomID,
+			Payload:        string(payload),
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+		})
+	}
+
+	if err := s.webhookRepo.CreateDeliveries(deliveries); err != nil {
+		log.Printf("Error queueing %s webhook deliveries: %v", eventType, err)
+	}
+}
+
+// StartDeliveryJob periodically delivers queued webhook events and prunes the delivery log until the context is cancelled
+func (s *WebhookService) StartDeliveryJob(ctx context.Context) {
+	ticker := time.NewTicker(webhookDeliveryInterval)
+	defer ticker.Stop()
+	pruneTicker := time.NewTicker(webhookPruneInterval)
+	defer pruneTicker.Stop()
+
+	s.DeliverDue(time.Now())
+	s.PruneDeliveries(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.DeliverDue(now)
+		case now := <-pruneTicker.C:
+			s.PruneDeliveries(now)
+		}
+	}
+}
+
+// PruneDeliveries deletes delivered and failed deliveries older than the retention period
+// Pending deliveries are kept however old, they are still being retried
+func (s *WebhookService) PruneDeliveries(now time.Time) {
+	if s.cfg.Retention <= 0 {
+		return
+	}
+	cutoff := now.Add(-s.cfg.Retention)
+
+	var total int64
+	for {
+		deleted, err := s.webhookRepo.DeleteFinishedDeliveriesBefore(cutoff, webhookPruneBatch)
+		if err != nil {
+			log.Printf("Error pruning webhook deliveries: %v", err)
+			break
+		}
+		total += deleted
+		if deleted < webhookPruneBatch {
+			break
+		}
+	}
+	if total > 0 {
+		log.Printf("Pruned %d webhook deliveries older than %s", total, cutoff.Format(time.RFC3339))
+	}
+}
+
+// DeliverDue attempts every pending delivery whose next attempt is due
+func (s *WebhookService) DeliverDue(now time.Time) {
+	deliveries, err := s.webhookRepo.GetDueDeliveries(now, webhookDeliveryBatch)
+	if err != nil {
+		log.Printf("Error fetching due webhook deliveries: %v", err)
+		return
+	}
+
+	// Hold each claim past the request timeout so a slow receiver isn't sent the event twice
+	leaseUntil := now.Add(2 * s.cfg.Timeout)
+
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		claimed, err := s.webhookRepo.ClaimDelivery(delivery.ID, now, leaseUntil)
+		if err != nil {
+			log.Printf("Error claiming webhook delivery %d: %v", delivery.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.attempt(delivery)
+		}()
+	}
+	wg.Wait()
+}
+
+// attempt sends a delivery once and records the outcome, scheduling a retry on failure
+func (s *WebhookService) attempt(delivery *models.WebhookDelivery) {
+	var statusCode int
+	var err error
+	if delivery.Subscription == nil || !delivery.Subscription.IsActive {
+		err = errors.New("subscription is inactive")
+	} else {
+		statusCode, err = s.post(delivery.Subscription, delivery)
+	}
+
+	s.recordOutcome(delivery, statusCode, err, time.Now())
+	if err := s.webhookRepo.UpdateDelivery(delivery); err != nil {
+		log.Printf("Error saving webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// recordOutcome updates a delivery after an attempt: delivered, retried after a backoff, or failed for good
+func (s *WebhookService) recordOutcome(delivery *models.WebhookDelivery, statusCode int, err error, now time.Time) {
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.LastStatusCode = nil
+	if statusCode != 0 {
+		delivery.LastStatusCode = &statusCode
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= s.cfg.MaxAttempts || delivery.Subscription == nil || !delivery.Subscription.IsActive:
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.LastError = truncate(err.Error(), 500)
+		log.Printf("Webhook delivery %d (%s) failed after %d attempts: %v", delivery.ID, delivery.EventType, delivery.Attempts, err)
+	default:
+		delivery.NextAttemptAt = now.Add(backoffDelay(delivery.Attempts, s.cfg.RetryBaseDelay, s.cfg.RetryMaxDelay))
+		delivery.LastError = truncate(err.Error(), 500)
+	}
+}
+
+// post sends a delivery's payload signed with the subscription secret
+// Any non-2xx response is a failure; the status code is returned when a response arrived
+func (s *WebhookService) post(subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "iot-backend-room-monitoring-webhooks")
+	req.Header.Set("X-Webhook-Id", delivery.EventID)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", utils.SignWebhookPayload(subscription.Secret, timestamp, body))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// The limit can cut a character in half; drop the partial bytes so the error stays valid UTF-8
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, webhookErrorBodyLimit))
+		body := strings.ToValidUTF8(string(snippet), "")
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d: %s", resp.StatusCode, strings.TrimSpace(body))
+	}
+	return resp.StatusCode, nil
+}
+
+// GetSubscriptions lists every webhook subscription (admin only)
+func (s *WebhookService) GetSubscriptions() ([]models.WebhookSubscription, error) {
+	subscriptions, err := s.webhookRepo.GetSubscriptions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch webhook subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// CreateSubscription creates a webhook subscription (admin only)
+// The signing secret is only returned here and by RotateSecret
+func (s *WebhookService) CreateSubscription(req *WebhookSubscriptionRequest, userID uint) (*models.WebhookSubscription, string, error) {
+	subscription := &models.WebhookSubscription{CreatedBy: &userID}
+	if err := applySubscriptionRequest(subscription, req); err != nil {
+		return nil, "", err
+	}
+	if subscription.Secret == "" {
+		secret, err := utils.GenerateWebhookSecret()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		subscription.Secret = secret
+	}
+	if err := s.webhookRepo.CreateSubscription(subscription); err != nil {
+		return nil, "", fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Created webhook subscription ID: %d %q to %s", subscription.ID, subscription.Name, subscription.URL)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "webhook_subscription_create", details)
+
+	return subscription, subscription.Secret, nil
+}
+
+// UpdateSubscription updates a webhook subscription (admin only)
+func (s *WebhookService) UpdateSubscription(subscriptionID uint, req *WebhookSubscriptionRequest, userID uint) (*models.WebhookSubscription, error) {
+	subscription, err := s.webhookRepo.GetSubscriptionByID(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applySubscriptionRequest(subscription, req); err != nil {
+		return nil, err
+	}
+	if err := s.webhookRepo.UpdateSubscription(subscription); err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Updated webhook subscription ID: %d %q to %s", subscription.ID, subscription.Name, subscription.URL)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "webhook_subscription_update", details)
+
+	return subscription, nil
+}
+
+// RotateSecret replaces the signing secret of a webhook subscription (admin only)
+// Deliveries still queued are signed with the new secret when they are attempted
+func (s *WebhookService) RotateSecret(subscriptionID uint, userID uint) (*models.WebhookSubscription, string, error) {
+	subscription, err := s.webhookRepo.GetSubscriptionByID(subscriptionID)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := utils.GenerateWebhookSecret()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	subscription.Secret = secret
+	if err := s.webhookRepo.UpdateSubscription(subscription); err != nil {
+		return nil, "", fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Rotated secret of webhook subscription ID: %d %q", subscription.ID, subscription.Name)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "webhook_subscription_rotate_secret", details)
+
+	return subscription, secret, nil
+}
+
+// DeleteSubscription deletes a webhook subscription and its delivery log (admin only)
+func (s *WebhookService) DeleteSubscription(subscriptionID uint, userID uint) error {
+	subscription, err := s.webhookRepo.GetSubscriptionByID(subscriptionID)
+	if err != nil {
+		return err
+	}
+	if err := s.webhookRepo.DeleteSubscription(subscription.ID); err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Deleted webhook subscription ID: %d %q", subscription.ID, subscription.Name)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "webhook_subscription_delete", details)
+
+	return nil
+}
+
+// GetDeliveries lists the delivery log (admin only)
+func (s *WebhookService) GetDeliveries(filter repository.WebhookDeliveryFilter) ([]models.WebhookDelivery, int64, error) {
+	deliveries, total, err := s.webhookRepo.GetDeliveries(filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch webhook deliveries: %w", err)
+	}
+	return deliveries, total, nil
+}
+
+// RetryDelivery queues a failed delivery for one more attempt on the delivery job's next tick (admin only)
+func (s *WebhookService) RetryDelivery(deliveryID uint, userID uint) (*models.WebhookDelivery, error) {
+	delivery, err := s.webhookRepo.GetDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status != models.WebhookDeliveryFailed {
+		return nil, errors.New("only failed deliveries can be retried")
+	}
+
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.NextAttemptAt = time.Now()
+	if err := s.webhookRepo.UpdateDelivery(delivery); err != nil {
+		return nil, fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+
+	userIDPtr := &userID
+	details := fmt.Sprintf("Retried webhook delivery ID: %d (%s) of subscription ID: %d", delivery.ID, delivery.EventType, delivery.SubscriptionID)
+	_ = s.auditRepo.CreateAuditLog(userIDPtr, "webhook_delivery_retry", details)
+
+	return delivery, nil
+}
+
+// applySubscriptionRequest validates a subscription request and copies it onto the subscription
+func applySubscriptionRequest(subscription *models.WebhookSubscription, req *WebhookSubscriptionRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return errors.New("subscription name is required")
+	}
+	if !strings.HasPrefix(req.URL, "http://") && !strings.HasPrefix(req.URL, "https://") {
+		return errors.New("url must be an http or https URL")
+	}
+	for _, eventType := range req.EventTypes {
+		if !isWebhookEventType(eventType) {
+			return fmt.Errorf("unknown event type %q, must be one of: %s", eventType, strings.Join(webhookEventTypes, ", "))
+		}
+	}
+
+	subscription.Name = name
+	subscription.URL = req.URL
+	subscription.EventTypes = strings.Join(req.EventTypes, ",")
+	subscription.HospitalID = req.HospitalID
+	if req.Secret != "" {
+		subscription.Secret = req.Secret
+	}
+	subscription.IsActive = req.IsActive == nil || *req.IsActive
+	return nil
+}
+
+func isWebhookEventType(eventType string) bool {
+	for _, known := range webhookEventTypes {
+		if known == eventType {
+			return true
+		}
+	}
+	return false
+}
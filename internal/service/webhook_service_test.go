@@ -0,0 +1,235 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"iot-backend-room-monitoring/internal/config"
+	"iot-backend-room-monitoring/internal/models"
+	"iot-backend-room-monitoring/pkg/utils"
+)
+
+func newTestWebhookService() *WebhookService {
+	return NewWebhookService(nil, nil, nil, config.WebhookConfig{
+		Timeout:        time.Second,
+		MaxAttempts:    3,
+		RetryBaseDelay: 30 * time.Second,
+		RetryMaxDelay:  time.Hour,
+	})
+}
+
+func newTestDelivery(url string) *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		ID:        42,
+		EventID:   "3f0c9a8e-6a51-4d7e-9a39-0d4f6c1b2a10",
+		EventType: models.WebhookEventAlarmRaised,
+		Payload:   `{"type":"alarm_raised","data":{"metric":"temp"}}`,
+		Status:    models.WebhookDeliveryPending,
+		Subscription: &models.WebhookSubscription{
+			ID:       7,
+			URL:      url,
+			Secret:   "whsec_test-secret",
+			IsActive: true,
+		},
+	}
+}
+
+func TestPostSignsPayload(t *testing.T) {
+	var got *http.Request
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	s := newTestWebhookService()
+	delivery := newTestDelivery(server.URL)
+
+	status, err := s.post(delivery.Subscription, delivery)
+	if err != nil {
+		t.Fatalf("post returned error: %v", err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", status, http.StatusNoContent)
+	}
+	if string(gotBody) != delivery.Payload {
+		t.Errorf("body = %s, want the stored payload", gotBody)
+	}
+
+	timestamp, err := strconv.ParseInt(got.Header.Get("X-Webhook-Timestamp"), 10, 64)
+	if err != nil {
+		t.Fatalf("X-Webhook-Timestamp %q is not a unix time", got.Header.Get("X-Webhook-Timestamp"))
+	}
+	want := utils.SignWebhookPayload(delivery.Subscription.Secret, timestamp, gotBody)
+	if signature := got.Header.Get("X-Webhook-Signature"); signature != want {
+		t.Errorf("X-Webhook-Signature = %q, want %q", signature, want)
+	}
+	if other := utils.SignWebhookPayload("whsec_other", timestamp, gotBody); other == want {
+		t.Errorf("signature does not depend on the secret")
+	}
+
+	headers := map[string]string{
+		"Content-Type":       "application/json",
+		"X-Webhook-Id":       delivery.EventID,
+		"X-Webhook-Event":    delivery.EventType,
+		"X-Webhook-Delivery": "42",
+	}
+	for name, value := range headers {
+		if got.Header.Get(name) != value {
+			t.Errorf("%s = %q, want %q", name, got.Header.Get(name), value)
+		}
+	}
+}
+
+func TestPostFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		// Longer than the kept snippet, with a character across the cut
+		io.WriteString(w, "x"+strings.Repeat("é", webhookErrorBodyLimit))
+	}))
+	defer server.Close()
+
+	s := newTestWebhookService()
+	delivery := newTestDelivery(server.URL)
+
+	status, err := s.post(delivery.Subscription, delivery)
+	if err == nil {
+		t.Fatal("post succeeded, want error for a 503 response")
+	}
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+	if !utf8.ValidString(err.Error()) {
+		t.Errorf("error %q is not valid UTF-8", err.Error())
+	}
+}
+
+func TestDeliveryRetriesUntilReceiverRecovers(t *testing.T) {
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	s := newTestWebhookService()
+	delivery := newTestDelivery(server.URL)
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	status, err := s.post(delivery.Subscription, delivery)
+	s.recordOutcome(delivery, status, err, now)
+	if delivery.Status != models.WebhookDeliveryPending {
+		t.Fatalf("status after a failed attempt = %s, want pending", delivery.Status)
+	}
+	if want := now.Add(30 * time.Second); !delivery.NextAttemptAt.Equal(want) {
+		t.Errorf("next attempt at %v, want %v", delivery.NextAttemptAt, want)
+	}
+	if delivery.LastStatusCode == nil || *delivery.LastStatusCode != http.StatusInternalServerError {
+		t.Errorf("last status code = %v, want 500", delivery.LastStatusCode)
+	}
+	if delivery.LastError == "" {
+		t.Error("last error is empty after a failed attempt")
+	}
+
+	later := now.Add(30 * time.Second)
+	status, err = s.post(delivery.Subscription, delivery)
+	s.recordOutcome(delivery, status, err, later)
+	if delivery.Status != models.WebhookDeliveryDelivered {
+		t.Fatalf("status after a successful retry = %s, want delivered", delivery.Status)
+	}
+	if delivery.Attempts != 2 {
+		t.Errorf("attempts = %d, want 2", delivery.Attempts)
+	}
+	if delivery.DeliveredAt == nil || !delivery.DeliveredAt.Equal(later) {
+		t.Errorf("delivered at %v, want %v", delivery.DeliveredAt, later)
+	}
+	if delivery.LastError != "" {
+		t.Errorf("last error = %q, want it cleared", delivery.LastError)
+	}
+}
+
+func TestDeliveryFailsAfterMaxAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	s := newTestWebhookService()
+	delivery := newTestDelivery(server.URL)
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	for i := 0; i < s.cfg.MaxAttempts; i++ {
+		status, err := s.post(delivery.Subscription, delivery)
+		s.recordOutcome(delivery, status, err, now)
+	}
+	if delivery.Status != models.WebhookDeliveryFailed {
+		t.Errorf("status after %d failed attempts = %s, want failed", s.cfg.MaxAttempts, delivery.Status)
+	}
+}
+
+func TestDeliveryToInactiveSubscriptionFailsAtOnce(t *testing.T) {
+	s := newTestWebhookService()
+	delivery := newTestDelivery("http://127.0.0.1:0")
+	delivery.Subscription.IsActive = false
+
+	s.recordOutcome(delivery, 0, io.ErrUnexpectedEOF, time.Now())
+	if delivery.Status != models.WebhookDeliveryFailed {
+		t.Errorf("status = %s, want failed without retries", delivery.Status)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 30 * time.Second},
+		{attempts: 2, want: time.Minute},
+		{attempts: 3, want: 2 * time.Minute},
+		{attempts: 8, want: 64 * time.Minute},
+		{attempts: 9, want: 90 * time.Minute},
+		{attempts: 50, want: 90 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := backoffDelay(tt.attempts, 30*time.Second, 90*time.Minute); got != tt.want {
+			t.Errorf("backoffDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateKeepsCharactersWhole(t *testing.T) {
+	tests := []struct {
+		value string
+		n     int
+		want  string
+	}{
+		{value: "short", n: 10, want: "short"},
+		{value: "abcdef", n: 3, want: "abc"},
+		{value: "aé", n: 2, want: "a"},   // é is two bytes
+		{value: "a€b", n: 3, want: "a"},  // € is three bytes
+		{value: "a€b", n: 4, want: "a€"}, // Cut right after the character
+	}
+
+	for _, tt := range tests {
+		got := truncate(tt.value, tt.n)
+		if got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.value, tt.n, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("truncate(%q, %d) = %q is not valid UTF-8", tt.value, tt.n, got)
+		}
+	}
+}
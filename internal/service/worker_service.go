@@ -4,42 +4,188 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
+	"iot-backend-room-monitoring/internal/config"
+	"iot-backend-room-monitoring/internal/metrics"
 	"iot-backend-room-monitoring/internal/models"
 	"iot-backend-room-monitoring/internal/repository"
 )
 
+// telemetryJob holds readings of one room waiting to be applied to its live state, oldest first
+type telemetryJob struct {
+	readings []models.TheaterRawTelemetry
+}
+
 type WorkerService struct {
-	theaterRepo *repository.TheaterRepository
+	theaterRepo      *repository.TheaterRepository
+	rollupService    *RollupService
+	alarmService     *AlarmService
+	streamService    *StreamService
+	deviceService    *DeviceService
+	stalenessService *StalenessService
+	countdownService *CountdownService
+	webhookService   *WebhookService
+	cfg              config.WorkerConfig
+
+	// Each room always maps to the same shard, so its readings are processed in order
+	shards []chan telemetryJob
 }
 
-func NewWorkerService(theaterRepo *repository.TheaterRepository) *WorkerService {
+func NewWorkerService(
+	theaterRepo *repository.TheaterRepository,
+	rollupService *RollupService,
+	alarmService *AlarmService,
+	streamService *StreamService,
+	deviceService *DeviceService,
+	stalenessService *StalenessService,
+	countdownService *CountdownService,
+	webhookService *WebhookService,
+	cfg config.WorkerConfig,
+) *WorkerService {
+	shards := make([]chan telemetryJob, cfg.Shards)
+	for i := range shards {
+		shards[i] = make(chan telemetryJob, cfg.QueueSize)
+	}
+
 	return &WorkerService{
-		theaterRepo: theaterRepo,
+		theaterRepo:      theaterRepo,
+		rollupService:    rollupService,
+		alarmService:     alarmService,
+		streamService:    streamService,
+		deviceService:    deviceService,
+		stalenessService: stalenessService,
+		countdownService: countdownService,
+		webhookService:   webhookService,
+		cfg:              cfg,
+		shards:           shards,
 	}
 }
 
 // Start begins the background worker that processes telemetry data
+// Readings arrive through Enqueue; a periodic reconciliation poll catches anything the queue missed
 func (w *WorkerService) Start(ctx context.Context) {
-	ticker := time.NewTicker(500 * time.Millisecond)
-	defer ticker.Stop()
+	var wg sync.WaitGroup
+	for i := range w.shards {
+		wg.Add(1)
+		go func(jobs chan telemetryJob) {
+			defer wg.Done()
+			w.runShard(ctx, jobs)
+		}(w.shards[i])
+	}
+	defer wg.Wait()
+
+	reconcileTicker := time.NewTicker(w.cfg.ReconcileInterval)
+	defer reconcileTicker.Stop()
 
-	log.Println("Background worker started - polling every 500ms")
+	// Rollups only change when a bucket closes, so they run on a slower cadence
+	rollupTicker := time.NewTicker(15 * time.Second)
+	defer rollupTicker.Stop()
+
+	// Devices that stopped reporting are marked offline and stale rooms alarmed
+	deviceTicker := time.NewTicker(30 * time.Second)
+	defer deviceTicker.Stop()
+
+	// Countdowns expire and scheduled countdowns start within a few seconds of their time
+	countdownTicker := time.NewTicker(5 * time.Second)
+	defer countdownTicker.Stop()
+
+	log.Printf("Background worker started - %d shards, reconciling every %v", len(w.shards), w.cfg.ReconcileInterval)
+
+	// Pick up anything written while the server was down
+	timeJob("reconcile", w.reconcile)
 
 	for {
 		select {
 		case <-ctx.Done():
 			log.Println("Background worker stopped")
 			return
-		case <-ticker.C:
-			w.processNewTelemetry()
+		case <-reconcileTicker.C:
+			timeJob("reconcile", w.reconcile)
+		case now := <-rollupTicker.C:
+			timeJob("rollup", func() { w.rollupService.Run(now) })
+		case now := <-deviceTicker.C:
+			timeJob("devices", func() {
+				w.publishOfflineDevices(w.deviceService.MarkOfflineDevices(now), now)
+				w.checkStaleRooms(now)
+			})
+		case now := <-countdownTicker.C:
+			timeJob("countdowns", func() {
+				// Expire first so a schedule due now can take over the room's countdown
+				w.countdownService.ExpireCountdowns(now)
+				w.countdownService.StartDueSchedules(now)
+			})
 		}
 	}
 }
 
-// processNewTelemetry processes updated raw telemetry data and updates live state for all rooms
-func (w *WorkerService) processNewTelemetry() {
+// timeJob runs a worker job, recording its duration
+func timeJob(job string, fn func()) {
+	start := time.Now()
+	fn()
+	metrics.WorkerJobDuration.WithLabelValues(job).Observe(time.Since(start).Seconds())
+}
+
+// publishOfflineDevices queues a device offline event per device for webhook subscribers
+func (w *WorkerService) publishOfflineDevices(devices []models.Device, now time.Time) {
+	if w.webhookService == nil {
+		return
+	}
+	for _, device := range devices {
+		device.Room = nil
+		roomID := device.RoomID
+		w.webhookService.Publish(models.WebhookEventDeviceOffline, &roomID, device, now)
+	}
+}
+
+// Enqueue hands a reading to the worker for processing
+// Returns false when the room's shard is full; the reading is then picked up by the next reconciliation
+func (w *WorkerService) Enqueue(raw *models.TheaterRawTelemetry) bool {
+	return w.EnqueueBatch([]models.TheaterRawTelemetry{*raw})
+}
+
+// EnqueueBatch hands several readings of the same room to the worker, ordered oldest first
+// They are applied in sequence so AHU cycles inside the batch are detected
+func (w *WorkerService) EnqueueBatch(readings []models.TheaterRawTelemetry) bool {
+	if len(readings) == 0 {
+		return true
+	}
+	raw := &readings[len(readings)-1]
+	select {
+	case w.shardFor(raw) <- telemetryJob{readings: readings}:
+		return true
+	default:
+		log.Printf("Warning: telemetry queue full for %s, deferring to reconciliation", rawRoomIdentifier(raw))
+		metrics.WorkerQueueFull.Inc()
+		return false
+	}
+}
+
+// shardFor picks the queue for a reading's room
+func (w *WorkerService) shardFor(raw *models.TheaterRawTelemetry) chan telemetryJob {
+	key := raw.ID
+	if raw.RoomID != nil {
+		key = *raw.RoomID
+	}
+	return w.shards[key%uint(len(w.shards))]
+}
+
+// runShard processes queued readings until the context is cancelled
+func (w *WorkerService) runShard(ctx context.Context, jobs chan telemetryJob) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-jobs:
+			timeJob("telemetry", func() { w.processTelemetryJob(job.readings) })
+		}
+	}
+}
+
+// reconcile polls every room's raw telemetry and queues rooms whose latest reading hasn't been processed
+// This is a fallback for readings dropped from a full queue or written outside the API
+func (w *WorkerService) reconcile() {
 	// 1. Get all raw telemetry data (one row per room)
 	rawTelemetry, err := w.theaterRepo.GetAllRawTelemetry()
 	if err != nil {
@@ -72,68 +218,136 @@ func (w *WorkerService) processNewTelemetry() {
 		}
 	}
 
-	// 3. Process each room's telemetry data
-	for _, raw := range rawTelemetry {
+	// 3. Queue each room whose raw data changed since it was last processed
+	for i := range rawTelemetry {
+		raw := &rawTelemetry[i]
+
 		var liveState *models.TheaterLiveState
 		var exists bool
-		var roomIdentifier string
-
-		// Try to find live state by room_id first (new method)
 		if raw.RoomID != nil {
 			liveState, exists = liveStateMapByID[*raw.RoomID]
-			roomIdentifier = fmt.Sprintf("room_id=%d", *raw.RoomID)
-		} else if raw.RoomName != "" {
-			// Fallback to room_name (legacy method)
-			liveState, exists = liveStateMapByName[raw.RoomName]
-			roomIdentifier = fmt.Sprintf("room_name=%s", raw.RoomName)
 		} else {
-			log.Printf("Warning: Raw telemetry ID %d has no room_id or room_name", raw.ID)
+			liveState, exists = liveStateMapByName[raw.RoomName]
+		}
+
+		if exists && liveState.LastProcessedAt != nil && !raw.UpdatedAt.After(*liveState.LastProcessedAt) {
+			// No new update for this room, skip
 			continue
 		}
 
-		if !exists {
-			// Create live state if it doesn't exist for this room
-			createIdentifier := raw.RoomName
-			if createIdentifier == "" && raw.RoomID != nil {
-				// If we only have room_id, we can't use the old CreateLiveStateIfNotExists
-				log.Printf("Warning: Cannot auto-create live state for room_id %d without room_name", *raw.RoomID)
-				continue
-			}
-			
-			if err := w.theaterRepo.CreateLiveStateIfNotExists(createIdentifier); err != nil {
-				log.Printf("Error creating live state for %s: %v", roomIdentifier, err)
-				continue
-			}
-			// Fetch the newly created live state
-			liveState, err = w.theaterRepo.GetLiveState(createIdentifier)
-			if err != nil {
-				log.Printf("Error fetching newly created live state for %s: %v", roomIdentifier, err)
-				continue
-			}
+		w.Enqueue(raw)
+	}
+}
+
+// processTelemetryJob applies a room's queued readings to its live state
+func (w *WorkerService) processTelemetryJob(readings []models.TheaterRawTelemetry) {
+	raw := &readings[len(readings)-1]
+	roomIdentifier := rawRoomIdentifier(raw)
+
+	// 1. Load the live state, by room_id (new method) or room_name (legacy method)
+	var liveState *models.TheaterLiveState
+	var err error
+	if raw.RoomID != nil {
+		liveState, err = w.theaterRepo.FindLiveStateByRoomID(*raw.RoomID)
+		if err != nil {
+			// If we only have room_id, we can't use the old CreateLiveStateIfNotExists
+			log.Printf("Warning: Cannot load live state for %s: %v", roomIdentifier, err)
+			return
+		}
+	} else if raw.RoomName != "" {
+		// Create live state if it doesn't exist for this room
+		if err := w.theaterRepo.CreateLiveStateIfNotExists(raw.RoomName); err != nil {
+			log.Printf("Error creating live state for %s: %v", roomIdentifier, err)
+			return
 		}
+		liveState, err = w.theaterRepo.GetLiveState(raw.RoomName)
+		if err != nil {
+			log.Printf("Error fetching live state for %s: %v", roomIdentifier, err)
+			return
+		}
+	} else {
+		log.Printf("Warning: Raw telemetry ID %d has no room_id or room_name", raw.ID)
+		return
+	}
 
-		// Check if the raw data has been updated since last processed
-		if liveState.LastProcessedAt != nil && !raw.UpdatedAt.After(*liveState.LastProcessedAt) {
-			// No new update for this room, skip
+	// 2. Apply each reading newer than what was already processed
+	applied := 0
+	for i := range readings {
+		reading := &readings[i]
+		if liveState.LastProcessedAt != nil && !reading.UpdatedAt.After(*liveState.LastProcessedAt) {
 			continue
 		}
 
-		// 4. Process the updated telemetry data
-		w.processRoomTelemetry(liveState, &raw)
+		w.processRoomTelemetry(liveState, reading)
+
+		// Update the last processed timestamp
+		processedAt := reading.UpdatedAt
+		liveState.LastProcessedAt = &processedAt
+		applied++
+	}
+	if applied == 0 {
+		return
+	}
+
+	// 3. Persist updated state to database
+	if err := w.theaterRepo.UpdateLiveState(liveState); err != nil {
+		log.Printf("Error updating live state for %s: %v", roomIdentifier, err)
+		return
+	}
+
+	metrics.WorkerRoomsProcessed.Inc()
+	metrics.WorkerReadingsApplied.Add(float64(applied))
+
+	// 4. Fresh data ends any device offline alarm
+	if raw.RoomID != nil {
+		w.alarmService.ClearDeviceOffline(*raw.RoomID, *liveState.LastProcessedAt)
+	}
 
-		// 5. Update the last processed timestamp
-		liveState.LastProcessedAt = &raw.UpdatedAt
+	// 5. Push the change to streaming clients
+	w.streamService.PublishLiveState(liveState)
 
-		// 6. Persist updated state to database
-		if err := w.theaterRepo.UpdateLiveState(liveState); err != nil {
-			log.Printf("Error updating live state for %s: %v", roomIdentifier, err)
+	log.Printf("Processed telemetry for %s - Updated at: %v", roomIdentifier, *liveState.LastProcessedAt)
+}
+
+// checkStaleRooms raises a device offline alarm for rooms whose telemetry stopped arriving
+// Rooms that never reported are shown as stale but don't alarm; the alarm clears on the next reading
+func (w *WorkerService) checkStaleRooms(now time.Time) {
+	liveStates, err := w.theaterRepo.GetAllLiveStates()
+	if err != nil {
+		log.Printf("Error fetching live states for stale check: %v", err)
+		return
+	}
+
+	for i := range liveStates {
+		state := &liveStates[i]
+		if state.RoomID == nil || state.LastProcessedAt == nil || !w.stalenessService.IsMonitored(*state.RoomID) {
 			continue
 		}
 
-		log.Printf("Processed telemetry for %s - Updated at: %v", roomIdentifier, raw.UpdatedAt)
+		w.stalenessService.Annotate(state, now)
+		if !state.IsStale {
+			continue
+		}
+
+		staleAfter := time.Duration(state.StaleAfterSeconds) * time.Second
+		if w.alarmService.RaiseDeviceOffline(*state.RoomID, *state.LastProcessedAt, staleAfter, now) {
+			// Let dashboards know the room went stale without waiting for a reading
+			w.streamService.PublishLiveState(state)
+		}
 	}
 }
 
+// rawRoomIdentifier describes the room of a raw telemetry row for logging
+func rawRoomIdentifier(raw *models.TheaterRawTelemetry) string {
+	if raw.RoomID != nil {
+		return fmt.Sprintf("room_id=%d", *raw.RoomID)
+	}
+	if raw.RoomName != "" {
+		return fmt.Sprintf("room_name=%s", raw.RoomName)
+	}
+	return "unknown"
+}
+
 // processRoomTelemetry processes telemetry data for a single room
 func (w *WorkerService) processRoomTelemetry(liveState *models.TheaterLiveState, raw *models.TheaterRawTelemetry) {
 	// Determine room identifier for logging
@@ -150,14 +364,16 @@ func (w *WorkerService) processRoomTelemetry(liveState *models.TheaterLiveState,
 	}
 
 	// METHOD 2: Empirical ACH (Edge Detection)
+	// Timed with the device clock when trusted, so network jitter doesn't distort the cycle
+	eventTime := telemetryEventTime(raw)
 	// 0 -> 1: Start cycle
 	if liveState.CurrentLogicAhu == 0 && raw.LogicAhu == 1 {
-		liveState.AhuCycleStartTime = &raw.UpdatedAt
-		log.Printf("[%s] ACH cycle started at %v", roomIdentifier, raw.UpdatedAt)
+		liveState.AhuCycleStartTime = &eventTime
+		log.Printf("[%s] ACH cycle started at %v", roomIdentifier, eventTime)
 	} else if liveState.CurrentLogicAhu == 1 && raw.LogicAhu == 0 {
 		// 1 -> 0: End cycle, calculate
 		if liveState.AhuCycleStartTime != nil {
-			duration := raw.UpdatedAt.Sub(*liveState.AhuCycleStartTime).Seconds()
+			duration := eventTime.Sub(*liveState.AhuCycleStartTime).Seconds()
 			if duration > 0 {
 				liveState.AchEmpirical = 3600 / duration
 				log.Printf("[%s] ACH cycle completed - Duration: %.2fs, Empirical ACH: %.2f", 
@@ -183,16 +399,65 @@ func (w *WorkerService) processRoomTelemetry(liveState *models.TheaterLiveState,
 	liveState.Instrument = raw.Instrument
 	liveState.Carbon = raw.Carbon
 
+	// Dashboards read every schema sensor, with or without a dedicated column, from here
+	liveState.SensorValues = rawReportedSensors(raw)
+
 	liveState.CurrentLogicAhu = raw.LogicAhu
 
 	// Keep the old LastProcessedRawID for backward compatibility (deprecated)
 	liveState.LastProcessedRawID = int(raw.ID)
 
-	// Check countdown timer expiry
-	if liveState.CdIsRunning && liveState.CdTargetTime != nil {
-		if time.Now().After(*liveState.CdTargetTime) {
-			liveState.CdIsRunning = false
-			log.Printf("[%s] Countdown timer expired", roomIdentifier)
+	// Evaluate threshold alarms (only rooms managed by room_id have rules)
+	if raw.RoomID != nil {
+		w.alarmService.EvaluateTelemetry(*raw.RoomID, rawSensorValues(raw), raw.UpdatedAt)
+	}
+}
+
+// telemetryEventTime returns when a reading was taken
+// Uses the device timestamp unless it was flagged for clock skew, otherwise the receive time
+func telemetryEventTime(raw *models.TheaterRawTelemetry) time.Time {
+	if raw.DeviceTimestamp != nil && !raw.ClockSkewFlagged {
+		return *raw.DeviceTimestamp
+	}
+	return raw.UpdatedAt
+}
+
+// rawSensorValues returns the reported sensor and control values of a raw telemetry row keyed by field name
+// Sensors that did not report a value are omitted
+func rawSensorValues(raw *models.TheaterRawTelemetry) map[string]float64 {
+	values := rawReportedSensors(raw)
+	values["room_status"] = float64(raw.RoomStatus)
+	values["laju_aliran_ahu"] = float64(raw.LajuAliranAhu)
+	values["logic_ahu"] = float64(raw.LogicAhu)
+	return values
+}
+
+// rawReportedSensors returns the sensor values of a raw telemetry row, including sensors without a column
+// Sensors that did not report a value are omitted
+func rawReportedSensors(raw *models.TheaterRawTelemetry) models.SensorValues {
+	values := models.SensorValues{}
+	floats := map[string]*float64{
+		"temp":          raw.Temp,
+		"room_pressure": raw.RoomPressure,
+		"oxygen":        raw.Oxygen,
+		"nitrous":       raw.Nitrous,
+		"air":           raw.Air,
+		"instrument":    raw.Instrument,
+		"carbon":        raw.Carbon,
+	}
+	for name, v := range floats {
+		if v != nil {
+			values[name] = *v
 		}
 	}
+	if raw.Humidity != nil {
+		values["humidity"] = float64(*raw.Humidity)
+	}
+	if raw.Vacuum != nil {
+		values["vacuum"] = float64(*raw.Vacuum)
+	}
+	for name, v := range raw.ExtraSensors {
+		values[name] = v
+	}
+	return values
 }
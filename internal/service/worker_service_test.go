@@ -0,0 +1,77 @@
+package service
+
+import (
+	"testing"
+
+	"iot-backend-room-monitoring/internal/config"
+	"iot-backend-room-monitoring/internal/models"
+)
+
+func TestShardForKeepsRoomsOnOneShard(t *testing.T) {
+	w := NewWorkerService(nil, nil, nil, nil, nil, nil, nil, nil, config.WorkerConfig{Shards: 4, QueueSize: 1})
+	roomID, otherRoomID := uint(6), uint(7)
+
+	tests := []struct {
+		name string
+		a, b models.TheaterRawTelemetry
+		same bool
+	}{
+		{
+			name: "readings of one room share a shard whatever their row",
+			a:    models.TheaterRawTelemetry{ID: 1, RoomID: &roomID},
+			b:    models.TheaterRawTelemetry{ID: 2, RoomID: &roomID},
+			same: true,
+		},
+		{
+			name: "neighbouring rooms are spread over shards",
+			a:    models.TheaterRawTelemetry{ID: 1, RoomID: &roomID},
+			b:    models.TheaterRawTelemetry{ID: 1, RoomID: &otherRoomID},
+		},
+		{
+			name: "legacy rows without a room_id are keyed by row",
+			a:    models.TheaterRawTelemetry{ID: 3},
+			b:    models.TheaterRawTelemetry{ID: 3},
+			same: true,
+		},
+	}
+
+	for _, tt := range tests {
+		if same := w.shardFor(&tt.a) == w.shardFor(&tt.b); same != tt.same {
+			t.Errorf("%s: same shard = %v, want %v", tt.name, same, tt.same)
+		}
+	}
+}
+
+func TestEnqueueDefersToReconciliationWhenFull(t *testing.T) {
+	w := NewWorkerService(nil, nil, nil, nil, nil, nil, nil, nil, config.WorkerConfig{Shards: 2, QueueSize: 1})
+	roomID, otherRoomID := uint(2), uint(3)
+
+	if !w.Enqueue(&models.TheaterRawTelemetry{ID: 1, RoomID: &roomID}) {
+		t.Fatal("first reading refused by an empty shard")
+	}
+	if w.Enqueue(&models.TheaterRawTelemetry{ID: 1, RoomID: &roomID}) {
+		t.Error("reading queued on a full shard, want it left to reconciliation")
+	}
+	if !w.Enqueue(&models.TheaterRawTelemetry{ID: 2, RoomID: &otherRoomID}) {
+		t.Error("a full shard blocked a room on another shard")
+	}
+}
+
+func TestEnqueueBatchKeepsReadingsInOneJob(t *testing.T) {
+	w := NewWorkerService(nil, nil, nil, nil, nil, nil, nil, nil, config.WorkerConfig{Shards: 2, QueueSize: 1})
+	roomID := uint(2)
+
+	if !w.EnqueueBatch(nil) {
+		t.Error("empty batch refused, want it accepted as a no-op")
+	}
+
+	// A batch travels as one job keyed by its newest reading, so it stays in order
+	batch := []models.TheaterRawTelemetry{{ID: 1, RoomID: &roomID}, {ID: 1, RoomID: &roomID, LogicAhu: 1}}
+	if !w.EnqueueBatch(batch) {
+		t.Fatal("batch refused by an empty shard")
+	}
+	job := <-w.shardFor(&batch[1])
+	if len(job.readings) != 2 || job.readings[1].LogicAhu != 1 {
+		t.Errorf("got job %+v, want the batch in its original order", job.readings)
+	}
+}
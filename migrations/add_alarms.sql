@@ -0,0 +1,58 @@
+-- Alarm Engine Migration
+-- Creates tables for configurable threshold rules and the alarms they raise
+
+-- Rules apply to one room (room_id) or to all rooms of a type (room_type)
+CREATE TABLE IF NOT EXISTS alarm_rules (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    room_id INT NULL,
+    room_type ENUM('operating_theater', 'icu', 'isolation', 'general') NULL,
+    sensor VARCHAR(50) NOT NULL COMMENT 'Telemetry field, e.g. temp, room_pressure, oxygen',
+    low_limit DOUBLE NULL,
+    high_limit DOUBLE NULL,
+    hysteresis DOUBLE NOT NULL DEFAULT 0 COMMENT 'Recovery margin before an alarm clears',
+    min_duration_seconds INT NOT NULL DEFAULT 0 COMMENT 'Violation must persist this long before raising',
+    severity ENUM('info', 'warning', 'critical') DEFAULT 'warning',
+    description VARCHAR(255) DEFAULT NULL,
+    is_active TINYINT(1) DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+    INDEX idx_alarm_rules_room_id (room_id),
+    INDEX idx_alarm_rules_room_type (room_type)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Alarm lifecycle: raised -> acknowledged -> cleared (or raised -> cleared)
+CREATE TABLE IF NOT EXISTS alarms (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    rule_id INT NULL,
+    room_id INT NOT NULL,
+    alarm_type VARCHAR(50) NOT NULL DEFAULT 'threshold',
+    sensor VARCHAR(50) DEFAULT NULL,
+    severity ENUM('info', 'warning', 'critical') DEFAULT 'warning',
+    state ENUM('raised', 'acknowledged', 'cleared') DEFAULT 'raised',
+    limit_type VARCHAR(20) DEFAULT NULL COMMENT 'low or high',
+    trigger_value DOUBLE NULL,
+    limit_value DOUBLE NULL,
+    message VARCHAR(255) DEFAULT NULL,
+    raised_at DATETIME NOT NULL,
+    acknowledged_at DATETIME NULL,
+    acknowledged_by INT NULL,
+    acknowledge_note VARCHAR(255) DEFAULT NULL,
+    cleared_at DATETIME NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+    FOREIGN KEY (rule_id) REFERENCES alarm_rules(id) ON DELETE SET NULL,
+    INDEX idx_alarms_room_id (room_id),
+    INDEX idx_alarms_state (state),
+    INDEX idx_alarms_raised_at (raised_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Optional: example operating theater rules (adjust limits to your clinical guidelines)
+-- INSERT INTO alarm_rules (room_type, sensor, low_limit, high_limit, hysteresis, min_duration_seconds, severity, description)
+-- VALUES
+--     ('operating_theater', 'temp', 18, 24, 0.5, 60, 'warning', 'Theater temperature out of range'),
+--     ('operating_theater', 'humidity', 30, 60, 2, 120, 'warning', 'Theater humidity out of range'),
+--     ('operating_theater', 'room_pressure', 0.5, NULL, 0.2, 30, 'critical', 'Positive pressure lost'),
+--     ('operating_theater', 'oxygen', 350, NULL, 10, 10, 'critical', 'O2 pipeline pressure low');
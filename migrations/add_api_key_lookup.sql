@@ -0,0 +1,11 @@
+-- Migration: Indexed Device API Key Lookup
+-- Description: New keys carry a public key ID prefix ("<key_id>.<secret>") and are stored
+-- as an HMAC-SHA256 hash, so authentication is one indexed lookup instead of a bcrypt scan.
+-- Existing bcrypt keys keep working (hash_scheme = 'bcrypt') until they are rotated.
+
+ALTER TABLE device_api_keys
+ADD COLUMN key_id VARCHAR(16) NULL UNIQUE COMMENT 'Public key ID prefix, NULL for legacy bcrypt keys' AFTER room_id,
+ADD COLUMN hash_scheme VARCHAR(20) NOT NULL DEFAULT 'bcrypt' COMMENT 'bcrypt (legacy) or hmac-sha256' AFTER api_key_hash;
+
+-- Find legacy keys that still need rotating:
+-- SELECT id, room_id, description FROM device_api_keys WHERE hash_scheme = 'bcrypt' AND is_active = 1;
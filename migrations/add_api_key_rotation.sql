@@ -0,0 +1,11 @@
+-- Migration: Device API Key Rotation and Usage Tracking
+-- Description: Link rotated keys to their predecessor and record when and from where each key was last used
+
+ALTER TABLE device_api_keys
+ADD COLUMN replaces_key_id INT NULL COMMENT 'Key this one was issued to succeed' AFTER description,
+ADD COLUMN last_used_at DATETIME NULL AFTER replaces_key_id,
+ADD COLUMN last_used_ip VARCHAR(45) NULL COMMENT 'IPv4 or IPv6 source address' AFTER last_used_at,
+ADD CONSTRAINT fk_device_api_keys_replaces FOREIGN KEY (replaces_key_id) REFERENCES device_api_keys(id) ON DELETE SET NULL;
+
+-- Expired keys are deactivated by the background expiry job
+CREATE INDEX idx_device_api_keys_expires_at ON device_api_keys (expires_at);
@@ -0,0 +1,24 @@
+-- Migration: Calibration Profiles
+-- Description: Per-room or per-device linear calibration (gain/offset) and unit conversion applied
+-- to telemetry before storage. History keeps the values as reported by the device for audit.
+
+CREATE TABLE IF NOT EXISTS calibration_profiles (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    room_id INT NOT NULL,
+    device_id INT NULL COMMENT 'NULL for the room profile; a device profile replaces it for that device',
+    fields JSON NOT NULL COMMENT 'Field name -> {gain, offset, unit}',
+    description VARCHAR(255) DEFAULT NULL,
+    updated_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+    FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE,
+    FOREIGN KEY (updated_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_calibration_profiles_room_id (room_id),
+    INDEX idx_calibration_profiles_device_id (device_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE theater_telemetry_history
+ADD COLUMN calibration_profile_id INT NULL COMMENT 'Profile applied to this reading',
+ADD COLUMN uncalibrated_values JSON NULL COMMENT 'Values as reported, for fields the profile changed';
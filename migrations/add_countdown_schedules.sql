@@ -0,0 +1,62 @@
+-- Migration: Scheduled Countdowns
+-- Description: Named countdown presets per room type, countdowns scheduled in advance (optionally
+-- repeating daily or weekly) that the background worker starts at their time, and a log of countdown
+-- start, completion, expiry and missed events.
+
+CREATE TABLE IF NOT EXISTS countdown_presets (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    room_type ENUM('operating_theater', 'icu', 'isolation', 'general') NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    duration_minutes INT NOT NULL,
+    description VARCHAR(255) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    UNIQUE KEY idx_countdown_presets_type_name (room_type, name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS countdown_schedules (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    room_id INT NOT NULL,
+    preset_id INT NULL,
+    label VARCHAR(100) DEFAULT NULL,
+    duration_minutes INT NOT NULL,
+    scheduled_at DATETIME NOT NULL,
+    recurrence ENUM('none', 'daily', 'weekly') DEFAULT 'none' COMMENT 'Next occurrence is queued when this one fires',
+    status ENUM('pending', 'started', 'missed', 'cancelled') DEFAULT 'pending',
+    started_at DATETIME NULL,
+    created_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+    FOREIGN KEY (preset_id) REFERENCES countdown_presets(id) ON DELETE SET NULL,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_countdown_schedules_room_id (room_id),
+    INDEX idx_countdown_schedules_status_scheduled (status, scheduled_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS countdown_events (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    room_id INT NOT NULL,
+    schedule_id INT NULL COMMENT 'NULL for countdowns started by staff',
+    event ENUM('started', 'completed', 'expired', 'missed') NOT NULL,
+    label VARCHAR(100) DEFAULT NULL,
+    duration_seconds INT DEFAULT 0,
+    occurred_at DATETIME NOT NULL,
+    user_id INT NULL COMMENT 'NULL when triggered by the worker',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+    FOREIGN KEY (schedule_id) REFERENCES countdown_schedules(id) ON DELETE SET NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_countdown_events_room_occurred (room_id, occurred_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE theater_live_state
+ADD COLUMN cd_label VARCHAR(100) DEFAULT NULL COMMENT 'Preset or schedule name of the countdown',
+ADD COLUMN cd_schedule_id INT NULL COMMENT 'Schedule that started the countdown';
+
+-- Example preset:
+-- INSERT INTO countdown_presets (room_type, name, duration_minutes, description)
+-- VALUES ('operating_theater', 'Turnaround cleaning', 30, 'Cleaning between operations');
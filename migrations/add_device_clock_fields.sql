@@ -0,0 +1,15 @@
+-- Migration: Device Timestamps and Clock Skew
+-- Description: Store the optional device timestamp and sequence number sent by ESP32 firmware,
+-- together with the measured clock skew (receive time minus device time)
+
+ALTER TABLE theater_raw_telemetry
+ADD COLUMN device_timestamp DATETIME(3) NULL COMMENT 'When the device took the reading',
+ADD COLUMN sequence INT UNSIGNED NULL COMMENT 'Per-device reading counter',
+ADD COLUMN clock_skew_ms BIGINT NULL COMMENT 'Server receive time minus device time',
+ADD COLUMN clock_skew_flagged TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'Device time not trusted for timing';
+
+ALTER TABLE theater_telemetry_history
+ADD COLUMN device_timestamp DATETIME(3) NULL,
+ADD COLUMN sequence INT UNSIGNED NULL,
+ADD COLUMN clock_skew_ms BIGINT NULL,
+ADD COLUMN clock_skew_flagged TINYINT(1) NOT NULL DEFAULT 0;
@@ -0,0 +1,8 @@
+-- Migration: Device Command Delivery Limit
+-- Description: Count how often each command was fetched without an acknowledgement, so a command
+-- the device never acknowledges (such as a reboot that restarts it first) is failed instead of
+-- being delivered on every poll. Delivered commands now also expire at expires_at.
+
+ALTER TABLE device_commands
+ADD COLUMN delivery_count INT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'Fetches without an acknowledgement' AFTER expires_at,
+MODIFY COLUMN expires_at DATETIME NOT NULL COMMENT 'Commands not acknowledged by then are expired';
@@ -0,0 +1,36 @@
+-- Migration: Remote Device Configuration and Commands
+-- Description: Per-room config fetched by the ESP32 (versioned so devices only download changes)
+-- and a queue of commands admins post and devices acknowledge.
+
+CREATE TABLE IF NOT EXISTS device_configs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    room_id INT NOT NULL UNIQUE,
+    version INT UNSIGNED NOT NULL DEFAULT 1 COMMENT 'Bumped on every change',
+    reporting_interval_seconds INT NOT NULL DEFAULT 1,
+    calibration_offsets JSON NULL COMMENT 'Sensor name -> offset applied on the device',
+    enabled_sensors JSON NULL COMMENT 'Sensor names the device should report',
+    updated_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+    FOREIGN KEY (updated_by) REFERENCES users(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS device_commands (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    room_id INT NOT NULL,
+    command ENUM('reboot', 'recalibrate', 'identify') NOT NULL,
+    state ENUM('pending', 'delivered', 'completed', 'failed', 'expired') DEFAULT 'pending',
+    result VARCHAR(255) DEFAULT NULL COMMENT 'Reported by the device on acknowledgement',
+    created_by INT NULL,
+    expires_at DATETIME NOT NULL COMMENT 'Pending commands not fetched by then are expired',
+    delivered_at DATETIME NULL,
+    acknowledged_at DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_device_commands_room_state (room_id, state)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
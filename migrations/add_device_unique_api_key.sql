@@ -0,0 +1,12 @@
+-- Migration: One Device per API Key
+-- Description: A key authenticates a single device, so concurrent first heartbeats cannot register
+-- the same device twice. Older duplicates keep the key on the device seen last.
+
+UPDATE devices d
+JOIN devices newer ON newer.api_key_id = d.api_key_id
+    AND (COALESCE(newer.last_seen_at, newer.created_at) > COALESCE(d.last_seen_at, d.created_at)
+        OR (COALESCE(newer.last_seen_at, newer.created_at) = COALESCE(d.last_seen_at, d.created_at) AND newer.id > d.id))
+SET d.api_key_id = NULL;
+
+ALTER TABLE devices ADD UNIQUE INDEX uq_devices_api_key_id (api_key_id);
+ALTER TABLE devices DROP INDEX idx_devices_api_key_id;
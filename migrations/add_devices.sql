@@ -0,0 +1,23 @@
+-- Migration: Device Registry
+-- Description: Track the physical ESP32 behind each room. Devices are registered from their
+-- telemetry calls and marked offline by the background worker after a period of silence.
+
+CREATE TABLE IF NOT EXISTS devices (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    room_id INT NOT NULL,
+    api_key_id INT NULL COMMENT 'Key the device last authenticated with',
+    mac_address VARCHAR(17) NULL UNIQUE,
+    firmware_version VARCHAR(50) DEFAULT NULL,
+    ip_address VARCHAR(45) DEFAULT NULL,
+    uptime_seconds BIGINT NULL COMMENT 'Uptime reported by the device',
+    status ENUM('online', 'offline') DEFAULT 'online',
+    last_seen_at DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+    FOREIGN KEY (api_key_id) REFERENCES device_api_keys(id) ON DELETE SET NULL,
+    INDEX idx_devices_room_id (room_id),
+    INDEX idx_devices_api_key_id (api_key_id),
+    INDEX idx_devices_status_last_seen (status, last_seen_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,11 @@
+-- Migration: Event-Driven Telemetry Processing
+-- Description: Store telemetry timestamps with millisecond precision
+-- ESP32 devices report at 1-2 Hz, so second precision makes two readings in the
+-- same second look identical to the worker's "newer than last processed" check
+
+ALTER TABLE theater_raw_telemetry
+MODIFY COLUMN updated_at TIMESTAMP(3) DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3);
+
+ALTER TABLE theater_live_state
+MODIFY COLUMN last_processed_at TIMESTAMP(3) NULL
+COMMENT 'Timestamp of the last raw telemetry reading applied by the worker';
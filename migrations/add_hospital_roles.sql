@@ -0,0 +1,18 @@
+-- Migration: Per-Hospital Roles
+-- Description: A role per hospital assignment. Users with users.role = 'admin' remain super admins
+-- with every permission; other users get permissions from their role in each hospital:
+--   viewer         - sees the hospital's rooms, readings and reports
+--   operator       - viewer who also runs the operation stopwatch, countdowns and countdown schedules
+--   hospital_admin - operator who also manages the hospital, its rooms, devices and users
+-- Existing assignments become viewers, which matches what non-admin users could do before.
+
+ALTER TABLE user_hospitals
+ADD COLUMN role ENUM('viewer', 'operator', 'hospital_admin') NOT NULL DEFAULT 'viewer' AFTER hospital_id;
+
+-- One role per user and hospital
+DELETE uh1 FROM user_hospitals uh1
+INNER JOIN user_hospitals uh2
+    ON uh1.user_id = uh2.user_id AND uh1.hospital_id = uh2.hospital_id AND uh1.id > uh2.id;
+
+ALTER TABLE user_hospitals
+ADD UNIQUE INDEX idx_user_hospitals_user_hospital (user_id, hospital_id);
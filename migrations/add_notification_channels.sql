@@ -0,0 +1,26 @@
+-- Migration: Countdown Notifications
+-- Description: Outbound notification channels (webhook or email) for countdown expiry and missed
+-- schedules, and an email address per user so hospital staff can be notified.
+
+ALTER TABLE users
+ADD COLUMN email VARCHAR(255) NULL COMMENT 'Notification address';
+
+CREATE TABLE IF NOT EXISTS notification_channels (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    hospital_id INT NULL COMMENT 'NULL = rooms of every hospital',
+    name VARCHAR(100) NOT NULL,
+    type ENUM('webhook', 'email') NOT NULL,
+    webhook_url VARCHAR(500) DEFAULT NULL,
+    recipients VARCHAR(1000) DEFAULT NULL COMMENT 'Extra email addresses, comma separated',
+    notify_hospital_staff BOOLEAN DEFAULT TRUE COMMENT 'Email users assigned to the room''s hospital',
+    event_types VARCHAR(255) DEFAULT NULL COMMENT 'Comma separated; empty = every event',
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX idx_notification_channels_hospital_id (hospital_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Example webhook channel for every hospital:
+-- INSERT INTO notification_channels (name, type, webhook_url, event_types)
+-- VALUES ('On-call pager', 'webhook', 'https://example.org/hooks/or', 'countdown_expired,countdown_missed');
@@ -0,0 +1,24 @@
+-- Migration: Notification Delivery Queue
+-- Description: Persists each notification queued for a channel so failed emails
+-- are retried with exponential backoff instead of being lost, and keeps a record of every delivery.
+
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    channel_id INT NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    room_id INT NULL,
+    payload TEXT NOT NULL COMMENT 'The notification as JSON',
+    status ENUM('pending', 'delivered', 'failed') DEFAULT 'pending',
+    attempts INT DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    last_attempt_at DATETIME NULL,
+    last_error VARCHAR(500) DEFAULT NULL,
+    delivered_at DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (channel_id) REFERENCES notification_channels(id) ON DELETE CASCADE,
+    INDEX idx_notification_deliveries_channel_id (channel_id),
+    INDEX idx_notification_deliveries_room_id (room_id),
+    INDEX idx_notification_deliveries_status_next (status, next_attempt_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
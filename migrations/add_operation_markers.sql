@@ -0,0 +1,31 @@
+-- Migration: Operation Stopwatch Markers
+-- Description: Explicit pause/resume for the operation stopwatch, separate from stop, and a timeline
+-- of markers per operation session: start, pause and resume with reasons, stop, and named laps
+-- such as induction, incision or closure.
+
+ALTER TABLE theater_live_state
+ADD COLUMN op_is_paused BOOLEAN DEFAULT FALSE COMMENT 'Stopwatch paused, can be resumed',
+ADD COLUMN op_pause_reason VARCHAR(255) DEFAULT NULL;
+
+-- Stopwatches stopped before this migration could still be continued; treat them as paused
+UPDATE theater_live_state
+SET op_is_paused = TRUE
+WHERE op_is_running = FALSE AND op_accumulated_seconds > 0;
+
+CREATE TABLE IF NOT EXISTS operation_markers (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    session_id INT NOT NULL,
+    room_id INT NOT NULL,
+    type ENUM('start', 'pause', 'resume', 'stop', 'lap') NOT NULL,
+    name VARCHAR(100) DEFAULT NULL COMMENT 'Lap name',
+    reason VARCHAR(255) DEFAULT NULL COMMENT 'Why the stopwatch was paused, resumed or stopped',
+    elapsed_seconds INT DEFAULT 0 COMMENT 'Stopwatch reading at the marker',
+    occurred_at DATETIME NOT NULL,
+    user_id INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (session_id) REFERENCES operation_sessions(id) ON DELETE CASCADE,
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_operation_markers_session_occurred (session_id, occurred_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,38 @@
+-- Migration: Operation Sessions
+-- Description: Records every use of the operation stopwatch. Starting the stopwatch opens a session,
+-- each start/stop pair is an interval, and reset completes the session with its final duration.
+
+CREATE TABLE IF NOT EXISTS operation_sessions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    room_id INT NOT NULL,
+    procedure_label VARCHAR(255) DEFAULT NULL,
+    status ENUM('running', 'paused', 'completed') DEFAULT 'running',
+    started_at DATETIME NOT NULL,
+    ended_at DATETIME NULL COMMENT 'Set when the stopwatch is reset',
+    total_seconds INT DEFAULT 0 COMMENT 'Sum of closed intervals',
+    started_by INT NULL,
+    stopped_by INT NULL COMMENT 'User who reset the stopwatch',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+    FOREIGN KEY (started_by) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (stopped_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_operation_sessions_room_started (room_id, started_at),
+    INDEX idx_operation_sessions_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS operation_intervals (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    session_id INT NOT NULL,
+    started_at DATETIME NOT NULL,
+    ended_at DATETIME NULL COMMENT 'NULL while the stopwatch is running',
+    duration_seconds INT DEFAULT 0,
+    started_by INT NULL,
+    stopped_by INT NULL,
+
+    FOREIGN KEY (session_id) REFERENCES operation_sessions(id) ON DELETE CASCADE,
+    FOREIGN KEY (started_by) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (stopped_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_operation_intervals_session_id (session_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,47 @@
+-- Migration: Configurable Sensor Schema
+-- Description: Per-room-type sensor definitions (unit, required/optional, valid range) that drive
+-- telemetry validation, storage and dashboard output. Room types without definitions keep the
+-- built-in schema (temp, humidity, room_pressure and the six medical gases, all required).
+-- Sensors without a dedicated column are stored in extra_sensors.
+
+CREATE TABLE IF NOT EXISTS sensor_definitions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    room_type ENUM('operating_theater', 'icu', 'isolation', 'general') NOT NULL,
+    name VARCHAR(50) NOT NULL COMMENT 'Telemetry field name',
+    label VARCHAR(100) DEFAULT NULL,
+    unit VARCHAR(20) DEFAULT NULL COMMENT 'Built-in sensors always use their storage unit',
+    required BOOLEAN DEFAULT FALSE COMMENT 'Readings without this sensor are rejected',
+    min_value DOUBLE NULL,
+    max_value DOUBLE NULL,
+    sort_order INT DEFAULT 0 COMMENT 'Dashboard display order',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    UNIQUE KEY idx_sensor_definitions_type_name (room_type, name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE theater_raw_telemetry
+ADD COLUMN extra_sensors JSON NULL COMMENT 'Schema sensors without a dedicated column';
+
+ALTER TABLE theater_telemetry_history
+ADD COLUMN extra_sensors JSON NULL COMMENT 'Schema sensors without a dedicated column';
+
+ALTER TABLE theater_live_state
+ADD COLUMN sensor_values JSON NULL COMMENT 'Latest value of every schema sensor';
+
+-- Example schemas (adjust to the installed sensors before enabling):
+-- INSERT INTO sensor_definitions (room_type, name, label, unit, required, min_value, max_value, sort_order)
+-- VALUES
+--     ('icu', 'temp', 'Temperature', 'C', TRUE, -50, 100, 0),
+--     ('icu', 'humidity', 'Humidity', '%', TRUE, 0, 100, 1),
+--     ('icu', 'room_pressure', 'Room Pressure', 'Pa', FALSE, 0, NULL, 2),
+--     ('icu', 'oxygen', 'Oxygen', 'kPa', TRUE, NULL, NULL, 3),
+--     ('icu', 'air', 'Medical Air', 'kPa', TRUE, NULL, NULL, 4),
+--     ('icu', 'vacuum', 'Vacuum', 'kPa', TRUE, NULL, NULL, 5),
+--     ('isolation', 'temp', 'Temperature', 'C', TRUE, -50, 100, 0),
+--     ('isolation', 'humidity', 'Humidity', '%', TRUE, 0, 100, 1),
+--     ('isolation', 'room_pressure', 'Room Pressure', 'Pa', TRUE, -100, 0, 2),
+--     ('isolation', 'anteroom_pressure', 'Anteroom Pressure', 'Pa', FALSE, -100, 100, 3),
+--     ('general', 'temp', 'Temperature', 'C', TRUE, -50, 100, 0),
+--     ('general', 'humidity', 'Humidity', '%', FALSE, 0, 100, 1),
+--     ('general', 'co2_ppm', 'CO2', 'ppm', FALSE, 0, 5000, 2);
@@ -0,0 +1,6 @@
+-- Migration: Stale Data Detection
+-- Description: Per-room threshold after which the live state is considered stale and the background
+-- worker raises a device_offline alarm. NULL uses the TELEMETRY_STALE_AFTER default.
+
+ALTER TABLE rooms
+ADD COLUMN stale_after_seconds INT NULL COMMENT 'Overrides the default staleness threshold' AFTER volume_ruangan;
@@ -0,0 +1,32 @@
+-- Telemetry History Migration
+-- Creates an append-only table that keeps every ESP32 reading
+-- theater_raw_telemetry still holds only the latest reading per room
+
+CREATE TABLE IF NOT EXISTS theater_telemetry_history (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    room_id INT NOT NULL,
+    recorded_at DATETIME(3) NOT NULL COMMENT 'Time the reading was taken',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    -- Inputs from Hardware
+    temp FLOAT DEFAULT NULL,
+    humidity INT DEFAULT NULL,
+    room_pressure FLOAT DEFAULT NULL,
+    room_status INT DEFAULT 0,
+
+    -- ACH Calculation Inputs
+    laju_aliran_ahu INT DEFAULT 0,
+    volume_ruangan INT DEFAULT 0,
+    logic_ahu INT DEFAULT 0,
+
+    -- Medical Gases
+    oxygen FLOAT DEFAULT NULL,
+    nitrous FLOAT DEFAULT NULL,
+    air FLOAT DEFAULT NULL,
+    vacuum INT DEFAULT NULL,
+    instrument FLOAT DEFAULT NULL,
+    carbon FLOAT DEFAULT NULL,
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+    INDEX idx_history_room_recorded (room_id, recorded_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
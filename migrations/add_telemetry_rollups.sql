@@ -0,0 +1,20 @@
+-- Telemetry Rollups Migration
+-- Creates the downsampled aggregate table maintained by the background worker
+-- One row per (room, resolution, bucket, sensor) with min/max/avg and sample count
+
+CREATE TABLE IF NOT EXISTS telemetry_rollups (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    room_id INT NOT NULL,
+    resolution ENUM('1m', '15m', '1h') NOT NULL,
+    bucket_start DATETIME NOT NULL COMMENT 'Inclusive start of the aggregation bucket',
+    sensor VARCHAR(50) NOT NULL,
+    min_value DOUBLE NOT NULL,
+    max_value DOUBLE NOT NULL,
+    avg_value DOUBLE NOT NULL,
+    sample_count INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+    UNIQUE KEY uq_rollup_bucket (room_id, resolution, bucket_start, sensor),
+    INDEX idx_rollup_resolution_bucket (resolution, bucket_start)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
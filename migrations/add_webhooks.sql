@@ -0,0 +1,45 @@
+-- Migration: Outbound Webhooks
+-- Description: Admin-managed webhook subscriptions for the hospital integration engine and a
+-- persistent delivery queue. Each event is queued once per matching subscription, signed with the
+-- subscription's HMAC secret, and retried with exponential backoff until delivered or given up.
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    url VARCHAR(500) NOT NULL,
+    secret VARCHAR(100) NOT NULL COMMENT 'HMAC-SHA256 signing secret',
+    event_types VARCHAR(255) DEFAULT NULL COMMENT 'Comma separated; empty = every event',
+    hospital_id INT NULL COMMENT 'NULL = rooms of every hospital',
+    is_active BOOLEAN DEFAULT TRUE,
+    created_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_webhook_subscriptions_hospital_id (hospital_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    subscription_id INT NOT NULL,
+    event_id CHAR(36) NOT NULL COMMENT 'Shared by every delivery of the event',
+    event_type VARCHAR(50) NOT NULL,
+    room_id INT NULL,
+    payload TEXT NOT NULL,
+    status ENUM('pending', 'delivered', 'failed') DEFAULT 'pending',
+    attempts INT DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    last_attempt_at DATETIME NULL,
+    last_status_code INT NULL,
+    last_error VARCHAR(500) DEFAULT NULL,
+    delivered_at DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    INDEX idx_webhook_deliveries_subscription_id (subscription_id),
+    INDEX idx_webhook_deliveries_room_id (room_id),
+    INDEX idx_webhook_deliveries_status_next (status, next_attempt_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Receivers verify X-Webhook-Signature = "sha256=" + hex(HMAC-SHA256(secret, X-Webhook-Timestamp + "." + body))
@@ -0,0 +1,17 @@
+-- Migration: Fold Notification Webhooks Into Webhook Subscriptions
+-- Description: Webhooks have one delivery system. Webhook notification channels become webhook
+-- subscriptions to countdown_timer events, which cover countdown expiry and missed schedules among
+-- other countdown transitions; receivers get the signed event envelope instead of the notification
+-- body. Each migrated subscription gets a random secret, rotate it to read it. Notification channels
+-- are email only afterwards.
+
+INSERT INTO webhook_subscriptions (name, url, secret, event_types, hospital_id, is_active)
+SELECT name, webhook_url, CONCAT('whsec_', SHA2(CONCAT(UUID(), RAND()), 256)), 'countdown_timer', hospital_id, is_active
+FROM notification_channels
+WHERE type = 'webhook' AND webhook_url IS NOT NULL AND webhook_url <> '';
+
+DELETE FROM notification_channels WHERE type = 'webhook';
+
+ALTER TABLE notification_channels
+    DROP COLUMN webhook_url,
+    MODIFY type ENUM('email') NOT NULL;
@@ -0,0 +1,139 @@
+// Package fhir holds the subset of HL7 FHIR R4 resources the backend exports
+package fhir
+
+import "time"
+
+// ContentType is the media type of FHIR JSON responses
+const ContentType = "application/fhir+json"
+
+// Bundle types
+const (
+	BundleTypeCollection  = "collection"  // Read-only set of resources
+	BundleTypeTransaction = "transaction" // Each entry carries the request that stores it on a FHIR server
+)
+
+// Code systems defined by HL7
+const (
+	SystemUCUM                 = "http://unitsofmeasure.org"
+	SystemLocationPhysicalType = "http://terminology.hl7.org/CodeSystem/location-physical-type"
+)
+
+// Bundle is a FHIR Bundle resource
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Timestamp    string        `json:"timestamp"`
+	Total        *int          `json:"total,omitempty"`
+	Link         []BundleLink  `json:"link,omitempty"`
+	Entry        []BundleEntry `json:"entry"`
+}
+
+// BundleLink is a link related to a bundle, such as the next page
+type BundleLink struct {
+	Relation string `json:"relation"`
+	URL      string `json:"url"`
+}
+
+// BundleEntry is one resource of a bundle
+type BundleEntry struct {
+	Resource interface{}    `json:"resource"`
+	Request  *BundleRequest `json:"request,omitempty"`
+}
+
+// BundleRequest tells a FHIR server how to process a transaction entry
+type BundleRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+// NewBundle creates an empty bundle of the given type
+func NewBundle(bundleType string, at time.Time) *Bundle {
+	return &Bundle{
+		ResourceType: "Bundle",
+		Type:         bundleType,
+		Timestamp:    FormatInstant(at),
+		Entry:        []BundleEntry{},
+	}
+}
+
+// Add appends a resource to the bundle
+// Transaction entries are stored with PUT so exporting the same resource twice updates it in place
+func (b *Bundle) Add(resourceType, id string, resource interface{}) {
+	entry := BundleEntry{Resource: resource}
+	if b.Type == BundleTypeTransaction {
+		entry.Request = &BundleRequest{Method: "PUT", URL: resourceType + "/" + id}
+	}
+	b.Entry = append(b.Entry, entry)
+}
+
+// Identifier is a business identifier of a resource
+type Identifier struct {
+	System string `json:"system"`
+	Value  string `json:"value"`
+}
+
+// Coding is a code from a code system
+type Coding struct {
+	System  string `json:"system"`
+	Code    string `json:"code"`
+	Display string `json:"display,omitempty"`
+}
+
+// CodeableConcept is a concept given by codes and/or text
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+// Reference points at another resource
+type Reference struct {
+	Reference string `json:"reference"`
+	Display   string `json:"display,omitempty"`
+}
+
+// Quantity is a measured amount, coded in UCUM when the unit is known
+type Quantity struct {
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit,omitempty"`
+	System string  `json:"system,omitempty"`
+	Code   string  `json:"code,omitempty"`
+}
+
+// Address is a postal address
+type Address struct {
+	Text string `json:"text,omitempty"`
+	City string `json:"city,omitempty"`
+}
+
+// Observation is a FHIR Observation resource
+type Observation struct {
+	ResourceType      string            `json:"resourceType"`
+	ID                string            `json:"id"`
+	Identifier        []Identifier      `json:"identifier,omitempty"`
+	Status            string            `json:"status"`
+	Category          []CodeableConcept `json:"category,omitempty"`
+	Code              CodeableConcept   `json:"code"`
+	Subject           *Reference        `json:"subject,omitempty"`
+	EffectiveDateTime string            `json:"effectiveDateTime"`
+	Issued            string            `json:"issued,omitempty"`
+	ValueQuantity     *Quantity         `json:"valueQuantity,omitempty"`
+}
+
+// Location is a FHIR Location resource
+type Location struct {
+	ResourceType string            `json:"resourceType"`
+	ID           string            `json:"id"`
+	Identifier   []Identifier      `json:"identifier,omitempty"`
+	Status       string            `json:"status"`
+	Name         string            `json:"name"`
+	Mode         string            `json:"mode"`
+	Type         []CodeableConcept `json:"type,omitempty"`
+	Address      *Address          `json:"address,omitempty"`
+	PhysicalType *CodeableConcept  `json:"physicalType,omitempty"`
+	PartOf       *Reference        `json:"partOf,omitempty"`
+}
+
+// FormatInstant formats a time as a FHIR instant/dateTime
+func FormatInstant(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// API key hash schemes stored in device_api_keys.hash_scheme
+const (
+	APIKeySchemeBcrypt = "bcrypt"      // Legacy keys, matched by scanning a room's keys
+	APIKeySchemeHMAC   = "hmac-sha256" // Keys with a public ID prefix, looked up by index
+)
+
+// apiKeyIDSeparator splits the public key ID from the secret part of an API key
+const apiKeyIDSeparator = "."
+
+var apiKeySecret []byte
+
+// InitAPIKeyHasher sets the server-side secret used to hash device API keys
+func InitAPIKeyHasher(secret string) {
+	apiKeySecret = []byte(secret)
+}
+
+// GenerateAPIKey creates a new device API key of the form "<key id>.<secret>"
+// The key ID is public and used to find the stored hash without scanning
+func GenerateAPIKey() (plainKey string, keyID string, err error) {
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", err
+	}
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", err
+	}
+
+	keyID = hex.EncodeToString(idBytes)
+	plainKey = keyID + apiKeyIDSeparator + base64.RawURLEncoding.EncodeToString(secretBytes)
+	return plainKey, keyID, nil
+}
+
+// ParseAPIKeyID returns the public key ID of an API key
+// Returns false for legacy keys, which have no ID prefix
+func ParseAPIKeyID(plainKey string) (string, bool) {
+	keyID, secret, found := strings.Cut(plainKey, apiKeyIDSeparator)
+	if !found || len(keyID) != 16 || secret == "" {
+		return "", false
+	}
+	if _, err := hex.DecodeString(keyID); err != nil {
+		return "", false
+	}
+	return keyID, true
+}
+
+// HashAPIKey computes the keyed hash stored for an API key
+func HashAPIKey(plainKey string) string {
+	mac := hmac.New(sha256.New, apiKeySecret)
+	mac.Write([]byte(plainKey))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CompareAPIKey checks a plain API key against its stored keyed hash in constant time
+func CompareAPIKey(hashedKey, plainKey string) bool {
+	return hmac.Equal([]byte(hashedKey), []byte(HashAPIKey(plainKey)))
+}
@@ -0,0 +1,220 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 page layout for TextPDF, in points
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 50
+	pdfFontSize   = 10
+	pdfLineHeight = 14
+)
+
+// Helvetica and Helvetica-Bold glyph widths of the printable ASCII characters (32-126),
+// in thousandths of the font size, from the standard Adobe font metrics
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// TextPDF builds a simple text-only PDF document, breaking onto new pages as needed
+// Lines wider than the page are wrapped at spaces, or mid-word for words that don't fit on a line
+// Only the standard Helvetica fonts are used, so characters outside ASCII are replaced
+type TextPDF struct {
+	pages [][]pdfLine
+}
+
+type pdfLine struct {
+	text string
+	bold bool
+}
+
+// NewTextPDF creates an empty document
+func NewTextPDF() *TextPDF {
+	return &TextPDF{}
+}
+
+// Heading adds a bold line
+func (p *TextPDF) Heading(text string) {
+	p.add(pdfLine{text: text, bold: true})
+}
+
+// Line adds a regular line
+func (p *TextPDF) Line(text string) {
+	p.add(pdfLine{text: text})
+}
+
+// Blank adds an empty line
+func (p *TextPDF) Blank() {
+	p.add(pdfLine{})
+}
+
+// add appends a line, wrapped to the page width
+func (p *TextPDF) add(line pdfLine) {
+	for _, text := range wrapPDFText(line.text, line.bold) {
+		p.addWrapped(pdfLine{text: text, bold: line.bold})
+	}
+}
+
+// addWrapped appends a line that fits the page width, starting a new page when the current one is full
+func (p *TextPDF) addWrapped(line pdfLine) {
+	perPage := (pdfPageHeight - 2*pdfMargin) / pdfLineHeight
+	if len(p.pages) == 0 || len(p.pages[len(p.pages)-1]) >= perPage {
+		p.pages = append(p.pages, nil)
+	}
+	last := len(p.pages) - 1
+	p.pages[last] = append(p.pages[last], line)
+}
+
+// Bytes renders the document
+func (p *TextPDF) Bytes() []byte {
+	pages := p.pages
+	if len(pages) == 0 {
+		pages = [][]pdfLine{nil}
+	}
+
+	// Objects: 1 catalog, 2 page tree, 3 regular font, 4 bold font, then a page and its content per page
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	)
+	for i, lines := range pages {
+		var content bytes.Buffer
+		y := pdfPageHeight - pdfMargin
+		for _, line := range lines {
+			if line.text != "" {
+				font := "F1"
+				if line.bold {
+					font = "F2"
+				}
+				fmt.Fprintf(&content, "BT /%s %d Tf %d %d Td (%s) Tj ET\n", font, pdfFontSize, pdfMargin, y, pdfEscape(line.text))
+			}
+			y -= pdfLineHeight
+		}
+		fmt.Fprintf(&content, "BT /F1 8 Tf %d %d Td (Page %d of %d) Tj ET\n", pdfMargin, pdfMargin/2, i+1, len(pages))
+
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, 6+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+// pdfEscape escapes a string for a PDF literal, replacing characters the standard fonts can't show
+func pdfEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// wrapPDFText splits text into lines that fit between the page margins
+// An empty text is kept as a single empty line
+func wrapPDFText(text string, bold bool) []string {
+	maxWidth := (pdfPageWidth - 2*pdfMargin) * 1000 / pdfFontSize
+	if pdfTextWidth(text, bold) <= maxWidth {
+		return []string{text}
+	}
+
+	var lines []string
+	var current []rune
+	currentWidth := 0
+	flush := func() {
+		lines = append(lines, strings.TrimRight(string(current), " "))
+		current, currentWidth = nil, 0
+	}
+
+	for _, word := range strings.SplitAfter(text, " ") {
+		wordWidth := pdfTextWidth(strings.TrimRight(word, " "), bold)
+		if currentWidth > 0 && currentWidth+wordWidth > maxWidth {
+			flush()
+		}
+		for _, r := range word {
+			w := pdfRuneWidth(r, bold)
+			// A word wider than a whole line is broken wherever it reaches the margin
+			if r != ' ' && currentWidth > 0 && currentWidth+w > maxWidth {
+				flush()
+			}
+			if r == ' ' && currentWidth == 0 && len(lines) > 0 {
+				continue // Don't start a wrapped line with the space it was wrapped at
+			}
+			current = append(current, r)
+			currentWidth += w
+		}
+	}
+	if len(current) > 0 {
+		flush()
+	}
+	return lines
+}
+
+// pdfTextWidth returns the width of text in thousandths of the font size
+func pdfTextWidth(text string, bold bool) int {
+	width := 0
+	for _, r := range text {
+		width += pdfRuneWidth(r, bold)
+	}
+	return width
+}
+
+// pdfRuneWidth returns the width of a character as rendered by pdfEscape
+func pdfRuneWidth(r rune, bold bool) int {
+	if r < 32 || r > 126 {
+		r = '?'
+	}
+	if bold {
+		return helveticaBoldWidths[r-32]
+	}
+	return helveticaWidths[r-32]
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWrapPDFTextKeepsShortLines(t *testing.T) {
+	for _, text := range []string{"", "Room OT-1 compliance report"} {
+		lines := wrapPDFText(text, false)
+		if len(lines) != 1 || lines[0] != text {
+			t.Errorf("wrapPDFText(%q) = %q, want the text unchanged", text, lines)
+		}
+	}
+}
+
+func TestWrapPDFTextFitsPageWidth(t *testing.T) {
+	maxWidth := (pdfPageWidth - 2*pdfMargin) * 1000 / pdfFontSize
+	text := strings.Repeat("Temperature excursion above the high limit during closure ", 6)
+
+	for _, bold := range []bool{false, true} {
+		lines := wrapPDFText(text, bold)
+		if len(lines) < 2 {
+			t.Fatalf("wrapPDFText(bold=%v) returned %d line, want the text wrapped", bold, len(lines))
+		}
+		for _, line := range lines {
+			if width := pdfTextWidth(line, bold); width > maxWidth {
+				t.Errorf("line %q is %d wide, want at most %d", line, width, maxWidth)
+			}
+			if strings.HasPrefix(line, " ") || strings.HasSuffix(line, " ") {
+				t.Errorf("line %q has a space at the wrap point", line)
+			}
+		}
+		if got := strings.Join(lines, " "); got != strings.TrimSpace(text) {
+			t.Errorf("wrapped words = %q, want %q", got, strings.TrimSpace(text))
+		}
+	}
+}
+
+func TestWrapPDFTextBreaksLongWords(t *testing.T) {
+	maxWidth := (pdfPageWidth - 2*pdfMargin) * 1000 / pdfFontSize
+	word := strings.Repeat("W", 200)
+
+	lines := wrapPDFText("Room: "+word, false)
+	if len(lines) < 3 {
+		t.Fatalf("wrapPDFText returned %d lines, want the long word split", len(lines))
+	}
+	for _, line := range lines {
+		if width := pdfTextWidth(line, false); width > maxWidth {
+			t.Errorf("line %q is %d wide, want at most %d", line, width, maxWidth)
+		}
+	}
+	if got := strings.Join(lines, ""); got != "Room:"+word {
+		t.Errorf("wrapped text = %q, want all characters kept", got)
+	}
+}
+
+func TestTextPDFWrapsLongLinesOntoPages(t *testing.T) {
+	doc := NewTextPDF()
+	doc.Line(strings.Repeat("procedure label ", 100))
+
+	if len(doc.pages) != 1 || len(doc.pages[0]) < 2 {
+		t.Fatalf("got %d pages, first with %d lines, want one page of wrapped lines", len(doc.pages), len(doc.pages[0]))
+	}
+	if out := doc.Bytes(); !bytes.HasPrefix(out, []byte("%PDF-1.4")) {
+		t.Errorf("output does not start with a PDF header")
+	}
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+)
+
+// webhookSecretPrefix marks webhook signing secrets so they are recognizable in receiver config
+const webhookSecretPrefix = "whsec_"
+
+// GenerateWebhookSecret creates a random signing secret for a webhook subscription
+func GenerateWebhookSecret() (string, error) {
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(secretBytes), nil
+}
+
+// SignWebhookPayload computes the signature header value of a webhook request
+// Receivers recompute HMAC-SHA256 over "<timestamp>.<body>" with the shared secret
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}